@@ -916,6 +916,8 @@ CREATE FUNCTION "check_book_addition" () RETURNS opaque AS '
 
 CREATE VIEW "stock_view" as SELECT stock.isbn, stock.retail, stock.stock FROM stock;
 
+CREATE VIEW "expensive_stock" as SELECT stock.isbn, stock.retail FROM stock WHERE stock.retail > 40 ORDER BY stock.retail DESC;
+
 CREATE MATERIALIZED VIEW "m_stock_view" as SELECT stock.isbn, stock.retail, stock.stock FROM stock;
 
 --
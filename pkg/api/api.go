@@ -4,12 +4,17 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	neturl "net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +26,7 @@ import (
 	"github.com/flowbi/pgweb/pkg/command"
 	"github.com/flowbi/pgweb/pkg/connect"
 	"github.com/flowbi/pgweb/pkg/connection"
+	"github.com/flowbi/pgweb/pkg/format"
 	"github.com/flowbi/pgweb/pkg/metrics"
 	"github.com/flowbi/pgweb/pkg/queries"
 	"github.com/flowbi/pgweb/pkg/shared"
@@ -44,19 +50,45 @@ var (
 	MetadataCache *cache.Cache
 )
 
+const (
+	// Maximum number of bookmarks queried in a single multi-query request
+	maxMultiQueryBookmarks = 10
+
+	// Maximum number of bookmarks queried concurrently
+	maxMultiQueryParallelism = 4
+
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// Metadata read at least twice is refreshed within the last minute before expiration
+	metadataRefreshInterval = 15 * time.Second
+	metadataRefreshWindow   = time.Minute
+	metadataRefreshMinReads = 2
+)
+
 var (
 	// Regex to identify SELECT queries that are safe to cache
 	selectQueryRegex = regexp.MustCompile(`(?i)^\s*SELECT\s+`)
+
+	// Regex to extract the target table of data modifying statements
+	mutationQueryRegex = regexp.MustCompile(`(?is)^\s*(?:WITH\s.*?\)\s*)?(?:INSERT\s+INTO|UPDATE(?:\s+ONLY)?|DELETE\s+FROM(?:\s+ONLY)?|TRUNCATE(?:\s+TABLE)?(?:\s+ONLY)?|ALTER\s+TABLE(?:\s+IF\s+EXISTS)?(?:\s+ONLY)?|DROP\s+TABLE(?:\s+IF\s+EXISTS)?|MERGE\s+INTO|COPY)\s+(?:(?:"[^"]+"|[\w$]+)\.)?("[^"]+"|[\w$]+)`)
 )
 
 func InitializeCaches() {
+	IdempotencyCache = cache.NewWithMaxItems(idempotencyKeyTTL, idempotencyMaxKeys)
+
 	if !command.Opts.DisableQueryCache {
 		// Use memory-based cache limiting (50MB default) for better resource control
 		// This handles both large result sets and many small queries
 		QueryCache = cache.NewWithMemoryLimit(time.Duration(command.Opts.QueryCacheTTL)*time.Second, 50)
+		metrics.RegisterCache("query", QueryCache.HitsAndMisses)
 	}
 	if !command.Opts.DisableMetadataCache {
 		MetadataCache = cache.New(time.Duration(command.Opts.MetadataCacheTTL) * time.Second)
+		metrics.RegisterCache("metadata", MetadataCache.HitsAndMisses)
+
+		if command.Opts.MetadataCacheRefresh {
+			MetadataCache.StartRefresher(metadataRefreshInterval, metadataRefreshWindow, metadataRefreshMinReads)
+		}
 	}
 }
 
@@ -76,6 +108,7 @@ func setClient(c *gin.Context, newClient *client.Client) error {
 	}
 
 	if !command.Opts.Sessions {
+		openHistory(newClient, "")
 		DbClient = newClient
 		return nil
 	}
@@ -85,10 +118,22 @@ func setClient(c *gin.Context, newClient *client.Client) error {
 		return errSessionRequired
 	}
 
-	DbSessions.Add(sid, newClient)
+	if err := DbSessions.Add(sid, newClient); err != nil {
+		return err
+	}
+
+	openHistory(newClient, sid)
 	return nil
 }
 
+// openHistory loads the persisted query history of the session into the client.
+// The client can be used without it, failures are only logged.
+func openHistory(cl *client.Client, sid string) {
+	if err := cl.OpenHistory(sid); err != nil {
+		logger.WithError(err).Warn("can't load query history")
+	}
+}
+
 // GetHome renders the home page
 func GetHome(prefix string) http.Handler {
 	if prefix != "" {
@@ -152,6 +197,19 @@ func ConnectWithBackend(c *gin.Context) {
 	}
 	cl.External = true
 
+	// Re-fetch credentials from the backend when they expire or get rotated
+	resource, headers := c.Param("resource"), c.Request.Header.Clone()
+	cl.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+		defer cancel()
+
+		cred, err := backend.FetchCredential(ctx, resource, headers)
+		if err != nil {
+			return "", err
+		}
+		return cred.DatabaseURL, nil
+	})
+
 	// Finalize session seetup
 	_, err = cl.Info()
 	if err == nil {
@@ -271,14 +329,15 @@ func SwitchDb(c *gin.Context) {
 		return
 	}
 
-	currentURL, err := neturl.Parse(conn.ConnectionString)
+	connStr, sshInfo := conn.ConnectionTarget()
+	currentURL, err := neturl.Parse(connStr)
 	if err != nil {
 		badRequest(c, errInvalidConnString)
 		return
 	}
 	currentURL.Path = name
 
-	cl, err := client.NewFromUrl(currentURL.String(), nil)
+	cl, err := client.NewFromUrl(currentURL.String(), sshInfo)
 	if err != nil {
 		badRequest(c, err)
 		return
@@ -334,6 +393,43 @@ func Disconnect(c *gin.Context) {
 	successResponse(c, gin.H{"success": true})
 }
 
+// DisconnectAll closes every database connection while keeping the server running.
+// Clients have to reconnect before running any further queries.
+func DisconnectAll(c *gin.Context) {
+	sessions := 0
+	if DbSessions != nil {
+		sessions = DbSessions.RemoveAll()
+	}
+
+	closedClient := DbClient != nil
+	if closedClient {
+		DbClient.Close()
+		DbClient = nil
+	}
+
+	logger.
+		WithField("sessions", sessions).
+		WithField("client", closedClient).
+		WithField("remote_addr", c.ClientIP()).
+		Warn("closed all database connections")
+
+	successResponse(c, gin.H{
+		"success":  true,
+		"sessions": sessions,
+		"client":   closedClient,
+	})
+}
+
+// PingSession keeps the current session from being closed as idle
+func PingSession(c *gin.Context) {
+	conn := DB(c)
+	conn.Touch()
+
+	successResponse(c, gin.H{
+		"last_query_time": conn.LastQueryTime(),
+	})
+}
+
 // RunQuery executes the query
 func RunQuery(c *gin.Context) {
 	query := cleanQuery(c.Request.FormValue("query"))
@@ -358,6 +454,35 @@ func ExplainQuery(c *gin.Context) {
 	HandleQuery(fmt.Sprintf("EXPLAIN %s", query), c)
 }
 
+// ExplainPlan renders the query plan as a tree of nodes. The query is executed
+// when the analyze parameter is set. With the generic parameter the plan of the
+// query prepared as a statement is rendered, regardless of parameter values.
+func ExplainPlan(c *gin.Context) {
+	query := cleanQuery(c.Request.FormValue("query"))
+
+	if query == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	analyze := c.Request.FormValue("analyze") == "true"
+	generic := c.Request.FormValue("generic") == "true"
+
+	if generic {
+		if analyze {
+			badRequest(c, errGenericPlanAnalyze)
+			return
+		}
+
+		res, err := DB(c).ExplainGenericPlanContext(c.Request.Context(), query)
+		serveResult(c, res, err)
+		return
+	}
+
+	res, err := DB(c).ExplainQueryContext(c.Request.Context(), query, analyze)
+	serveResult(c, res, err)
+}
+
 // AnalyzeQuery renders query explain plan and analyze profile
 func AnalyzeQuery(c *gin.Context) {
 	query := cleanQuery(c.Request.FormValue("query"))
@@ -370,6 +495,235 @@ func AnalyzeQuery(c *gin.Context) {
 	HandleQuery(fmt.Sprintf("EXPLAIN ANALYZE %s", query), c)
 }
 
+// StreamQuery runs the query and streams its result as newline-delimited JSON.
+// Errors raised after the output has started are sent as the last line.
+func StreamQuery(c *gin.Context) {
+	query := cleanQuery(c.Request.FormValue("query"))
+
+	if query == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	w := &ndjsonWriter{c: c}
+	err := DB(c).QueryStreamContext(c.Request.Context(), query, w)
+	if err == nil {
+		return
+	}
+
+	// Once the output has started the error can only be reported as the last line,
+	// along with the number of rows sent so that partial results can be told apart
+	if w.started {
+		count := 0
+		var partial *client.PartialStreamError
+		if errors.As(err, &partial) {
+			count = partial.RowsCount
+		}
+
+		json.NewEncoder(c.Writer).Encode(gin.H{"error": err.Error(), "rows_count": count}) //nolint:errcheck
+		return
+	}
+	badRequest(c, err)
+}
+
+// ndjsonWriter sends the response headers on the first write, so errors raised
+// before any output is produced can still be rendered as a regular response
+type ndjsonWriter struct {
+	c       *gin.Context
+	started bool
+}
+
+func (w *ndjsonWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "application/x-ndjson")
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+func (w *ndjsonWriter) Flush() {
+	w.c.Writer.Flush()
+}
+
+// LintQuery renders advisory warnings about the query
+func LintQuery(c *gin.Context) {
+	query := c.Request.FormValue("query")
+
+	if strings.TrimSpace(query) == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	successResponse(c, DB(c).LintQuery(query))
+}
+
+// CancelQuery aborts the query currently running on the connection
+func CancelQuery(c *gin.Context) {
+	err := DB(c).CancelQuery()
+	serveResult(c, gin.H{"success": true}, err)
+}
+
+// FormatQuery renders the pretty-printed query
+func FormatQuery(c *gin.Context) {
+	query := c.Request.FormValue("query")
+
+	if strings.TrimSpace(query) == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	formatted, err := format.Format(query)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	successResponse(c, gin.H{"query": formatted})
+}
+
+// BeginTransaction opens a transaction for the current connection
+func BeginTransaction(c *gin.Context) {
+	err := DB(c).BeginTransactionContext(c.Request.Context())
+	serveTransactionState(c, err)
+}
+
+// CommitTransaction commits the open transaction
+func CommitTransaction(c *gin.Context) {
+	err := DB(c).CommitTransaction()
+	serveTransactionState(c, err)
+}
+
+// RollbackTransaction aborts the open transaction
+func RollbackTransaction(c *gin.Context) {
+	err := DB(c).RollbackTransaction()
+	serveTransactionState(c, err)
+}
+
+// GetTransaction renders the transaction state and savepoint stack
+func GetTransaction(c *gin.Context) {
+	serveTransactionState(c, nil)
+}
+
+// CreateSavepoint creates a named savepoint in the open transaction
+func CreateSavepoint(c *gin.Context) {
+	handleSavepoint(c, DB(c).Savepoint)
+}
+
+// ReleaseSavepoint releases a named savepoint in the open transaction
+func ReleaseSavepoint(c *gin.Context) {
+	handleSavepoint(c, DB(c).ReleaseSavepoint)
+}
+
+// RollbackToSavepoint rolls back the open transaction to a named savepoint
+func RollbackToSavepoint(c *gin.Context) {
+	handleSavepoint(c, DB(c).RollbackToSavepoint)
+}
+
+func handleSavepoint(c *gin.Context, fn func(string) error) {
+	name := strings.TrimSpace(c.Request.FormValue("name"))
+	if name == "" {
+		badRequest(c, errSavepointNameRequired)
+		return
+	}
+
+	serveTransactionState(c, fn(name))
+}
+
+func serveTransactionState(c *gin.Context, err error) {
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	conn := DB(c)
+	successResponse(c, gin.H{
+		"active":     conn.InTransaction(),
+		"savepoints": conn.Savepoints(),
+	})
+}
+
+// RunScript executes the statements of the query on a single connection and
+// renders the result of each one
+func RunScript(c *gin.Context) {
+	query := cleanQuery(c.Request.FormValue("query"))
+	if query == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	metrics.IncrementQueriesCount()
+
+	conn := DB(c)
+	res, err := conn.QueryScript(c.Request.Context(), query)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	// Make sure subsequent reads of the modified tables are not served from cache
+	if QueryCache != nil {
+		for _, stmt := range res {
+			if table := mutatedTable(stmt.Query); table != "" && stmt.Error == "" {
+				invalidateQueryCache(conn.ConnectionString, table)
+			}
+		}
+	}
+
+	successResponse(c, res)
+}
+
+// RunBatch executes several independent queries and renders all their results
+func RunBatch(c *gin.Context) {
+	queries := []client.BatchQuery{}
+	if err := c.ShouldBindJSON(&queries); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	parallel, err := parseIntFormValue(c, "parallel", 1)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	for i := range queries {
+		queries[i].Query = cleanQuery(queries[i].Query)
+	}
+
+	metrics.IncrementQueriesCount()
+
+	res, err := DB(c).Batch(c.Request.Context(), queries, parallel)
+	serveResult(c, res, err)
+}
+
+// CrosstabQuery renders a pivoted result of the source query
+func CrosstabQuery(c *gin.Context) {
+	query := cleanQuery(c.Request.FormValue("query"))
+	if query == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	aggs, err := parseAggregations(c.Request.FormValue("aggregate"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+	if len(aggs) != 1 {
+		badRequest(c, "exactly one aggregate is required")
+		return
+	}
+
+	res, err := DB(c).Crosstab(client.CrosstabOptions{
+		Query:    query,
+		RowKey:   c.Request.FormValue("row_key"),
+		Category: c.Request.FormValue("category"),
+		Value:    aggs[0],
+	})
+	serveResult(c, res, err)
+}
+
 // GetDatabases renders a list of all databases on the server
 func GetDatabases(c *gin.Context) {
 	if command.Opts.LockSession {
@@ -388,7 +742,42 @@ func GetDatabases(c *gin.Context) {
 
 // GetObjects renders a list of database objects
 func GetObjects(c *gin.Context) {
-	result, err := DB(c).Objects()
+	limit, err := parseIntFormValue(c, "limit", 0)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	offset, err := parseIntFormValue(c, "offset", 0)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	opts := client.ObjectsOptions{
+		Schema: c.Request.FormValue("schema"),
+		Name:   c.Request.FormValue("name"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	result, err := DB(c).ObjectsWithOptions(c.Request.Context(), opts)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+	successResponse(c, client.ObjectsFromResult(result))
+}
+
+// SearchObjects renders the objects with the search term in their name or comment
+func SearchObjects(c *gin.Context) {
+	res, err := DB(c).SearchObjectsContext(c.Request.Context(), c.Request.FormValue("q"))
+	serveResult(c, res, err)
+}
+
+// GetSchemaObjects renders a list of objects in a single schema
+func GetSchemaObjects(c *gin.Context) {
+	result, err := DB(c).SchemaObjectsContext(c.Request.Context(), c.Params.ByName("schema"))
 	if err != nil {
 		badRequest(c, err)
 		return
@@ -396,6 +785,23 @@ func GetObjects(c *gin.Context) {
 	successResponse(c, client.ObjectsFromResult(result))
 }
 
+// GetRecentObjects renders the list of recently opened objects, most recent first
+func GetRecentObjects(c *gin.Context) {
+	successResponse(c, DB(c).GetRecentObjects())
+}
+
+// GetUnindexedForeignKeys renders the foreign keys of the schema missing an index
+func GetUnindexedForeignKeys(c *gin.Context) {
+	res, err := DB(c).UnindexedForeignKeys(c.Params.ByName("schema"))
+	serveResult(c, res, err)
+}
+
+// GetSchemaSummary renders object counts and sizes for each schema
+func GetSchemaSummary(c *gin.Context) {
+	res, err := DB(c).SchemaSummary()
+	serveResult(c, res, err)
+}
+
 // GetSchemas renders list of available schemas
 func GetSchemas(c *gin.Context) {
 	res, err := DB(c).Schemas()
@@ -413,12 +819,19 @@ func GetTable(c *gin.Context) {
 	tableName := c.Params.ByName("table")
 
 	switch c.Request.FormValue("type") {
+	case client.ObjTypeView:
+		res, err = db.View(tableName)
 	case client.ObjTypeMaterializedView:
 		res, err = db.MaterializedView(tableName)
 	case client.ObjTypeFunction:
 		res, err = db.Function(tableName)
 	default:
-		res, err = db.Table(tableName)
+		res, err = db.TableContext(c.Request.Context(), tableName)
+	}
+
+	// Tables and views are tracked once opened, functions are opened by their OID
+	if err == nil && c.Request.FormValue("type") != client.ObjTypeFunction {
+		db.TrackRecentObject(tableName)
 	}
 
 	serveResult(c, res, err)
@@ -432,18 +845,20 @@ func GetTableRows(c *gin.Context) {
 		return
 	}
 
-	limit, err := parseIntFormValue(c, "limit", 100)
-	if err != nil {
-		badRequest(c, err)
+	// Zero limit uses the default rows limit and -1 fetches all the rows
+	limit, err := parseIntFormValue(c, "limit", 0)
+	if err != nil || limit < client.RowsUnlimited {
+		badRequest(c, errInvalidRowsLimit)
 		return
 	}
 
 	opts := client.RowsOptions{
-		Limit:      limit,
-		Offset:     offset,
-		SortColumn: c.Request.FormValue("sort_column"),
-		SortOrder:  c.Request.FormValue("sort_order"),
-		Where:      c.Request.FormValue("where"),
+		Limit:       limit,
+		Offset:      offset,
+		SortColumn:  c.Request.FormValue("sort_column"),
+		SortOrder:   c.Request.FormValue("sort_order"),
+		SortColumns: parseSortParams(c.Request.URL.Query()["sort"]),
+		Where:       c.Request.FormValue("where"),
 	}
 
 	res, err := DB(c).TableRows(c.Params.ByName("table"), opts)
@@ -451,6 +866,14 @@ func GetTableRows(c *gin.Context) {
 		badRequest(c, err)
 		return
 	}
+	DB(c).TrackRecentObject(c.Params.ByName("table"))
+
+	if c.Request.FormValue("format") == "csv" {
+		filename := attachmentFilename(c, c.Params.ByName("table"), "csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		serveCSV(c, res)
+		return
+	}
 
 	countRes, err := DB(c).TableRowsCount(c.Params.ByName("table"), opts)
 	if err != nil {
@@ -458,9 +881,21 @@ func GetTableRows(c *gin.Context) {
 		return
 	}
 
-	numFetch := int64(opts.Limit)
+	numFetch := int64(client.RowsLimit(opts.Limit))
 	numOffset := int64(opts.Offset)
-	numRows := countRes.Rows[0][0].(int64)
+	numRows, err := client.Int64Value(countRes.Rows[0][0])
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	// All the rows are on a single page
+	if numFetch == 0 {
+		numFetch = int64(len(res.Rows))
+		if numFetch == 0 {
+			numFetch = 1
+		}
+	}
 
 	// Handle foreign tables where count is -1 (unknown)
 	if numRows == -1 {
@@ -487,13 +922,68 @@ func GetTableRows(c *gin.Context) {
 		}
 	}
 
+	serveResult(c, res.TruncateColumns(command.Opts.MaxColumns), err)
+}
+
+// GetTableGroupBy renders a grouped summary of the table rows
+func GetTableGroupBy(c *gin.Context) {
+	aggs, err := parseAggregations(c.Request.FormValue("aggregate"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	groupCols := splitList(c.Request.FormValue("group_by"))
+
+	res, err := DB(c).GroupBy(c.Params.ByName("table"), groupCols, aggs)
+	serveResult(c, res, err)
+}
+
+// GetColumnHistogram renders the distribution of the table column values
+func GetColumnHistogram(c *gin.Context) {
+	buckets, err := parseIntFormValue(c, "buckets", 10)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	res, err := DB(c).ColumnHistogram(c.Params.ByName("table"), c.Request.FormValue("column"), buckets)
+	serveResult(c, res, err)
+}
+
+// GetTableStorageParams renders storage parameters of the table
+func GetTableStorageParams(c *gin.Context) {
+	res, err := DB(c).TableStorageParams(c.Params.ByName("table"))
+	serveResult(c, res, err)
+}
+
+// SetTableStorageParam changes a storage parameter of the table
+func SetTableStorageParam(c *gin.Context) {
+	res, err := DB(c).SetStorageParam(
+		c.Params.ByName("table"),
+		c.Request.FormValue("key"),
+		c.Request.FormValue("value"),
+	)
+	serveResult(c, res, err)
+}
+
+// SetColumnStatistics changes the statistics target of a table column
+func SetColumnStatistics(c *gin.Context) {
+	target, err := strconv.Atoi(c.Request.FormValue("target"))
+	if err != nil {
+		badRequest(c, "target must be a number")
+		return
+	}
+
+	res, err := DB(c).SetColumnStatistics(c.Params.ByName("table"), c.Request.FormValue("column"), target)
 	serveResult(c, res, err)
 }
 
 // GetTableInfo renders a selected table information
 func GetTableInfo(c *gin.Context) {
-	res, err := DB(c).TableInfo(c.Params.ByName("table"))
+	res, err := DB(c).TableInfoContext(c.Request.Context(), c.Params.ByName("table"))
 	if err == nil {
+		DB(c).TrackRecentObject(c.Params.ByName("table"))
 		successResponse(c, res.Format()[0])
 	} else {
 		badRequest(c, err)
@@ -522,6 +1012,7 @@ func GetConnectionInfo(c *gin.Context) {
 
 	info := res.Format()[0]
 	info["session_lock"] = command.Opts.LockSession
+	info["banner"] = banner()
 
 	successResponse(c, info)
 }
@@ -538,18 +1029,111 @@ func GetActivity(c *gin.Context) {
 	serveResult(c, res, err)
 }
 
+// TerminateBackend terminates the server process with the pid
+func TerminateBackend(c *gin.Context) {
+	pid, err := parseIntFormValue(c, "pid", 0)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	terminated, err := DB(c).TerminateBackend(pid)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	successResponse(c, gin.H{"pid": pid, "terminated": terminated})
+}
+
+// TerminateIdleBackends terminates the server processes idle for the given number of minutes
+func TerminateIdleBackends(c *gin.Context) {
+	minutes, err := parseIntFormValue(c, "minutes", 0)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	res, err := DB(c).TerminateIdleBackends(minutes)
+	serveResult(c, res, err)
+}
+
+// GetTopStatements renders the most expensive queries from pg_stat_statements
+func GetTopStatements(c *gin.Context) {
+	limit, err := parseIntFormValue(c, "limit", 20)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	res, err := DB(c).TopStatements(c.Request.FormValue("order_by"), limit)
+	serveResult(c, res, err)
+}
+
+// ResetStats resets pg_stat_statements or database statistics counters
+func ResetStats(c *gin.Context) {
+	kind := c.Request.FormValue("kind")
+
+	if err := DB(c).ResetStats(kind); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	successResponse(c, gin.H{"success": true, "kind": kind})
+}
+
 // GetTableIndexes renders a list of database table indexes
 func GetTableIndexes(c *gin.Context) {
-	res, err := DB(c).TableIndexes(c.Params.ByName("table"))
+	res, err := DB(c).TableIndexesContext(c.Request.Context(), c.Params.ByName("table"))
 	serveResult(c, res, err)
 }
 
 // GetTableConstraints renders a list of database constraints
 func GetTableConstraints(c *gin.Context) {
-	res, err := DB(c).TableConstraints(c.Params.ByName("table"))
+	res, err := DB(c).TableConstraintsContext(c.Request.Context(), c.Params.ByName("table"))
+	serveResult(c, res, err)
+}
+
+// GetTableTriggers renders a list of database table triggers
+func GetTableTriggers(c *gin.Context) {
+	res, err := DB(c).TableTriggersContext(c.Request.Context(), c.Params.ByName("table"))
 	serveResult(c, res, err)
 }
 
+// GetTablePolicies renders the row level security policies of a database table
+func GetTablePolicies(c *gin.Context) {
+	res, err := DB(c).TablePoliciesContext(c.Request.Context(), c.Params.ByName("table"))
+	serveResult(c, res, err)
+}
+
+// GetTablePartitions renders the partitions hierarchy of a partitioned table
+func GetTablePartitions(c *gin.Context) {
+	res, err := DB(c).TablePartitionsContext(c.Request.Context(), c.Params.ByName("table"))
+	serveResult(c, res, err)
+}
+
+// GetTableColumnStats renders the planner statistics of the table columns
+func GetTableColumnStats(c *gin.Context) {
+	res, err := DB(c).ColumnStatsContext(c.Request.Context(), c.Params.ByName("table"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	// Statistics are only collected by ANALYZE, so tell why there are none
+	if res != nil && len(res.Rows) == 0 {
+		successResponse(c, gin.H{
+			"columns":  res.Columns,
+			"rows":     res.Rows,
+			"analyzed": false,
+			"message":  "No column statistics found, run ANALYZE on the table to collect them",
+		})
+		return
+	}
+
+	serveResult(c, res, nil)
+}
+
 // GetTablesStats renders data sizes and estimated rows for all tables in the database
 func GetTablesStats(c *gin.Context) {
 	db := DB(c)
@@ -584,6 +1168,8 @@ func GetTablesStats(c *gin.Context) {
 		c.JSON(http.StatusOK, res)
 	case "csv":
 		c.Data(http.StatusOK, "text/csv", res.CSV())
+	case "tsv":
+		c.Data(http.StatusOK, "text/tab-separated-values", res.TSV())
 	case "xml":
 		c.XML(200, res)
 	default:
@@ -593,30 +1179,59 @@ func GetTablesStats(c *gin.Context) {
 
 // CachedResponse represents a cached final response
 type CachedResponse struct {
-	Result *client.Result `json:"result"`
-	Format string         `json:"format"`
+	Result           *client.Result `json:"result"`
+	Format           string         `json:"format"`
+	Query            string         `json:"-"`
+	ConnectionString string         `json:"-"`
 }
 
 // handleFormatResponse serves the result in the requested format
 func handleFormatResponse(c *gin.Context, result *client.Result, format string) {
-	filename := getQueryParam(c, "filename")
-	if filename == "" {
-		filename = fmt.Sprintf("pgweb-%v.%v", time.Now().Unix(), format)
-	}
-
 	if format != "" {
-		c.Writer.Header().Set("Content-disposition", "attachment;filename="+filename)
+		filename := attachmentFilename(c, fmt.Sprintf("pgweb-%v", time.Now().Unix()), format)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
 	}
 
 	switch format {
 	case "csv":
-		c.Data(200, "text/csv", result.CSV())
+		serveCSV(c, result)
+	case "tsv":
+		c.Data(200, "text/tab-separated-values", result.TSV())
+	case "xlsx":
+		serveXLSX(c, result)
 	case "json":
 		c.Data(200, "application/json", result.JSON())
 	case "xml":
 		c.XML(200, result)
 	default:
-		c.JSON(200, result)
+		c.JSON(200, result.TruncateColumns(command.Opts.MaxColumns))
+	}
+}
+
+// serveCSV streams the result as CSV
+func serveCSV(c *gin.Context, result *client.Result) {
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	if err := result.WriteCSV(c.Writer); err != nil {
+		logger.WithError(err).Error("csv export failed")
+	}
+}
+
+// serveXLSX streams the result as an Excel workbook
+func serveXLSX(c *gin.Context, result *client.Result) {
+	c.Header("Content-Type", xlsxContentType)
+	c.Status(http.StatusOK)
+
+	if err := result.WriteXLSX(c.Writer); err != nil {
+		if !c.Writer.Written() {
+			// Nothing was sent yet, so the error can still be reported
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			badRequest(c, err)
+			return
+		}
+		logger.WithError(err).Error("xlsx export failed")
 	}
 }
 
@@ -628,6 +1243,38 @@ func generateQueryCacheKey(query, connectionString, role string) string {
 }
 
 // isCacheableQuery checks if a query is safe to cache
+// mutatedTable returns the unqualified name of the table modified by the query, if any
+func mutatedTable(query string) string {
+	match := mutationQueryRegex.FindStringSubmatch(query)
+	if match == nil {
+		return ""
+	}
+
+	name := match[1]
+	if strings.HasPrefix(name, `"`) {
+		return strings.Trim(name, `"`)
+	}
+	return strings.ToLower(name)
+}
+
+// invalidateQueryCache removes cached results of queries on the connection referencing the table
+func invalidateQueryCache(connectionString, table string) int {
+	pattern, err := regexp.Compile(`(?i)(^|[^\w$])"?` + regexp.QuoteMeta(table) + `"?($|[^\w$])`)
+	if err != nil {
+		return 0
+	}
+
+	removed := QueryCache.DeleteFunc(func(key string, value interface{}) bool {
+		cached, ok := value.(*CachedResponse)
+		return ok && cached.ConnectionString == connectionString && pattern.MatchString(cached.Query)
+	})
+
+	if command.Opts.Debug && removed > 0 {
+		fmt.Printf("[CACHE] Invalidated %d cached queries referencing table %s\n", removed, table)
+	}
+	return removed
+}
+
 func isCacheableQuery(query string) bool {
 	trimmed := strings.TrimSpace(query)
 	return selectQueryRegex.MatchString(trimmed) &&
@@ -638,8 +1285,6 @@ func isCacheableQuery(query string) bool {
 
 // HandleQuery runs the database query
 func HandleQuery(query string, c *gin.Context) {
-	metrics.IncrementQueriesCount()
-
 	// Only attempt base64 decoding for GET requests (URL parameters)
 	// POST requests have plain text queries in form data
 	if c.Request.Method == "GET" {
@@ -653,6 +1298,58 @@ func HandleQuery(query string, c *gin.Context) {
 		}
 	}
 
+	params, err := parseQueryParams(c.Request.FormValue("params"))
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	handleQueryWithParams(query, params, c)
+}
+
+// RunParameterizedQuery runs the query with the @name placeholders bound to the
+// values of the JSON request body
+func RunParameterizedQuery(c *gin.Context) {
+	body := struct {
+		Query  string                 `json:"query"`
+		Params map[string]interface{} `json:"params"`
+	}{}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		badRequest(c, errInvalidNamedParams)
+		return
+	}
+
+	query := cleanQuery(body.Query)
+	if query == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	for name, value := range body.Params {
+		param, err := normalizeQueryParam(value)
+		if err != nil {
+			badRequest(c, errInvalidNamedParams)
+			return
+		}
+		body.Params[name] = param
+	}
+
+	query, params, err := client.BindNamedParams(query, body.Params)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	handleQueryWithParams(query, params, c)
+}
+
+// handleQueryWithParams runs the query with the $N placeholders bound to params
+func handleQueryWithParams(query string, params []interface{}, c *gin.Context) {
+	metrics.IncrementQueriesCount()
+
 	// Check cache for SELECT queries
 	conn := DB(c)
 	if conn == nil {
@@ -660,10 +1357,18 @@ func HandleQuery(query string, c *gin.Context) {
 		return
 	}
 
+	// Cache keys do not include the query parameters
+	cacheable := len(params) == 0 && isCacheableQuery(query)
+
 	format := getQueryParam(c, "format")
 
+	// Sessions bypassing the cache neither read nor populate it
+	if conn.CacheBypassed() {
+		cacheable = false
+	}
+
 	// Check cache first
-	if !command.Opts.DisableQueryCache && QueryCache != nil && isCacheableQuery(query) {
+	if !command.Opts.DisableQueryCache && QueryCache != nil && cacheable {
 		cacheKey := generateQueryCacheKey(query, conn.ConnectionString, conn.GetRole())
 		if cached, found := QueryCache.Get(cacheKey); found {
 			// Return cached final response (already processed)
@@ -678,8 +1383,8 @@ func HandleQuery(query string, c *gin.Context) {
 				cachedResp.Result.Stats.QueryFinishTime = cacheTime.UTC()
 				cachedResp.Result.Stats.QueryDuration = 1 // 1ms for cache hit
 
-				// Serve cached result with proper format handling
-				handleFormatResponse(c, cachedResp.Result, cachedResp.Format)
+				// Serve cached result in the format of the current request
+				handleFormatResponse(c, cachedResp.Result, format)
 				return
 			} else {
 				if command.Opts.Debug {
@@ -690,21 +1395,31 @@ func HandleQuery(query string, c *gin.Context) {
 	}
 
 	// Execute query
-	result, err := conn.Query(query)
+	result, err := conn.QueryWithParamsContext(c.Request.Context(), query, params)
 	if err != nil {
+		metrics.IncrementQueryErrors(err)
 		badRequest(c, err)
 		return
 	}
 
+	// Make sure subsequent reads of the modified table are not served from cache
+	if QueryCache != nil {
+		if table := mutatedTable(query); table != "" {
+			invalidateQueryCache(conn.ConnectionString, table)
+		}
+	}
+
 	// Post-process the result
 	result.PostProcess()
 
 	// Cache the final processed result
-	if !command.Opts.DisableQueryCache && QueryCache != nil && isCacheableQuery(query) && len(result.Rows) <= 10000 {
+	if !command.Opts.DisableQueryCache && QueryCache != nil && cacheable && len(result.Rows) <= 10000 {
 		cacheKey := generateQueryCacheKey(query, conn.ConnectionString, conn.GetRole())
 		cachedResp := &CachedResponse{
-			Result: result,
-			Format: format,
+			Result:           result,
+			Format:           format,
+			Query:            query,
+			ConnectionString: conn.ConnectionString,
 		}
 		QueryCache.Set(cacheKey, cachedResp, time.Duration(command.Opts.QueryCacheTTL)*time.Second)
 		if command.Opts.Debug {
@@ -717,6 +1432,75 @@ func HandleQuery(query string, c *gin.Context) {
 	handleFormatResponse(c, result, format)
 }
 
+// RunMultiQuery executes the query against each of the listed bookmarks
+func RunMultiQuery(c *gin.Context) {
+	if command.Opts.LockSession {
+		badRequest(c, errSessionLocked)
+		return
+	}
+
+	query := cleanQuery(c.Request.FormValue("query"))
+	if query == "" {
+		badRequest(c, errQueryRequired)
+		return
+	}
+
+	ids := splitList(c.Request.FormValue("bookmarks"))
+	if len(ids) == 0 {
+		badRequest(c, errBookmarksRequired)
+		return
+	}
+	if len(ids) > maxMultiQueryBookmarks {
+		badRequest(c, fmt.Errorf("at most %d bookmarks are allowed", maxMultiQueryBookmarks))
+		return
+	}
+
+	metrics.IncrementQueriesCount()
+	successResponse(c, runMultiQuery(c.Request.Context(), ids, query, ConnectWithBookmark))
+}
+
+// runMultiQuery runs the query on a separate connection for each bookmark, with bounded
+// concurrency. The queries run with the values of the request context, ie. read-only mode.
+func runMultiQuery(ctx context.Context, ids []string, query string, connect func(string) (*client.Client, error)) []multiQueryResult {
+	results := make([]multiQueryResult, len(ids))
+	sem := make(chan struct{}, maxMultiQueryParallelism)
+	wg := sync.WaitGroup{}
+
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i] = multiQueryResult{Bookmark: id}
+
+			conn, err := connect(id)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			defer conn.Close()
+
+			res, err := conn.QueryContext(ctx, query)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			if res != nil {
+				res.PostProcess()
+			}
+			results[i].Result = res
+		}(i, id)
+	}
+	wg.Wait()
+
+	return results
+}
+
 // GetBookmarks renders the list of available bookmarks
 func GetBookmarks(c *gin.Context) {
 	manager := bookmarks.NewManager(command.Opts.BookmarksDir)
@@ -729,14 +1513,33 @@ func GetInfo(c *gin.Context) {
 	successResponse(c, gin.H{
 		"app": command.Info,
 		"features": gin.H{
-			"session_lock":   command.Opts.LockSession,
-			"query_timeout":  command.Opts.QueryTimeout,
-			"local_queries":  QueryStore != nil,
-			"bookmarks_only": command.Opts.BookmarksOnly,
+			"session_lock":      command.Opts.LockSession,
+			"query_timeout":     command.Opts.QueryTimeout,
+			"max_query_timeout": command.Opts.MaxQueryTimeout,
+			"local_queries":     QueryStore != nil,
+			"bookmarks_only":    command.Opts.BookmarksOnly,
 		},
+		"banner": banner(),
 	})
 }
 
+// banner returns the message configured to be displayed to all users, if any
+func banner() gin.H {
+	if command.Opts.BannerText == "" {
+		return nil
+	}
+
+	return gin.H{
+		"text":  command.Opts.BannerText,
+		"level": command.Opts.BannerLevel,
+	}
+}
+
+// GetAdminConfig returns the effective options with the secrets redacted
+func GetAdminConfig(c *gin.Context) {
+	successResponse(c, command.RedactedOptions(command.Opts))
+}
+
 // GetConfig returns client configuration including custom parameter patterns and font settings
 func GetConfig(c *gin.Context) {
 	// Get custom parameter patterns from environment variable
@@ -780,6 +1583,11 @@ func DataExport(c *gin.Context) {
 		return
 	}
 
+	if c.Request.FormValue("format") == "csv" {
+		copyExport(c, db, info)
+		return
+	}
+
 	dump := client.Dump{
 		Table: strings.TrimSpace(c.Request.FormValue("table")),
 	}
@@ -812,12 +1620,123 @@ func DataExport(c *gin.Context) {
 	}
 }
 
+// copyExport streams table data in CSV format using COPY TO STDOUT
+func copyExport(c *gin.Context, db *client.Client, info *client.Result) {
+	export := client.CopyExport{
+		Table:            strings.TrimSpace(c.Request.FormValue("table")),
+		Gzip:             c.Request.FormValue("gzip") == "true",
+		StatementTimeout: db.QueryTimeout(c.Request.Context()),
+	}
+
+	rangeHeader := c.GetHeader("Range")
+	if rangeHeader != "" {
+		var err error
+		export.Offset, export.Limit, err = parseByteRange(rangeHeader)
+		if err != nil {
+			errorResponse(c, http.StatusRequestedRangeNotSatisfiable, err)
+			return
+		}
+
+		// Order rows by the primary key so that the byte offsets of the output
+		// stay the same between requests
+		export.OrderBy, err = db.TablePrimaryKey(c.Request.Context(), export.Table)
+		if err != nil {
+			badRequest(c, err)
+			return
+		}
+		if len(export.OrderBy) == 0 {
+			badRequest(c, client.ErrExportNotResumable)
+			return
+		}
+	}
+
+	if err := export.Validate(); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	filename := info.Format()[0]["current_database"].(string) + "_" + export.Table
+	filename = sanitizeFilename(filename)
+	filename = fmt.Sprintf("%s_%s", filename, time.Now().Format("20060102_150405"))
+
+	c.Header("Content-Type", "text/csv")
+	c.Header(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s.csv"`, filename),
+	)
+	if export.Gzip {
+		c.Header("Content-Encoding", "gzip")
+	}
+	if rangeHeader != "" {
+		c.Header("Accept-Ranges", "bytes")
+		// The total size is not known until the export completes
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/*", export.Offset, export.Offset+export.Limit-1))
+		c.Status(http.StatusPartialContent)
+	}
+
+	err := export.Export(c.Request.Context(), db.ConnectionString, c.Writer)
+	if err != nil {
+		logger.WithError(err).Error("copy export failed")
+
+		if c.Writer.Written() {
+			// Part of the file was already sent, so the error can't be reported
+			abortConnection(c)
+			return
+		}
+
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.Writer.Header().Del("Content-Encoding")
+		c.Writer.Header().Del("Content-Range")
+		badRequest(c, err)
+	}
+}
+
+// GetLargeObject streams the content of a large object
+func GetLargeObject(c *gin.Context) {
+	oid, err := strconv.ParseUint(c.Param("oid"), 10, 32)
+	if err != nil {
+		badRequest(c, errInvalidOID)
+		return
+	}
+
+	reader, err := DB(c).LargeObject(uint32(oid))
+	if err != nil {
+		if errors.Is(err, client.ErrLargeObjectNotFound) {
+			errorResponse(c, http.StatusNotFound, err)
+			return
+		}
+		badRequest(c, err)
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="largeobject_%d"`, oid))
+
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		logger.WithError(err).Error("large object export failed")
+	}
+}
+
 // GetFunction renders function information
 func GetFunction(c *gin.Context) {
 	res, err := DB(c).Function(c.Param("id"))
 	serveResult(c, res, err)
 }
 
+// GetSequences renders a list of database sequences
+func GetSequences(c *gin.Context) {
+	res, err := DB(c).Sequences()
+	serveResult(c, res, err)
+}
+
+// GetSequence renders the sequence details
+func GetSequence(c *gin.Context) {
+	res, err := DB(c).Sequence(c.Params.ByName("sequence"))
+	serveResult(c, res, err)
+}
+
 func GetLocalQueries(c *gin.Context) {
 	connCtx, err := DB(c).GetConnContext()
 	if err != nil {
@@ -906,6 +1825,10 @@ func GetCacheStats(c *gin.Context) {
 		},
 	}
 
+	if conn := DB(c); conn != nil {
+		stats["session_bypass"] = conn.CacheBypassed()
+	}
+
 	if QueryCache != nil {
 		stats["query_cache"] = QueryCache.Stats()
 	}
@@ -917,6 +1840,18 @@ func GetCacheStats(c *gin.Context) {
 	successResponse(c, stats)
 }
 
+// SetCacheBypass enables or disables caching for the current session only
+func SetCacheBypass(c *gin.Context) {
+	enabled, err := strconv.ParseBool(c.Request.FormValue("enabled"))
+	if err != nil {
+		badRequest(c, errInvalidCacheBypass)
+		return
+	}
+
+	DB(c).SetCacheBypass(enabled)
+	successResponse(c, gin.H{"bypass": enabled})
+}
+
 // ClearCache clears all cache entries
 func ClearCache(c *gin.Context) {
 	cleared := []string{}
@@ -1,9 +1,23 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/cache"
+	"github.com/flowbi/pgweb/pkg/client"
+	"github.com/flowbi/pgweb/pkg/command"
 )
 
 func Test_assetContentType(t *testing.T) {
@@ -38,3 +52,695 @@ func Test_assetContentType(t *testing.T) {
 		}
 	}
 }
+
+func TestDisconnectAll(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		DbClient = nil
+	}(command.Opts)
+
+	command.Opts = command.Options{AdminToken: "secret"}
+
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(dbCheckMiddleware())
+	api.GET("/query", func(c *gin.Context) { successResponse(c, gin.H{"ok": true}) })
+	api.POST("/admin/disconnect-all", requireAdmin(), DisconnectAll)
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	DbClient = &client.Client{}
+	assert.Equal(t, 200, request("GET", "/api/query", "").Code)
+
+	w := request("POST", "/api/admin/disconnect-all", "wrong")
+	assert.Equal(t, 403, w.Code)
+	assert.NotNil(t, DbClient)
+
+	conn := DbClient
+	w = request("POST", "/api/admin/disconnect-all", "secret")
+	assert.Equal(t, 200, w.Code)
+	assert.Nil(t, DbClient)
+	assert.True(t, conn.IsClosed())
+
+	w = request("GET", "/api/query", "")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), errNotConnected.Error())
+
+	// Reconnecting restores service
+	DbClient = &client.Client{}
+	assert.Equal(t, 200, request("GET", "/api/query", "").Code)
+}
+
+func TestGetAdminConfig(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	command.Opts = command.Options{AdminToken: "admin-secret", AuthUser: "admin", AuthPass: "auth-secret", QueryTimeout: 300}
+
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(dbCheckMiddleware())
+	api.GET("/admin/config", requireAdmin(), GetAdminConfig)
+
+	request := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/config", nil)
+		req.Header.Set("X-Admin-Token", token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, 403, request("wrong").Code)
+
+	// The config is available without a database connection
+	w := request("admin-secret")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"admin-token":"xxxxx"`)
+	assert.Contains(t, w.Body.String(), `"auth-pass":"xxxxx"`)
+	assert.Contains(t, w.Body.String(), `"auth-user":"admin"`)
+	assert.Contains(t, w.Body.String(), `"query-timeout":300`)
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestCancelQuery(t *testing.T) {
+	defer func() {
+		DbClient = nil
+	}()
+
+	router := gin.New()
+	router.POST("/api/cancel", CancelQuery)
+
+	// Nothing is running, cancelling is a no-op
+	DbClient = &client.Client{}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/cancel", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"success": true}`, w.Body.String())
+}
+
+func Test_handleFormatResponse(t *testing.T) {
+	result := &client.Result{
+		Columns: []string{"id", "name"},
+		Rows:    []client.Row{{1, "Doe, John"}, {2, nil}},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/query?format=csv&filename=books.csv", nil)
+
+	handleFormatResponse(c, result, "csv")
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="books.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,name\n1,\"Doe, John\"\n2,\n", w.Body.String())
+}
+
+func Test_handleFormatResponseMaxColumns(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.MaxColumns = 1
+
+	result := &client.Result{
+		Columns: []string{"id", "name"},
+		Rows:    []client.Row{{1, "Dune"}},
+	}
+
+	request := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/query?format="+format, nil)
+		handleFormatResponse(c, result, format)
+		return w
+	}
+
+	w := request("")
+	assert.JSONEq(t, `{"type":"rows","columns":["id"],"columns_truncated":true,"columns_total":2,"rows":[[1]]}`, w.Body.String())
+
+	// Exports are not truncated
+	w = request("csv")
+	assert.Equal(t, "id,name\n1,Dune\n", w.Body.String())
+}
+
+func Test_handleFormatResponseXLSX(t *testing.T) {
+	request := func(result *client.Result) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/query?format=xlsx&filename=report", nil)
+		handleFormatResponse(c, result, "xlsx")
+		return w
+	}
+
+	t.Run("workbook", func(t *testing.T) {
+		w := request(&client.Result{Columns: []string{"id"}, Rows: []client.Row{{1}}})
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, xlsxContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="report.xlsx"`, w.Header().Get("Content-Disposition"))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "PK"))
+	})
+
+	t.Run("too many columns", func(t *testing.T) {
+		w := request(&client.Result{Columns: make([]string, 20000)})
+		assert.Equal(t, 400, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Contains(t, w.Body.String(), "too many columns")
+	})
+}
+
+func TestRunParameterizedQuery(t *testing.T) {
+	defer func() {
+		DbClient = nil
+	}()
+
+	router := gin.New()
+	router.POST("/api/query/parameterized", RunParameterizedQuery)
+	DbClient = &client.Client{}
+
+	request := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/query/parameterized", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	examples := []struct {
+		body  string
+		error string
+	}{
+		{`not json`, "Request body must be a JSON object with the query and an object of scalar params"},
+		{`{"params": {"id": 1}}`, "Query parameter is required"},
+		{`{"query": "SELECT @id", "params": {"id": [1]}}`, "Request body must be a JSON object with the query and an object of scalar params"},
+		{`{"query": "SELECT @id", "params": {"name": 1}}`, `parameter \"id\" is missing`},
+		{`{"query": "SELECT @id", "params": {"id": 1}}`, "database connection is not established"},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.body, func(t *testing.T) {
+			w := request(ex.body)
+			assert.Equal(t, 400, w.Code)
+			assert.JSONEq(t, `{"status": 400, "error": "`+ex.error+`"}`, w.Body.String())
+		})
+	}
+}
+
+func TestStreamQuery(t *testing.T) {
+	defer func() {
+		DbClient = nil
+	}()
+
+	router := gin.New()
+	router.GET("/api/query/stream", StreamQuery)
+	DbClient = &client.Client{}
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/query/stream?query="+neturl.QueryEscape(query), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("empty query", func(t *testing.T) {
+		w := request("")
+		assert.Equal(t, 400, w.Code)
+		assert.JSONEq(t, `{"status": 400, "error": "Query parameter is required"}`, w.Body.String())
+	})
+
+	t.Run("error before output", func(t *testing.T) {
+		w := request("SELECT 1")
+		assert.Equal(t, 400, w.Code)
+		assert.NotContains(t, w.Header().Get("Content-Type"), "application/x-ndjson")
+	})
+}
+
+func TestExplainPlan(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		DbClient = nil
+	}(command.Opts)
+
+	router := gin.New()
+	router.POST("/api/explain", ExplainPlan)
+	DbClient = &client.Client{}
+	command.Opts.ReadOnly = true
+
+	request := func(form string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/explain", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("")
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"status": 400, "error": "Query parameter is required"}`, w.Body.String())
+
+	w = request("analyze=true&query=" + neturl.QueryEscape("DELETE FROM books"))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), client.ErrExplainAnalyzeReadOnly.Error())
+
+	w = request("generic=true&analyze=true&query=" + neturl.QueryEscape("SELECT * FROM books WHERE id = $1"))
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"status": 400, "error": "Generic plan can not be explained with analyze"}`, w.Body.String())
+}
+
+func TestFormatQuery(t *testing.T) {
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(dbCheckMiddleware())
+	api.POST("/format", FormatQuery)
+
+	request := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/format", strings.NewReader(neturl.Values{"query": {query}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Formatting does not require a database connection
+	w := request("select id from books -- all")
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"query": "SELECT\n  id\nFROM\n  books -- all"}`, w.Body.String())
+
+	w = request(" ")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), errQueryRequired.Error())
+
+	w = request("select 'abc")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "unterminated string literal")
+}
+
+func TestTerminateBackend(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		DbClient = nil
+	}(command.Opts)
+
+	command.Opts = command.Options{ReadOnly: true}
+	DbClient = &client.Client{}
+
+	router := gin.New()
+	router.POST("/api/activity/terminate", TerminateBackend)
+	router.POST("/api/activity/terminate-idle", TerminateIdleBackends)
+
+	request := func(path, form string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/api/activity/terminate", "pid=abc")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "pid must be a number")
+
+	// Terminating backends is blocked in read-only mode
+	w = request("/api/activity/terminate", "pid=123")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), client.ErrReadOnly.Error())
+
+	w = request("/api/activity/terminate-idle", "minutes=10")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), client.ErrReadOnly.Error())
+}
+
+func TestGetInfoBanner(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	router := gin.New()
+	router.GET("/api/info", GetInfo)
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/info", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	command.Opts = command.Options{}
+	w := request()
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"banner":null`)
+
+	command.Opts = command.Options{BannerText: "You are connected to PRODUCTION", BannerLevel: "danger"}
+	w = request()
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"banner":{"level":"danger","text":"You are connected to PRODUCTION"}`)
+}
+
+func TestPrefixHandler(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		DbClient = nil
+	}(command.Opts)
+
+	request := func(handler http.Handler, path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	newHandler := func(opts command.Options) http.Handler {
+		command.Opts = opts
+		router := gin.New()
+		SetupRoutes(router)
+		return PrefixHandler(router)
+	}
+
+	t.Run("prefix required", func(t *testing.T) {
+		handler := newHandler(command.Options{Prefix: "pgweb/"})
+
+		assert.Equal(t, 200, request(handler, "/pgweb/api/info"))
+		assert.Equal(t, 404, request(handler, "/api/info"))
+	})
+
+	t.Run("prefix stripped", func(t *testing.T) {
+		handler := newHandler(command.Options{Prefix: "pgweb/", StripPrefix: true})
+
+		assert.Equal(t, 200, request(handler, "/pgweb/api/info"))
+		assert.Equal(t, 200, request(handler, "/api/info"))
+
+		// Connection checks apply regardless of the prefix
+		assert.Equal(t, 400, request(handler, "/pgweb/api/connection"))
+		assert.Equal(t, 400, request(handler, "/api/connection"))
+
+		DbClient = &client.Client{}
+		assert.Equal(t, 404, request(handler, "/api/unknown"))
+	})
+}
+
+func Test_trimURLPrefix(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	command.Opts = command.Options{}
+	assert.Equal(t, "/api/info", trimURLPrefix("/api/info"))
+
+	command.Opts = command.Options{Prefix: "pgweb/"}
+	assert.Equal(t, "/api/info", trimURLPrefix("/pgweb/api/info"))
+	assert.Equal(t, "/api/info", trimURLPrefix("/api/info"))
+	assert.Equal(t, "/api/pgweb/info", trimURLPrefix("/pgweb/api/pgweb/info"))
+}
+
+func Test_runMultiQuery(t *testing.T) {
+	mu := sync.Mutex{}
+	connected := []*client.Client{}
+	connect := func(id string) (*client.Client, error) {
+		if id == "missing" {
+			return nil, errors.New("bookmark not found")
+		}
+		conn := &client.Client{ConnectionString: "postgres://localhost/" + id}
+
+		mu.Lock()
+		connected = append(connected, conn)
+		mu.Unlock()
+
+		return conn, nil
+	}
+
+	results := runMultiQuery(context.Background(), []string{"prod", "staging"}, "SELECT 1", connect)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "prod", results[0].Bookmark)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "staging", results[1].Bookmark)
+	assert.Empty(t, results[1].Error)
+
+	// Connections are closed once the query completes
+	for _, conn := range connected {
+		assert.True(t, conn.IsClosed())
+	}
+
+	results = runMultiQuery(context.Background(), []string{"prod", "missing"}, "SELECT 1", connect)
+	assert.Len(t, results, 2)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "missing", results[1].Bookmark)
+	assert.Equal(t, "bookmark not found", results[1].Error)
+}
+
+func Test_mutatedTable(t *testing.T) {
+	examples := map[string]string{
+		"SELECT * FROM books":                               "",
+		"UPDATE books SET title = 'foo'":                    "books",
+		"update public.Books set title = 'foo'":             "books",
+		`UPDATE "public"."Books" SET title = 'foo'`:         "Books",
+		"UPDATE only_books SET title = 'foo'":               "only_books",
+		"INSERT INTO authors (name) VALUES ('foo')":         "authors",
+		"DELETE FROM ONLY books WHERE id = 1":               "books",
+		"TRUNCATE TABLE books":                              "books",
+		"ALTER TABLE IF EXISTS books ADD COLUMN foo int":    "books",
+		"DROP TABLE books":                                  "books",
+		"WITH x AS (SELECT 1) UPDATE books SET title = 'a'": "books",
+	}
+
+	for query, expected := range examples {
+		assert.Equal(t, expected, mutatedTable(query), query)
+	}
+}
+
+func TestSessionCacheBypass(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		QueryCache = nil
+		DbSessions = nil
+	}(command.Opts)
+
+	// Both sessions share the connection and so the cache entries, the server
+	// is not reachable so only cached results can be served
+	connStr := "postgres://127.0.0.1:1/booktown?sslmode=disable&connect_timeout=1"
+	debug, err := client.NewFromUrl(connStr, nil)
+	assert.NoError(t, err)
+	other, err := client.NewFromUrl(connStr, nil)
+	assert.NoError(t, err)
+
+	command.Opts.Sessions = true
+	QueryCache = cache.NewWithoutCleanup(time.Minute)
+	DbSessions = NewSessionManager(nil)
+	DbSessions.Add("debug", debug)
+	DbSessions.Add("other", other)
+
+	query := "SELECT * FROM books"
+	cached := &client.Result{Columns: []string{"id"}, Rows: []client.Row{{1}}, Stats: &client.ResultStats{}}
+	QueryCache.Set(generateQueryCacheKey(query, connStr, ""), &CachedResponse{Result: cached, Query: query, ConnectionString: connStr}, 0)
+
+	router := gin.New()
+	router.Use(noCacheMiddleware())
+	router.POST("/api/query", RunQuery)
+	router.POST("/api/cache/bypass", SetCacheBypass)
+
+	request := func(path, session, form string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Session-ID", session)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	runQuery := func(session string, headers map[string]string) *httptest.ResponseRecorder {
+		return request("/api/query", session, "query="+neturl.QueryEscape(query), headers)
+	}
+
+	// The flagged session runs the query on its connection
+	w := runQuery("debug", map[string]string{"X-No-Cache": "session"})
+	assert.Equal(t, 400, w.Code)
+	assert.True(t, DbSessions.Get("debug").CacheBypassed())
+
+	// The flag lasts for the session, without the header
+	w = runQuery("debug", nil)
+	assert.Equal(t, 400, w.Code)
+
+	w = runQuery("other", nil)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"rows":[[1]]`)
+	assert.False(t, DbSessions.Get("other").CacheBypassed())
+
+	w = request("/api/cache/bypass", "debug", "enabled=false", nil)
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"bypass": false}`, w.Body.String())
+
+	w = runQuery("debug", nil)
+	assert.Equal(t, 200, w.Code)
+
+	w = request("/api/cache/bypass", "debug", "enabled=maybe", nil)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestApplicationNameMiddleware(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		client.ApplicationNameTemplate = ""
+	}(command.Opts)
+
+	command.Opts = command.Options{LogForwardedUser: true}
+
+	router := gin.New()
+	router.Use(requestIDMiddleware())
+	router.Use(applicationNameMiddleware())
+	router.GET("/api/name", func(c *gin.Context) {
+		successResponse(c, gin.H{"name": client.ApplicationNameFromContext(c.Request.Context())})
+	})
+
+	request := func(user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/name", nil)
+		req.Header.Set("X-Request-Id", "f00d-1234")
+		req.Header.Set("X-Session-ID", "session-1")
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Not set without a template
+	assert.JSONEq(t, `{"name": ""}`, request("jane").Body.String())
+
+	require.NoError(t, client.SetApplicationNameTemplate("pgweb:{user}:{session}:{request_id}"))
+	assert.JSONEq(t, `{"name": "pgweb:jane:session-1:f00d-1234"}`, request("jane").Body.String())
+	assert.JSONEq(t, `{"name": "pgweb:jane_:session-1:f00d-1234"}`, request("jane'").Body.String())
+	assert.JSONEq(t, `{"name": "pgweb::session-1:f00d-1234"}`, request("").Body.String())
+
+	// The forwarded user is ignored unless trusted
+	command.Opts.LogForwardedUser = false
+	assert.JSONEq(t, `{"name": "pgweb::session-1:f00d-1234"}`, request("jane").Body.String())
+
+	// The authenticated user takes precedence
+	authRouter := gin.New()
+	authRouter.Use(requestIDMiddleware())
+	authRouter.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, "admin")
+	})
+	authRouter.Use(applicationNameMiddleware())
+	authRouter.GET("/api/name", func(c *gin.Context) {
+		successResponse(c, gin.H{"name": client.ApplicationNameFromContext(c.Request.Context())})
+	})
+
+	command.Opts.LogForwardedUser = true
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/name", nil)
+	req.Header.Set("X-Request-Id", "f00d-1234")
+	req.Header.Set("X-Session-ID", "session-1")
+	req.Header.Set("X-Forwarded-User", "jane")
+	authRouter.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"name": "pgweb:admin:session-1:f00d-1234"}`, w.Body.String())
+}
+
+func TestQueryTimeoutMiddleware(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		DbSessions = nil
+	}(command.Opts)
+
+	command.Opts = command.Options{Sessions: true, AllowLocalhost: true, QueryTimeout: 300, MaxQueryTimeout: 3600}
+
+	cl, err := client.NewFromUrl("postgres://127.0.0.1:1/booktown?sslmode=disable&connect_timeout=1", nil)
+	require.NoError(t, err)
+	DbSessions = NewSessionManager(nil)
+	require.NoError(t, DbSessions.Add("test", cl))
+
+	router := gin.New()
+	router.Use(queryTimeoutMiddleware())
+	router.GET("/api/timeout", func(c *gin.Context) {
+		successResponse(c, gin.H{"timeout": DB(c).QueryTimeout(c.Request.Context()).Seconds()})
+	})
+
+	request := func(path, timeout string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-Session-ID", "test")
+		if timeout != "" {
+			req.Header.Set("X-Query-Timeout", timeout)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.JSONEq(t, `{"timeout": 300}`, request("/api/timeout", "").Body.String())
+	assert.JSONEq(t, `{"timeout": 1800}`, request("/api/timeout", "1800").Body.String())
+	assert.JSONEq(t, `{"timeout": 5}`, request("/api/timeout?timeout=5", "").Body.String())
+
+	// The header takes precedence, and the override only lasts for the request
+	assert.JSONEq(t, `{"timeout": 10}`, request("/api/timeout?timeout=5", "10").Body.String())
+	assert.JSONEq(t, `{"timeout": 300}`, request("/api/timeout", "").Body.String())
+
+	w := request("/api/timeout", "7200")
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"error": "query timeout must not exceed 3600 seconds", "status": 400}`, w.Body.String())
+}
+
+func Test_invalidateQueryCache(t *testing.T) {
+	defer func() {
+		QueryCache = nil
+	}()
+	QueryCache = cache.NewWithoutCleanup(time.Minute)
+
+	connStr := "postgres://localhost/booktown"
+	cacheQuery := func(query, connStr string) string {
+		key := generateQueryCacheKey(query, connStr, "")
+		QueryCache.Set(key, &CachedResponse{Result: &client.Result{}, Query: query, ConnectionString: connStr}, 0)
+		return key
+	}
+
+	booksKey := cacheQuery("SELECT * FROM books WHERE id = 1", connStr)
+	authorsKey := cacheQuery("SELECT * FROM authors", connStr)
+	booksOtherDbKey := cacheQuery("SELECT * FROM books WHERE id = 1", "postgres://localhost/other")
+
+	removed := invalidateQueryCache(connStr, mutatedTable("UPDATE books SET title = 'foo' WHERE id = 1"))
+	assert.Equal(t, 1, removed)
+
+	// Reading the updated table must miss the cache
+	_, found := QueryCache.Get(booksKey)
+	assert.False(t, found)
+
+	_, found = QueryCache.Get(authorsKey)
+	assert.True(t, found)
+	_, found = QueryCache.Get(booksOtherDbKey)
+	assert.True(t, found)
+}
+
+func Test_copyExportRange(t *testing.T) {
+	router := gin.New()
+	router.GET("/api/export", func(c *gin.Context) {
+		copyExport(c, &client.Client{}, nil)
+	})
+
+	request := func(rangeHeader string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/export?table=books", nil)
+		req.Header.Set("Range", rangeHeader)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The last byte of open-ended ranges can't be reported in Content-Range
+	w := request("bytes=100-")
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Range"))
+	assert.JSONEq(t, `{"error": "Range must end at a given byte, the export size is not known in advance", "status": 416}`, w.Body.String())
+
+	w = request("bytes=0-10,20-30")
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/flowbi/pgweb/pkg/client"
+)
+
+// Access modes of the users listed in the --auth-users file
+const (
+	authModeReadWrite = "rw"
+	authModeReadOnly  = "ro"
+)
+
+// Context key set for the requests of read-only users
+const authReadOnlyKey = "auth_readonly"
+
+// AuthUser is a user allowed to sign in with HTTP basic auth
+type AuthUser struct {
+	Name     string
+	PassHash string // Bcrypt hash of the password
+	ReadOnly bool   // Force read-only mode on all the user connections
+}
+
+// LoadAuthUsers reads the users from a file of user:passhash:mode lines, where mode
+// is rw or ro. Blank lines and lines starting with # are skipped.
+func LoadAuthUsers(path string) (map[string]AuthUser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	users := map[string]AuthUser{}
+	scanner := bufio.NewScanner(file)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		user, err := parseAuthUser(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		if _, ok := users[user.Name]; ok {
+			return nil, fmt.Errorf("line %d: duplicate user %q", lineNum, user.Name)
+		}
+		users[user.Name] = user
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no users found in %s", path)
+	}
+
+	return users, nil
+}
+
+func parseAuthUser(line string) (AuthUser, error) {
+	parts := strings.Split(line, ":")
+	if len(parts) != 3 {
+		return AuthUser{}, fmt.Errorf("expected user:passhash:mode")
+	}
+
+	user := AuthUser{Name: parts[0], PassHash: parts[1]}
+	if user.Name == "" {
+		return user, fmt.Errorf("user name is required")
+	}
+	if _, err := bcrypt.Cost([]byte(user.PassHash)); err != nil {
+		return user, fmt.Errorf("password of %q is not a bcrypt hash", user.Name)
+	}
+
+	switch parts[2] {
+	case authModeReadWrite:
+	case authModeReadOnly:
+		user.ReadOnly = true
+	default:
+		return user, fmt.Errorf("mode of %q must be %s or %s", user.Name, authModeReadWrite, authModeReadOnly)
+	}
+
+	return user, nil
+}
+
+// HasReadOnlyAuthUsers returns true if any of the users is read-only
+func HasReadOnlyAuthUsers(users map[string]AuthUser) bool {
+	for _, user := range users {
+		if user.ReadOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// BasicAuthUsers returns a middleware authenticating the requests against the users.
+// The queries of read-only users run in read-only mode, whatever the mode of the
+// session connection they share.
+func BasicAuthUsers(users map[string]AuthUser) gin.HandlerFunc {
+	// Bcrypt is slow by design, remember the credentials that already matched
+	var verified sync.Map
+
+	return func(c *gin.Context) {
+		name, pass, ok := c.Request.BasicAuth()
+		user, found := users[name]
+		if !ok || !found || !checkAuthPassword(&verified, user, pass) {
+			c.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+			c.AbortWithStatus(401)
+			return
+		}
+
+		c.Set(gin.AuthUserKey, user.Name)
+		if user.ReadOnly {
+			c.Set(authReadOnlyKey, true)
+			c.Request = c.Request.WithContext(client.WithReadOnly(c.Request.Context()))
+		}
+
+		c.Next()
+	}
+}
+
+func checkAuthPassword(verified *sync.Map, user AuthUser, pass string) bool {
+	sum := sha256.Sum256([]byte(pass))
+	if prev, ok := verified.Load(user.Name); ok {
+		prevSum := prev.([sha256.Size]byte)
+		if subtle.ConstantTimeCompare(prevSum[:], sum[:]) == 1 {
+			return true
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PassHash), []byte(pass)) != nil {
+		return false
+	}
+
+	verified.Store(user.Name, sum)
+	return true
+}
+
+// isReadOnlyUser returns true if the request is made by a read-only user
+func isReadOnlyUser(c *gin.Context) bool {
+	return c.GetBool(authReadOnlyKey)
+}
+
+// requireReadWrite rejects the requests of read-only users
+func requireReadWrite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isReadOnlyUser(c) {
+			errorResponse(c, 403, client.ErrReadOnly)
+			return
+		}
+		c.Next()
+	}
+}
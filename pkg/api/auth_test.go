@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/flowbi/pgweb/pkg/client"
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func writeAuthUsers(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "users")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func authHash(t *testing.T, pass string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+func TestLoadAuthUsers(t *testing.T) {
+	hash := authHash(t, "secret")
+
+	users, err := LoadAuthUsers(writeAuthUsers(t, "# Users\nadmin:"+hash+":rw\n\nviewer:"+hash+":ro\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]AuthUser{
+		"admin":  {Name: "admin", PassHash: hash},
+		"viewer": {Name: "viewer", PassHash: hash, ReadOnly: true},
+	}, users)
+	assert.True(t, HasReadOnlyAuthUsers(users))
+
+	examples := map[string]string{
+		"admin:" + hash:                        "line 1: expected user:passhash:mode",
+		"admin:" + hash + ":rw:extra":          "line 1: expected user:passhash:mode",
+		":" + hash + ":rw":                     "line 1: user name is required",
+		"admin:secret:rw":                      `line 1: password of "admin" is not a bcrypt hash`,
+		"\n\nviewer:" + hash + ":readonly":     `line 3: mode of "viewer" must be rw or ro`,
+		"a:" + hash + ":rw\na:" + hash + ":ro": `line 2: duplicate user "a"`,
+		"# no users\n":                         "no users found in ",
+	}
+	for content, expected := range examples {
+		_, err := LoadAuthUsers(writeAuthUsers(t, content))
+		require.Error(t, err, content)
+		assert.Contains(t, err.Error(), expected, content)
+	}
+
+	_, err = LoadAuthUsers(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestBasicAuthUsers(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+		DbSessions = nil
+	}(command.Opts)
+
+	command.Opts = command.Options{Sessions: true, AllowLocalhost: true}
+	DbSessions = NewSessionManager(nil)
+
+	connStr := "postgres://127.0.0.1:1/booktown?sslmode=disable&connect_timeout=1"
+	users := map[string]AuthUser{
+		"admin":  {Name: "admin", PassHash: authHash(t, "admin-pass")},
+		"viewer": {Name: "viewer", PassHash: authHash(t, "viewer-pass"), ReadOnly: true},
+	}
+
+	router := gin.New()
+	router.Use(BasicAuthUsers(users))
+	router.POST("/api/connect", func(c *gin.Context) {
+		cl, err := client.NewFromUrl(connStr, nil)
+		require.NoError(t, err)
+		require.NoError(t, setClient(c, cl))
+		successResponse(c, gin.H{"readonly": client.ReadOnlyFromContext(c.Request.Context()), "session_readonly": cl.IsReadOnly()})
+	})
+	router.GET("/api/mode", func(c *gin.Context) {
+		successResponse(c, gin.H{"readonly": client.ReadOnlyFromContext(c.Request.Context()), "session_readonly": DB(c).IsReadOnly()})
+	})
+	router.POST("/api/activity/terminate", requireReadWrite(), func(c *gin.Context) {
+		successResponse(c, gin.H{"terminated": true})
+	})
+
+	request := func(method, path, session, user, pass string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Session-ID", session)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		w := request("GET", "/api/mode", "admin", "", "")
+		assert.Equal(t, 401, w.Code)
+		assert.Equal(t, `Basic realm="Authorization Required"`, w.Header().Get("WWW-Authenticate"))
+
+		assert.Equal(t, 401, request("GET", "/api/mode", "admin", "admin", "viewer-pass").Code)
+		assert.Equal(t, 401, request("GET", "/api/mode", "admin", "nobody", "admin-pass").Code)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		w := request("POST", "/api/connect", "admin", "admin", "admin-pass")
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{"readonly": false, "session_readonly": false}`, w.Body.String())
+
+		// Verified credentials are remembered, wrong ones are still rejected
+		assert.JSONEq(t, `{"readonly": false, "session_readonly": false}`, request("GET", "/api/mode", "admin", "admin", "admin-pass").Body.String())
+		assert.Equal(t, 401, request("GET", "/api/mode", "admin", "admin", "wrong").Code)
+	})
+
+	t.Run("viewer", func(t *testing.T) {
+		w := request("POST", "/api/connect", "viewer", "viewer", "viewer-pass")
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{"readonly": true, "session_readonly": false}`, w.Body.String())
+
+		w = request("POST", "/api/activity/terminate", "viewer", "viewer", "viewer-pass")
+		assert.Equal(t, 403, w.Code)
+		assert.Equal(t, 200, request("POST", "/api/activity/terminate", "admin", "admin", "admin-pass").Code)
+	})
+
+	t.Run("viewer with existing session", func(t *testing.T) {
+		cl, err := client.NewFromUrl(connStr, nil)
+		require.NoError(t, err)
+		require.NoError(t, DbSessions.Add("shared", cl))
+
+		w := request("GET", "/api/mode", "shared", "viewer", "viewer-pass")
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{"readonly": true, "session_readonly": false}`, w.Body.String())
+
+		// Read-only mode only applies to the viewer requests, not to the shared session
+		w = request("GET", "/api/mode", "shared", "admin", "admin-pass")
+		assert.JSONEq(t, `{"readonly": false, "session_readonly": false}`, w.Body.String())
+		assert.False(t, cl.IsReadOnly())
+	})
+}
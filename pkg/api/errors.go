@@ -5,12 +5,24 @@ import (
 )
 
 var (
-	errNotConnected         = errors.New("Not connected")
-	errNotPermitted         = errors.New("Not permitted")
-	errInvalidConnString    = errors.New("Invalid connection string")
-	errSessionRequired      = errors.New("Session ID is required")
-	errSessionLocked        = errors.New("Session is locked")
-	errURLRequired          = errors.New("URL parameter is required")
-	errQueryRequired        = errors.New("Query parameter is required")
-	errDatabaseNameRequired = errors.New("Database name is required")
+	errNotConnected          = errors.New("Not connected")
+	errNotPermitted          = errors.New("Not permitted")
+	errInvalidConnString     = errors.New("Invalid connection string")
+	errSessionRequired       = errors.New("Session ID is required")
+	errSessionLocked         = errors.New("Session is locked")
+	errURLRequired           = errors.New("URL parameter is required")
+	errQueryRequired         = errors.New("Query parameter is required")
+	errDatabaseNameRequired  = errors.New("Database name is required")
+	errSavepointNameRequired = errors.New("Savepoint name is required")
+	errTooManySessions       = errors.New("Maximum number of sessions reached")
+	errBookmarksRequired     = errors.New("Bookmarks parameter is required")
+	errQueryRunning          = errors.New("Another query is already running")
+	errInvalidOID            = errors.New("Large object OID must be a positive number")
+	errInvalidParams         = errors.New("Params must be a JSON array of scalar values")
+	errInvalidNamedParams    = errors.New("Request body must be a JSON object with the query and an object of scalar params")
+	errInvalidRange          = errors.New("Only a single bytes=start-end range is supported")
+	errOpenEndedRange        = errors.New("Range must end at a given byte, the export size is not known in advance")
+	errGenericPlanAnalyze    = errors.New("Generic plan can not be explained with analyze")
+	errInvalidCacheBypass    = errors.New("Enabled parameter must be true or false")
+	errInvalidRowsLimit      = errors.New("Limit must be a number, 0 for the default limit or -1 for all rows")
 )
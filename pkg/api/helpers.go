@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"mime"
 	"net/http"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/flowbi/pgweb/pkg/client"
+	"github.com/flowbi/pgweb/pkg/command"
 	"github.com/flowbi/pgweb/pkg/shared"
 )
 
@@ -27,11 +30,15 @@ var (
 
 	// Paths that dont require database connection
 	allowedPaths = map[string]bool{
-		"/api/sessions":  true,
-		"/api/info":      true,
-		"/api/connect":   true,
-		"/api/bookmarks": true,
-		"/api/history":   true,
+		"/api/sessions":             true,
+		"/api/info":                 true,
+		"/api/connect":              true,
+		"/api/bookmarks":            true,
+		"/api/history":              true,
+		"/api/admin/config":         true,
+		"/api/admin/disconnect-all": true,
+		"/api/multi/query":          true,
+		"/api/format":               true,
 	}
 
 	// List of characters replaced by javascript code to make queries url-safe.
@@ -83,6 +90,23 @@ func sanitizeFilename(str string) string {
 	return regexCleanFilename.ReplaceAllString(str, "")
 }
 
+// attachmentFilename returns the sanitized download filename from the filename
+// parameter, or the default name when it's not provided
+func attachmentFilename(c *gin.Context, name, format string) string {
+	if val := getQueryParam(c, "filename"); val != "" {
+		name = strings.TrimSuffix(val, "."+format)
+	}
+	return sanitizeFilename(name) + "." + format
+}
+
+// trimURLPrefix returns the request path relative to the configured url prefix
+func trimURLPrefix(path string) string {
+	if command.Opts.Prefix == "" {
+		return path
+	}
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(path, "/"), command.Opts.Prefix)
+}
+
 func getSessionId(req *http.Request) string {
 	id := req.Header.Get("x-session-id")
 	if id == "" {
@@ -121,6 +145,119 @@ func parseIntFormValue(c *gin.Context, name string, defValue int) (int, error) {
 	return num, nil
 }
 
+// parseAggregations parses aggregations in the "function:column,function:column" format
+func parseAggregations(val string) ([]client.Aggregation, error) {
+	aggs := []client.Aggregation{}
+
+	for _, chunk := range splitList(val) {
+		parts := strings.SplitN(chunk, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid aggregation %q, expected function:column", chunk)
+		}
+		aggs = append(aggs, client.Aggregation{Function: parts[0], Column: parts[1]})
+	}
+
+	return aggs, nil
+}
+
+// parseSortParams parses sort columns in the "column[:asc|desc]" format
+func parseSortParams(values []string) []client.SortSpec {
+	sorts := []client.SortSpec{}
+
+	for _, val := range values {
+		if val = strings.TrimSpace(val); val == "" {
+			continue
+		}
+
+		sort := client.SortSpec{Column: val}
+		if i := strings.LastIndexByte(val, ':'); i >= 0 {
+			if order := strings.ToUpper(val[i+1:]); order == "ASC" || order == "DESC" {
+				sort = client.SortSpec{Column: val[:i], Order: order}
+			}
+		}
+		sorts = append(sorts, sort)
+	}
+
+	return sorts
+}
+
+// parseQueryParams parses the query parameters from a JSON array. Numbers are
+// kept as strings so that the server converts them to the placeholder type.
+func parseQueryParams(val string) ([]interface{}, error) {
+	params := []interface{}{}
+	if strings.TrimSpace(val) == "" {
+		return params, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(val))
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		return nil, errInvalidParams
+	}
+
+	for i, param := range params {
+		value, err := normalizeQueryParam(param)
+		if err != nil {
+			return nil, errInvalidParams
+		}
+		params[i] = value
+	}
+
+	return params, nil
+}
+
+// normalizeQueryParam returns the decoded JSON value of a query parameter.
+// Numbers are kept as strings, arrays and objects are not supported.
+func normalizeQueryParam(param interface{}) (interface{}, error) {
+	switch v := param.(type) {
+	case json.Number:
+		return v.String(), nil
+	case []interface{}, map[string]interface{}:
+		return nil, errInvalidParams
+	}
+	return param, nil
+}
+
+// parseByteRange returns the offset and length of a single "bytes=start-[end]" range.
+// A zero length means the range extends to the end of the content.
+func parseByteRange(header string) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, errInvalidRange
+	}
+
+	start, end, _ := strings.Cut(spec, "-")
+	offset, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, errInvalidRange
+	}
+
+	// Content-Range of the response must include the last byte
+	end = strings.TrimSpace(end)
+	if end == "" {
+		return 0, 0, errOpenEndedRange
+	}
+
+	last, err := strconv.ParseInt(end, 10, 64)
+	if err != nil || last < offset {
+		return 0, 0, errInvalidRange
+	}
+	return offset, last - offset + 1, nil
+}
+
+// splitList returns non-empty trimmed elements of a comma-separated list
+func splitList(val string) []string {
+	result := []string{}
+
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
 func parseSshInfo(c *gin.Context) *shared.SSHInfo {
 	info := shared.SSHInfo{
 		Host:        c.Request.FormValue("ssh_host"),
@@ -188,3 +325,23 @@ func errorResponse(c *gin.Context, status int, err interface{}) {
 func badRequest(c *gin.Context, err interface{}) {
 	errorResponse(c, 400, err)
 }
+
+// Close the client connection without finishing the response, so that a
+// download that failed mid-stream is not mistaken for a complete one
+func abortConnection(c *gin.Context) {
+	c.Abort()
+
+	// gin refuses to hijack a connection once the response was written to, so
+	// go straight to the underlying writer
+	var writer http.ResponseWriter = c.Writer
+	if w, ok := writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+		writer = w.Unwrap()
+	}
+
+	conn, _, err := http.NewResponseController(writer).Hijack()
+	if err != nil {
+		logger.WithError(err).Error("unable to abort the connection")
+		return
+	}
+	conn.Close()
+}
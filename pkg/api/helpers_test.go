@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -9,6 +10,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/client"
 )
 
 func Test_desanitize64(t *testing.T) {
@@ -86,3 +90,116 @@ func Test_serveResult(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Equal(t, `null`, w.Body.String())
 }
+
+func Test_attachmentFilename(t *testing.T) {
+	examples := map[string]string{
+		"":                 "public_books.csv",
+		"report":           "report.csv",
+		"report.csv":       "report.csv",
+		"../../etc/passwd": "____etcpasswd.csv",
+		`a"; b.csv`:        "ab.csv",
+	}
+
+	for given, expected := range examples {
+		t.Run(given, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/?filename="+url.QueryEscape(given), nil)
+			assert.Equal(t, expected, attachmentFilename(c, "public.books", "csv"))
+		})
+	}
+}
+
+func Test_parseAggregations(t *testing.T) {
+	aggs, err := parseAggregations("")
+	assert.NoError(t, err)
+	assert.Equal(t, []client.Aggregation{}, aggs)
+
+	aggs, err = parseAggregations("count:*, sum:stock")
+	assert.NoError(t, err)
+	assert.Equal(t, []client.Aggregation{
+		{Function: "count", Column: "*"},
+		{Function: "sum", Column: "stock"},
+	}, aggs)
+
+	_, err = parseAggregations("count")
+	assert.EqualError(t, err, `invalid aggregation "count", expected function:column`)
+}
+
+func Test_parseSortParams(t *testing.T) {
+	assert.Equal(t, []client.SortSpec{}, parseSortParams(nil))
+
+	assert.Equal(t, []client.SortSpec{
+		{Column: "created_at", Order: "DESC"},
+		{Column: "id"},
+		{Column: "id", Order: "ASC"},
+		{Column: "time:zone"},
+	}, parseSortParams([]string{"created_at:desc", "id", " ", "id:ASC", "time:zone"}))
+}
+
+func Test_parseQueryParams(t *testing.T) {
+	params, err := parseQueryParams("")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{}, params)
+
+	params, err = parseQueryParams(`[42, 1.5, "text", true, null]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"42", "1.5", "text", true, nil}, params)
+
+	_, err = parseQueryParams(`{"id": 1}`)
+	assert.Equal(t, errInvalidParams, err)
+
+	_, err = parseQueryParams(`[[1, 2]]`)
+	assert.Equal(t, errInvalidParams, err)
+}
+
+func Test_parseByteRange(t *testing.T) {
+	examples := []struct {
+		header string
+		offset int64
+		limit  int64
+		err    error
+	}{
+		{"bytes=0-", 0, 0, errOpenEndedRange},
+		{"bytes=100-", 0, 0, errOpenEndedRange},
+		{"bytes=100-199", 100, 100, nil},
+		{"bytes=5-5", 5, 1, nil},
+		{"bytes=-100", 0, 0, errInvalidRange},
+		{"bytes=10-5", 0, 0, errInvalidRange},
+		{"bytes=0-10,20-30", 0, 0, errInvalidRange},
+		{"items=0-10", 0, 0, errInvalidRange},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.header, func(t *testing.T) {
+			offset, limit, err := parseByteRange(ex.header)
+			assert.Equal(t, ex.err, err)
+			assert.Equal(t, ex.offset, offset)
+			assert.Equal(t, ex.limit, limit)
+		})
+	}
+}
+
+func Test_abortConnection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Writer.WriteString("id,title\n")
+		c.Writer.Flush()
+		abortConnection(c)
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 200, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, "id,title\n", string(body))
+}
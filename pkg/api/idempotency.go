@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/flowbi/pgweb/pkg/cache"
+)
+
+const (
+	// How long the responses of requests with an Idempotency-Key header are remembered
+	idempotencyKeyTTL = 5 * time.Minute
+
+	// Maximum number of remembered responses
+	idempotencyMaxKeys = 10000
+
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	// IdempotencyCache stores the responses of requests with an Idempotency-Key header
+	IdempotencyCache *cache.Cache
+
+	// Guards the check and reservation of the keys
+	idempotencyMu sync.Mutex
+
+	errIdempotencyKeyTooLong    = errors.New("idempotency key is too long")
+	errIdempotencyKeyInProgress = errors.New("a request with the same idempotency key is in progress")
+	errIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+)
+
+// idempotentResponse is the response of a request made with an idempotency key
+type idempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+	Pending     bool              // The request is still being processed
+	RequestHash [sha256.Size]byte // Hash of the request parameters and body
+}
+
+// idempotencyWriter records the response body
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Middleware to remember the successful responses of requests with an Idempotency-Key
+// header, per session. Retries with the same key get the prior response back instead
+// of running the request again.
+func idempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || IdempotencyCache == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			badRequest(c, errIdempotencyKeyTooLong)
+			return
+		}
+
+		requestHash, err := idempotencyRequestHash(c.Request)
+		if err != nil {
+			badRequest(c, err)
+			return
+		}
+
+		cacheKey := cache.GenerateKey("idempotency", getSessionId(c.Request), c.Request.URL.Path, key)
+
+		idempotencyMu.Lock()
+		if val, ok := IdempotencyCache.Get(cacheKey); ok {
+			idempotencyMu.Unlock()
+
+			resp := val.(*idempotentResponse)
+			if resp.RequestHash != requestHash {
+				errorResponse(c, http.StatusUnprocessableEntity, errIdempotencyKeyReused)
+				return
+			}
+			if resp.Pending {
+				errorResponse(c, http.StatusConflict, errIdempotencyKeyInProgress)
+				return
+			}
+
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(resp.Status, resp.ContentType, resp.Body)
+			c.Abort()
+			return
+		}
+		IdempotencyCache.Set(cacheKey, &idempotentResponse{Pending: true, RequestHash: requestHash}, 0)
+		idempotencyMu.Unlock()
+
+		// Failed requests can be retried, including the ones that panicked or
+		// were aborted without a response
+		stored := false
+		defer func() {
+			if !stored {
+				IdempotencyCache.Delete(cacheKey)
+			}
+		}()
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if !writer.Written() || status < 200 || status >= 300 {
+			return
+		}
+
+		IdempotencyCache.Set(cacheKey, &idempotentResponse{
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+			RequestHash: requestHash,
+		}, 0)
+		stored = true
+	}
+}
+
+// idempotencyRequestHash returns the hash of the request parameters and body, which
+// is restored for the handlers. Retries must send the same request as the original one.
+func idempotencyRequestHash(req *http.Request) ([sha256.Size]byte, error) {
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(req.URL.RawQuery))
+	hash.Write([]byte{0})
+	hash.Write(body)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flowbi/pgweb/pkg/cache"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	defer func() {
+		IdempotencyCache = nil
+	}()
+	IdempotencyCache = cache.NewWithoutCleanup(time.Minute)
+
+	writes := 0
+	router := gin.New()
+	router.POST("/api/query", idempotencyMiddleware(), func(c *gin.Context) {
+		if c.Request.FormValue("query") == "invalid" {
+			badRequest(c, "syntax error")
+			return
+		}
+
+		writes++
+		successResponse(c, gin.H{"rows_affected": 1, "write": writes})
+	})
+
+	request := func(session, key, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/query", strings.NewReader("query="+query))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Session-ID", session)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("same key", func(t *testing.T) {
+		first := request("a", "key-1", "insert")
+		assert.Equal(t, 200, first.Code)
+		assert.JSONEq(t, `{"rows_affected": 1, "write": 1}`, first.Body.String())
+
+		second := request("a", "key-1", "insert")
+		assert.Equal(t, 200, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+		assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, writes)
+	})
+
+	t.Run("other key or session", func(t *testing.T) {
+		assert.JSONEq(t, `{"rows_affected": 1, "write": 2}`, request("a", "key-2", "insert").Body.String())
+		assert.JSONEq(t, `{"rows_affected": 1, "write": 3}`, request("b", "key-1", "insert").Body.String())
+		assert.Equal(t, 3, writes)
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		request("a", "", "insert")
+		request("a", "", "insert")
+		assert.Equal(t, 5, writes)
+	})
+
+	t.Run("failed request", func(t *testing.T) {
+		assert.Equal(t, 400, request("a", "key-3", "invalid").Code)
+
+		w := request("a", "key-3", "insert")
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 6, writes)
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/api/query", strings.NewReader("query=insert"))
+		hash, err := idempotencyRequestHash(req)
+		assert.NoError(t, err)
+		IdempotencyCache.Set(cache.GenerateKey("idempotency", "a", "/api/query", "key-4"), &idempotentResponse{Pending: true, RequestHash: hash}, 0)
+
+		w := request("a", "key-4", "insert")
+		assert.Equal(t, 409, w.Code)
+		assert.Equal(t, 6, writes)
+	})
+
+	t.Run("different request", func(t *testing.T) {
+		assert.Equal(t, 200, request("a", "key-5", "insert").Code)
+		assert.Equal(t, 7, writes)
+
+		w := request("a", "key-5", "update")
+		assert.Equal(t, 422, w.Code)
+		assert.JSONEq(t, `{"error": "idempotency key was already used with a different request", "status": 422}`, w.Body.String())
+		assert.Equal(t, 7, writes)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		assert.Equal(t, 400, request("a", strings.Repeat("k", 256), "insert").Code)
+		assert.Equal(t, 7, writes)
+	})
+}
+
+func TestIdempotencyMiddlewarePanic(t *testing.T) {
+	defer func() {
+		IdempotencyCache = nil
+	}()
+	IdempotencyCache = cache.NewWithoutCleanup(time.Minute)
+
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(500)
+	}))
+	router.POST("/api/query", idempotencyMiddleware(), func(c *gin.Context) {
+		if c.Request.FormValue("query") == "panic" {
+			panic("boom")
+		}
+		c.Abort()
+	})
+
+	request := func(query string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/query", strings.NewReader("query="+query))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Session-ID", "a")
+		req.Header.Set("Idempotency-Key", "key-1")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The key is released, retries are not rejected as in progress
+	assert.Equal(t, 500, request("panic"))
+	assert.Equal(t, 500, request("panic"))
+
+	request("abort")
+	_, found := IdempotencyCache.Get(cache.GenerateKey("idempotency", "a", "/api/query", "key-1"))
+	assert.False(t, found)
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 
+	"github.com/flowbi/pgweb/pkg/client"
 	"github.com/flowbi/pgweb/pkg/command"
 )
 
@@ -102,11 +106,44 @@ func RequestLogger(logger *logrus.Logger) gin.HandlerFunc {
 	}
 }
 
+// QueryLogger returns the logger of the query fingerprints, used with the
+// --log-query-fingerprint option. Errors are logged with their SQLSTATE code
+// only since their messages can contain the query values.
+func QueryLogger(logger *logrus.Logger) func(ctx context.Context, entry client.QueryLogEntry) {
+	return func(ctx context.Context, entry client.QueryLogEntry) {
+		fields := logrus.Fields{
+			"fingerprint": entry.Fingerprint,
+			"duration":    entry.Duration.String(),
+			"duration_ms": entry.Duration.Milliseconds(),
+			"rows":        entry.RowsCount,
+		}
+
+		if reqID := client.RequestIDFromContext(ctx); reqID != "" {
+			fields["id"] = reqID
+		}
+
+		if entry.Err != nil {
+			fields["failed"] = true
+
+			var pqErr *pq.Error
+			if errors.As(entry.Err, &pqErr) {
+				fields["error_code"] = string(pqErr.Code)
+			}
+		}
+
+		logger.WithFields(fields).Info("query")
+	}
+}
+
 func sanitizeLogPath(str string) string {
 	return reConnectToken.ReplaceAllString(str, "/connect/REDACTED")
 }
 
 func getRequestID(c *gin.Context) string {
+	if id := c.GetString(requestIDKey); id != "" {
+		return id
+	}
+
 	id := c.GetHeader("x-request-id")
 	if id == "" {
 		id = c.GetHeader("x-amzn-trace-id")
@@ -1,11 +1,21 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/client"
 )
 
 func Test_getRequestID(t *testing.T) {
@@ -29,3 +39,95 @@ func Test_getRequestID(t *testing.T) {
 		assert.Equal(t, ex.result, getRequestID(&gin.Context{Request: req}))
 	}
 }
+
+func TestRequestIDLogging(t *testing.T) {
+	defer func() {
+		DbClient = nil
+	}()
+	DbClient = &client.Client{}
+
+	buff := &bytes.Buffer{}
+	log := logrus.New()
+	log.SetOutput(buff)
+	log.SetFormatter(&logrus.JSONFormatter{})
+
+	router := gin.New()
+	router.Use(RequestLogger(log))
+	router.Use(requestIDMiddleware())
+	router.GET("/api/query", func(c *gin.Context) {
+		c.String(http.StatusOK, client.RequestIDFromContext(c.Request.Context()))
+	})
+
+	request := func(id string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		buff.Reset()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/query", nil)
+		if id != "" {
+			req.Header.Set("X-Request-Id", id)
+		}
+		router.ServeHTTP(w, req)
+
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buff.Bytes(), &entry))
+		return w, entry
+	}
+
+	t.Run("provided id", func(t *testing.T) {
+		w, entry := request("req-123")
+		assert.Equal(t, "req-123", w.Header().Get("X-Request-Id"))
+		assert.Equal(t, "req-123", w.Body.String())
+		assert.Equal(t, "req-123", entry["id"])
+	})
+
+	t.Run("generated id", func(t *testing.T) {
+		w, entry := request("")
+		id := w.Header().Get("X-Request-Id")
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, w.Body.String())
+		assert.Equal(t, id, entry["id"])
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		w, entry := request("abc */ DROP TABLE books; /*")
+		id := w.Header().Get("X-Request-Id")
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, entry["id"])
+	})
+}
+
+func TestQueryLogger(t *testing.T) {
+	buff := &bytes.Buffer{}
+	log := logrus.New()
+	log.SetOutput(buff)
+	log.SetFormatter(&logrus.JSONFormatter{})
+
+	logQuery := QueryLogger(log)
+	entry := func() map[string]interface{} {
+		fields := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buff.Bytes(), &fields))
+		buff.Reset()
+		return fields
+	}
+
+	ctx := client.WithRequestID(context.Background(), "req-123")
+	logQuery(ctx, client.QueryLogEntry{Fingerprint: "SELECT * FROM t WHERE id=?", Duration: 1500 * time.Millisecond, RowsCount: 1})
+
+	fields := entry()
+	assert.Equal(t, "query", fields["msg"])
+	assert.Equal(t, "SELECT * FROM t WHERE id=?", fields["fingerprint"])
+	assert.Equal(t, float64(1500), fields["duration_ms"])
+	assert.Equal(t, float64(1), fields["rows"])
+	assert.Equal(t, "req-123", fields["id"])
+	assert.NotContains(t, fields, "failed")
+
+	// Error messages may contain the query values
+	err := &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint, Key (id)=(5) already exists`}
+	logQuery(context.Background(), client.QueryLogEntry{Fingerprint: "INSERT INTO t VALUES (?)", Err: err})
+
+	fields = entry()
+	assert.Equal(t, true, fields["failed"])
+	assert.Equal(t, "23505", fields["error_code"])
+	assert.NotContains(t, fields, "id")
+	assert.NotContains(t, fields, "error")
+}
@@ -1,19 +1,86 @@
 package api
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/flowbi/pgweb/pkg/client"
 	"github.com/flowbi/pgweb/pkg/command"
 )
 
+// Context key of the request ID
+const requestIDKey = "request_id"
+
+// Middleware to assign the request ID from the X-Request-Id header, or a generated
+// one, and tag the client queries run with the request context with it
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := getRequestID(c)
+		if !client.ValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header("X-Request-Id", id)
+		c.Request = c.Request.WithContext(client.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf) //nolint:errcheck
+	return hex.EncodeToString(buf)
+}
+
+// Middleware to set the application_name of the request queries, rendered from
+// the --application-name template
+func applicationNameMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client.ApplicationNameTemplate == "" {
+			c.Next()
+			return
+		}
+
+		// The forwarded user is only trusted when the proxy setting it is
+		user := c.GetString(gin.AuthUserKey)
+		if user == "" && command.Opts.LogForwardedUser {
+			user = c.GetHeader("X-Forwarded-User")
+		}
+
+		database := ""
+		if conn := DB(c); conn != nil {
+			database = conn.DatabaseName()
+		}
+
+		name, err := client.RenderApplicationName(map[string]string{
+			"user":       user,
+			"session":    getSessionId(c.Request),
+			"database":   database,
+			"request_id": c.GetString(requestIDKey),
+		})
+		if err != nil {
+			badRequest(c, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(client.WithApplicationName(c.Request.Context(), name))
+		c.Next()
+	}
+}
+
 // Middleware to check database connection status before running queries
 func dbCheckMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		path := strings.Replace(c.Request.URL.Path, command.Opts.Prefix, "", -1)
+		path := trimURLPrefix(c.Request.URL.Path)
 
 		// Allow whitelisted paths
 		if allowedPaths[path] {
@@ -50,6 +117,26 @@ func dbCheckMiddleware() gin.HandlerFunc {
 	}
 }
 
+// PrefixHandler restores the url prefix on requests forwarded by a reverse proxy
+// that strips it, so that routes match whether or not the prefix is present.
+func PrefixHandler(handler http.Handler) http.Handler {
+	if !command.Opts.StripPrefix || command.Opts.Prefix == "" {
+		return handler
+	}
+
+	prefix := "/" + strings.TrimSuffix(command.Opts.Prefix, "/")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != prefix && !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			r.URL.Path = prefix + r.URL.Path
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = prefix + r.URL.RawPath
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // Middleware to inject CORS headers
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -93,6 +180,57 @@ func roleInjectionMiddleware() gin.HandlerFunc {
 	}
 }
 
+// Middleware to apply the work_mem requested in X-Work-Mem header, or the default one
+func workMemMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value := c.GetHeader("X-Work-Mem"); value != "" {
+			if err := client.ValidateWorkMem(value); err != nil {
+				badRequest(c, err)
+				return
+			}
+			c.Request = c.Request.WithContext(client.WithWorkMem(c.Request.Context(), value))
+		}
+
+		c.Next()
+	}
+}
+
+// Middleware to apply the query timeout requested in X-Query-Timeout header or
+// timeout parameter, or the global one
+func queryTimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader("X-Query-Timeout")
+		if value == "" {
+			value = c.Query("timeout")
+		}
+
+		if value != "" {
+			timeout, err := client.ParseQueryTimeout(value)
+			if err != nil {
+				badRequest(c, err)
+				return
+			}
+			c.Request = c.Request.WithContext(client.WithQueryTimeout(c.Request.Context(), timeout))
+		}
+
+		c.Next()
+	}
+}
+
+// Middleware to bypass the caches for the rest of the session when the request
+// has the X-No-Cache: session header
+func noCacheMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.EqualFold(c.GetHeader("X-No-Cache"), "session") {
+			if client := DB(c); client != nil {
+				client.SetCacheBypass(true)
+			}
+		}
+
+		c.Next()
+	}
+}
+
 func requireLocalQueries() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if QueryStore == nil {
@@ -104,6 +242,24 @@ func requireLocalQueries() gin.HandlerFunc {
 	}
 }
 
+// Middleware to restrict access to admin endpoints to requests carrying the admin token
+func requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if command.Opts.AdminToken == "" {
+			badRequest(c, "admin endpoints are disabled")
+			return
+		}
+
+		token := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(command.Opts.AdminToken)) != 1 {
+			errorResponse(c, 403, errNotPermitted)
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // Middleware to provide better error messages for common database operation failures
 func errorHandlingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
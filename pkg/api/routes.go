@@ -13,8 +13,13 @@ func SetupMiddlewares(group *gin.RouterGroup) {
 	}
 
 	group.Use(errorHandlingMiddleware()) // Add error handling first
+	group.Use(requestIDMiddleware())
 	group.Use(dbCheckMiddleware())
 	group.Use(roleInjectionMiddleware()) // Add role injection after db check
+	group.Use(workMemMiddleware())
+	group.Use(applicationNameMiddleware())
+	group.Use(queryTimeoutMiddleware())
+	group.Use(noCacheMiddleware())
 }
 
 func SetupRoutes(router *gin.Engine) {
@@ -35,31 +40,75 @@ func SetupRoutes(router *gin.Engine) {
 	api.GET("/config", GetConfig)
 	api.POST("/connect", Connect)
 	api.POST("/disconnect", Disconnect)
+	api.POST("/session/ping", PingSession)
 	api.POST("/switchdb", SwitchDb)
 	api.GET("/databases", GetDatabases)
 	api.GET("/connection", GetConnectionInfo)
 	api.GET("/server_settings", GetServerSettings)
 	api.GET("/activity", GetActivity)
+	api.POST("/activity/terminate", requireReadWrite(), TerminateBackend)
+	api.POST("/activity/terminate-idle", requireReadWrite(), TerminateIdleBackends)
+	api.GET("/stat_statements", GetTopStatements)
 	api.GET("/schemas", GetSchemas)
+	api.GET("/schemas/summary", GetSchemaSummary)
+	api.GET("/schemas/:schema/objects", GetSchemaObjects)
+	api.GET("/schemas/:schema/unindexed_foreign_keys", GetUnindexedForeignKeys)
 	api.GET("/objects", GetObjects)
+	api.GET("/objects/recent", GetRecentObjects)
+	api.GET("/search", SearchObjects)
 	api.GET("/tables/:table", GetTable)
 	api.GET("/tables/:table/rows", GetTableRows)
 	api.GET("/tables/:table/info", GetTableInfo)
+	api.GET("/tables/:table/group", GetTableGroupBy)
+	api.GET("/tables/:table/histogram", GetColumnHistogram)
+	api.GET("/tables/:table/storage", GetTableStorageParams)
+	api.POST("/tables/:table/storage", requireReadWrite(), SetTableStorageParam)
+	api.POST("/tables/:table/statistics", requireReadWrite(), SetColumnStatistics)
 	api.GET("/tables/:table/indexes", GetTableIndexes)
 	api.GET("/tables/:table/constraints", GetTableConstraints)
+	api.GET("/tables/:table/triggers", GetTableTriggers)
+	api.GET("/tables/:table/policies", GetTablePolicies)
+	api.GET("/tables/:table/partitions", GetTablePartitions)
+	api.GET("/tables/:table/stats/columns", GetTableColumnStats)
 	api.GET("/tables_stats", GetTablesStats)
 	api.GET("/functions/:id", GetFunction)
+	api.GET("/sequences", GetSequences)
+	api.GET("/sequences/:sequence", GetSequence)
+	api.GET("/largeobjects/:oid", GetLargeObject)
 	api.GET("/query", RunQuery)
-	api.POST("/query", RunQuery)
+	api.POST("/query", idempotencyMiddleware(), RunQuery)
+	api.POST("/query/parameterized", idempotencyMiddleware(), RunParameterizedQuery)
+	api.POST("/query/script", idempotencyMiddleware(), RunScript)
+	api.POST("/cancel", CancelQuery)
+	api.GET("/query/socket", QuerySocket)
+	api.GET("/ws/notifications", NotificationsSocket)
+	api.GET("/query/stream", StreamQuery)
+	api.GET("/query/lint", LintQuery)
+	api.POST("/query/lint", LintQuery)
 	api.GET("/explain", ExplainQuery)
-	api.POST("/explain", ExplainQuery)
+	api.POST("/explain", ExplainPlan)
 	api.GET("/analyze", AnalyzeQuery)
 	api.POST("/analyze", AnalyzeQuery)
+	api.POST("/format", FormatQuery)
+	api.POST("/crosstab", CrosstabQuery)
+	api.POST("/batch", idempotencyMiddleware(), RunBatch)
+	api.POST("/multi/query", RunMultiQuery)
 	api.GET("/history", GetHistory)
+	api.GET("/transaction", GetTransaction)
+	api.POST("/transaction/begin", BeginTransaction)
+	api.POST("/transaction/commit", CommitTransaction)
+	api.POST("/transaction/rollback", RollbackTransaction)
+	api.POST("/savepoints", CreateSavepoint)
+	api.POST("/savepoints/release", ReleaseSavepoint)
+	api.POST("/savepoints/rollback", RollbackToSavepoint)
 	api.GET("/bookmarks", GetBookmarks)
 	api.GET("/export", DataExport)
 	api.GET("/cache/stats", GetCacheStats)
 	api.POST("/cache/clear", ClearCache)
+	api.POST("/cache/bypass", SetCacheBypass)
+	api.GET("/admin/config", requireAdmin(), GetAdminConfig)
+	api.POST("/admin/disconnect-all", requireAdmin(), DisconnectAll)
+	api.POST("/admin/reset_stats", requireAdmin(), requireReadWrite(), ResetStats)
 	api.GET("/local_queries", requireLocalQueries(), GetLocalQueries)
 	api.GET("/local_queries/:id", requireLocalQueries(), RunLocalQuery)
 	api.POST("/local_queries/:id", requireLocalQueries(), RunLocalQuery)
@@ -15,6 +15,8 @@ type SessionManager struct {
 	sessions    map[string]*client.Client
 	mu          sync.Mutex
 	idleTimeout time.Duration
+	maxSessions int
+	evictIdle   bool
 }
 
 func NewSessionManager(logger *logrus.Logger) *SessionManager {
@@ -29,6 +31,13 @@ func (m *SessionManager) SetIdleTimeout(timeout time.Duration) {
 	m.idleTimeout = timeout
 }
 
+// SetMaxSessions limits the number of sessions. When evictIdle is set, the least
+// recently used idle session is closed to make room for a new one instead of rejecting it.
+func (m *SessionManager) SetMaxSessions(max int, evictIdle bool) {
+	m.maxSessions = max
+	m.evictIdle = evictIdle
+}
+
 func (m *SessionManager) IDs() []string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -60,12 +69,61 @@ func (m *SessionManager) Get(id string) *client.Client {
 	return m.sessions[id]
 }
 
-func (m *SessionManager) Add(id string, conn *client.Client) {
+func (m *SessionManager) Add(id string, conn *client.Client) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if _, exists := m.sessions[id]; !exists && m.maxSessions > 0 && len(m.sessions) >= m.maxSessions {
+		if !m.evictIdle || !m.evictLeastRecentlyUsed() {
+			return errTooManySessions
+		}
+	}
+
 	m.sessions[id] = conn
+	m.updateMetrics()
+	return nil
+}
+
+// evictLeastRecentlyUsed closes the idle session with the oldest last query time.
+// Sessions not idle for the connection idle timeout, running a query or with an
+// open transaction are never evicted. Must be called with the lock held.
+func (m *SessionManager) evictLeastRecentlyUsed() bool {
+	var (
+		oldestID   string
+		oldestTime time.Time
+	)
+
+	for id, conn := range m.sessions {
+		if !conn.IsIdle() || conn.InTransaction() {
+			continue
+		}
+		if oldestID == "" || conn.LastQueryTime().Before(oldestTime) {
+			oldestID = id
+			oldestTime = conn.LastQueryTime()
+		}
+	}
+	if oldestID == "" {
+		return false
+	}
+
+	if m.logger != nil {
+		m.logger.WithField("id", oldestID).Info("evicting least recently used session")
+	}
+
+	m.sessions[oldestID].Close()
+	delete(m.sessions, oldestID)
+	return true
+}
+
+// updateMetrics reports the sessions count. Must be called with the lock held.
+func (m *SessionManager) updateMetrics() {
+	databases := map[string]int{}
+	for _, conn := range m.sessions {
+		databases[conn.DatabaseName()]++
+	}
+
 	metrics.SetSessionsCount(len(m.sessions))
+	metrics.SetActiveSessions(databases)
 }
 
 func (m *SessionManager) Remove(id string) bool {
@@ -78,10 +136,25 @@ func (m *SessionManager) Remove(id string) bool {
 		delete(m.sessions, id)
 	}
 
-	metrics.SetSessionsCount(len(m.sessions))
+	m.updateMetrics()
 	return ok
 }
 
+// RemoveAll closes and removes all sessions, returning the number of sessions removed
+func (m *SessionManager) RemoveAll() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := len(m.sessions)
+	for id, conn := range m.sessions {
+		conn.Close()
+		delete(m.sessions, id)
+	}
+
+	m.updateMetrics()
+	return removed
+}
+
 func (m *SessionManager) Len() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -102,7 +175,7 @@ func (m *SessionManager) Cleanup() int {
 	}()
 
 	for _, id := range m.staleSessions() {
-		m.logger.WithField("id", id).Debug("closing stale session")
+		m.logger.WithField("id", id).WithField("timeout", m.idleTimeout).Debug("closing idle session")
 		if m.Remove(id) {
 			removed++
 		}
@@ -127,6 +200,10 @@ func (m *SessionManager) staleSessions() []string {
 	ids := []string{}
 
 	for id, conn := range m.sessions {
+		// Sessions running long queries are not idle
+		if conn.IsBusy() {
+			continue
+		}
 		if now.Sub(conn.LastQueryTime()) > m.idleTimeout {
 			ids = append(ids, id)
 		}
@@ -5,10 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/flowbi/pgweb/pkg/client"
+	"github.com/flowbi/pgweb/pkg/command"
 )
 
 func TestSessionManager(t *testing.T) {
@@ -51,6 +53,90 @@ func TestSessionManager(t *testing.T) {
 		assert.Nil(t, manager.Get("foo"))
 	})
 
+	t.Run("reject sessions over the limit", func(t *testing.T) {
+		manager := NewSessionManager(nil)
+		manager.SetMaxSessions(2, false)
+
+		assert.NoError(t, manager.Add("foo", &client.Client{}))
+		assert.NoError(t, manager.Add("bar", &client.Client{}))
+		assert.Equal(t, errTooManySessions, manager.Add("baz", &client.Client{}))
+		assert.Nil(t, manager.Get("baz"))
+		assert.Equal(t, 2, manager.Len())
+
+		// Replacing an existing session is always allowed
+		assert.NoError(t, manager.Add("foo", &client.Client{}))
+	})
+
+	t.Run("evict least recently used session over the limit", func(t *testing.T) {
+		defer func(timeout int) {
+			command.Opts.ConnectionIdleTimeout = timeout
+		}(command.Opts.ConnectionIdleTimeout)
+		command.Opts.ConnectionIdleTimeout = 180
+
+		manager := NewSessionManager(nil)
+		manager.SetMaxSessions(2, true)
+
+		idle := &client.Client{}
+		active := &client.Client{}
+		active.Touch()
+
+		assert.NoError(t, manager.Add("idle", idle))
+		assert.NoError(t, manager.Add("active", active))
+		assert.NoError(t, manager.Add("new", &client.Client{}))
+
+		assert.Equal(t, 2, manager.Len())
+		assert.Nil(t, manager.Get("idle"))
+		assert.True(t, idle.IsClosed())
+		assert.NotNil(t, manager.Get("active"))
+		assert.NotNil(t, manager.Get("new"))
+	})
+
+	t.Run("reject sessions over the limit without idle sessions", func(t *testing.T) {
+		manager := NewSessionManager(nil)
+		manager.SetMaxSessions(1, true)
+
+		busy := &client.Client{}
+		done := busy.BeginQuery()
+		defer done()
+
+		assert.NoError(t, manager.Add("busy", busy))
+		assert.Equal(t, errTooManySessions, manager.Add("new", &client.Client{}))
+		assert.False(t, busy.IsClosed())
+		assert.NotNil(t, manager.Get("busy"))
+	})
+
+	t.Run("reject sessions over the limit when no session is idle", func(t *testing.T) {
+		defer func(timeout int) {
+			command.Opts.ConnectionIdleTimeout = timeout
+		}(command.Opts.ConnectionIdleTimeout)
+		command.Opts.ConnectionIdleTimeout = 180
+
+		manager := NewSessionManager(nil)
+		manager.SetMaxSessions(1, true)
+
+		recent := &client.Client{}
+		recent.Touch()
+
+		assert.NoError(t, manager.Add("recent", recent))
+		assert.Equal(t, errTooManySessions, manager.Add("new", &client.Client{}))
+		assert.False(t, recent.IsClosed())
+		assert.NotNil(t, manager.Get("recent"))
+	})
+
+	t.Run("report active sessions metrics", func(t *testing.T) {
+		manager := NewSessionManager(nil)
+
+		manager.Add("foo", &client.Client{ConnectionString: "postgres://localhost/metrics_db"})
+		manager.Add("bar", &client.Client{ConnectionString: "postgres://localhost/metrics_db"})
+		assert.Equal(t, 2.0, activeSessionsMetric(t, "metrics_db"))
+
+		manager.Remove("foo")
+		assert.Equal(t, 1.0, activeSessionsMetric(t, "metrics_db"))
+
+		manager.Remove("bar")
+		assert.Equal(t, 0.0, activeSessionsMetric(t, "metrics_db"))
+	})
+
 	t.Run("return len", func(t *testing.T) {
 		manager := NewSessionManager(nil)
 		manager.sessions["foo"] = &client.Client{}
@@ -77,4 +163,43 @@ func TestSessionManager(t *testing.T) {
 		assert.Equal(t, 0, manager.Len())
 		assert.True(t, conn.IsClosed())
 	})
+
+	t.Run("keep sessions with queries in flight", func(t *testing.T) {
+		manager := NewSessionManager(logrus.New())
+		manager.SetIdleTimeout(time.Nanosecond)
+
+		conn := &client.Client{}
+		manager.Add("foo", conn)
+
+		done := conn.BeginQuery()
+		time.Sleep(time.Millisecond)
+		assert.True(t, conn.IsBusy())
+		assert.Equal(t, 0, manager.Cleanup())
+		assert.Equal(t, 1, manager.Len())
+
+		done()
+		time.Sleep(time.Millisecond)
+		assert.Equal(t, 1, manager.Cleanup())
+		assert.True(t, conn.IsClosed())
+	})
+}
+
+func activeSessionsMetric(t *testing.T, database string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "pgweb_active_sessions" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == "database" && label.GetValue() == database {
+					return metric.Gauge.GetValue()
+				}
+			}
+		}
+	}
+
+	return 0
 }
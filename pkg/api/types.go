@@ -1,8 +1,19 @@
 package api
 
+import (
+	"github.com/flowbi/pgweb/pkg/client"
+)
+
 type localQuery struct {
 	ID          string `json:"id"`
 	Title       string `json:"title,omitempty"`
 	Description string `json:"description,omitempty"`
 	Query       string `json:"query"`
 }
+
+// multiQueryResult holds the outcome of a query executed against a single bookmark
+type multiQueryResult struct {
+	Bookmark string         `json:"bookmark"`
+	Result   *client.Result `json:"result,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
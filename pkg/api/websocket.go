@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/flowbi/pgweb/pkg/client"
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/metrics"
+)
+
+const (
+	// Messages accepted from the websocket client
+	socketMessageQuery  = "query"
+	socketMessageCancel = "cancel"
+
+	// Frames sent to the websocket client
+	socketFrameColumns  = "columns"
+	socketFrameRows     = "rows"
+	socketFrameProgress = "progress"
+	socketFrameNotice   = "notice"
+	socketFrameDone     = "done"
+	socketFrameError    = "error"
+
+	// Frame of a notification sent with NOTIFY
+	socketFrameNotification = "notification"
+)
+
+type (
+	// socketMessage is a request sent by the websocket client
+	socketMessage struct {
+		Type  string `json:"type"`
+		ID    string `json:"id"`
+		Query string `json:"query"`
+	}
+
+	// socketFrame is a single message of the streamed query output
+	socketFrame struct {
+		Type      string              `json:"type"`
+		ID        string              `json:"id,omitempty"`
+		Columns   []string            `json:"columns,omitempty"`
+		Rows      []client.Row        `json:"rows,omitempty"`
+		RowsCount int                 `json:"rows_count,omitempty"`
+		Notice    *client.Notice      `json:"notice,omitempty"`
+		Stats     *client.ResultStats `json:"stats,omitempty"`
+		Error     string              `json:"error,omitempty"`
+		Channel   string              `json:"channel,omitempty"`
+		Payload   string              `json:"payload,omitempty"`
+	}
+
+	// queryStreamer runs the query, delivering its output to the stream
+	queryStreamer func(ctx context.Context, query string, stream client.QueryStream) (*client.ResultStats, error)
+
+	// notificationsListener forwards the notification payloads to ch until the context is cancelled
+	notificationsListener func(ctx context.Context, ch chan<- string) error
+)
+
+// QuerySocket runs queries sent over a websocket connection and streams back their output
+func QuerySocket(c *gin.Context) {
+	conn := DB(c)
+	if conn == nil {
+		badRequest(c, errNotConnected)
+		return
+	}
+
+	readOnly := isReadOnlyUser(c)
+	streamer := func(ctx context.Context, query string, stream client.QueryStream) (*client.ResultStats, error) {
+		if readOnly {
+			ctx = client.WithReadOnly(ctx)
+		}
+		stats, err := conn.Stream(ctx, query, stream)
+
+		// Make sure subsequent reads of the modified table are not served from cache
+		if err == nil && QueryCache != nil {
+			if table := mutatedTable(query); table != "" {
+				invalidateQueryCache(conn.ConnectionString, table)
+			}
+		}
+
+		return stats, err
+	}
+
+	server := websocket.Server{
+		Handshake: checkSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			serveQuerySocket(ws, streamer)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// NotificationsSocket forwards the notifications sent to the --notify-channel channel
+// over a websocket connection, ie. the progress reported by long-running jobs
+func NotificationsSocket(c *gin.Context) {
+	conn := DB(c)
+	if conn == nil {
+		badRequest(c, errNotConnected)
+		return
+	}
+
+	channel := command.Opts.NotifyChannel
+	listen := func(ctx context.Context, ch chan<- string) error {
+		return conn.ListenContext(ctx, channel, ch)
+	}
+
+	server := websocket.Server{
+		Handshake: checkSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			serveNotificationsSocket(ws, channel, listen)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkSocketOrigin rejects cross-origin websocket connections unless CORS is enabled
+func checkSocketOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+
+	if originURL.Host == req.Host {
+		return nil
+	}
+	if command.Opts.Cors && (command.Opts.CorsOrigin == "*" || command.Opts.CorsOrigin == origin) {
+		return nil
+	}
+	return errNotPermitted
+}
+
+// serveQuerySocket reads client messages until the socket is closed. A single query
+// runs at a time and is cancelled when requested or once the socket goes away.
+func serveQuerySocket(ws *websocket.Conn, streamer queryStreamer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		running     string
+		cancelQuery context.CancelFunc
+	)
+
+	send := func(frame socketFrame) error {
+		return websocket.JSON.Send(ws, frame)
+	}
+
+	for {
+		msg := socketMessage{}
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			break
+		}
+
+		switch msg.Type {
+		case socketMessageQuery:
+			query := cleanQuery(msg.Query)
+			if query == "" {
+				send(socketFrame{Type: socketFrameError, ID: msg.ID, Error: errQueryRequired.Error()}) //nolint:errcheck
+				continue
+			}
+
+			mu.Lock()
+			if cancelQuery != nil {
+				mu.Unlock()
+				send(socketFrame{Type: socketFrameError, ID: msg.ID, Error: errQueryRunning.Error()}) //nolint:errcheck
+				continue
+			}
+			queryCtx, queryCancel := context.WithCancel(ctx)
+			running, cancelQuery = msg.ID, queryCancel
+			mu.Unlock()
+
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				defer func() {
+					mu.Lock()
+					cancelQuery()
+					running, cancelQuery = "", nil
+					mu.Unlock()
+				}()
+
+				runSocketQuery(queryCtx, id, query, streamer, send)
+			}(msg.ID)
+
+		case socketMessageCancel:
+			mu.Lock()
+			if cancelQuery != nil && (msg.ID == "" || msg.ID == running) {
+				cancelQuery()
+			}
+			mu.Unlock()
+
+		default:
+			send(socketFrame{Type: socketFrameError, ID: msg.ID, Error: "Unsupported message type"}) //nolint:errcheck
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func runSocketQuery(ctx context.Context, id, query string, streamer queryStreamer, send func(socketFrame) error) {
+	metrics.IncrementQueriesCount()
+
+	count := 0
+	stream := client.QueryStream{
+		OnColumns: func(columns []string) error {
+			return send(socketFrame{Type: socketFrameColumns, ID: id, Columns: columns})
+		},
+		OnRows: func(rows []client.Row) error {
+			if err := send(socketFrame{Type: socketFrameRows, ID: id, Rows: rows}); err != nil {
+				return err
+			}
+			count += len(rows)
+			return send(socketFrame{Type: socketFrameProgress, ID: id, RowsCount: count})
+		},
+		OnNotice: func(notice client.Notice) {
+			send(socketFrame{Type: socketFrameNotice, ID: id, Notice: &notice}) //nolint:errcheck
+		},
+	}
+
+	stats, err := streamer(ctx, query, stream)
+	if err != nil {
+		metrics.IncrementQueryErrors(err)
+
+		// Rows sent before the error are kept by the client as a partial result
+		frame := socketFrame{Type: socketFrameError, ID: id, Error: err.Error()}
+		var partial *client.PartialStreamError
+		if errors.As(err, &partial) {
+			frame.RowsCount = partial.RowsCount
+		}
+
+		send(frame) //nolint:errcheck
+		return
+	}
+
+	send(socketFrame{Type: socketFrameDone, ID: id, Stats: stats}) //nolint:errcheck
+}
+
+// serveNotificationsSocket sends the notifications to the websocket client until
+// the socket is closed, the listener stops once the client goes away
+func serveNotificationsSocket(ws *websocket.Conn, channel string, listen notificationsListener) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Messages are not expected from the client, reads only detect the closed socket
+	go func() {
+		defer cancel()
+
+		var msg socketMessage
+		for websocket.JSON.Receive(ws, &msg) == nil {
+		}
+	}()
+
+	ch := make(chan string)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- listen(ctx, ch)
+	}()
+
+	for {
+		select {
+		case payload := <-ch:
+			if err := websocket.JSON.Send(ws, socketFrame{Type: socketFrameNotification, Channel: channel, Payload: payload}); err != nil {
+				cancel()
+				<-errCh
+				return
+			}
+
+		case err := <-errCh:
+			if err != nil {
+				websocket.JSON.Send(ws, socketFrame{Type: socketFrameError, Error: err.Error()}) //nolint:errcheck
+			}
+			return
+		}
+	}
+}
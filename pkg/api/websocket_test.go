@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	"github.com/flowbi/pgweb/pkg/client"
+)
+
+func startQuerySocket(t *testing.T, streamer queryStreamer) *websocket.Conn {
+	server := httptest.NewServer(websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			serveQuerySocket(ws, streamer)
+		},
+	})
+	t.Cleanup(server.Close)
+
+	ws, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1), "", server.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+
+	return ws
+}
+
+func receiveFrames(t *testing.T, ws *websocket.Conn, last string) []socketFrame {
+	frames := []socketFrame{}
+	for {
+		frame := socketFrame{}
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, websocket.JSON.Receive(ws, &frame))
+
+		frames = append(frames, frame)
+		if frame.Type == last || frame.Type == socketFrameError {
+			return frames
+		}
+	}
+}
+
+func Test_serveQuerySocket(t *testing.T) {
+	t.Run("select", func(t *testing.T) {
+		ws := startQuerySocket(t, func(ctx context.Context, query string, stream client.QueryStream) (*client.ResultStats, error) {
+			assert.Equal(t, "SELECT 1 AS num", query)
+
+			stream.OnNotice(client.Notice{Severity: "NOTICE", Message: "hello"})
+			if err := stream.OnColumns([]string{"num"}); err != nil {
+				return nil, err
+			}
+			if err := stream.OnRows([]client.Row{{1}}); err != nil {
+				return nil, err
+			}
+			return &client.ResultStats{ColumnsCount: 1, RowsCount: 1}, nil
+		})
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "query", ID: "q1", Query: "SELECT 1 AS num"}))
+		frames := receiveFrames(t, ws, socketFrameDone)
+
+		types := []string{}
+		for _, frame := range frames {
+			assert.Equal(t, "q1", frame.ID)
+			types = append(types, frame.Type)
+		}
+		assert.Equal(t, []string{"notice", "columns", "rows", "progress", "done"}, types)
+
+		assert.Equal(t, "hello", frames[0].Notice.Message)
+		assert.Equal(t, []string{"num"}, frames[1].Columns)
+		assert.Equal(t, []client.Row{{float64(1)}}, frames[2].Rows)
+		assert.Equal(t, 1, frames[3].RowsCount)
+		assert.Equal(t, 1, frames[4].Stats.RowsCount)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ws := startQuerySocket(t, func(ctx context.Context, query string, stream client.QueryStream) (*client.ResultStats, error) {
+			return nil, errors.New("syntax error")
+		})
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "query", ID: "q1"}))
+		frames := receiveFrames(t, ws, socketFrameDone)
+		assert.Equal(t, socketFrame{Type: "error", ID: "q1", Error: errQueryRequired.Error()}, frames[0])
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "query", ID: "q2", Query: "SELEC"}))
+		frames = receiveFrames(t, ws, socketFrameDone)
+		assert.Equal(t, socketFrame{Type: "error", ID: "q2", Error: "syntax error"}, frames[0])
+	})
+
+	t.Run("error after rows", func(t *testing.T) {
+		ws := startQuerySocket(t, func(ctx context.Context, query string, stream client.QueryStream) (*client.ResultStats, error) {
+			if err := stream.OnColumns([]string{"num"}); err != nil {
+				return nil, err
+			}
+			if err := stream.OnRows([]client.Row{{1}, {2}}); err != nil {
+				return nil, err
+			}
+			return nil, &client.PartialStreamError{RowsCount: 2, Err: errors.New("division by zero")}
+		})
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "query", ID: "q1", Query: "SELECT 1 / (3 - i) FROM generate_series(1, 3) i"}))
+		frames := receiveFrames(t, ws, socketFrameDone)
+
+		require.Len(t, frames, 4)
+		assert.Equal(t, []client.Row{{float64(1)}, {float64(2)}}, frames[1].Rows)
+		assert.Equal(t, socketFrame{Type: "error", ID: "q1", Error: "division by zero", RowsCount: 2}, frames[3])
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		started := make(chan struct{})
+		ws := startQuerySocket(t, func(ctx context.Context, query string, stream client.QueryStream) (*client.ResultStats, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "query", ID: "q1", Query: "SELECT pg_sleep(10)"}))
+		<-started
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "query", ID: "q2", Query: "SELECT 1"}))
+		frames := receiveFrames(t, ws, socketFrameDone)
+		assert.Equal(t, socketFrame{Type: "error", ID: "q2", Error: errQueryRunning.Error()}, frames[0])
+
+		require.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: "cancel", ID: "q1"}))
+		frames = receiveFrames(t, ws, socketFrameDone)
+		assert.Equal(t, socketFrame{Type: "error", ID: "q1", Error: context.Canceled.Error()}, frames[0])
+	})
+}
+
+func startNotificationsSocket(t *testing.T, listen notificationsListener) *websocket.Conn {
+	server := httptest.NewServer(websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			serveNotificationsSocket(ws, "progress", listen)
+		},
+	})
+	t.Cleanup(server.Close)
+
+	ws, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1), "", server.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+
+	return ws
+}
+
+func Test_serveNotificationsSocket(t *testing.T) {
+	t.Run("notifications", func(t *testing.T) {
+		stopped := make(chan struct{})
+		ws := startNotificationsSocket(t, func(ctx context.Context, ch chan<- string) error {
+			defer close(stopped)
+
+			ch <- "10%"
+			ch <- "20%"
+			<-ctx.Done()
+			return nil
+		})
+
+		frames := []socketFrame{}
+		for range 2 {
+			frame := socketFrame{}
+			require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+			require.NoError(t, websocket.JSON.Receive(ws, &frame))
+			frames = append(frames, frame)
+		}
+		assert.Equal(t, []socketFrame{
+			{Type: "notification", Channel: "progress", Payload: "10%"},
+			{Type: "notification", Channel: "progress", Payload: "20%"},
+		}, frames)
+
+		// Closing the socket stops the listener
+		ws.Close()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("listener did not stop")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ws := startNotificationsSocket(t, func(ctx context.Context, ch chan<- string) error {
+			return errors.New("database connection is not established")
+		})
+
+		frames := receiveFrames(t, ws, socketFrameNotification)
+		assert.Equal(t, []socketFrame{{Type: "error", Error: "database connection is not established"}}, frames)
+	})
+}
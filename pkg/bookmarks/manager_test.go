@@ -96,6 +96,7 @@ func Test_readBookmark(t *testing.T) {
 		assert.Equal(t, "ssh-password", sshc.Password)
 		assert.Equal(t, "/path/to/key-file", sshc.Key)
 		assert.Equal(t, "key-file-password", sshc.KeyPassword)
+		assert.Equal(t, "/path/to/known_hosts", sshc.KnownHosts)
 	})
 
 	t.Run("invalid ssl", func(t *testing.T) {
@@ -1,18 +1,33 @@
 package cache
 
 import (
+	"container/heap"
 	"crypto/md5"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
+// evictionPolicy defines which items are removed first when the cache is full
+type evictionPolicy int
+
+const (
+	evictByExpiration evictionPolicy = iota // Items expiring first are removed first
+	evictLRU                                // Least recently used items are removed first
+)
+
 type item struct {
-	value     interface{}
-	expiresAt time.Time
-	size      int64 // Estimated memory size in bytes
+	value      interface{}
+	expiresAt  time.Time
+	ttl        time.Duration
+	size       int64                       // Estimated memory size in bytes
+	accessedAt atomic.Uint64               // Cache access counter value of the last Set or Get
+	reads      atomic.Uint64               // Number of Get calls returning the item
+	load       func() (interface{}, error) // Reloads the value in the background, if set
 }
 
 type Cache struct {
@@ -22,6 +37,10 @@ type Cache struct {
 	maxItems    int   // Maximum number of items (0 = unlimited)
 	maxMemory   int64 // Maximum memory usage in bytes (0 = unlimited)
 	currentSize int64 // Current memory usage tracking
+	policy      evictionPolicy
+	accesses    atomic.Uint64 // Access counter ordering items by recency
+	hits        atomic.Uint64 // Number of Get calls returning a value
+	misses      atomic.Uint64 // Number of Get calls finding no value
 }
 
 func New(defaultTTL time.Duration) *Cache {
@@ -68,6 +87,22 @@ func NewWithMemoryLimit(defaultTTL time.Duration, maxMemoryMB int) *Cache {
 	return c
 }
 
+// NewWithLRU creates a cache evicting the least recently used items first
+func NewWithLRU(defaultTTL time.Duration) *Cache {
+	c := &Cache{
+		items:      make(map[string]*item),
+		defaultTTL: defaultTTL,
+		maxItems:   500,               // Reasonable default max items
+		maxMemory:  100 * 1024 * 1024, // Default 100MB memory limit
+		policy:     evictLRU,
+	}
+
+	// Start cleanup goroutine
+	go c.cleanup()
+
+	return c
+}
+
 // NewWithoutCleanup creates a cache without starting the cleanup goroutine
 // Useful for testing or when cleanup is managed externally
 func NewWithoutCleanup(defaultTTL time.Duration) *Cache {
@@ -80,6 +115,16 @@ func NewWithoutCleanup(defaultTTL time.Duration) *Cache {
 }
 
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.set(key, value, ttl, nil)
+}
+
+// SetWithLoader is like Set, the load function is used to refresh the value
+// before it expires when the item is read frequently, see RefreshExpiring
+func (c *Cache) SetWithLoader(key string, value interface{}, ttl time.Duration, load func() (interface{}, error)) {
+	c.set(key, value, ttl, load)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl time.Duration, load func() (interface{}, error)) {
 	if ttl == 0 {
 		ttl = c.defaultTTL
 	}
@@ -113,8 +158,11 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	newItem := &item{
 		value:     value,
 		expiresAt: time.Now().Add(ttl),
+		ttl:       ttl,
 		size:      itemSize,
+		load:      load,
 	}
+	newItem.accessedAt.Store(c.accesses.Add(1))
 
 	c.items[key] = newItem
 	c.currentSize += itemSize
@@ -126,14 +174,22 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 
 	item, exists := c.items[key]
 	if !exists {
+		c.misses.Add(1)
 		return nil, false
 	}
 
 	if time.Now().After(item.expiresAt) {
 		// Item expired, will be cleaned up later
+		c.misses.Add(1)
 		return nil, false
 	}
 
+	c.hits.Add(1)
+
+	// Updated atomically since only the read lock is held
+	item.accessedAt.Store(c.accesses.Add(1))
+	item.reads.Add(1)
+
 	return item.value, true
 }
 
@@ -146,6 +202,32 @@ func (c *Cache) Delete(key string) {
 	}
 }
 
+// DeleteFunc removes all items for which the match function returns true,
+// returning the number of removed items
+func (c *Cache) DeleteFunc(match func(key string, value interface{}) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, item := range c.items {
+		if match(key, item.value) {
+			c.currentSize -= item.size
+			delete(c.items, key)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// DeleteByPrefix removes all items with keys starting with the prefix,
+// returning the number of removed items
+func (c *Cache) DeleteByPrefix(prefix string) int {
+	return c.DeleteFunc(func(key string, _ interface{}) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
 func (c *Cache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -153,6 +235,71 @@ func (c *Cache) Clear() {
 	c.currentSize = 0
 }
 
+// RefreshExpiring reloads the items expiring within the window that were read
+// at least minReads times since they were set, so frequent reads never miss.
+// Only items set with a loader are refreshed, failed loads are left to expire.
+// Returns the number of refreshed items.
+func (c *Cache) RefreshExpiring(window time.Duration, minReads uint64) int {
+	type candidate struct {
+		key  string
+		item *item
+	}
+
+	c.mu.RLock()
+	deadline := time.Now().Add(window)
+	candidates := []candidate{}
+	for key, item := range c.items {
+		if item.load != nil && item.expiresAt.Before(deadline) && item.reads.Load() >= minReads {
+			candidates = append(candidates, candidate{key, item})
+		}
+	}
+	c.mu.RUnlock()
+
+	refreshed := 0
+	for _, cand := range candidates {
+		// Loaders might be slow, so they run without the lock held
+		value, err := cand.item.load()
+		if err != nil || value == nil {
+			continue
+		}
+
+		c.mu.Lock()
+		// Items deleted or replaced in the meantime are not brought back
+		current := c.items[cand.key] == cand.item
+		c.mu.Unlock()
+
+		if current {
+			c.set(cand.key, value, cand.item.ttl, cand.item.load)
+			refreshed++
+		}
+	}
+
+	return refreshed
+}
+
+// StartRefresher calls RefreshExpiring at the interval until the returned stop
+// function is called
+func (c *Cache) StartRefresher(interval, window time.Duration, minReads uint64) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.RefreshExpiring(window, minReads)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
 func (c *Cache) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -188,9 +335,16 @@ func (c *Cache) Stats() map[string]interface{} {
 		"memory_used_mb":    c.currentSize / (1024 * 1024),
 		"memory_limit_mb":   c.maxMemory / (1024 * 1024),
 		"memory_used_bytes": c.currentSize,
+		"hits":              c.hits.Load(),
+		"misses":            c.misses.Load(),
 	}
 }
 
+// HitsAndMisses returns the number of Get calls that found a value and that did not
+func (c *Cache) HitsAndMisses() (uint64, uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
 // estimateSize estimates the memory size of a value in bytes
 func (c *Cache) estimateSize(value interface{}) int64 {
 	if value == nil {
@@ -248,72 +402,82 @@ func (c *Cache) evictExpired() {
 	}
 }
 
-// evictOldest removes the N oldest items by expiration time (called with lock held)
+// evictsBefore returns true if item a is evicted before item b under the cache policy
+func (c *Cache) evictsBefore(a, b *item) bool {
+	if c.policy == evictLRU {
+		return a.accessedAt.Load() < b.accessedAt.Load()
+	}
+	return a.expiresAt.Before(b.expiresAt)
+}
+
+// evictOldest removes the N first items in eviction order (called with lock held)
 func (c *Cache) evictOldest(count int) {
 	if count <= 0 {
 		return
 	}
 
-	// Collect items with their keys and sort by expiration time
-	type keyItem struct {
-		key  string
-		item *item
-	}
-
-	items := make([]keyItem, 0, len(c.items))
-	for key, item := range c.items {
-		items = append(items, keyItem{key, item})
-	}
-
-	// Sort by expiration time (oldest first)
-	for i := 0; i < len(items); i++ {
-		for j := i + 1; j < len(items); j++ {
-			if items[i].item.expiresAt.After(items[j].item.expiresAt) {
-				items[i], items[j] = items[j], items[i]
-			}
-		}
-	}
-
-	// Remove the oldest count items
-	for i := 0; i < count && i < len(items); i++ {
-		c.currentSize -= items[i].item.size
-		delete(c.items, items[i].key)
+	queue := c.evictionQueue()
+	for i := 0; i < count && queue.Len() > 0; i++ {
+		entry := heap.Pop(queue).(evictionEntry)
+		c.currentSize -= entry.item.size
+		delete(c.items, entry.key)
 	}
 }
 
-// evictOldestBySize removes items until the specified amount of memory is freed
+// evictOldestBySize removes items in eviction order until the specified amount
+// of memory is freed (called with lock held)
 func (c *Cache) evictOldestBySize(targetBytesToFree int64) {
 	if targetBytesToFree <= 0 {
 		return
 	}
 
-	// Collect items with their keys and sort by expiration time (oldest first)
-	type keyItem struct {
-		key  string
-		item *item
+	queue := c.evictionQueue()
+	freedBytes := int64(0)
+	for freedBytes < targetBytesToFree && queue.Len() > 0 {
+		entry := heap.Pop(queue).(evictionEntry)
+		freedBytes += entry.item.size
+		c.currentSize -= entry.item.size
+		delete(c.items, entry.key)
 	}
+}
 
-	items := make([]keyItem, 0, len(c.items))
+// evictionQueue returns a heap of all items, popping them in eviction order
+func (c *Cache) evictionQueue() *evictionHeap {
+	queue := &evictionHeap{
+		entries: make([]evictionEntry, 0, len(c.items)),
+		less:    c.evictsBefore,
+	}
 	for key, item := range c.items {
-		items = append(items, keyItem{key, item})
+		queue.entries = append(queue.entries, evictionEntry{key, item})
 	}
 
-	// Sort by expiration time (oldest first)
-	for i := 0; i < len(items); i++ {
-		for j := i + 1; j < len(items); j++ {
-			if items[i].item.expiresAt.After(items[j].item.expiresAt) {
-				items[i], items[j] = items[j], items[i]
-			}
-		}
-	}
+	heap.Init(queue)
+	return queue
+}
 
-	// Remove items until we've freed enough memory
-	freedBytes := int64(0)
-	for i := 0; i < len(items) && freedBytes < targetBytesToFree; i++ {
-		freedBytes += items[i].item.size
-		c.currentSize -= items[i].item.size
-		delete(c.items, items[i].key)
-	}
+type evictionEntry struct {
+	key  string
+	item *item
+}
+
+// evictionHeap implements heap.Interface over the cache items
+type evictionHeap struct {
+	entries []evictionEntry
+	less    func(a, b *item) bool
+}
+
+func (h *evictionHeap) Len() int           { return len(h.entries) }
+func (h *evictionHeap) Less(i, j int) bool { return h.less(h.entries[i].item, h.entries[j].item) }
+func (h *evictionHeap) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *evictionHeap) Push(x interface{}) {
+	h.entries = append(h.entries, x.(evictionEntry))
+}
+
+func (h *evictionHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
 }
 
 // GenerateKey creates a cache key from multiple string components
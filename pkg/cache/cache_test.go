@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -21,6 +23,28 @@ func TestCache_BasicOperations(t *testing.T) {
 	}
 }
 
+func TestCache_DeleteFunc(t *testing.T) {
+	cache := New(5 * time.Second)
+	defer cache.Clear()
+
+	cache.Set("foo:1", "one", 0)
+	cache.Set("foo:2", "two", 0)
+	cache.Set("bar:1", "one", 0)
+
+	removed := cache.DeleteFunc(func(key string, value interface{}) bool {
+		return value.(string) == "one"
+	})
+	if removed != 2 {
+		t.Errorf("Expected 2 removed items, got %v", removed)
+	}
+	if _, found := cache.Get("foo:1"); found {
+		t.Error("Expected foo:1 to be removed")
+	}
+	if _, found := cache.Get("foo:2"); !found {
+		t.Error("Expected foo:2 to be kept")
+	}
+}
+
 func TestCache_Expiration(t *testing.T) {
 	cache := New(100 * time.Millisecond)
 	defer cache.Clear()
@@ -55,3 +79,211 @@ func TestCache_GenerateKey(t *testing.T) {
 		t.Error("Different components should generate different keys")
 	}
 }
+
+func TestCache_LRUEviction(t *testing.T) {
+	cache := NewWithLRU(5 * time.Second)
+	defer cache.Clear()
+	cache.maxItems = 3
+
+	cache.Set("hot", "hot_value", 0)
+	cache.Set("cold", "cold_value", 0)
+	cache.Set("warm", "warm_value", 0)
+
+	// Reading keeps the first written key in use
+	for i := 0; i < 3; i++ {
+		if _, found := cache.Get("hot"); !found {
+			t.Fatal("Expected to find hot key")
+		}
+	}
+
+	cache.Set("new", "new_value", 0)
+
+	if _, found := cache.Get("hot"); !found {
+		t.Error("Expected repeatedly read key to survive eviction")
+	}
+	if _, found := cache.Get("cold"); found {
+		t.Error("Expected least recently used key to be evicted")
+	}
+	if _, found := cache.Get("warm"); !found {
+		t.Error("Expected warm key to be kept")
+	}
+}
+
+func TestCache_LRUEvictionBySize(t *testing.T) {
+	cache := NewWithLRU(5 * time.Second)
+	defer cache.Clear()
+
+	cache.Set("hot", "hot_value", 0)
+	cache.Set("cold", "cold_value", 0)
+	cache.Get("hot")
+
+	// Leave room for a single additional item
+	cache.maxMemory = cache.currentSize + cache.estimateSize("new_value") - 1
+	cache.Set("new", "new_value", 0)
+
+	if _, found := cache.Get("hot"); !found {
+		t.Error("Expected recently read key to survive eviction")
+	}
+	if _, found := cache.Get("cold"); found {
+		t.Error("Expected least recently used key to be evicted")
+	}
+}
+
+func TestCache_ExpirationEviction(t *testing.T) {
+	cache := NewWithoutCleanup(5 * time.Second)
+	cache.maxItems = 2
+
+	cache.Set("first", "first_value", 0)
+	cache.Set("second", "second_value", 0)
+	cache.Get("first")
+	cache.Set("third", "third_value", 0)
+
+	// Reads do not matter, the item expiring first is evicted
+	if _, found := cache.Get("first"); found {
+		t.Error("Expected first key to be evicted")
+	}
+	if _, found := cache.Get("second"); !found {
+		t.Error("Expected second key to be kept")
+	}
+}
+
+func TestCache_EvictOldestOrder(t *testing.T) {
+	cache := NewWithoutCleanup(5 * time.Second)
+	cache.maxItems = 0
+
+	for i := 0; i < 100; i++ {
+		// Later keys expire first
+		cache.Set("key_"+strconv.Itoa(i), i, time.Duration(200-i)*time.Second)
+	}
+
+	cache.evictOldest(10)
+
+	for i := 0; i < 100; i++ {
+		_, found := cache.Get("key_" + strconv.Itoa(i))
+		if evicted := i >= 90; found == evicted {
+			t.Errorf("Expected key_%d evicted=%v", i, evicted)
+		}
+	}
+}
+
+func BenchmarkEvictLargeCache(b *testing.B) {
+	cache := NewWithoutCleanup(time.Hour)
+	cache.maxItems = 10000
+
+	for i := 0; i < cache.maxItems; i++ {
+		cache.Set("key_"+strconv.Itoa(i), i, 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Every Set on the full cache evicts the oldest item
+		cache.Set("new_"+strconv.Itoa(i), i, 0)
+	}
+}
+
+func TestCache_HitsAndMisses(t *testing.T) {
+	cache := NewWithoutCleanup(5 * time.Second)
+
+	cache.Set("key", "value", 0)
+	cache.Set("expired", "value", -time.Second)
+
+	cache.Get("key")
+	cache.Get("key")
+	cache.Get("missing")
+	cache.Get("expired")
+
+	hits, misses := cache.HitsAndMisses()
+	if hits != 2 || misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %d and %d", hits, misses)
+	}
+
+	stats := cache.Stats()
+	if stats["hits"] != uint64(2) || stats["misses"] != uint64(2) {
+		t.Errorf("Expected hits and misses in stats, got %v", stats)
+	}
+}
+
+func TestCache_DeleteByPrefix(t *testing.T) {
+	cache := NewWithoutCleanup(5 * time.Second)
+
+	cache.Set("table:books|count", 1, 0)
+	cache.Set("table:books|rows", 2, 0)
+	cache.Set("table:books_backup|count", 3, 0)
+
+	if removed := cache.DeleteByPrefix("table:books|"); removed != 2 {
+		t.Errorf("Expected 2 removed items, got %v", removed)
+	}
+	if _, found := cache.Get("table:books|count"); found {
+		t.Error("Expected table:books|count to be removed")
+	}
+	if _, found := cache.Get("table:books_backup|count"); !found {
+		t.Error("Expected table:books_backup|count to be kept")
+	}
+}
+
+func TestCache_RefreshExpiring(t *testing.T) {
+	cache := NewWithoutCleanup(time.Minute)
+
+	loads := 0
+	load := func() (interface{}, error) {
+		loads++
+		return "value " + strconv.Itoa(loads), nil
+	}
+
+	cache.SetWithLoader("hot", "value 0", 100*time.Millisecond, load)
+	cache.SetWithLoader("cold", "value 0", 100*time.Millisecond, load)
+	cache.Set("plain", "value 0", 100*time.Millisecond)
+	cache.Get("hot")
+	cache.Get("hot")
+	cache.Get("plain")
+	cache.Get("plain")
+
+	// Nothing is expiring soon enough
+	if refreshed := cache.RefreshExpiring(10*time.Millisecond, 2); refreshed != 0 {
+		t.Errorf("Expected no refreshed items, got %v", refreshed)
+	}
+
+	// Only the frequently read item with a loader is refreshed
+	if refreshed := cache.RefreshExpiring(time.Second, 2); refreshed != 1 {
+		t.Errorf("Expected 1 refreshed item, got %v", refreshed)
+	}
+	if value, _ := cache.Get("hot"); value != "value 1" {
+		t.Errorf("Expected refreshed value, got %v", value)
+	}
+	if value, _ := cache.Get("cold"); value != "value 0" {
+		t.Errorf("Expected the cold value to be kept, got %v", value)
+	}
+
+	// Failed loads leave the item as is
+	cache.SetWithLoader("failing", "value 0", 100*time.Millisecond, func() (interface{}, error) {
+		return nil, errors.New("connection closed")
+	})
+	cache.Get("failing")
+	cache.RefreshExpiring(time.Second, 1)
+	if value, _ := cache.Get("failing"); value != "value 0" {
+		t.Errorf("Expected the failing value to be kept, got %v", value)
+	}
+}
+
+func TestCache_StartRefresher(t *testing.T) {
+	cache := NewWithoutCleanup(time.Minute)
+	stop := cache.StartRefresher(5*time.Millisecond, 50*time.Millisecond, 1)
+	defer stop()
+
+	cache.SetWithLoader("hot", "value", 100*time.Millisecond, func() (interface{}, error) {
+		return "value", nil
+	})
+
+	// Reads past the original expiration never miss
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if _, found := cache.Get("hot"); !found {
+			t.Fatal("Expected the hot item to be refreshed before expiration")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, misses := cache.HitsAndMisses(); misses != 0 {
+		t.Errorf("Expected no misses, got %v", misses)
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/flowbi/pgweb/pkg/bookmarks"
+	"github.com/flowbi/pgweb/pkg/client"
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+var errCheckSkipped = errors.New("skipped")
+
+// check is a single validation performed in the --check mode
+type check struct {
+	name string
+	run  func(opts command.Options) error
+}
+
+var checks = []check{
+	{"ssl files", checkSSLFiles},
+	{"bookmark", checkBookmark},
+	{"connection", checkConnection},
+}
+
+// runChecks validates the options and database connectivity, writing a report
+// to the output. Returns false if any of the checks failed.
+func runChecks(opts command.Options, optsErr error, out io.Writer) bool {
+	if optsErr != nil {
+		fmt.Fprintf(out, "[FAIL] options: %v\n", optsErr)
+		return false
+	}
+	fmt.Fprintln(out, "[OK]   options")
+
+	ok := true
+	for _, c := range checks {
+		err := c.run(opts)
+		switch {
+		case err == nil:
+			fmt.Fprintf(out, "[OK]   %s\n", c.name)
+		case errors.Is(err, errCheckSkipped):
+			fmt.Fprintf(out, "[SKIP] %s\n", c.name)
+		default:
+			fmt.Fprintf(out, "[FAIL] %s: %v\n", c.name, err)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+func checkSSLFiles(opts command.Options) error {
+	files := []struct {
+		flag string
+		path string
+	}{
+		{"--ssl-rootcert", opts.SSLRootCert},
+		{"--ssl-cert", opts.SSLCert},
+		{"--ssl-key", opts.SSLKey},
+	}
+
+	checked := false
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		checked = true
+
+		info, err := os.Stat(file.path)
+		if err != nil {
+			return fmt.Errorf("%s file is not readable: %w", file.flag, err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("%s path %s is a directory", file.flag, file.path)
+		}
+	}
+
+	if !checked {
+		return errCheckSkipped
+	}
+	return nil
+}
+
+func checkBookmark(opts command.Options) error {
+	if opts.Bookmark == "" {
+		return errCheckSkipped
+	}
+
+	_, err := bookmarks.NewManager(opts.BookmarksDir).Get(opts.Bookmark)
+	return err
+}
+
+func checkConnection(opts command.Options) error {
+	// Same as on startup, connection failures are only reported with explicit database details
+	if opts.URL == "" && opts.DbName == "" && opts.Bookmark == "" {
+		return errCheckSkipped
+	}
+
+	var (
+		cl  *client.Client
+		err error
+	)
+
+	if opts.Bookmark != "" {
+		cl, err = initClientUsingBookmark(opts.BookmarksDir, opts.Bookmark)
+	} else {
+		cl, err = client.New()
+	}
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	return cl.Test()
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func TestRunChecks(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "root.crt")
+	require.NoError(t, os.WriteFile(certPath, []byte("cert"), 0600))
+
+	check := func(args ...string) (bool, string) {
+		out := bytes.NewBuffer(nil)
+		opts, err := command.ParseOptions(append([]string{"--check", "--bookmarks-dir", dir}, args...))
+		return runChecks(opts, err, out), out.String()
+	}
+
+	t.Run("valid config", func(t *testing.T) {
+		ok, report := check("--ssl-rootcert", certPath)
+		assert.True(t, ok)
+		assert.Equal(t, "[OK]   options\n[OK]   ssl files\n[SKIP] bookmark\n[SKIP] connection\n", report)
+	})
+
+	t.Run("invalid flag combination", func(t *testing.T) {
+		ok, report := check("--require-ssh", "--no-ssh")
+		assert.False(t, ok)
+		assert.Equal(t, "[FAIL] options: --require-ssh and --no-ssh flags can't be used together\n", report)
+	})
+
+	t.Run("missing ssl file", func(t *testing.T) {
+		ok, report := check("--ssl-key", filepath.Join(dir, "missing.key"))
+		assert.False(t, ok)
+		assert.Contains(t, report, "[FAIL] ssl files: --ssl-key file is not readable")
+	})
+
+	t.Run("invalid bookmark", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.toml"), []byte("host = "), 0600))
+
+		ok, report := check("--bookmark", "broken")
+		assert.False(t, ok)
+		assert.Contains(t, report, "[FAIL] bookmark: ")
+		assert.Contains(t, report, "[FAIL] connection: ")
+	})
+}
@@ -3,6 +3,8 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -21,6 +23,7 @@ import (
 	"github.com/flowbi/pgweb/pkg/connection"
 	"github.com/flowbi/pgweb/pkg/metrics"
 	"github.com/flowbi/pgweb/pkg/queries"
+	"github.com/flowbi/pgweb/pkg/statements"
 	"github.com/flowbi/pgweb/pkg/util"
 )
 
@@ -33,6 +36,12 @@ var (
 SECURITY WARNING: You are running Pgweb in read-only mode.
 This mode is designed for environments where users could potentially delete or change data.
 For proper read-only access please follow PostgreSQL role management documentation.
+--------------------------------------------------------------------------------`
+
+	sshHostKeyWarning = `
+--------------------------------------------------------------------------------
+SECURITY WARNING: SSH server host keys are not verified.
+SSH tunnels are open to man-in-the-middle attacks, use --ssh-known-hosts instead.
 --------------------------------------------------------------------------------`
 )
 
@@ -99,11 +108,22 @@ func initClient() {
 		exitWithMessage(err.Error())
 	}
 
+	if err := cl.OpenHistory(""); err != nil {
+		logger.WithError(err).Warn("can't load query history")
+	}
+
 	api.DbClient = cl
 }
 
 func initOptions() {
 	opts, err := command.ParseOptions(os.Args)
+	if opts.Check {
+		command.Opts = opts
+		if !runChecks(opts, err, os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	if err != nil {
 		switch errVal := err.(type) {
 		case *flags.Error:
@@ -134,12 +154,28 @@ func initOptions() {
 		fmt.Println(readonlyWarning)
 	}
 
+	if options.SSHInsecureIgnoreHostKey {
+		fmt.Println(sshHostKeyWarning)
+	}
+
 	if options.BinaryCodec != "" {
 		if err := client.SetBinaryCodec(options.BinaryCodec); err != nil {
 			exitWithMessage(err.Error())
 		}
 	}
 
+	if err := client.SetWorkMemLimits(options.WorkMem, options.MaxWorkMem); err != nil {
+		exitWithMessage(err.Error())
+	}
+
+	if err := client.SetApplicationNameTemplate(options.ApplicationName); err != nil {
+		exitWithMessage(err.Error())
+	}
+
+	if err := statements.SetOverrideDir(options.SQLOverrideDir); err != nil {
+		exitWithMessage(err.Error())
+	}
+
 	configureLocalQueryStore()
 	printVersion()
 }
@@ -197,10 +233,24 @@ func printVersion() {
 func startServer() {
 	router := gin.New()
 	router.Use(api.RequestLogger(logger))
+
+	if options.LogQueryFingerprint {
+		client.QueryLogger = api.QueryLogger(logger)
+	}
 	router.Use(gin.Recovery())
 
-	// Enable HTTP basic authentication only if both user and password are set
-	if options.AuthUser != "" && options.AuthPass != "" {
+	// Enable HTTP basic authentication with the users file, or if both user and password are set
+	if options.AuthUsers != "" {
+		users, err := api.LoadAuthUsers(options.AuthUsers)
+		if err != nil {
+			exitWithMessage("can't load auth users: " + err.Error())
+		}
+		// Users of a single connection would share its mode
+		if api.HasReadOnlyAuthUsers(users) && !options.Sessions {
+			exitWithMessage("read-only auth users require --sessions mode")
+		}
+		router.Use(api.BasicAuthUsers(users))
+	} else if options.AuthUser != "" && options.AuthPass != "" {
 		auth := map[string]string{options.AuthUser: options.AuthPass}
 		router.Use(gin.BasicAuth(auth))
 	}
@@ -210,24 +260,62 @@ func startServer() {
 	api.SetupMetrics(router)
 
 	fmt.Println("Starting server...")
-	go func() {
-		metrics.SetHealthy(true)
 
-		err := router.Run(fmt.Sprintf("%v:%v", options.HTTPHost, options.HTTPPort))
+	listeners, err := listen(command.BindAddresses(options))
+	if err != nil {
+		fmt.Println("Can't start server:", err)
+		if strings.Contains(err.Error(), "address already in use") {
+			openPage()
+		}
+		os.Exit(1)
+	}
+
+	metrics.SetHealthy(true)
+
+	for _, listener := range listeners {
+		fmt.Println("Listening on", listener.Addr())
+	}
+
+	serve(listeners, api.PrefixHandler(router), func(listener net.Listener, err error) {
+		fmt.Println("Can't start server on", listener.Addr(), ":", err)
+		os.Exit(1)
+	})
+}
+
+// listen binds all server addresses, failing fast on the first one that can't be bound
+func listen(addrs []string) ([]net.Listener, error) {
+	listeners := []net.Listener{}
+
+	for _, addr := range addrs {
+		listener, err := net.Listen("tcp", addr)
 		if err != nil {
-			fmt.Println("Can't start server:", err)
-			if strings.Contains(err.Error(), "address already in use") {
-				openPage()
+			for _, l := range listeners {
+				l.Close()
 			}
-			os.Exit(1)
+			return nil, fmt.Errorf("%s: %w", addr, err)
 		}
-	}()
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// serve runs the handler on every listener in the background
+func serve(listeners []net.Listener, handler http.Handler, onError func(net.Listener, error)) {
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			if err := http.Serve(listener, handler); err != nil {
+				onError(listener, err)
+			}
+		}(listener)
+	}
 }
 
 func startMetricsServer() {
-	serverAddr := fmt.Sprintf("%v:%v", command.Opts.HTTPHost, command.Opts.HTTPPort)
-	if options.MetricsAddr == serverAddr {
-		return
+	for _, serverAddr := range command.BindAddresses(options) {
+		if options.MetricsAddr == serverAddr {
+			return
+		}
 	}
 
 	err := metrics.StartServer(logger, options.MetricsPath, options.MetricsAddr)
@@ -243,7 +331,7 @@ func handleSignals() {
 }
 
 func openPage() {
-	url := fmt.Sprintf("http://%v:%v/%s", options.HTTPHost, options.HTTPPort, options.Prefix)
+	url := fmt.Sprintf("http://%v/%s", command.BindAddresses(options)[0], options.Prefix)
 	fmt.Println("To view database open", url, "in browser")
 
 	if options.SkipOpen {
@@ -321,6 +409,7 @@ func Run() {
 	// Start session cleanup worker
 	if options.Sessions {
 		api.DbSessions = api.NewSessionManager(logger)
+		api.DbSessions.SetMaxSessions(command.Opts.MaxSessions, command.Opts.EvictIdleSessions)
 
 		if !command.Opts.DisableConnectionIdleTimeout {
 			api.DbSessions.SetIdleTimeout(time.Minute * time.Duration(command.Opts.ConnectionIdleTimeout))
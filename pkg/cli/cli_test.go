@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/api"
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func TestListen(t *testing.T) {
+	t.Run("multiple addresses", func(t *testing.T) {
+		defer func(opts command.Options) {
+			command.Opts = opts
+		}(command.Opts)
+		command.Opts = command.Options{}
+
+		router := gin.New()
+		api.SetupRoutes(router)
+
+		listeners, err := listen([]string{"127.0.0.1:0", "127.0.0.1:0"})
+		require.NoError(t, err)
+		require.Len(t, listeners, 2)
+		defer func() {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+		}()
+
+		serve(listeners, router, func(net.Listener, error) {})
+
+		for _, listener := range listeners {
+			resp, err := http.Get("http://" + listener.Addr().String() + "/api/info")
+			require.NoError(t, err)
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Contains(t, string(body), `"features"`)
+		}
+	})
+
+	t.Run("bind failure", func(t *testing.T) {
+		taken, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer taken.Close()
+
+		_, err = listen([]string{"127.0.0.1:0", taken.Addr().String()})
+		assert.ErrorContains(t, err, taken.Addr().String()+": ")
+		assert.ErrorContains(t, err, "address already in use")
+	})
+}
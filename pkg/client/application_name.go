@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Maximum length of application_name, longer values are truncated by the server
+const maxApplicationNameLength = 63
+
+var (
+	// ApplicationNameTemplate is rendered into the application_name of the request queries
+	ApplicationNameTemplate string
+
+	// Variables of the application name template, ie. {user}
+	applicationNameVars = []string{"user", "session", "database", "request_id"}
+
+	// Application names are set with a SET statement, so only a safe subset of characters is allowed
+	reApplicationName       = regexp.MustCompile(`^[\w .:;=@+/-]*$`)
+	reApplicationNameUnsafe = regexp.MustCompile(`[^\w .:;=@+/-]`)
+	reApplicationNameVar    = regexp.MustCompile(`\{(\w*)\}`)
+)
+
+// applicationNameKey is the context key of the rendered application name
+type applicationNameKey struct{}
+
+// pinnedApplicationNameKey is the context key of the application name already set
+// on the pinned connection the queries run on
+type pinnedApplicationNameKey struct{}
+
+// SetApplicationNameTemplate validates and configures the application name template
+func SetApplicationNameTemplate(template string) error {
+	for _, match := range reApplicationNameVar.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(applicationNameVars, match[1]) {
+			return fmt.Errorf("unknown application name variable: %s", match[0])
+		}
+	}
+
+	if literal := reApplicationNameVar.ReplaceAllString(template, ""); !reApplicationName.MatchString(literal) {
+		return fmt.Errorf("invalid application name template: %q", template)
+	}
+
+	ApplicationNameTemplate = template
+	return nil
+}
+
+// RenderApplicationName returns the application name template with the variables
+// substituted. Unsafe characters of the values are replaced, and the result is
+// truncated to the length kept by the server.
+func RenderApplicationName(vars map[string]string) (string, error) {
+	name := reApplicationNameVar.ReplaceAllStringFunc(ApplicationNameTemplate, func(match string) string {
+		value := vars[strings.Trim(match, "{}")]
+		return reApplicationNameUnsafe.ReplaceAllString(value, "_")
+	})
+
+	if len(name) > maxApplicationNameLength {
+		name = name[:maxApplicationNameLength]
+	}
+	if !reApplicationName.MatchString(name) {
+		return "", fmt.Errorf("invalid application name: %q", name)
+	}
+
+	return name, nil
+}
+
+// WithApplicationName returns a copy of the context carrying the application name
+// set before the queries run with the context. Invalid names are ignored.
+func WithApplicationName(ctx context.Context, name string) context.Context {
+	if name == "" || len(name) > maxApplicationNameLength || !reApplicationName.MatchString(name) {
+		return ctx
+	}
+	return context.WithValue(ctx, applicationNameKey{}, name)
+}
+
+// ApplicationNameFromContext returns the application name queries run with the context set
+func ApplicationNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(applicationNameKey{}).(string)
+	return name
+}
+
+// setApplicationName sets the application name carried by the context for the
+// transaction the query runs in. The name is local to the transaction, so it never
+// outlives it. Queries run out of a transaction are attributed to the name of their
+// pinned connection, or to the connection one when run on the pool.
+func (client *Client) setApplicationName(ctx context.Context, conn queryConn) error {
+	name := ApplicationNameFromContext(ctx)
+	if name == "" || ctx.Value(pinnedApplicationNameKey{}) == name {
+		return nil
+	}
+
+	tx, ok := conn.(*sqlx.Tx)
+	if !ok || (tx == client.tx && client.txApplicationName == name) {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('application_name', $1, true)", name); err != nil {
+		return fmt.Errorf("failed to set application name: %w", err)
+	}
+	if tx == client.tx {
+		client.txApplicationName = name
+	}
+	return nil
+}
+
+// pinApplicationName sets the application name carried by the context on the pinned
+// connection. It returns the context of the queries run on the connection, and the
+// func restoring the connection name before it goes back to the pool. Connections
+// whose name can't be restored are discarded instead.
+func (client *Client) pinApplicationName(ctx context.Context, conn *sqlx.Conn) (context.Context, func(), error) {
+	name := ApplicationNameFromContext(ctx)
+	if name == "" {
+		return ctx, func() {}, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", name); err != nil {
+		return nil, nil, fmt.Errorf("failed to set application name: %w", err)
+	}
+
+	restore := func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "RESET application_name"); err != nil {
+			conn.Raw(func(interface{}) error { //nolint:errcheck
+				return driver.ErrBadConn
+			})
+		}
+	}
+
+	return context.WithValue(ctx, pinnedApplicationNameKey{}, name), restore, nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationName(t *testing.T) {
+	defer func() {
+		ApplicationNameTemplate = ""
+	}()
+
+	t.Run("template", func(t *testing.T) {
+		assert.NoError(t, SetApplicationNameTemplate(""))
+		assert.NoError(t, SetApplicationNameTemplate("pgweb:{user}:{session}:{database}:{request_id}"))
+		assert.EqualError(t, SetApplicationNameTemplate("pgweb:{email}"), "unknown application name variable: {email}")
+		assert.EqualError(t, SetApplicationNameTemplate("pgweb'; DROP TABLE books; --{user}"), `invalid application name template: "pgweb'; DROP TABLE books; --{user}"`)
+	})
+
+	t.Run("render", func(t *testing.T) {
+		require.NoError(t, SetApplicationNameTemplate("pgweb:{user}:{request_id}"))
+
+		name, err := RenderApplicationName(map[string]string{"user": "jane@example.com", "request_id": "f00d"})
+		assert.NoError(t, err)
+		assert.Equal(t, "pgweb:jane@example.com:f00d", name)
+
+		// Unsafe characters of the values are replaced
+		name, err = RenderApplicationName(map[string]string{"user": "jane'; DROP TABLE books; --", "request_id": "f00d"})
+		assert.NoError(t, err)
+		assert.Equal(t, "pgweb:jane_; DROP TABLE books; --:f00d", name)
+
+		name, err = RenderApplicationName(map[string]string{"user": "rené"})
+		assert.NoError(t, err)
+		assert.Equal(t, "pgweb:ren_:", name)
+
+		name, err = RenderApplicationName(map[string]string{"user": strings.Repeat("a", 100)})
+		assert.NoError(t, err)
+		assert.Len(t, name, maxApplicationNameLength)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx := context.Background()
+		assert.Equal(t, "", ApplicationNameFromContext(ctx))
+		assert.Equal(t, "pgweb:jane", ApplicationNameFromContext(WithApplicationName(ctx, "pgweb:jane")))
+		assert.Equal(t, "", ApplicationNameFromContext(WithApplicationName(ctx, "pgweb'jane")))
+		assert.Equal(t, "", ApplicationNameFromContext(WithApplicationName(ctx, strings.Repeat("a", 64))))
+	})
+	t.Run("pool connections", func(t *testing.T) {
+		client := &Client{}
+		conn := &recordingConn{}
+		ctx := WithApplicationName(context.Background(), "pgweb:jane")
+
+		// A session setting would outlive the query on the pool connection
+		assert.NoError(t, client.setApplicationName(ctx, conn))
+		assert.Equal(t, "", conn.query)
+	})
+}
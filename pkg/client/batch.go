@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+const (
+	// Maximum number of queries accepted in a single batch
+	maxBatchSize = 50
+
+	// Maximum number of batch queries executed concurrently
+	maxBatchParallelism = 8
+)
+
+var (
+	ErrBatchEmpty    = errors.New("batch must contain at least one query")
+	ErrBatchTooLarge = errors.New("batch contains too many queries")
+)
+
+type (
+	// BatchQuery is a single query of a batch request
+	BatchQuery struct {
+		ID     string        `json:"id"`
+		Query  string        `json:"query"`
+		Params []interface{} `json:"params"`
+	}
+
+	// BatchResult holds the outcome of a single batch query
+	BatchResult struct {
+		ID     string  `json:"id"`
+		Result *Result `json:"result,omitempty"`
+		Error  string  `json:"error,omitempty"`
+	}
+)
+
+// Batch runs independent queries and collects their results in the request order.
+// A failing query does not abort the rest of the batch. Queries run sequentially
+// unless parallel is greater than 1; an open transaction always forces sequential runs.
+func (client *Client) Batch(ctx context.Context, queries []BatchQuery, parallel int) ([]BatchResult, error) {
+	if len(queries) == 0 {
+		return nil, ErrBatchEmpty
+	}
+	if len(queries) > maxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	if parallel < 1 || client.InTransaction() {
+		parallel = 1
+	}
+	if parallel > maxBatchParallelism {
+		parallel = maxBatchParallelism
+	}
+
+	results := make([]BatchResult, len(queries))
+	sem := make(chan struct{}, parallel)
+	wg := sync.WaitGroup{}
+
+	for i, q := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, q BatchQuery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = client.runBatchQuery(ctx, q)
+		}(i, q)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+func (client *Client) runBatchQuery(ctx context.Context, q BatchQuery) BatchResult {
+	result := BatchResult{ID: q.ID}
+
+	query := strings.TrimSpace(q.Query)
+	if query == "" {
+		result.Error = "query is required"
+		return result
+	}
+
+	res, err := client.queryContext(ctx, query, q.Params...)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if res != nil {
+		res.PostProcess()
+	}
+
+	result.Result = res
+	return result
+}
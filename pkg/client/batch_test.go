@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchValidation(t *testing.T) {
+	client := &Client{}
+
+	_, err := client.Batch(context.Background(), nil, 1)
+	assert.Equal(t, ErrBatchEmpty, err)
+
+	_, err = client.Batch(context.Background(), make([]BatchQuery, maxBatchSize+1), 1)
+	assert.Equal(t, ErrBatchTooLarge, err)
+
+	results, err := client.Batch(context.Background(), []BatchQuery{
+		{ID: "a", Query: "SELECT 1"},
+		{ID: "b", Query: "  "},
+		{ID: "c", Query: "SELECT 2"},
+	}, 4)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "a", results[0].ID)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "b", results[1].ID)
+	assert.Equal(t, "query is required", results[1].Error)
+	assert.Equal(t, "c", results[2].ID)
+	assert.Empty(t, results[2].Error)
+}
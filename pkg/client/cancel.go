@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+// Maximum wait time for the cancel request to be delivered
+var cancelTimeout = 10 * time.Second
+
+// runCancelableQuery runs the query on a pinned connection and records its
+// backend PID while the query is in flight, so it can be cancelled with CancelQuery
+func (client *Client) runCancelableQuery(parent context.Context, query string) (*Result, error) {
+	if client.db == nil {
+		return nil, nil
+	}
+
+	var db txConn = client.db
+	var conn queryConn = client.tx
+
+	if client.tx == nil {
+		pinned, err := client.db.Connx(parent)
+		if err != nil {
+			return nil, err
+		}
+		defer pinned.Close()
+		db, conn = pinned, pinned
+
+		var restore func()
+		parent, restore, err = client.pinApplicationName(parent, pinned)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+	}
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	// Read-only mode is set by runQueryOn on another connection from the pool,
+	// so it's set on the pinned connection too
+	if client.tx == nil && client.isReadOnly() {
+		if _, err := conn.ExecContext(ctx, "SET default_transaction_read_only=on;"); err != nil {
+			return nil, err
+		}
+	}
+
+	var pid int
+	if err := sqlx.GetContext(ctx, conn, &pid, "SELECT pg_backend_pid()"); err != nil {
+		return nil, err
+	}
+
+	client.setBackendPID(pid)
+	defer client.setBackendPID(0)
+
+	if client.tx == nil && client.isReadOnlyContext(parent) && isAllowedFunctionCall(query, command.Opts.ReadOnlyFunctionNames) {
+		return client.runAllowedFunctionCall(parent, db, query)
+	}
+
+	return client.withSelectReadOnly(parent, db, query, func(conn queryConn) (*Result, error) {
+		return client.runQueryOn(parent, conn, query)
+	})
+}
+
+// runAllowedFunctionCall calls a function allowed in read-only mode. The function
+// may write, so it's called in a read-write transaction overriding the read-only session default.
+func (client *Client) runAllowedFunctionCall(parent context.Context, db txConn, query string) (*Result, error) {
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, "SET TRANSACTION READ WRITE"); err != nil {
+		return nil, err
+	}
+
+	if workMem := workMemFrom(parent); workMem != "" {
+		if _, err := tx.ExecContext(ctx, workMemStatement(workMem)); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := client.runQueryOn(parent, tx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, tx.Commit()
+}
+
+func (client *Client) setBackendPID(pid int) {
+	client.cancelMu.Lock()
+	defer client.cancelMu.Unlock()
+
+	client.backendPID = pid
+}
+
+// CancelQuery cancels the query currently running with Query. The cancel request
+// is sent over a separate connection from the pool. Does nothing if no query is running.
+func (client *Client) CancelQuery() error {
+	// Hold the lock so the query connection is not released while being cancelled
+	client.cancelMu.Lock()
+	defer client.cancelMu.Unlock()
+
+	if client.backendPID == 0 || client.db == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	_, err := client.db.ExecContext(ctx, "SELECT pg_cancel_backend($1)", client.backendPID)
+	return err
+}
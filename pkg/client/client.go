@@ -6,10 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	neturl "net/url"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -24,6 +28,12 @@ import (
 	"github.com/flowbi/pgweb/pkg/statements"
 )
 
+// Maximum number of recently used objects tracked per client
+const maxRecentObjects = 20
+
+// Maximum number of objects returned by SearchObjects
+const maxSearchResults = 200
+
 // Shared metadata cache - will be set by API package
 var MetadataCache *cache.Cache
 
@@ -34,9 +44,21 @@ var (
 )
 
 var (
-	ErrAuthFailed        = errors.New("authentication failed")
-	ErrConnectionRefused = errors.New("connection refused")
-	ErrDatabaseNotExist  = errors.New("database does not exist")
+	ErrAuthFailed             = errors.New("authentication failed")
+	ErrConnectionRefused      = errors.New("connection refused")
+	ErrDatabaseNotExist       = errors.New("database does not exist")
+	ErrStatStatementsMissing  = errors.New("pg_stat_statements extension is not installed in this database")
+	ErrInvalidStatementsOrder = errors.New("invalid order, must be one of: total_time, mean_time, calls")
+	ErrInvalidStatsReset      = errors.New("invalid stats kind, must be one of: statements, database")
+	ErrReadOnly               = errors.New("operation not allowed in read-only mode")
+	ErrDatabaseNotAllowed     = errors.New("connection to this database is not allowed")
+	ErrSSHRequired            = errors.New("connections are only allowed through an SSH tunnel")
+	ErrTablefuncMissing       = errors.New("tablefunc extension is not installed in this database")
+	ErrNotConnected           = errors.New("database connection is not established")
+	ErrSearchTermRequired     = errors.New("search term is required")
+	ErrInvalidPid             = errors.New("pid must be a positive number")
+	ErrInvalidIdleMinutes     = errors.New("idle minutes must be a positive number")
+	ErrTerminateUnsupported   = errors.New("terminating backends is only supported by PostgreSQL")
 )
 
 // CompileRegexPatterns compiles comma-separated regex patterns into compiled regexes
@@ -74,6 +96,25 @@ func shouldHideItem(item string, patterns []*regexp.Regexp) bool {
 	return false
 }
 
+// isDatabaseAllowed checks the database name against the configured allowlist
+func isDatabaseAllowed(name string) (bool, error) {
+	if command.Opts.AllowedDatabases == "" {
+		return true, nil
+	}
+
+	patterns, err := command.CompileAllowPatterns(command.Opts.AllowedDatabases)
+	if err != nil {
+		return false, fmt.Errorf("failed to compile allowed database patterns: %v", err)
+	}
+
+	for _, pattern := range patterns {
+		if pattern.MatchString(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // FilterStringSlice removes strings that match any of the hiding patterns
 func FilterStringSlice(items []string, patterns []*regexp.Regexp) []string {
 	if len(patterns) == 0 {
@@ -136,22 +177,122 @@ func filterObjectsResult(result *Result, schemaPatterns []*regexp.Regexp, object
 	}
 }
 
+// categorizeObjectsResult appends a category column based on the first rule matching the object name
+func categorizeObjectsResult(result *Result, rules []command.CategoryRule) *Result {
+	if len(rules) == 0 {
+		return result
+	}
+
+	rows := make([]Row, len(result.Rows))
+	for i, row := range result.Rows {
+		category := ""
+
+		if len(row) > 2 {
+			if name, ok := row[2].(string); ok {
+				for _, rule := range rules {
+					if rule.Pattern.MatchString(name) {
+						category = rule.Label
+						break
+					}
+				}
+			}
+		}
+
+		rows[i] = append(append(Row{}, row...), category)
+	}
+
+	return &Result{
+		Columns:    append(append([]string{}, result.Columns...), "category"),
+		Rows:       rows,
+		Pagination: result.Pagination,
+		Stats:      result.Stats,
+	}
+}
+
 type Client struct {
-	db               *sqlx.DB
-	tunnel           *Tunnel
-	serverVersion    string
-	serverType       string
-	lastQueryTime    time.Time
-	queryTimeout     time.Duration
-	readonly         bool
-	closed           bool
-	defaultRole      string           // Role from X-Database-Role header
-	External         bool             `json:"external"`
-	History          []history.Record `json:"history"`
-	ConnectionString string           `json:"connection_string"`
+	db                *sqlx.DB
+	connector         *dsnConnector // Opens the db connections
+	tunnel            *Tunnel
+	serverVersion     string
+	serverType        string
+	lastQueryTime     time.Time
+	activeQueries     atomic.Int32 // Number of queries in flight
+	queryTimeout      time.Duration
+	readonly          bool
+	closed            bool
+	defaultRole       string   // Role from X-Database-Role header
+	cacheBypass       bool     // Query and metadata caches are not used for the session
+	pgbouncer         bool     // Connected through PgBouncer, prepared statements are not available
+	backendPID        int      // Backend PID of the connection running Query, 0 if none
+	tx                *sqlx.Tx // Open transaction, if any
+	txReadOnly        bool     // The open transaction is read-only
+	txApplicationName string   // Application name set for the open transaction
+	savepoints        []string // Savepoints created within the open transaction
+	refresher         CredentialRefresher
+	reconnectMu       sync.Mutex
+	cancelMu          sync.Mutex
+	statements        map[string]*sqlx.Stmt // Prepared statements keyed by normalized query
+	statementKeys     []string              // Prepared statement keys, most recently used first
+	statementsMu      sync.Mutex
+	historyStore      *history.Store // Persists the history records, nil if disabled
+	recentObjects     []string       // Recently opened objects, most recent first
+	recentObjectsMu   sync.Mutex
+	done              chan struct{}    // Closed along with the client to stop its listeners
+	External          bool             `json:"external"`
+	History           []history.Record `json:"history"`
+	ConnectionString  string           `json:"connection_string"`
 	// Remove per-client cache - we'll use shared cache instead
 }
 
+// SetCacheBypass enables or disables the query and metadata caches for the client,
+// the shared caches are still used by other sessions
+func (client *Client) SetCacheBypass(bypass bool) {
+	client.cacheBypass = bypass
+}
+
+// IsReadOnly returns true if the client queries run in read-only mode
+func (client *Client) IsReadOnly() bool {
+	return client.isReadOnly()
+}
+
+// CacheBypassed returns true if the client queries and metadata are never cached
+func (client *Client) CacheBypassed() bool {
+	return client.cacheBypass
+}
+
+// metadataCache returns the shared metadata cache, or nil if the client bypasses it
+func (client *Client) metadataCache() *cache.Cache {
+	if client.cacheBypass {
+		return nil
+	}
+	return MetadataCache
+}
+
+// cachedMetadata returns the cached result of the key, or the one fetched and
+// cached on a miss. Cached results are refetched in the background, without the
+// request context, when the cache refresher is enabled.
+func (client *Client) cachedMetadata(ctx context.Context, cacheKey string, fetch func(ctx context.Context) (*Result, error)) (*Result, error) {
+	metadataCache := client.metadataCache()
+	if metadataCache != nil {
+		if cached, found := metadataCache.Get(cacheKey); found {
+			return cached.(*Result), nil
+		}
+	}
+
+	result, err := fetch(ctx)
+	if err == nil && metadataCache != nil {
+		metadataCache.SetWithLoader(cacheKey, result, 10*time.Minute, func() (interface{}, error) {
+			result, err := fetch(context.Background())
+			if err != nil || result == nil {
+				return nil, err
+			}
+			return result, nil
+		})
+	}
+
+	return result, err
+}
+
 // generateMetadataCacheKey creates a cache key for metadata queries
 func (client *Client) generateMetadataCacheKey(queryType string, params ...string) string {
 	data := fmt.Sprintf("%s|%s|%s", client.ConnectionString, queryType, strings.Join(params, "|"))
@@ -159,15 +300,49 @@ func (client *Client) generateMetadataCacheKey(queryType string, params ...strin
 	return fmt.Sprintf("metadata:%x", hash)
 }
 
+// tableCachePrefix returns the prefix of metadata cache keys holding data of the
+// table contents, so they can be invalidated together after writes into the table
+func (client *Client) tableCachePrefix(schema, table string) string {
+	hash := md5.Sum([]byte(client.ConnectionString))
+	return fmt.Sprintf("table:%x:%q.%q|", hash, schema, table)
+}
+
+// invalidateTableCache removes cached data of the tables the query writes into
+func (client *Client) invalidateTableCache(query string) {
+	if MetadataCache == nil {
+		return
+	}
+
+	for _, target := range writeTargetTables(query) {
+		removed := MetadataCache.DeleteByPrefix(client.tableCachePrefix(target[0], target[1]))
+		if command.Opts.Debug && removed > 0 {
+			log.Printf("Invalidated %d cached entries of table %s.%s", removed, target[0], target[1])
+		}
+	}
+}
+
+// defaultSchema returns the schema of unqualified object names
+func defaultSchema() string {
+	if command.Opts.DefaultSchema != "" {
+		return command.Opts.DefaultSchema
+	}
+	return "public"
+}
+
+// getSchemaAndTable splits the object name, falling back to the default schema when unqualified
 func getSchemaAndTable(str string) (string, string) {
 	chunks := strings.Split(str, ".")
 	if len(chunks) == 1 {
-		return "public", chunks[0]
+		return defaultSchema(), chunks[0]
 	}
 	return chunks[0], chunks[1]
 }
 
 func New() (*Client, error) {
+	if command.Opts.RequireSSH {
+		return nil, ErrSSHRequired
+	}
+
 	str, err := connection.BuildStringFromOptions(command.Opts)
 
 	if command.Opts.Debug && str != "" {
@@ -178,13 +353,15 @@ func New() (*Client, error) {
 		return nil, err
 	}
 
-	db, err := sqlx.Open("postgres", str)
+	connector := &dsnConnector{dsn: str}
+	db, err := openConnector(connector)
 	if err != nil {
 		return nil, err
 	}
 
 	client := Client{
 		db:               db,
+		connector:        connector,
 		ConnectionString: str,
 		History:          history.New(),
 	}
@@ -199,6 +376,19 @@ func NewFromUrl(url string, sshInfo *shared.SSHInfo) (*Client, error) {
 		err    error
 	)
 
+	// Unix sockets are local to pgweb, there's no remote port to tunnel to
+	if connection.IsSocketURL(url) {
+		sshInfo = nil
+	}
+
+	if command.Opts.RequireSSH && sshInfo == nil {
+		return nil, ErrSSHRequired
+	}
+
+	if err := checkConnectionTarget(url, sshInfo); err != nil {
+		return nil, err
+	}
+
 	if sshInfo != nil {
 		if command.Opts.DisableSSH {
 			return nil, fmt.Errorf("ssh connections are disabled")
@@ -221,14 +411,11 @@ func NewFromUrl(url string, sshInfo *shared.SSHInfo) (*Client, error) {
 
 		go tunnel.Start()
 
-		uri, err := neturl.Parse(url)
+		url, err = tunnelURL(url, tunnel)
 		if err != nil {
 			tunnel.Close()
 			return nil, err
 		}
-
-		// Override remote postgres port with local proxy port
-		url = strings.Replace(url, uri.Host, fmt.Sprintf("127.0.0.1:%v", tunnel.Port), 1)
 	}
 
 	if command.Opts.Debug {
@@ -240,13 +427,15 @@ func NewFromUrl(url string, sshInfo *shared.SSHInfo) (*Client, error) {
 		return nil, fmt.Errorf("Database name is not provided")
 	}
 
-	db, err := sqlx.Open("postgres", url)
+	connector := newConnector(url, tunnel)
+	db, err := openConnector(connector)
 	if err != nil {
 		return nil, err
 	}
 
 	client := Client{
 		db:               db,
+		connector:        connector,
 		tunnel:           tunnel,
 		serverType:       postgresType,
 		ConnectionString: url,
@@ -257,6 +446,76 @@ func NewFromUrl(url string, sshInfo *shared.SSHInfo) (*Client, error) {
 	return &client, nil
 }
 
+// tunnelURL returns the connection string connecting through the SSH tunnel
+func tunnelURL(url string, tunnel *Tunnel) (string, error) {
+	uri, err := neturl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+
+	// Override remote postgres port with local proxy port
+	return strings.Replace(url, uri.Host, fmt.Sprintf("127.0.0.1:%v", tunnel.Port), 1), nil
+}
+
+// newConnector returns the connector opening the client connections
+func newConnector(url string, tunnel *Tunnel) *dsnConnector {
+	connector := &dsnConnector{dsn: url}
+	if command.Opts.Sessions && tunnel == nil {
+		// Verify every dialed address, the host may resolve differently after the connection target check
+		connector.dialer = netDialer{dialer: connection.RestrictedDialer(command.Opts.AllowLocalhost)}
+	}
+	return connector
+}
+
+// checkConnectionTarget validates the connection against the configured database and host allowlists,
+// and against local network restrictions in sessions mode
+func checkConnectionTarget(url string, sshInfo *shared.SSHInfo) error {
+	if command.Opts.AllowedDatabases == "" && command.Opts.AllowedHosts == "" && !command.Opts.Sessions {
+		return nil
+	}
+
+	// Validate the parameters the driver connects with, query parameters of the
+	// URL override the database and host set in its path and authority
+	params, err := connection.ParseParams(url)
+	if err != nil {
+		return err
+	}
+	name, host := params["dbname"], params["host"]
+	if host == "" {
+		host = "localhost"
+	}
+
+	// Database host is resolved on the SSH server side, only the SSH host is reached directly
+	if sshInfo != nil {
+		host = sshInfo.Host
+	}
+
+	allowed, err := isDatabaseAllowed(name)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrDatabaseNotAllowed
+	}
+
+	if command.Opts.AllowedHosts != "" {
+		allowlist, err := connection.ParseHostAllowlist(command.Opts.AllowedHosts)
+		if err != nil {
+			return err
+		}
+
+		if err := allowlist.Check(host); err != nil {
+			return err
+		}
+	}
+
+	if command.Opts.Sessions {
+		return connection.CheckHostRestricted(host, command.Opts.AllowLocalhost)
+	}
+
+	return nil
+}
+
 func NewFromBookmark(bookmark *bookmarks.Bookmark) (*Client, error) {
 	var (
 		connStr string
@@ -291,6 +550,8 @@ func NewFromBookmark(bookmark *bookmarks.Bookmark) (*Client, error) {
 }
 
 func (client *Client) init() {
+	client.done = make(chan struct{})
+
 	if command.Opts.QueryTimeout > 0 {
 		client.queryTimeout = time.Second * time.Duration(command.Opts.QueryTimeout)
 	}
@@ -365,8 +626,8 @@ func (client *Client) TestWithTimeout(timeout time.Duration) (result error) {
 
 func (client *Client) Info() (*Result, error) {
 	cacheKey := client.generateMetadataCacheKey("info")
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
+	if client.metadataCache() != nil {
+		if cached, found := client.metadataCache().Get(cacheKey); found {
 			return cached.(*Result), nil
 		}
 	}
@@ -380,21 +641,37 @@ func (client *Client) Info() (*Result, error) {
 		}
 	}
 
-	if err == nil && MetadataCache != nil {
-		MetadataCache.Set(cacheKey, result, 10*time.Minute)
+	if err == nil && client.metadataCache() != nil {
+		client.metadataCache().Set(cacheKey, result, 10*time.Minute)
 	}
 
 	return result, err
 }
 
 func (client *Client) Databases() ([]string, error) {
-	return client.fetchRows(statements.Databases)
+	databases, err := client.fetchRows(statements.Databases)
+	if err != nil || command.Opts.AllowedDatabases == "" {
+		return databases, err
+	}
+
+	allowed := make([]string, 0, len(databases))
+	for _, name := range databases {
+		ok, err := isDatabaseAllowed(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allowed = append(allowed, name)
+		}
+	}
+
+	return allowed, nil
 }
 
 func (client *Client) Schemas() ([]string, error) {
 	cacheKey := client.generateMetadataCacheKey("schemas", command.Opts.HideSchemas)
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
+	if client.metadataCache() != nil {
+		if cached, found := client.metadataCache().Get(cacheKey); found {
 			return cached.([]string), nil
 		}
 	}
@@ -411,62 +688,159 @@ func (client *Client) Schemas() ([]string, error) {
 	}
 
 	filteredSchemas := FilterStringSlice(schemas, patterns)
-	if MetadataCache != nil {
-		MetadataCache.Set(cacheKey, filteredSchemas, 10*time.Minute)
+	if client.metadataCache() != nil {
+		client.metadataCache().Set(cacheKey, filteredSchemas, 10*time.Minute)
 	}
 
 	return filteredSchemas, nil
 }
 
-func (client *Client) Objects() (*Result, error) {
-	cacheKey := client.generateMetadataCacheKey("objects", command.Opts.HideSchemas, command.Opts.HideObjects)
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
-			return cached.(*Result), nil
+// SchemaSummary returns the number of objects and total data size for each schema
+func (client *Client) SchemaSummary() (*Result, error) {
+	result, err := client.query(statements.SchemaSummary)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, err := CompileRegexPatterns(command.Opts.HideSchemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema hide patterns: %v", err)
+	}
+	if len(patterns) == 0 {
+		return result, nil
+	}
+
+	rows := make([]Row, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		if schema, ok := row[0].(string); ok && shouldHideItem(schema, patterns) {
+			continue
 		}
+		rows = append(rows, row)
+	}
+	result.Rows = rows
+	result.Stats.RowsCount = len(rows)
+
+	return result, nil
+}
+
+func (client *Client) Objects() (*Result, error) {
+	return client.ObjectsContext(context.Background())
+}
+
+// ObjectsContext is like Objects but the query is cancelled along with the context
+func (client *Client) ObjectsContext(ctx context.Context) (*Result, error) {
+	return client.ObjectsWithOptions(ctx, ObjectsOptions{})
+}
+
+// SchemaObjects returns the objects of a single schema, so that schemas can be
+// loaded on demand
+func (client *Client) SchemaObjects(schema string) (*Result, error) {
+	return client.SchemaObjectsContext(context.Background(), schema)
+}
+
+// SchemaObjectsContext is like SchemaObjects but the query is cancelled along with the context
+func (client *Client) SchemaObjectsContext(ctx context.Context, schema string) (*Result, error) {
+	return client.ObjectsWithOptions(ctx, ObjectsOptions{Schema: schema})
+}
+
+// ObjectsWithOptions returns a page of the objects list, filtered by schema and name
+// on the server. Hidden objects are removed from the page afterwards, so a page
+// may contain fewer objects than the limit.
+func (client *Client) ObjectsWithOptions(ctx context.Context, opts ObjectsOptions) (*Result, error) {
+	cacheKey := client.generateMetadataCacheKey("objects", command.Opts.HideSchemas, command.Opts.HideObjects, command.Opts.ObjectCategories,
+		opts.Schema, opts.Name, strconv.Itoa(opts.Offset), strconv.Itoa(opts.Limit))
+
+	return client.cachedMetadata(ctx, cacheKey, func(ctx context.Context) (*Result, error) {
+		return client.fetchObjects(ctx, opts)
+	})
+}
+
+// fetchObjects returns the page of the objects list with hidden objects removed
+func (client *Client) fetchObjects(ctx context.Context, opts ObjectsOptions) (*Result, error) {
+	if command.Opts.MetadataTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(command.Opts.MetadataTimeout)*time.Second)
+		defer cancel()
 	}
 
-	result, err := client.query(statements.Objects)
+	query, args := buildObjectsSQL(opts)
+	result, err := client.queryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply schema filtering if configured
+	schemaPatterns, objectPatterns, err := objectHidePatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	filteredResult := filterObjectsResult(result, schemaPatterns, objectPatterns)
+	return categorizeObjectsResult(filteredResult, command.Opts.CategoryRules), nil
+}
+
+// objectHidePatterns compiles the patterns of the schemas and objects hidden from the objects list
+func objectHidePatterns() ([]*regexp.Regexp, []*regexp.Regexp, error) {
 	schemaPatterns, err := CompileRegexPatterns(command.Opts.HideSchemas)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile schema hide patterns: %v", err)
+		return nil, nil, fmt.Errorf("failed to compile schema hide patterns: %v", err)
 	}
 
-	// Apply object filtering if configured
 	objectPatterns, err := CompileRegexPatterns(command.Opts.HideObjects)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile object hide patterns: %v", err)
+		return nil, nil, fmt.Errorf("failed to compile object hide patterns: %v", err)
 	}
 
-	filteredResult := filterObjectsResult(result, schemaPatterns, objectPatterns)
-	if MetadataCache != nil {
-		MetadataCache.Set(cacheKey, filteredResult, 10*time.Minute)
+	return schemaPatterns, objectPatterns, nil
+}
+
+func (client *Client) SearchObjects(term string) (*Result, error) {
+	return client.SearchObjectsContext(context.Background(), term)
+}
+
+// SearchObjectsContext returns the objects with the term in their name or comment,
+// name matches first. Hidden objects are not returned, and at most maxSearchResults
+// objects are, the result is truncated after removing the hidden ones.
+func (client *Client) SearchObjectsContext(ctx context.Context, term string) (*Result, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil, ErrSearchTermRequired
+	}
+
+	pattern := "%" + reLikeSpecialChars.ReplaceAllString(term, `\$0`) + "%"
+	result, err := client.queryContext(ctx, statements.SearchObjects, pattern)
+	if err != nil {
+		return nil, err
 	}
 
-	return filteredResult, nil
+	schemaPatterns, objectPatterns, err := objectHidePatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	result = filterObjectsResult(result, schemaPatterns, objectPatterns)
+	if len(result.Rows) > maxSearchResults {
+		result.Rows = result.Rows[:maxSearchResults]
+	}
+	return result, nil
 }
 
 func (client *Client) Table(table string) (*Result, error) {
+	return client.TableContext(context.Background(), table)
+}
+
+// TableContext is like Table but the query is cancelled along with the context
+func (client *Client) TableContext(ctx context.Context, table string) (*Result, error) {
 	schema, tableName := getSchemaAndTable(table)
 	cacheKey := client.generateMetadataCacheKey("table", schema, tableName)
 
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
-			return cached.(*Result), nil
-		}
-	}
-
-	result, err := client.query(statements.TableSchema, schema, tableName)
-	if err == nil && MetadataCache != nil {
-		MetadataCache.Set(cacheKey, result, 10*time.Minute)
-	}
+	return client.cachedMetadata(ctx, cacheKey, func(ctx context.Context) (*Result, error) {
+		return client.queryContext(ctx, statements.TableSchema, schema, tableName)
+	})
+}
 
-	return result, err
+// View returns the columns of the view, each row carrying the view definition
+func (client *Client) View(name string) (*Result, error) {
+	return client.query(statements.View, name)
 }
 
 func (client *Client) MaterializedView(name string) (*Result, error) {
@@ -477,24 +851,69 @@ func (client *Client) Function(id string) (*Result, error) {
 	return client.query(statements.Function, id)
 }
 
+// Sequences returns all sequences with their current value and the table column
+// owning them, for serial and identity columns
+func (client *Client) Sequences() (*Result, error) {
+	return client.query(statements.Sequences)
+}
+
+// Sequence returns the details of a single sequence
+func (client *Client) Sequence(name string) (*Result, error) {
+	schema, sequence := getSchemaAndTable(name)
+	sql := fmt.Sprintf("SELECT * FROM (%s) sequences WHERE schema = $1 AND name = $2", statements.Sequences)
+	return client.query(sql, schema, sequence)
+}
+
+// RowsUnlimited is the rows limit for fetching all the table rows
+const RowsUnlimited = -1
+
+// RowsLimit returns the number of table rows fetched for the requested limit, or 0
+// when all rows are fetched. A zero limit falls back to --default-rows-limit.
+func RowsLimit(limit int) int {
+	if limit == 0 {
+		limit = command.Opts.DefaultRowsLimit
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// TableRows returns the table rows, limited to RowsLimit(opts.Limit) rows. The
+// limit also bounds browsing of foreign tables, which TableRowsCount does not count
+// to avoid remote scans, so an unlimited request fetches the whole remote table.
 func (client *Client) TableRows(table string, opts RowsOptions) (*Result, error) {
 	schema, table := getSchemaAndTable(table)
 	sql := fmt.Sprintf(`SELECT * FROM "%s"."%s"`, schema, table)
 
 	if opts.Where != "" {
+		if err := validateWhere(opts.Where); err != nil {
+			return nil, err
+		}
 		sql += fmt.Sprintf(" WHERE %s", opts.Where)
 	}
 
+	sorts := opts.SortColumns
 	if opts.SortColumn != "" {
-		if opts.SortOrder == "" {
-			opts.SortOrder = "ASC"
+		sorts = append([]SortSpec{{Column: opts.SortColumn, Order: opts.SortOrder}}, sorts...)
+	}
+
+	if len(sorts) > 0 {
+		// Sort columns are interpolated into the query, so only existing columns are allowed
+		columns, err := client.tableColumnNames(schema, table)
+		if err != nil {
+			return nil, err
 		}
 
-		sql += fmt.Sprintf(` ORDER BY "%s" %s`, opts.SortColumn, opts.SortOrder)
+		orderBy, err := buildOrderBy(sorts, columns)
+		if err != nil {
+			return nil, err
+		}
+		sql += " " + orderBy
 	}
 
-	if opts.Limit > 0 {
-		sql += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	if limit := RowsLimit(opts.Limit); limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
 	if opts.Offset > 0 {
@@ -504,48 +923,220 @@ func (client *Client) TableRows(table string, opts RowsOptions) (*Result, error)
 	return client.query(sql)
 }
 
+// tableColumnNames returns the column names of the table, view or materialized view
+func (client *Client) tableColumnNames(schema, table string) (map[string]bool, error) {
+	res, err := client.query(statements.TableColumns, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]bool{}
+	if res == nil {
+		return columns, nil
+	}
+	for _, row := range res.Rows {
+		if name, ok := row[0].(string); ok {
+			columns[name] = true
+		}
+	}
+	return columns, nil
+}
+
+// GroupBy returns a summary of the table rows grouped by the given columns
+func (client *Client) GroupBy(table string, groupCols []string, aggs []Aggregation) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+
+	tableSchema, err := client.Table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]bool{}
+	for _, row := range tableSchema.Rows {
+		if name, ok := row[0].(string); ok {
+			columns[name] = true
+		}
+	}
+
+	for _, col := range groupCols {
+		if !columns[col] {
+			return nil, fmt.Errorf("column %q does not exist", col)
+		}
+	}
+	for _, agg := range aggs {
+		if agg.Column != "*" && !columns[agg.Column] {
+			return nil, fmt.Errorf("column %q does not exist", agg.Column)
+		}
+	}
+
+	sql, err := buildGroupBySQL(schema, tableName, groupCols, aggs)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.query(sql)
+}
+
+// ColumnHistogram returns the column values distribution over buckets of equal width
+func (client *Client) ColumnHistogram(table, column string, buckets int) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+
+	tableSchema, err := client.Table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	dataType := ""
+	for _, row := range tableSchema.Rows {
+		if row[0] == column {
+			dataType, _ = row[1].(string)
+			break
+		}
+	}
+	if dataType == "" {
+		return nil, fmt.Errorf("column %q does not exist", column)
+	}
+
+	sql, err := buildHistogramSQL(schema, tableName, column, dataType, buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.query(sql)
+}
+
+// Crosstab pivots the source query result using the tablefunc extension
+func (client *Client) Crosstab(opts CrosstabOptions) (*Result, error) {
+	if opts.Query == "" || opts.RowKey == "" || opts.Category == "" || opts.Value.Column == "" {
+		return nil, errors.New("query, row key, category and value are required")
+	}
+
+	// Pivots are read-only, source query must not modify any data
+	if err := checkRestrictedKeywords(opts.Query); err != nil {
+		return nil, err
+	}
+
+	res, err := client.query(statements.ExtensionInstalled, "tablefunc")
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) == 0 {
+		return nil, ErrTablefuncMissing
+	}
+
+	sourceSQL, categorySQL, err := buildCrosstabSourceSQL(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := client.fetchRows(categorySQL)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) > maxCrosstabCategories {
+		return nil, fmt.Errorf("too many categories: %d, maximum is %d", len(categories), maxCrosstabCategories)
+	}
+
+	return client.query(buildCrosstabSQL(opts.RowKey, categories), sourceSQL, categorySQL)
+}
+
 func (client *Client) EstimatedTableRowsCount(table string, opts RowsOptions) (*Result, error) {
 	schema, table := getSchemaAndTable(table)
-	result, err := client.query(statements.EstimatedTableRowCount, schema, table)
+
+	query := statements.EstimatedTableRowCount
+	if client.serverType == redshiftType {
+		query = statements.EstimatedTableRowCountRedshift
+	}
+
+	result, err := client.query(query, schema, table)
 	if err != nil {
 		return nil, err
 	}
 	// float64 to int64 conversion
-	estimatedRowsCount := result.Rows[0][0].(float64)
-	result.Rows[0] = Row{int64(estimatedRowsCount)}
+	estimatedRowsCount, err := Int64Value(result.Rows[0][0])
+	if err != nil {
+		return nil, err
+	}
+	result.Rows[0] = Row{estimatedRowsCount}
+	result.PostProcess()
 
 	return result, nil
 }
 
+// Columns of the table partitions result
+var tablePartitionsColumns = []string{"schema", "name", "parent", "level", "bound", "partitioned"}
+
 // isForeignTable checks if the given table is a foreign table by querying pg_class
-func (client *Client) isForeignTable(schema, tableName string) (bool, error) {
-	query := `SELECT c.relkind = 'f' as is_foreign 
-			  FROM pg_catalog.pg_class c 
-			  LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace 
+func (client *Client) isForeignTable(ctx context.Context, schema, tableName string) (bool, error) {
+	// Redshift has no foreign tables
+	if client.serverType == redshiftType {
+		return false, nil
+	}
+
+	kind, err := client.relationKind(ctx, schema, tableName)
+	return kind == "f", err
+}
+
+// isPartitionedTable checks if the given table is a declarative partitioned table
+func (client *Client) isPartitionedTable(ctx context.Context, schema, tableName string) (bool, error) {
+	// Only PostgreSQL 10+ has partitioned tables
+	if client.serverType != postgresType {
+		return false, nil
+	}
+
+	kind, err := client.relationKind(ctx, schema, tableName)
+	return kind == "p", err
+}
+
+// relationKind returns the pg_class relkind of the relation, empty if it does not exist
+func (client *Client) relationKind(ctx context.Context, schema, tableName string) (string, error) {
+	query := `SELECT c.relkind::text
+			  FROM pg_catalog.pg_class c
+			  LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
 			  WHERE c.relname = $1 AND n.nspname = $2`
 
-	result, err := client.query(query, tableName, schema)
+	result, err := client.queryContext(ctx, query, tableName, schema)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	if len(result.Rows) == 0 {
-		return false, nil
+	if result == nil || len(result.Rows) == 0 {
+		return "", nil
 	}
 
-	isForeign, ok := result.Rows[0][0].(bool)
+	kind, ok := result.Rows[0][0].(string)
 	if !ok {
-		return false, fmt.Errorf("unexpected type for is_foreign result")
+		return "", fmt.Errorf("unexpected type for relkind result")
 	}
 
-	return isForeign, nil
+	return kind, nil
+}
+
+func (client *Client) TablePartitions(table string) (*Result, error) {
+	return client.TablePartitionsContext(context.Background(), table)
+}
+
+// TablePartitionsContext is like TablePartitions but the queries are cancelled along with the context.
+// Tables that are not partitioned have no partitions.
+func (client *Client) TablePartitionsContext(ctx context.Context, table string) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+
+	partitioned, err := client.isPartitionedTable(ctx, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if !partitioned {
+		return &Result{Columns: tablePartitionsColumns, Rows: []Row{}}, nil
+	}
+
+	return client.queryContext(ctx, statements.TablePartitions, schema, tableName)
 }
 
 func (client *Client) TableRowsCount(table string, opts RowsOptions) (*Result, error) {
 	schema, tableName := getSchemaAndTable(table)
 
 	// Check if this is a foreign table
-	isForeign, err := client.isForeignTable(schema, tableName)
+	isForeign, err := client.isForeignTable(context.Background(), schema, tableName)
 	if err != nil {
 		// If we can't determine if it's foreign, log the error but proceed with normal count
 		if command.Opts.Debug {
@@ -567,12 +1158,15 @@ func (client *Client) TableRowsCount(table string, opts RowsOptions) (*Result, e
 	}
 
 	// Return postgres estimated rows count on empty filter
-	if opts.Where == "" && client.serverType == postgresType {
+	if opts.Where == "" && (client.serverType == postgresType || client.serverType == redshiftType) {
 		res, err := client.EstimatedTableRowsCount(table, opts)
 		if err != nil {
 			return nil, err
 		}
-		n := res.Rows[0][0].(int64)
+		n, err := Int64Value(res.Rows[0][0])
+		if err != nil {
+			return nil, err
+		}
 		if n >= 100000 {
 			return res, nil
 		}
@@ -581,32 +1175,61 @@ func (client *Client) TableRowsCount(table string, opts RowsOptions) (*Result, e
 	sql := fmt.Sprintf(`SELECT COUNT(1) FROM "%s"."%s"`, schema, tableName)
 
 	if opts.Where != "" {
+		if err := validateWhere(opts.Where); err != nil {
+			return nil, err
+		}
 		sql += fmt.Sprintf(" WHERE %s", opts.Where)
 	}
 
-	return client.query(sql)
+	// Role is part of the key since row level security may hide rows
+	cacheKey := client.tableCachePrefix(schema, tableName) + "rows_count|" + client.defaultRole + "|" + opts.Where
+	if client.metadataCache() != nil {
+		if cached, found := client.metadataCache().Get(cacheKey); found {
+			return cached.(*Result), nil
+		}
+	}
+
+	result, err := client.query(sql)
+	if err == nil && client.metadataCache() != nil {
+		client.metadataCache().Set(cacheKey, result, 10*time.Minute)
+	}
+
+	return result, err
 }
 
 func (client *Client) TableInfo(table string) (*Result, error) {
+	return client.TableInfoContext(context.Background(), table)
+}
+
+// TableInfoContext is like TableInfo but the queries are cancelled along with the context
+func (client *Client) TableInfoContext(ctx context.Context, table string) (*Result, error) {
 	schema, tableName := getSchemaAndTable(table)
 	cacheKey := client.generateMetadataCacheKey("table_info", schema, tableName, client.serverType)
 
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
+	if client.metadataCache() != nil {
+		if cached, found := client.metadataCache().Get(cacheKey); found {
 			return cached.(*Result), nil
 		}
 	}
 
 	if client.serverType == cockroachType {
-		result, err := client.query(statements.TableInfoCockroach)
-		if err == nil && MetadataCache != nil {
-			MetadataCache.Set(cacheKey, result, 10*time.Minute)
+		result, err := client.queryContext(ctx, statements.TableInfoCockroach)
+		if err == nil && client.metadataCache() != nil {
+			client.metadataCache().Set(cacheKey, result, 10*time.Minute)
+		}
+		return result, err
+	}
+
+	if client.serverType == redshiftType {
+		result, err := client.queryContext(ctx, statements.TableInfoRedshift, schema, tableName)
+		if err == nil && client.metadataCache() != nil {
+			client.metadataCache().Set(cacheKey, result, 10*time.Minute)
 		}
 		return result, err
 	}
 
 	// Check if this is a foreign table
-	isForeign, err := client.isForeignTable(schema, tableName)
+	isForeign, err := client.isForeignTable(ctx, schema, tableName)
 	if err != nil {
 		// If we can't determine if it's foreign, log the error but proceed with normal info
 		if command.Opts.Debug {
@@ -622,68 +1245,174 @@ func (client *Client) TableInfo(table string) (*Result, error) {
 				{"N/A", "N/A", "N/A", "Unknown", true},
 			},
 		}
-		if MetadataCache != nil {
-			MetadataCache.Set(cacheKey, result, 10*time.Minute)
+		if client.metadataCache() != nil {
+			client.metadataCache().Set(cacheKey, result, 10*time.Minute)
 		}
 		return result, nil
 	}
 
-	result, err := client.query(statements.TableInfo, fmt.Sprintf(`"%s"."%s"`, schema, tableName))
-	if err == nil && MetadataCache != nil {
-		MetadataCache.Set(cacheKey, result, 10*time.Minute)
+	result, err := client.queryContext(ctx, statements.TableInfo, fmt.Sprintf(`"%s"."%s"`, schema, tableName))
+	if err == nil && client.metadataCache() != nil {
+		client.metadataCache().Set(cacheKey, result, 10*time.Minute)
 	}
 
 	return result, err
 }
 
 func (client *Client) TableIndexes(table string) (*Result, error) {
+	return client.TableIndexesContext(context.Background(), table)
+}
+
+// TableIndexesContext is like TableIndexes but the query is cancelled along with the context
+func (client *Client) TableIndexesContext(ctx context.Context, table string) (*Result, error) {
 	schema, tableName := getSchemaAndTable(table)
 	cacheKey := client.generateMetadataCacheKey("table_indexes", schema, tableName)
 
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
-			return cached.(*Result), nil
-		}
-	}
+	return client.cachedMetadata(ctx, cacheKey, func(ctx context.Context) (*Result, error) {
+		return client.queryContext(ctx, statements.TableIndexes, schema, tableName)
+	})
+}
+
+// TablePrimaryKey returns the primary key columns of the table, in key order
+func (client *Client) TablePrimaryKey(ctx context.Context, table string) ([]string, error) {
+	schema, tableName := getSchemaAndTable(table)
 
-	res, err := client.query(statements.TableIndexes, schema, tableName)
-	if err == nil && MetadataCache != nil {
-		MetadataCache.Set(cacheKey, res, 10*time.Minute)
+	res, err := client.queryContext(ctx, statements.TablePrimaryKey, schema, tableName)
+	if err != nil {
+		return nil, err
 	}
 
-	return res, err
+	columns := make([]string, len(res.Rows))
+	for i, row := range res.Rows {
+		columns[i] = fmt.Sprintf("%v", row[0])
+	}
+	return columns, nil
 }
 
 func (client *Client) TableConstraints(table string) (*Result, error) {
+	return client.TableConstraintsContext(context.Background(), table)
+}
+
+// TableConstraintsContext is like TableConstraints but the query is cancelled along with the context
+func (client *Client) TableConstraintsContext(ctx context.Context, table string) (*Result, error) {
 	schema, tableName := getSchemaAndTable(table)
 	cacheKey := client.generateMetadataCacheKey("table_constraints", schema, tableName)
 
-	if MetadataCache != nil {
-		if cached, found := MetadataCache.Get(cacheKey); found {
-			return cached.(*Result), nil
-		}
-	}
+	return client.cachedMetadata(ctx, cacheKey, func(ctx context.Context) (*Result, error) {
+		return client.queryContext(ctx, statements.TableConstraints, schema, tableName)
+	})
+}
 
-	res, err := client.query(statements.TableConstraints, schema, tableName)
-	if err == nil && MetadataCache != nil {
-		MetadataCache.Set(cacheKey, res, 10*time.Minute)
-	}
+func (client *Client) TableTriggers(table string) (*Result, error) {
+	return client.TableTriggersContext(context.Background(), table)
+}
 
-	return res, err
+// TableTriggersContext is like TableTriggers but the query is cancelled along with the context
+func (client *Client) TableTriggersContext(ctx context.Context, table string) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+	cacheKey := client.generateMetadataCacheKey("table_triggers", schema, tableName)
+
+	return client.cachedMetadata(ctx, cacheKey, func(ctx context.Context) (*Result, error) {
+		return client.queryContext(ctx, statements.TableTriggers, schema, tableName)
+	})
+}
+
+func (client *Client) TablePolicies(table string) (*Result, error) {
+	return client.TablePoliciesContext(context.Background(), table)
+}
+
+// TablePoliciesContext is like TablePolicies but the query is cancelled along with the context.
+// Every row tells whether row level security is enabled and forced on the table, a table
+// without policies has a single row with only these columns set.
+func (client *Client) TablePoliciesContext(ctx context.Context, table string) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+	return client.queryContext(ctx, statements.TablePolicies, schema, tableName)
 }
 
 func (client *Client) TablesStats() (*Result, error) {
 	return client.query(statements.TablesStats)
 }
 
+func (client *Client) ColumnStats(table string) (*Result, error) {
+	return client.ColumnStatsContext(context.Background(), table)
+}
+
+// ColumnStatsContext is like ColumnStats but the query is cancelled along with the context.
+// Tables that were never analyzed have no column statistics, so the result has no rows.
+func (client *Client) ColumnStatsContext(ctx context.Context, table string) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+	return client.queryContext(ctx, statements.ColumnStats, schema, tableName)
+}
+
+// UnindexedForeignKeys returns the foreign keys of the schema with no index covering
+// the referencing columns. Updates and deletes of the referenced rows have to scan
+// the whole referencing table for such keys.
+func (client *Client) UnindexedForeignKeys(schema string) (*Result, error) {
+	return client.query(statements.UnindexedForeignKeys, schema)
+}
+
+// TopStatements returns the most expensive queries recorded by pg_stat_statements
+func (client *Client) TopStatements(orderBy string, limit int) (*Result, error) {
+	orderColumn, err := statStatementsOrderColumn(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.query(statements.ExtensionInstalled, "pg_stat_statements")
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) == 0 {
+		return nil, ErrStatStatementsMissing
+	}
+
+	// Timing columns were renamed in PostgreSQL 13
+	timeSuffix := "exec_time"
+	if major, _ := getMajorMinorVersion(client.serverVersion); major > 0 && major < 13 {
+		timeSuffix = "time"
+	}
+
+	sql := fmt.Sprintf(statements.TopStatements, timeSuffix, orderColumn)
+	return client.query(sql, limit)
+}
+
+// ResetStats resets the collected statistics of the given kind, either
+// pg_stat_statements data or the database-wide activity counters
+func (client *Client) ResetStats(kind string) error {
+	if client.isReadOnly() {
+		return ErrReadOnly
+	}
+
+	query, err := statsResetQuery(kind)
+	if err != nil {
+		return err
+	}
+
+	if kind == "statements" {
+		res, err := client.query(statements.ExtensionInstalled, "pg_stat_statements")
+		if err != nil {
+			return err
+		}
+		if len(res.Rows) == 0 {
+			return ErrStatStatementsMissing
+		}
+	}
+
+	_, err = client.query(query)
+	return err
+}
+
 func (client *Client) ServerSettings() (*Result, error) {
 	return client.query(statements.Settings)
 }
 
 // Returns all active queriers on the server
 func (client *Client) Activity() (*Result, error) {
-	if client.serverType == cockroachType {
+	switch client.serverType {
+	case cockroachType:
 		return client.query("SHOW QUERIES")
+	case redshiftType:
+		return client.query(statements.ActivityRedshift)
 	}
 
 	version := getMajorMinorVersionString(client.serverVersion)
@@ -695,11 +1424,59 @@ func (client *Client) Activity() (*Result, error) {
 	return client.query(query)
 }
 
+// TerminateBackend terminates the server process with the pid, ie. one listed by Activity.
+// It returns false if the process does not exist.
+func (client *Client) TerminateBackend(pid int) (bool, error) {
+	if err := client.checkTerminateAllowed(); err != nil {
+		return false, err
+	}
+	if pid <= 0 {
+		return false, ErrInvalidPid
+	}
+
+	var terminated bool
+	err := client.db.Get(&terminated, "SELECT pg_terminate_backend($1)", pid)
+	return terminated, err
+}
+
+// TerminateIdleBackends terminates the idle server processes of the Activity list
+// that didn't run a query for idleMinutes, and returns them
+func (client *Client) TerminateIdleBackends(idleMinutes int) (*Result, error) {
+	if err := client.checkTerminateAllowed(); err != nil {
+		return nil, err
+	}
+	if idleMinutes <= 0 {
+		return nil, ErrInvalidIdleMinutes
+	}
+
+	return client.query(statements.TerminateIdleBackends, idleMinutes)
+}
+
+func (client *Client) checkTerminateAllowed() error {
+	if client.isReadOnly() {
+		return ErrReadOnly
+	}
+	if client.serverType != postgresType {
+		return ErrTerminateUnsupported
+	}
+	return nil
+}
+
+// Query runs the user query, which can be aborted with CancelQuery while in flight
 func (client *Client) Query(query string) (*Result, error) {
-	res, err := client.query(query)
+	return client.QueryContext(context.Background(), query)
+}
+
+// QueryContext runs the query like Query, with the values of the context, ie. the request ID
+func (client *Client) QueryContext(ctx context.Context, query string) (*Result, error) {
+	start := time.Now()
+	res, err := client.withReconnect(ctx, func() (*Result, error) {
+		return client.runCancelableQuery(ctx, query)
+	})
+	logQuery(ctx, query, start, resultRowsCount(res), err)
 
-	if err == nil && !client.hasHistoryRecord(query) {
-		client.History = append(client.History, history.NewRecord(query))
+	if err == nil {
+		client.addHistoryRecord(query)
 	}
 
 	return res, err
@@ -728,41 +1505,81 @@ func (client *Client) ServerVersion() string {
 }
 
 func (client *Client) context() (context.Context, context.CancelFunc) {
-	if client.queryTimeout > 0 {
-		return context.WithTimeout(context.Background(), client.queryTimeout)
+	return client.contextFrom(context.Background())
+}
+
+// contextFrom returns a query context derived from the parent, with the query timeout applied
+func (client *Client) contextFrom(parent context.Context) (context.Context, context.CancelFunc) {
+	if timeout := client.QueryTimeout(parent); timeout > 0 {
+		return context.WithTimeout(parent, timeout)
 	}
-	return context.Background(), func() {}
+	return context.WithCancel(parent)
 }
 
 func (client *Client) exec(query string, args ...interface{}) (*Result, error) {
-	ctx, cancel := client.context()
+	return client.execContext(context.Background(), query, args...)
+}
+
+func (client *Client) execContext(parent context.Context, query string, args ...interface{}) (*Result, error) {
+	return client.withReconnect(parent, func() (*Result, error) {
+		return client.runExec(parent, query, args...)
+	})
+}
+
+func (client *Client) runExec(parent context.Context, query string, args ...interface{}) (*Result, error) {
+	return client.runExecOn(parent, client.conn(), query, args...)
+}
+
+func (client *Client) runExecOn(parent context.Context, conn queryConn, query string, args ...interface{}) (*Result, error) {
+	defer client.BeginQuery()()
+
+	ctx, cancel := client.contextFrom(parent)
 	defer cancel()
 
+	if err := client.checkReadOnlySchemasOn(ctx, conn, query); err != nil {
+		return nil, err
+	}
+
 	// Execute SET ROLE as a separate command if specified via X-Database-Role header
 	if client.defaultRole != "" {
 		setRoleQuery := fmt.Sprintf(`SET ROLE "%s"`, client.defaultRole)
 		if command.Opts.Debug {
 			log.Printf("Role injection (exec): SET ROLE %s", client.defaultRole)
 		}
-		_, err := client.db.ExecContext(ctx, setRoleQuery)
+		_, err := conn.ExecContext(ctx, setRoleQuery)
 		if err != nil {
 			return nil, fmt.Errorf("failed to set role %s: %w", client.defaultRole, err)
 		}
 	}
 
+	if err := client.setApplicationName(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	query, args, err := client.queryArgs(query, args)
+	if err != nil {
+		return nil, err
+	}
+
 	queryStart := time.Now()
-	res, err := client.db.ExecContext(ctx, query, args...)
+	res, err := conn.ExecContext(ctx, client.tagQuery(ctx, conn, query, args), args...)
 	queryFinish := time.Now()
 	if err != nil {
+		if client.detectPgBouncer(err, args) {
+			return client.runExecOn(parent, conn, query, args...)
+		}
 		return nil, err
 	}
 
+	client.invalidateTableCache(query)
+
 	affected, err := res.RowsAffected()
 	if err != nil {
 		return nil, err
 	}
 
 	result := Result{
+		Type:    ResultTypeCommand,
 		Columns: []string{"Rows Affected"},
 		Rows: []Row{
 			{affected},
@@ -780,14 +1597,28 @@ func (client *Client) exec(query string, args ...interface{}) (*Result, error) {
 }
 
 func (client *Client) query(query string, args ...interface{}) (*Result, error) {
+	return client.queryContext(context.Background(), query, args...)
+}
+
+// queryContext runs the query, aborting it once the parent context is cancelled
+func (client *Client) queryContext(parent context.Context, query string, args ...interface{}) (*Result, error) {
+	return client.withReconnect(parent, func() (*Result, error) {
+		return client.runQuery(parent, query, args...)
+	})
+}
+
+func (client *Client) runQuery(parent context.Context, query string, args ...interface{}) (*Result, error) {
 	if client.db == nil {
 		return nil, nil
 	}
 
-	// Update the last usage time
-	defer func() {
-		client.lastQueryTime = time.Now().UTC()
-	}()
+	return client.withWorkMem(parent, client.db, query, func(conn queryConn) (*Result, error) {
+		return client.runQueryOn(parent, conn, query, args...)
+	})
+}
+
+func (client *Client) runQueryOn(parent context.Context, conn queryConn, query string, args ...interface{}) (*Result, error) {
+	defer client.BeginQuery()()
 
 	// Execute SET ROLE as a separate command if specified via X-Database-Role header
 	if client.defaultRole != "" {
@@ -795,39 +1626,64 @@ func (client *Client) query(query string, args ...interface{}) (*Result, error)
 		if command.Opts.Debug {
 			log.Printf("Role injection: SET ROLE %s", client.defaultRole)
 		}
-		ctx, cancel := client.context()
-		_, err := client.db.ExecContext(ctx, setRoleQuery)
+		ctx, cancel := client.contextFrom(parent)
+		_, err := conn.ExecContext(ctx, setRoleQuery)
 		cancel()
 		if err != nil {
 			return nil, fmt.Errorf("failed to set role %s: %w", client.defaultRole, err)
 		}
 	}
 
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	if err := client.setApplicationName(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	if err := client.checkReadOnlySchemasOn(ctx, conn, query); err != nil {
+		return nil, err
+	}
+
 	// We're going to force-set transaction mode on every query.
 	// This is needed so that default mode could not be changed by user.
-	if command.Opts.ReadOnly || client.readonly {
+	if client.isReadOnly() {
 		if err := client.SetReadOnlyMode(); err != nil {
 			return nil, err
 		}
-		if containsRestrictedKeywords(query) {
-			return nil, errors.New("query contains keywords not allowed in read-only mode")
+	}
+	if client.isReadOnlyContext(parent) {
+		if err := checkReadOnlyQuery(query); err != nil {
+			return nil, err
 		}
 	}
 
 	action := strings.ToLower(strings.Split(query, " ")[0])
-	hasReturnValues := strings.Contains(strings.ToLower(query), " returning ")
+	hasReturnValues := hasReturning(query)
 
 	if (action == "update" || action == "delete") && !hasReturnValues {
-		return client.exec(query, args...)
+		returning, ok := "", false
+		if command.Opts.AutoReturning && !client.isReadOnlyContext(parent) {
+			returning, ok = addReturning(query)
+		}
+		if !ok {
+			return client.runExecOn(parent, conn, query, args...)
+		}
+		query = returning
 	}
 
-	ctx, cancel := client.context()
-	defer cancel()
+	query, args, err := client.queryArgs(query, args)
+	if err != nil {
+		return nil, err
+	}
 
 	queryStart := time.Now()
-	rows, err := client.db.QueryxContext(ctx, query, args...)
+	rows, err := conn.QueryxContext(ctx, client.tagQuery(ctx, conn, query, args), args...)
 	queryFinish := time.Now()
 	if err != nil {
+		if client.detectPgBouncer(err, args) {
+			return client.runQueryOn(parent, conn, query, args...)
+		}
 		if command.Opts.Debug {
 			log.Println("Failed query:", query, "\nArgs:", args)
 		}
@@ -835,6 +1691,8 @@ func (client *Client) query(query string, args ...interface{}) (*Result, error)
 	}
 	defer rows.Close()
 
+	client.invalidateTableCache(query)
+
 	cols, err := rows.Columns()
 	if err != nil {
 		return nil, err
@@ -846,10 +1704,18 @@ func (client *Client) query(query string, args ...interface{}) (*Result, error)
 	}
 
 	result := Result{
+		Type:    ResultTypeRows,
 		Columns: cols,
 		Rows:    []Row{},
 	}
 
+	// Statements like SET or CREATE do not return a result set
+	if len(cols) == 0 {
+		result.Type = ResultTypeCommand
+	}
+
+	result.ColumnTypes = columnTypeNames(rows)
+
 	for rows.Next() {
 		obj, err := rows.SliceScan()
 
@@ -860,7 +1726,7 @@ func (client *Client) query(query string, args ...interface{}) (*Result, error)
 				t := reflect.TypeOf(item).Kind().String()
 
 				if t == "slice" {
-					obj[i] = string(item.([]byte))
+					obj[i] = decodeTextData(item.([]byte))
 				}
 			}
 		}
@@ -883,6 +1749,21 @@ func (client *Client) query(query string, args ...interface{}) (*Result, error)
 	return &result, nil
 }
 
+// columnTypeNames returns the database type names of the result columns,
+// ie. INT8 or TIMESTAMPTZ
+func columnTypeNames(rows *sqlx.Rows) []string {
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, len(colTypes))
+	for i, colType := range colTypes {
+		names[i] = colType.DatabaseTypeName()
+	}
+	return names
+}
+
 // Close database connection
 func (client *Client) Close() error {
 	if client.closed {
@@ -893,17 +1774,56 @@ func (client *Client) Close() error {
 		client.tunnel = nil
 	}()
 
+	if client.tx != nil {
+		client.RollbackTransaction() //nolint
+	}
+
+	if client.historyStore != nil {
+		client.historyStore.Close()
+	}
+
+	if client.done != nil {
+		close(client.done)
+	}
+
 	if client.tunnel != nil {
 		client.tunnel.Close()
 	}
 
 	if client.db != nil {
+		client.closePreparedStatements()
 		return client.db.Close()
 	}
 
 	return nil
 }
 
+// DatabaseName returns the name of the connected database
+func (client *Client) DatabaseName() string {
+	uri, err := neturl.Parse(client.ConnectionString)
+	if err != nil || uri.Scheme == "" || uri.Path == "" {
+		return "unknown"
+	}
+	return strings.TrimPrefix(uri.Path, "/")
+}
+
+// ConnectionTarget returns the connection string of the database server along with
+// the SSH tunnel settings, if any. Unlike ConnectionString, the URL is not rewritten
+// to point to the local tunnel port.
+func (client *Client) ConnectionTarget() (string, *shared.SSHInfo) {
+	if client.tunnel == nil {
+		return client.ConnectionString, nil
+	}
+
+	uri, err := neturl.Parse(client.ConnectionString)
+	if err != nil {
+		return client.ConnectionString, client.tunnel.SSHInfo
+	}
+	uri.Host = net.JoinHostPort(client.tunnel.TargetHost, client.tunnel.TargetPort)
+
+	return uri.String(), client.tunnel.SSHInfo
+}
+
 func (c *Client) IsClosed() bool {
 	return c.closed
 }
@@ -912,7 +1832,33 @@ func (c *Client) LastQueryTime() time.Time {
 	return c.lastQueryTime
 }
 
+// Touch marks the client as recently used without running a query
+func (client *Client) Touch() {
+	client.lastQueryTime = time.Now().UTC()
+}
+
+// BeginQuery marks the client as used and running a query until the returned
+// function is called, so the client is never considered idle mid-query
+func (client *Client) BeginQuery() func() {
+	client.activeQueries.Add(1)
+	client.Touch()
+
+	return func() {
+		client.Touch()
+		client.activeQueries.Add(-1)
+	}
+}
+
+// IsBusy returns true while the client runs a query
+func (client *Client) IsBusy() bool {
+	return client.activeQueries.Load() > 0
+}
+
 func (client *Client) IsIdle() bool {
+	if client.IsBusy() {
+		return false
+	}
+
 	mins := int(time.Since(client.lastQueryTime).Minutes())
 
 	if command.Opts.ConnectionIdleTimeout > 0 {
@@ -940,6 +1886,44 @@ func (client *Client) fetchRows(q string) ([]string, error) {
 	return results, nil
 }
 
+// TrackRecentObject moves the table to the front of the recently used objects list.
+// Called once the table was opened successfully.
+func (client *Client) TrackRecentObject(table string) {
+	schema, name := getSchemaAndTable(table)
+	object := schema + "." + name
+
+	client.recentObjectsMu.Lock()
+	defer client.recentObjectsMu.Unlock()
+
+	if len(client.recentObjects) > 0 && client.recentObjects[0] == object {
+		return
+	}
+	if client.historyStore != nil {
+		client.historyStore.Append(history.NewObjectRecord(object))
+	}
+
+	recent := make([]string, 0, maxRecentObjects)
+	recent = append(recent, object)
+
+	for _, item := range client.recentObjects {
+		if item != object && len(recent) < maxRecentObjects {
+			recent = append(recent, item)
+		}
+	}
+
+	client.recentObjects = recent
+}
+
+// GetRecentObjects returns a copy of the recently used objects list, most recent first
+func (client *Client) GetRecentObjects() []string {
+	client.recentObjectsMu.Lock()
+	defer client.recentObjectsMu.Unlock()
+
+	result := make([]string, len(client.recentObjects))
+	copy(result, client.recentObjects)
+	return result
+}
+
 func (client *Client) hasHistoryRecord(query string) bool {
 	result := false
 
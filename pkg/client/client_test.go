@@ -1,7 +1,12 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -14,7 +19,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/flowbi/pgweb/pkg/cache"
 	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/shared"
+	"github.com/flowbi/pgweb/pkg/statements"
 )
 
 var (
@@ -322,6 +330,21 @@ func testDatabases(t *testing.T) {
 	assertMatches(t, []string{"booktown", "postgres"}, res)
 }
 
+func testAllowedDatabases(t *testing.T) {
+	command.Opts.AllowedDatabases = "book.*"
+	defer func() {
+		command.Opts.AllowedDatabases = ""
+	}()
+
+	res, err := testClient.Databases()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"booktown"}, res)
+
+	url := fmt.Sprintf("postgres://%s@%s:%s/postgres?sslmode=disable", serverUser, serverHost, serverPort)
+	_, err = NewFromUrl(url, nil)
+	assert.Equal(t, ErrDatabaseNotAllowed, err)
+}
+
 func testSchemas(t *testing.T) {
 	res, err := testClient.Schemas()
 	assert.NoError(t, err)
@@ -391,7 +414,7 @@ func testObjects(t *testing.T) {
 	assert.Equal(t, []string{"public"}, mapKeys(objects))
 	assert.Equal(t, tables, objectNames(objects["public"].Tables))
 	assertMatches(t, functions, objectNames(objects["public"].Functions))
-	assert.Equal(t, []string{"recent_shipments", "stock_view"}, objectNames(objects["public"].Views))
+	assert.Equal(t, []string{"expensive_stock", "recent_shipments", "stock_view"}, objectNames(objects["public"].Views))
 	assert.Equal(t, []string{"author_ids", "book_ids", "shipments_ship_id_seq", "subject_ids"}, objectNames(objects["public"].Sequences))
 
 	major, minor := pgVersion()
@@ -402,6 +425,91 @@ func testObjects(t *testing.T) {
 	}
 }
 
+func testObjectsWithOptions(t *testing.T) {
+	res, err := testClient.ObjectsWithOptions(context.Background(), ObjectsOptions{Schema: "public", Name: "BOOK"})
+	require.NoError(t, err)
+	names := []string{}
+	for _, row := range res.Rows {
+		assert.Equal(t, "public", row[1])
+		names = append(names, row[2].(string))
+	}
+	assert.Contains(t, names, "books")
+	assert.Contains(t, names, "book_ids")
+	assert.NotContains(t, names, "stock")
+
+	page, err := testClient.ObjectsWithOptions(context.Background(), ObjectsOptions{Schema: "public", Name: "book", Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, res.Rows[1:3], page.Rows)
+
+	res, err = testClient.ObjectsWithOptions(context.Background(), ObjectsOptions{Schema: "missing"})
+	require.NoError(t, err)
+	assert.Empty(t, res.Rows)
+}
+
+func testSchemaObjects(t *testing.T) {
+	_, err := testClient.db.Exec("CREATE SCHEMA lazy_schema; CREATE TABLE lazy_schema.lazy_table (id int)")
+	require.NoError(t, err)
+	defer testClient.db.Exec("DROP SCHEMA lazy_schema CASCADE") //nolint:errcheck
+
+	res, err := testClient.SchemaObjects("lazy_schema")
+	require.NoError(t, err)
+	objects := ObjectsFromResult(res)
+	assert.Equal(t, []string{"lazy_schema"}, mapKeys(objects))
+	assert.Equal(t, []string{"lazy_table"}, objectNames(objects["lazy_schema"].Tables))
+
+	res, err = testClient.SchemaObjects("public")
+	require.NoError(t, err)
+	objects = ObjectsFromResult(res)
+	assert.Equal(t, []string{"public"}, mapKeys(objects))
+	assert.Contains(t, objectNames(objects["public"].Tables), "books")
+
+	t.Run("hidden objects", func(t *testing.T) {
+		defer func(opts command.Options) {
+			command.Opts = opts
+		}(command.Opts)
+		command.Opts.HideObjects = "^lazy_"
+
+		res, err := testClient.SchemaObjects("lazy_schema")
+		require.NoError(t, err)
+		assert.Empty(t, res.Rows)
+	})
+}
+
+func testSchemaSummary(t *testing.T) {
+	res, err := testClient.SchemaSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"schema",
+		"tables_count",
+		"views_count",
+		"materialized_views_count",
+		"sequences_count",
+		"functions_count",
+		"total_size_bytes",
+		"total_size",
+	}, res.Columns)
+
+	summary := res.Format()
+	assert.Equal(t, 1, len(summary))
+	assert.Equal(t, "public", summary[0]["schema"])
+	assert.Equal(t, int64(24), summary[0]["tables_count"])
+	assert.Equal(t, int64(3), summary[0]["views_count"])
+	assert.Equal(t, int64(4), summary[0]["sequences_count"])
+	assert.GreaterOrEqual(t, summary[0]["functions_count"].(int64), int64(24))
+	assert.Greater(t, summary[0]["total_size_bytes"].(int64), int64(0))
+
+	t.Run("hidden schemas", func(t *testing.T) {
+		command.Opts.HideSchemas = "^public$"
+		defer func() {
+			command.Opts.HideSchemas = ""
+		}()
+
+		res, err := testClient.SchemaSummary()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(res.Rows))
+	})
+}
+
 func testTable(t *testing.T) {
 	columns := []string{
 		"column_name",
@@ -411,6 +519,7 @@ func testTable(t *testing.T) {
 		"character_set_catalog",
 		"column_default",
 		"comment",
+		"statistics_target",
 	}
 
 	res, err := testClient.Table("books")
@@ -419,11 +528,637 @@ func testTable(t *testing.T) {
 	assert.Equal(t, 4, len(res.Rows))
 }
 
+func testView(t *testing.T) {
+	res, err := testClient.View("expensive_stock")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"column_name",
+		"data_type",
+		"is_nullable",
+		"character_maximum_length",
+		"character_set_catalog",
+		"column_default",
+		"definition",
+	}, res.Columns)
+	require.Equal(t, 2, len(res.Rows))
+	assert.Equal(t, "isbn", res.Rows[0][0])
+	assert.Equal(t, "retail", res.Rows[1][0])
+
+	// The definition recreates the view
+	definition := res.Rows[0][6].(string)
+	_, err = testClient.db.Exec("CREATE VIEW expensive_stock_copy AS " + definition)
+	require.NoError(t, err)
+	defer testClient.db.Exec("DROP VIEW expensive_stock_copy") //nolint:errcheck
+
+	original, err := testClient.query("SELECT * FROM expensive_stock")
+	require.NoError(t, err)
+	copied, err := testClient.query("SELECT * FROM expensive_stock_copy")
+	require.NoError(t, err)
+	assert.NotEmpty(t, original.Rows)
+	assert.Equal(t, original.Rows, copied.Rows)
+
+	// Other objects are not views
+	res, err = testClient.View("books")
+	require.NoError(t, err)
+	assert.Empty(t, res.Rows)
+}
+
 func testTableRows(t *testing.T) {
 	res, err := testClient.TableRows("books", RowsOptions{})
 	assert.NoError(t, err)
 	assert.Equal(t, 4, len(res.Columns))
 	assert.Equal(t, 15, len(res.Rows))
+
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.DefaultRowsLimit = 10
+
+	res, err = testClient.TableRows("books", RowsOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, len(res.Rows))
+
+	res, err = testClient.TableRows("books", RowsOptions{Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(res.Rows))
+
+	res, err = testClient.TableRows("books", RowsOptions{Limit: RowsUnlimited})
+	assert.NoError(t, err)
+	assert.Equal(t, 15, len(res.Rows))
+}
+
+func testTableRowsSortColumns(t *testing.T) {
+	res, err := testClient.TableRows("books", RowsOptions{
+		SortColumns: []SortSpec{{Column: "author_id", Order: "DESC"}, {Column: "id", Order: "ASC"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 15, len(res.Rows))
+
+	for i := 1; i < len(res.Rows); i++ {
+		prevAuthor, author := res.Rows[i-1][2].(int64), res.Rows[i][2].(int64)
+		assert.GreaterOrEqual(t, prevAuthor, author)
+		if prevAuthor == author {
+			assert.Less(t, res.Rows[i-1][0].(int64), res.Rows[i][0].(int64))
+		}
+	}
+
+	// Legacy sort column goes first
+	res, err = testClient.TableRows("books", RowsOptions{
+		SortColumn:  "subject_id",
+		SortColumns: []SortSpec{{Column: "id", Order: "DESC"}},
+		Limit:       1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(res.Rows))
+
+	_, err = testClient.TableRows("books", RowsOptions{SortColumns: []SortSpec{{Column: "missing"}}})
+	assert.EqualError(t, err, `column "missing" does not exist`)
+
+	// Materialized view columns are missing from information_schema
+	testClient.db.MustExec("CREATE MATERIALIZED VIEW books_mv AS SELECT id, title FROM books")
+	defer testClient.db.MustExec("DROP MATERIALIZED VIEW books_mv")
+
+	res, err = testClient.TableRows("books_mv", RowsOptions{SortColumns: []SortSpec{{Column: "title"}}})
+	require.NoError(t, err)
+	assert.Equal(t, 15, len(res.Rows))
+}
+
+func testGroupBy(t *testing.T) {
+	t.Run("count by column", func(t *testing.T) {
+		res, err := testClient.GroupBy("books", []string{"subject_id"}, []Aggregation{{Function: "count", Column: "*"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"subject_id", "count"}, res.Columns)
+
+		total := int64(0)
+		for _, row := range res.Rows {
+			total += row[1].(int64)
+		}
+		assert.Equal(t, int64(15), total)
+	})
+
+	t.Run("sum with group", func(t *testing.T) {
+		res, err := testClient.GroupBy("stock", []string{"cost"}, []Aggregation{{Function: "sum", Column: "stock"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"cost", "sum_stock"}, res.Columns)
+		assert.Equal(t, Row{"16.00", int64(4)}, res.Rows[0])
+		assert.Equal(t, Row{"17.00", int64(77)}, res.Rows[1])
+	})
+
+	t.Run("invalid column", func(t *testing.T) {
+		_, err := testClient.GroupBy("books", []string{"foo"}, nil)
+		assert.EqualError(t, err, `column "foo" does not exist`)
+	})
+}
+
+func testCrosstab(t *testing.T) {
+	opts := CrosstabOptions{
+		Query:    "SELECT * FROM stock",
+		RowKey:   "retail",
+		Category: "cost",
+		Value:    Aggregation{Function: "sum", Column: "stock"},
+	}
+
+	res, err := testClient.query(statements.ExtensionInstalled, "tablefunc")
+	require.NoError(t, err)
+
+	if len(res.Rows) == 0 {
+		_, err := testClient.Crosstab(opts)
+		assert.Equal(t, ErrTablefuncMissing, err)
+		t.Log("tablefunc extension is not installed, skipping crosstab test")
+		return
+	}
+
+	categories, err := testClient.fetchRows("SELECT DISTINCT cost::text FROM stock ORDER BY 1")
+	require.NoError(t, err)
+
+	res, err = testClient.Crosstab(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, append([]string{"retail"}, categories...), res.Columns)
+}
+
+func testMetadataContextCancel(t *testing.T) {
+	t.Run("cancelled before query", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		res, err := testClient.TableContext(ctx, "books")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, res)
+
+		res, err = testClient.TableIndexesContext(ctx, "books")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, res)
+	})
+
+	t.Run("cancelled while running", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		start := time.Now()
+		res, err := testClient.queryContext(ctx, "SELECT pg_sleep(5)")
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+func testTableStorageParams(t *testing.T) {
+	res, err := testClient.TableStorageParams("books")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"option_name", "option_value"}, res.Columns)
+	assert.Equal(t, 0, len(res.Rows))
+
+	_, err = testClient.SetStorageParam("books", "fillfactor", "70")
+	assert.NoError(t, err)
+	_, err = testClient.SetStorageParam("books", "autovacuum_enabled", "false")
+	assert.NoError(t, err)
+
+	res, err = testClient.TableStorageParams("public.books")
+	assert.NoError(t, err)
+	assert.Equal(t, []Row{{"autovacuum_enabled", "false"}, {"fillfactor", "70"}}, res.Rows)
+
+	testClient.db.MustExec("ALTER TABLE books RESET (fillfactor, autovacuum_enabled)")
+}
+
+func testBatch(t *testing.T) {
+	for _, parallel := range []int{1, 4} {
+		results, err := testClient.Batch(context.Background(), []BatchQuery{
+			{ID: "ok", Query: "SELECT id FROM books WHERE id = $1", Params: []interface{}{7808}},
+			{ID: "missing", Query: "SELECT * FROM missing_table"},
+			{ID: "count", Query: "SELECT count(*) FROM books"},
+		}, parallel)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		assert.Equal(t, "ok", results[0].ID)
+		assert.Empty(t, results[0].Error)
+		assert.Equal(t, []Row{{int64(7808)}}, results[0].Result.Rows)
+
+		assert.Equal(t, "missing", results[1].ID)
+		assert.Nil(t, results[1].Result)
+		assert.Equal(t, `pq: relation "missing_table" does not exist`, results[1].Error)
+
+		assert.Equal(t, "count", results[2].ID)
+		assert.Empty(t, results[2].Error)
+		assert.Equal(t, []Row{{int64(15)}}, results[2].Result.Rows)
+	}
+}
+
+func testStream(t *testing.T) {
+	columns := []string{}
+	batches := [][]Row{}
+	notices := []Notice{}
+
+	stream := QueryStream{
+		BatchSize: 10,
+		OnColumns: func(cols []string) error {
+			columns = cols
+			return nil
+		},
+		OnRows: func(rows []Row) error {
+			batches = append(batches, rows)
+			return nil
+		},
+		OnNotice: func(notice Notice) {
+			notices = append(notices, notice)
+		},
+	}
+
+	stats, err := testClient.Stream(context.Background(), "SELECT id FROM books ORDER BY id", stream)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, columns)
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], 10)
+	assert.Len(t, batches[1], 5)
+	assert.Equal(t, 15, stats.RowsCount)
+
+	_, err = testClient.Stream(context.Background(), "DO $$ BEGIN RAISE NOTICE 'hello'; END $$", stream)
+	require.NoError(t, err)
+	require.Len(t, notices, 1)
+	assert.Equal(t, "NOTICE", notices[0].Severity)
+	assert.Equal(t, "hello", notices[0].Message)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = testClient.Stream(ctx, "SELECT pg_sleep(10)", stream)
+	assert.Error(t, err)
+}
+
+func testQueryStream(t *testing.T) {
+	buff := &bytes.Buffer{}
+	err := testClient.QueryStream("SELECT id, title FROM books WHERE id IN (156, 190) ORDER BY id", buff)
+	require.NoError(t, err)
+
+	expected := strings.Join([]string{
+		`["id","title"]`,
+		`[156,"The Tell-Tale Heart"]`,
+		`[190,"Little Women"]`,
+	}, "\n") + "\n"
+	assert.Equal(t, expected, buff.String())
+
+	// Rows produced before a runtime error are delivered
+	count := 0
+	_, err = testClient.Stream(context.Background(), "SELECT 1 / (150 - i) FROM generate_series(1, 200) i", QueryStream{
+		BatchSize: 100,
+		OnColumns: func(columns []string) error { return nil },
+		OnRows: func(rows []Row) error {
+			count += len(rows)
+			return nil
+		},
+	})
+	var partial *PartialStreamError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, 149, partial.RowsCount)
+	assert.Equal(t, 149, count)
+	assert.Contains(t, err.Error(), "division by zero")
+
+	command.Opts.ReadOnly = true
+	defer func() {
+		command.Opts.ReadOnly = false
+	}()
+
+	buff.Reset()
+	err = testClient.QueryStream("DELETE FROM books", buff)
+	assert.Error(t, err)
+	assert.Empty(t, buff.String())
+}
+
+func testQueryScript(t *testing.T) {
+	results, err := testClient.QueryScript(context.Background(), `
+		CREATE TEMP TABLE script_books AS SELECT id, title FROM books WHERE id = 156;
+		SET search_path TO pg_temp, public;
+		SELECT id, title FROM script_books;
+	`)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, res := range results {
+		assert.Empty(t, res.Error)
+	}
+	assert.Equal(t, "SELECT id, title FROM script_books", results[2].Query)
+	assert.Equal(t, []Row{{int64(156), "The Tell-Tale Heart"}}, results[2].Result.Rows)
+
+	// Session state does not outlive the script
+	_, err = testClient.Query("SELECT * FROM script_books")
+	assert.Error(t, err)
+
+	// Statements after a failing one are not run
+	results, err = testClient.QueryScript(context.Background(), "SELECT 1; SELECT * FROM script_books; SELECT 2")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Empty(t, results[0].Error)
+	assert.Contains(t, results[1].Error, `relation "script_books" does not exist`)
+
+	_, err = testClient.QueryScript(context.Background(), "-- nothing")
+	assert.Equal(t, ErrScriptEmpty, err)
+}
+
+func testWrapSelectReadOnly(t *testing.T) {
+	defer func() { command.Opts.WrapSelectReadOnly = false }()
+	command.Opts.WrapSelectReadOnly = true
+
+	testClient.db.MustExec(`CREATE TABLE wrapped_notes (id int)`)
+	defer testClient.db.MustExec(`DROP TABLE wrapped_notes`)
+
+	// Writes disguised as a SELECT fail
+	_, err := testClient.Query("WITH added AS (INSERT INTO wrapped_notes VALUES (1) RETURNING id) SELECT * FROM added")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only transaction")
+
+	err = testClient.QueryStream("WITH added AS (INSERT INTO wrapped_notes VALUES (2) RETURNING id) SELECT * FROM added", io.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only transaction")
+
+	// Other statements are unaffected
+	_, err = testClient.Query("INSERT INTO wrapped_notes VALUES (3)")
+	require.NoError(t, err)
+
+	res, err := testClient.Query("SELECT id FROM wrapped_notes")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(3)}}, res.Rows)
+}
+
+func testMoneyAsNumeric(t *testing.T) {
+	defer func() { command.Opts.MoneyAsNumeric = false }()
+	command.Opts.MoneyAsNumeric = true
+
+	res, err := testClient.Query("SELECT 1234.56::money AS price")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MONEY"}, res.ColumnTypes)
+	assert.Equal(t, []Row{{"1234.56"}}, res.Rows)
+}
+
+func testWorkMem(t *testing.T) {
+	defer func() {
+		DefaultWorkMem = ""
+		MaxWorkMem = ""
+	}()
+
+	require.NoError(t, SetWorkMemLimits("64MB", "256MB"))
+	ctx := WithWorkMem(context.Background(), "128MB")
+
+	res, err := testClient.QueryContext(ctx, "SHOW work_mem")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"128MB"}}, res.Rows)
+
+	// Setting is scoped to the query transaction
+	res, err = testClient.Query("SHOW work_mem")
+	require.NoError(t, err)
+	assert.NotEqual(t, []Row{{"128MB"}}, res.Rows)
+
+	// Within an open transaction the setting lasts until it ends
+	require.NoError(t, testClient.BeginTransaction())
+	res, err = testClient.QueryContext(WithWorkMem(context.Background(), "64MB"), "SHOW work_mem")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"64MB"}}, res.Rows)
+	require.NoError(t, testClient.RollbackTransaction())
+}
+
+func testColumnHistogram(t *testing.T) {
+	t.Run("integer column", func(t *testing.T) {
+		res, err := testClient.ColumnHistogram("stock", "stock", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bucket", "lower_bound", "upper_bound", "count"}, res.Columns)
+		assert.Equal(t, []Row{
+			{int64(1), float64(0), 44.5, int64(11)},
+			{int64(2), 44.5, float64(89), int64(5)},
+		}, res.Rows)
+	})
+
+	t.Run("single value", func(t *testing.T) {
+		_, err := testClient.Query("CREATE TABLE histogram_test (val integer)")
+		require.NoError(t, err)
+		defer testClient.Query("DROP TABLE histogram_test") //nolint:errcheck
+
+		res, err := testClient.ColumnHistogram("histogram_test", "val", 5)
+		require.NoError(t, err)
+		assert.Empty(t, res.Rows)
+
+		_, err = testClient.Query("INSERT INTO histogram_test VALUES (7), (7), (NULL)")
+		require.NoError(t, err)
+
+		res, err = testClient.ColumnHistogram("histogram_test", "val", 5)
+		require.NoError(t, err)
+		assert.Equal(t, []Row{{int64(1), float64(7), float64(7), int64(2)}}, res.Rows)
+	})
+
+	t.Run("invalid column", func(t *testing.T) {
+		_, err := testClient.ColumnHistogram("stock", "foo", 2)
+		assert.EqualError(t, err, `column "foo" does not exist`)
+	})
+}
+
+func testReadOnlySchemas(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.ReadOnlySchemaPatterns, _ = command.CompileAllowPatterns("reporting")
+
+	testClient.db.MustExec("CREATE SCHEMA reporting")
+	testClient.db.MustExec("CREATE TABLE reporting.sales (id integer)")
+	defer testClient.db.MustExec("DROP SCHEMA reporting CASCADE")
+
+	_, err := testClient.Query("INSERT INTO reporting.sales VALUES (1)")
+	assert.True(t, errors.Is(err, ErrReadOnlySchema))
+
+	_, err = testClient.Query("DELETE FROM reporting.sales")
+	assert.True(t, errors.Is(err, ErrReadOnlySchema))
+
+	_, err = testClient.Query("SET search_path = reporting; INSERT INTO sales VALUES (1)")
+	assert.True(t, errors.Is(err, ErrReadOnlySchema))
+
+	_, err = testClient.Query("DO $$ BEGIN EXECUTE 'DELETE FROM reporting.sales'; END $$")
+	assert.True(t, errors.Is(err, ErrReadOnlySchema))
+
+	res, err := testClient.Query("SELECT count(*) FROM reporting.sales")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), res.Rows[0][0])
+
+	res, err = testClient.Query("UPDATE books SET title = title WHERE id = 0")
+	assert.NoError(t, err)
+	assert.Equal(t, "Rows Affected", res.Columns[0])
+}
+
+func testCancelQuery(t *testing.T) {
+	// Cancelling without a running query is a no-op
+	assert.NoError(t, testClient.CancelQuery())
+
+	done := make(chan error)
+	go func() {
+		_, err := testClient.Query("SELECT pg_sleep(30)")
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		testClient.cancelMu.Lock()
+		defer testClient.cancelMu.Unlock()
+		return testClient.backendPID != 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, testClient.CancelQuery())
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "canceling statement due to user request")
+	case <-time.After(10 * time.Second):
+		t.Fatal("query was not cancelled")
+	}
+
+	// Query has finished, cancelling is a no-op again
+	assert.NoError(t, testClient.CancelQuery())
+	assert.Equal(t, 0, testClient.backendPID)
+}
+
+func testLintQuery(t *testing.T) {
+	columns := []string{}
+	for i := 0; i <= lintWideTableColumns; i++ {
+		columns = append(columns, fmt.Sprintf("col%d integer", i))
+	}
+	testClient.db.MustExec(fmt.Sprintf("CREATE TABLE lint_wide (%s)", strings.Join(columns, ", ")))
+	defer testClient.db.MustExec("DROP TABLE lint_wide")
+
+	assert.Equal(t, []LintWarning{
+		{Severity: LintSeverityInfo, Message: "SELECT * on a wide table lint_wide with 21 columns, consider listing the needed columns", Statement: 1},
+	}, testClient.LintQuery("SELECT * FROM lint_wide"))
+
+	assert.Empty(t, testClient.LintQuery("SELECT * FROM books"))
+	assert.Empty(t, testClient.LintQuery("UPDATE books SET title = title WHERE id = 1"))
+}
+
+func testPgBouncer(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.PgBouncer = true
+
+	res, err := testClient.query("SELECT $1::text, $2::int", "it's", 42)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"it's", int64(42)}}, res.Rows)
+}
+
+func testQueryWithNamedParams(t *testing.T) {
+	res, err := testClient.QueryWithNamedParams("SELECT id, title FROM books WHERE id = @id OR title = @title ORDER BY id", map[string]interface{}{
+		"id":    "156",
+		"title": "Dune'; DROP TABLE books; --",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(156), "The Tell-Tale Heart"}}, res.Rows)
+
+	_, err = testClient.QueryWithNamedParams("SELECT @id", nil)
+	assert.EqualError(t, err, `parameter "id" is missing`)
+}
+
+func testPreparedStatements(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.PreparedStatements = true
+	defer testClient.closePreparedStatements()
+
+	res, err := testClient.QueryWithParams("SELECT id, title FROM books WHERE id = $1", []interface{}{156})
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(156), "The Tell-Tale Heart"}}, res.Rows)
+	stmt := testClient.statements["SELECT id, title FROM books WHERE id = $1"]
+	require.NotNil(t, stmt)
+
+	// Same query with different formatting and params reuses the statement
+	res, err = testClient.QueryWithParams("SELECT id, title\n  FROM books\n WHERE id = $1;", []interface{}{"190"})
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(190), "Little Women"}}, res.Rows)
+	require.Len(t, testClient.statements, 1)
+	assert.Same(t, stmt, testClient.statements["SELECT id, title FROM books WHERE id = $1"])
+
+	// Least recently used statement is evicted once the limit is reached
+	for i := 0; i < maxPreparedStatements; i++ {
+		_, err = testClient.QueryWithParams(fmt.Sprintf("SELECT $1::int + %d", i), []interface{}{1})
+		require.NoError(t, err)
+	}
+	assert.Len(t, testClient.statements, maxPreparedStatements)
+	assert.Len(t, testClient.statementKeys, maxPreparedStatements)
+	assert.NotContains(t, testClient.statements, "SELECT id, title FROM books WHERE id = $1")
+	assert.Equal(t, fmt.Sprintf("SELECT $1::int + %d", maxPreparedStatements-1), testClient.statementKeys[0])
+
+	testClient.closePreparedStatements()
+	assert.Empty(t, testClient.statements)
+}
+
+func testExplainQuery(t *testing.T) {
+	plan, err := testClient.ExplainQuery("SELECT * FROM books WHERE id = 156", false)
+	require.NoError(t, err)
+	require.NotNil(t, plan.Plan)
+	assert.NotEmpty(t, plan.Plan.NodeType)
+	assert.Nil(t, plan.Plan.ActualRows)
+	assert.Nil(t, plan.ExecutionTime)
+
+	plan, err = testClient.ExplainQuery("SELECT * FROM books", true)
+	require.NoError(t, err)
+	require.NotNil(t, plan.Plan.ActualRows)
+	assert.Equal(t, 15.0, *plan.Plan.ActualRows)
+	assert.NotNil(t, plan.ExecutionTime)
+}
+
+func testExplainGenericPlan(t *testing.T) {
+	plan, err := testClient.ExplainGenericPlan("SELECT * FROM books WHERE id = $1 AND title <> $2")
+	require.NoError(t, err)
+	require.NotNil(t, plan.Plan)
+	assert.NotEmpty(t, plan.Plan.NodeType)
+	assert.Nil(t, plan.ExecutionTime)
+
+	// Forced plan mode only lasts for the explain transaction
+	res, err := testClient.Query("SHOW plan_cache_mode")
+	require.NoError(t, err)
+	assert.Equal(t, "auto", res.Rows[0][0])
+
+	res, err = testClient.Query("SELECT count(*) FROM pg_prepared_statements WHERE name = 'pgweb_generic_plan'")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), res.Rows[0][0])
+}
+
+func testLargeObject(t *testing.T) {
+	defer func(size int) {
+		largeObjectChunkSize = size
+	}(largeObjectChunkSize)
+	largeObjectChunkSize = 4
+
+	content := []byte("large object\x00with binary\xff content")
+
+	var oid uint32
+	require.NoError(t, testClient.db.Get(&oid, "SELECT lo_from_bytea(0, $1)", content))
+	defer testClient.db.Exec("SELECT lo_unlink($1)", oid) //nolint:errcheck
+
+	reader, err := testClient.LargeObject(oid)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.NoError(t, reader.Close())
+
+	_, err = testClient.LargeObject(oid + 1000000)
+	assert.Equal(t, ErrLargeObjectNotFound, err)
+}
+
+func testColumnStatistics(t *testing.T) {
+	statisticsTarget := func(column string) interface{} {
+		res, err := testClient.Table("books")
+		require.NoError(t, err)
+
+		for _, row := range res.Format() {
+			if row["column_name"] == column {
+				return row["statistics_target"]
+			}
+		}
+		return nil
+	}
+
+	_, err := testClient.SetColumnStatistics("books", "title", 500)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), statisticsTarget("title"))
+
+	_, err = testClient.SetColumnStatistics("books", "foobar", 500)
+	assert.EqualError(t, err, `column "foobar" does not exist`)
+
+	_, err = testClient.SetColumnStatistics("books", "title", -1)
+	assert.NoError(t, err)
 }
 
 func testTableInfo(t *testing.T) {
@@ -447,6 +1182,54 @@ func testTableRowsCount(t *testing.T) {
 	assert.Equal(t, []Row{{int64(15)}}, res.Rows)
 }
 
+func testTableRowsCountCache(t *testing.T) {
+	defer func(c *cache.Cache) {
+		MetadataCache = c
+	}(MetadataCache)
+	MetadataCache = cache.NewWithoutCleanup(time.Minute)
+
+	opts := RowsOptions{Where: "id > 0"}
+
+	res, err := testClient.TableRowsCount("books", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(15)}}, res.Rows)
+
+	_, err = testClient.Query("INSERT INTO books (id, title) VALUES (9998, 'Cache')")
+	require.NoError(t, err)
+	defer testClient.Query("DELETE FROM books WHERE id = 9998") //nolint:errcheck
+
+	// Count cached before the insert is purged
+	res, err = testClient.TableRowsCount("books", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(16)}}, res.Rows)
+}
+
+func testEmptyResult(t *testing.T) {
+	res, err := testClient.Query("SELECT id, title FROM books WHERE id < 0")
+	require.NoError(t, err)
+	assert.Equal(t, ResultTypeRows, res.Type)
+
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"rows","columns":["id","title"],"rows":[]}`, string(data))
+
+	res, err = testClient.Query("SET statement_timeout = 0")
+	require.NoError(t, err)
+	assert.Equal(t, ResultTypeCommand, res.Type)
+
+	data, err = json.Marshal(res)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"command","columns":[],"rows":[]}`, string(data))
+}
+
+func testRequestIDTag(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-123")
+
+	res, err := testClient.QueryContext(ctx, "SELECT current_query()")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"/* request_id=req-123 */ SELECT current_query()"}}, res.Rows)
+}
+
 func testTableRowsCountWithLargeTable(t *testing.T) {
 	testClient.db.MustExec(`CREATE TABLE large_table AS SELECT s FROM generate_series(1,1000000) s;`)
 	testClient.db.MustExec(`VACUUM large_table;`)
@@ -472,6 +1255,299 @@ func testTableConstraints(t *testing.T) {
 	assert.Equal(t, Row{"integrity", "CHECK (book_id IS NOT NULL AND edition IS NOT NULL)"}, res.Rows[1])
 }
 
+func testTableTriggers(t *testing.T) {
+	res, err := testClient.TableTriggers("shipments")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "timing", "events", "level", "function", "enabled", "definition"}, res.Columns)
+	assert.Equal(t, 1, len(res.Rows))
+	assert.Equal(t, Row{"check_shipment", "BEFORE", "INSERT OR UPDATE", "ROW", "public.check_shipment_addition", true}, res.Rows[0][:6])
+	assert.Contains(t, res.Rows[0][6], "CREATE TRIGGER check_shipment BEFORE INSERT OR UPDATE ON shipments FOR EACH ROW EXECUTE")
+
+	res, err = testClient.TableTriggers("books")
+	assert.NoError(t, err)
+	assert.Empty(t, res.Rows)
+}
+
+func testTablePolicies(t *testing.T) {
+	columns := []string{"name", "command", "permissive", "roles", "using", "with_check", "rls_enabled", "rls_forced"}
+
+	testClient.db.MustExec(`CREATE TABLE policy_notes (id int, owner name)`)
+	testClient.db.MustExec(`ALTER TABLE policy_notes ENABLE ROW LEVEL SECURITY`)
+	testClient.db.MustExec(`CREATE POLICY owner_notes ON policy_notes FOR UPDATE TO PUBLIC USING (owner = current_user) WITH CHECK (id > 0)`)
+	defer testClient.db.MustExec(`DROP TABLE policy_notes`)
+
+	res, err := testClient.TablePolicies("policy_notes")
+	require.NoError(t, err)
+	assert.Equal(t, columns, res.Columns)
+	assert.Equal(t, []Row{{"owner_notes", "UPDATE", "PERMISSIVE", "public", "(owner = CURRENT_USER)", "(id > 0)", true, false}}, res.Rows)
+
+	// Tables without policies only report the row level security status
+	res, err = testClient.TablePolicies("books")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{nil, nil, nil, nil, nil, nil, false, false}}, res.Rows)
+}
+
+func testSequences(t *testing.T) {
+	testClient.db.MustExec(`CREATE TABLE sequence_owners (id serial, code int GENERATED ALWAYS AS IDENTITY)`)
+	defer testClient.db.MustExec(`DROP TABLE sequence_owners`)
+
+	res, err := testClient.Sequences()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"schema", "name", "data_type", "last_value", "start_value", "increment_by", "min_value",
+		"max_value", "cycle", "cache_size", "owner_schema", "owner_table", "owner_column",
+	}, res.Columns)
+
+	owners := map[interface{}]Row{}
+	for _, row := range res.Rows {
+		owners[row[1]] = row
+	}
+	assert.Equal(t, Row{"public", "sequence_owners", "id"}, owners["sequence_owners_id_seq"][10:])
+	assert.Equal(t, Row{"public", "sequence_owners", "code"}, owners["sequence_owners_code_seq"][10:])
+	assert.Equal(t, Row{nil, nil, nil}, owners["book_ids"][10:])
+
+	res, err = testClient.Sequence("public.shipments_ship_id_seq")
+	require.NoError(t, err)
+	require.Len(t, res.Rows, 1)
+	assert.Equal(t, int64(1011), res.Rows[0][3])
+	assert.Equal(t, int64(1), res.Rows[0][5])
+
+	res, err = testClient.Sequence("missing_seq")
+	require.NoError(t, err)
+	assert.Empty(t, res.Rows)
+}
+
+func testTablePartitions(t *testing.T) {
+	testClient.db.MustExec(`CREATE TABLE measurements (id int, logged_at date) PARTITION BY RANGE (logged_at)`)
+	testClient.db.MustExec(`CREATE TABLE measurements_2024 PARTITION OF measurements FOR VALUES FROM ('2024-01-01') TO ('2025-01-01') PARTITION BY LIST (id)`)
+	testClient.db.MustExec(`CREATE TABLE measurements_2024_1 PARTITION OF measurements_2024 FOR VALUES IN (1)`)
+	testClient.db.MustExec(`CREATE TABLE measurements_default PARTITION OF measurements DEFAULT`)
+	defer testClient.db.MustExec(`DROP TABLE measurements`)
+
+	res, err := testClient.TablePartitions("measurements")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"schema", "name", "parent", "level", "bound", "partitioned"}, res.Columns)
+	assert.Equal(t, []Row{
+		{"public", "measurements_2024", "measurements", int64(1), "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')", true},
+		{"public", "measurements_default", "measurements", int64(1), "DEFAULT", false},
+		{"public", "measurements_2024_1", "measurements_2024", int64(2), "FOR VALUES IN (1)", false},
+	}, res.Rows)
+
+	res, err = testClient.TablePartitions("books")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"schema", "name", "parent", "level", "bound", "partitioned"}, res.Columns)
+	assert.Empty(t, res.Rows)
+}
+
+func testColumnStats(t *testing.T) {
+	columns := []string{"column_name", "inherited", "null_frac", "avg_width", "n_distinct", "most_common_vals", "most_common_freqs", "correlation"}
+
+	testClient.db.MustExec(`CREATE TABLE column_stats (id int, status text)`)
+	testClient.db.MustExec(`INSERT INTO column_stats SELECT i, CASE WHEN i % 2 = 0 THEN 'open' END FROM generate_series(1, 100) i`)
+	defer testClient.db.MustExec(`DROP TABLE column_stats`)
+
+	// Table was never analyzed
+	res, err := testClient.ColumnStats("column_stats")
+	require.NoError(t, err)
+	assert.Equal(t, columns, res.Columns)
+	assert.Empty(t, res.Rows)
+
+	testClient.db.MustExec(`ANALYZE column_stats`)
+
+	res, err = testClient.ColumnStats("public.column_stats")
+	require.NoError(t, err)
+	assert.Equal(t, columns, res.Columns)
+	require.Len(t, res.Rows, 2)
+	assert.Equal(t, "id", res.Rows[0][0])
+	assert.Equal(t, "status", res.Rows[1][0])
+	assert.InDelta(t, 0.5, res.Rows[1][2], 0.01)
+	assert.Equal(t, "{open}", res.Rows[1][5])
+}
+
+func testListen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan string)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- testClient.ListenContext(ctx, "pgweb_test", ch)
+	}()
+
+	// The listener may not be ready yet, keep notifying until a payload arrives
+	var payload string
+	require.Eventually(t, func() bool {
+		testClient.db.MustExec(`SELECT pg_notify('pgweb_test', '42%')`)
+		select {
+		case payload = <-ch:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "42%", payload)
+
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not stop")
+	}
+}
+
+func testApplicationName(t *testing.T) {
+	ctx := WithApplicationName(context.Background(), "pgweb:jane:f00d")
+
+	res, err := testClient.QueryContext(ctx, "SELECT current_setting('application_name')")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"pgweb:jane:f00d"}}, res.Rows)
+
+	// The name of the pinned connection is restored before it goes back to the pool
+	for i := 0; i < 3; i++ {
+		res, err = testClient.query("SELECT current_setting('application_name')")
+		require.NoError(t, err)
+		assert.NotEqual(t, []Row{{"pgweb:jane:f00d"}}, res.Rows)
+	}
+
+	// Queries run on the pool are not attributed, the name would outlive them
+	res, err = testClient.queryContext(ctx, "SELECT current_setting('application_name')")
+	require.NoError(t, err)
+	assert.NotEqual(t, []Row{{"pgweb:jane:f00d"}}, res.Rows)
+
+	// Unless they run in a transaction
+	require.NoError(t, testClient.BeginTransaction())
+	res, err = testClient.queryContext(ctx, "SELECT current_setting('application_name')")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"pgweb:jane:f00d"}}, res.Rows)
+	require.NoError(t, testClient.RollbackTransaction())
+}
+
+func testQueryLogger(t *testing.T) {
+	defer func() {
+		QueryLogger = nil
+	}()
+
+	entries := []QueryLogEntry{}
+	QueryLogger = func(ctx context.Context, entry QueryLogEntry) {
+		entries = append(entries, entry)
+	}
+
+	_, err := testClient.Query("SELECT id FROM books WHERE id = 7808")
+	require.NoError(t, err)
+	_, err = testClient.Query("SELECT id FROM books WHERE id = 'abc'")
+	require.Error(t, err)
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "SELECT id FROM books WHERE id = ?", entries[0].Fingerprint)
+	assert.Equal(t, 1, entries[0].RowsCount)
+	assert.NoError(t, entries[0].Err)
+	assert.Equal(t, "SELECT id FROM books WHERE id = ?", entries[1].Fingerprint)
+	assert.Error(t, entries[1].Err)
+}
+
+func testTerminateBackends(t *testing.T) {
+	url := fmt.Sprintf("postgres://%s@%s:%s/%s?sslmode=disable", serverUser, serverHost, serverPort, serverDatabase)
+	other, err := NewFromUrl(url, nil)
+	require.NoError(t, err)
+	defer other.Close()
+
+	var pid int
+	require.NoError(t, other.db.Get(&pid, "SELECT pg_backend_pid()"))
+
+	terminated, err := testClient.TerminateBackend(pid)
+	require.NoError(t, err)
+	assert.True(t, terminated)
+
+	// The process exits in the background
+	require.Eventually(t, func() bool {
+		var count int
+		return testClient.db.Get(&count, "SELECT COUNT(*) FROM pg_stat_activity WHERE pid = $1", pid) == nil && count == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	terminated, err = testClient.TerminateBackend(pid)
+	require.NoError(t, err)
+	assert.False(t, terminated)
+
+	res, err := testClient.TerminateIdleBackends(60)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pid", "usename", "datname", "application_name", "client_addr", "state_change", "terminated"}, res.Columns)
+}
+
+func testSearchObjects(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	testClient.db.MustExec(`CREATE TABLE search_invoices (id int)`)
+	testClient.db.MustExec(`CREATE TABLE search_ledger (id int)`)
+	testClient.db.MustExec(`COMMENT ON TABLE search_ledger IS 'Posted invoices by month'`)
+	testClient.db.MustExec(`CREATE TABLE "search_100%" (id int)`)
+	defer testClient.db.MustExec(`DROP TABLE search_invoices, search_ledger, "search_100%"`)
+
+	res, err := testClient.SearchObjects("INVOICES")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"oid", "schema", "name", "type", "matched_on", "comment"}, res.Columns)
+	require.Len(t, res.Rows, 2)
+	assert.Equal(t, Row{"public", "search_invoices", "table", "name", nil}, res.Rows[0][1:])
+	assert.Equal(t, Row{"public", "search_ledger", "table", "comment", "Posted invoices by month"}, res.Rows[1][1:])
+
+	// Like wildcards are matched literally
+	res, err = testClient.SearchObjects("100%")
+	require.NoError(t, err)
+	require.Len(t, res.Rows, 1)
+	assert.Equal(t, "search_100%", res.Rows[0][2])
+
+	command.Opts.HideObjects = "search_ledger"
+	res, err = testClient.SearchObjects("invoices")
+	require.NoError(t, err)
+	require.Len(t, res.Rows, 1)
+	assert.Equal(t, "search_invoices", res.Rows[0][2])
+
+	_, err = testClient.SearchObjects(" ")
+	assert.Equal(t, ErrSearchTermRequired, err)
+}
+
+func testUnindexedForeignKeys(t *testing.T) {
+	testClient.db.MustExec(`CREATE TABLE fk_authors (id int PRIMARY KEY)`)
+	testClient.db.MustExec(`CREATE TABLE fk_posts (id int, author_id int REFERENCES fk_authors (id), editor_id int REFERENCES fk_authors (id))`)
+	testClient.db.MustExec(`CREATE INDEX fk_posts_editor_id_idx ON fk_posts (editor_id, id)`)
+	defer testClient.db.MustExec(`DROP TABLE fk_posts, fk_authors`)
+
+	res, err := testClient.UnindexedForeignKeys("public")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"table_name", "constraint_name", "columns", "referenced_table", "table_size"}, res.Columns)
+
+	var constraints []interface{}
+	for _, row := range res.Rows {
+		if row[0] == "fk_posts" {
+			constraints = append(constraints, row[1])
+		}
+	}
+	assert.Equal(t, []interface{}{"fk_posts_author_id_fkey"}, constraints)
+
+	// Index with the constraint column not leading is not covering
+	testClient.db.MustExec(`CREATE INDEX fk_posts_id_author_id_idx ON fk_posts (id, author_id)`)
+	res, err = testClient.UnindexedForeignKeys("public")
+	require.NoError(t, err)
+	assert.Contains(t, res.Rows, Row{"fk_posts", "fk_posts_author_id_fkey", "author_id", "fk_authors", "0 bytes"})
+
+	testClient.db.MustExec(`CREATE INDEX fk_posts_author_id_idx ON fk_posts (author_id)`)
+	res, err = testClient.UnindexedForeignKeys("public")
+	require.NoError(t, err)
+	for _, row := range res.Rows {
+		assert.NotEqual(t, "fk_posts", row[0])
+	}
+}
+
+func testTablePrimaryKey(t *testing.T) {
+	columns, err := testClient.TablePrimaryKey(context.Background(), "books")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id"}, columns)
+
+	columns, err = testClient.TablePrimaryKey(context.Background(), "editions")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"isbn"}, columns)
+}
+
 func testTableNameWithCamelCase(t *testing.T) {
 	testClient.db.MustExec(`CREATE TABLE "exampleTable" (id int, name varchar);`)
 	testClient.db.MustExec(`INSERT INTO "exampleTable" (id, name) VALUES (1, 'foo'), (2, 'bar');`)
@@ -527,6 +1603,15 @@ func testQuery(t *testing.T) {
 		assert.Equal(t, "pq: canceling statement due to user request", err.Error())
 		assert.Nil(t, res)
 	})
+
+	t.Run("large numbers", func(t *testing.T) {
+		res, err := testClient.Query("SELECT 9007199254740993::bigint AS big, 12345678901234567890.123456789::numeric AS num")
+		require.NoError(t, err)
+
+		data, err := json.Marshal(res.Rows)
+		require.NoError(t, err)
+		assert.Equal(t, `[["9007199254740993","12345678901234567890.123456789"]]`, string(data))
+	})
 }
 
 func testUpdateQuery(t *testing.T) {
@@ -570,6 +1655,61 @@ func testUpdateQuery(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, int64(9999), res.Rows[0][0])
 	})
+	t.Run("auto returning", func(t *testing.T) {
+		command.Opts.AutoReturning = true
+		defer func() {
+			command.Opts.AutoReturning = false
+		}()
+
+		testClient.db.MustExec("INSERT INTO books (id, title) VALUES (9998, 'Test Book'), (9999, 'Test Book 2')")
+
+		res, err := testClient.Query("DELETE FROM books WHERE id >= 9998;")
+		assert.NoError(t, err)
+		assert.Equal(t, "id", res.Columns[0])
+		assert.Equal(t, "title", res.Columns[1])
+		assert.Equal(t, 2, len(res.Rows))
+		assert.Equal(t, int64(9998), res.Rows[0][0])
+		assert.Equal(t, "Test Book", res.Rows[0][1])
+	})
+}
+
+func testSavepoints(t *testing.T) {
+	countBooks := func(ids string) int64 {
+		res, err := testClient.Query("SELECT COUNT(*) FROM books WHERE id IN (" + ids + ")")
+		require.NoError(t, err)
+		return res.Rows[0][0].(int64)
+	}
+
+	assert.Equal(t, ErrNoTransaction, testClient.Savepoint("sp1"))
+
+	require.NoError(t, testClient.BeginTransaction())
+	defer testClient.RollbackTransaction() //nolint
+
+	_, err := testClient.Query("INSERT INTO books (id, title) VALUES (7771, 'Before savepoint')")
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrInvalidSavepoint, testClient.Savepoint("sp1; DROP TABLE books"))
+	require.NoError(t, testClient.Savepoint("sp1"))
+
+	_, err = testClient.Query("INSERT INTO books (id, title) VALUES (7772, 'After savepoint')")
+	require.NoError(t, err)
+
+	require.NoError(t, testClient.Savepoint("sp2"))
+	assert.Equal(t, []string{"sp1", "sp2"}, testClient.Savepoints())
+	assert.Equal(t, int64(2), countBooks("7771, 7772"))
+
+	require.NoError(t, testClient.RollbackToSavepoint("sp1"))
+	assert.Equal(t, []string{"sp1"}, testClient.Savepoints())
+	assert.Equal(t, int64(1), countBooks("7771"))
+	assert.Equal(t, int64(0), countBooks("7772"))
+
+	require.NoError(t, testClient.ReleaseSavepoint("sp1"))
+	assert.Equal(t, []string{}, testClient.Savepoints())
+	assert.Equal(t, ErrSavepointNotFound, testClient.ReleaseSavepoint("sp1"))
+
+	require.NoError(t, testClient.RollbackTransaction())
+	assert.False(t, testClient.InTransaction())
+	assert.Equal(t, int64(0), countBooks("7771, 7772"))
 }
 
 func testTableRowsOrderEscape(t *testing.T) {
@@ -720,6 +1860,45 @@ func testTablesStats(t *testing.T) {
 	assert.Equal(t, columns, result.Columns)
 }
 
+func testTopStatements(t *testing.T) {
+	res, err := testClient.query(statements.ExtensionInstalled, "pg_stat_statements")
+	require.NoError(t, err)
+
+	if len(res.Rows) == 0 {
+		t.Run("missing extension", func(t *testing.T) {
+			res, err := testClient.TopStatements("total_time", 10)
+			assert.Equal(t, ErrStatStatementsMissing, err)
+			assert.Nil(t, res)
+		})
+		return
+	}
+
+	res, err = testClient.TopStatements("calls", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"queryid", "query", "calls", "total_time", "mean_time", "rows"}, res.Columns)
+}
+
+func testResetStats(t *testing.T) {
+	var before time.Time
+	require.NoError(t, testClient.db.Get(&before, "SELECT clock_timestamp()"))
+	require.NoError(t, testClient.ResetStats("database"))
+
+	// pg_stat_reset() records when the database statistics were reset
+	var reset bool
+	err := testClient.db.Get(&reset, "SELECT coalesce(stats_reset >= $1, false) FROM pg_stat_database WHERE datname = current_database()", before)
+	require.NoError(t, err)
+	assert.True(t, reset)
+
+	res, err := testClient.query(statements.ExtensionInstalled, "pg_stat_statements")
+	require.NoError(t, err)
+
+	if len(res.Rows) == 0 {
+		assert.Equal(t, ErrStatStatementsMissing, testClient.ResetStats("statements"))
+		return
+	}
+	assert.NoError(t, testClient.ResetStats("statements"))
+}
+
 func testConnContext(t *testing.T) {
 	result, err := testClient.GetConnContext()
 	assert.NoError(t, err)
@@ -755,6 +1934,199 @@ func testServerSettings(t *testing.T) {
 	assert.Equal(t, expectedColumns, result.Columns)
 }
 
+func TestRecentObjects(t *testing.T) {
+	client := &Client{}
+
+	// Objects are tracked by the API once opened, not by internal lookups
+	_, err := client.Table("books")
+	assert.NoError(t, err)
+	_, err = client.TableRows("authors", RowsOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, client.GetRecentObjects())
+
+	client.TrackRecentObject("books")
+	client.TrackRecentObject("authors")
+	client.TrackRecentObject("private.subjects")
+	assert.Equal(t, []string{"private.subjects", "public.authors", "public.books"}, client.GetRecentObjects())
+
+	// Reopening an object moves it to the front
+	client.TrackRecentObject("public.books")
+	assert.Equal(t, []string{"public.books", "private.subjects", "public.authors"}, client.GetRecentObjects())
+
+	// The list is returned as a copy
+	recent := client.GetRecentObjects()
+	recent[0] = "public.other"
+	assert.Equal(t, "public.books", client.GetRecentObjects()[0])
+
+	for i := 0; i < maxRecentObjects+5; i++ {
+		client.TrackRecentObject(fmt.Sprintf("table_%d", i))
+	}
+	assert.Len(t, client.GetRecentObjects(), maxRecentObjects)
+	assert.Equal(t, fmt.Sprintf("public.table_%d", maxRecentObjects+4), client.GetRecentObjects()[0])
+}
+
+func TestTouch(t *testing.T) {
+	idleTimeout := command.Opts.ConnectionIdleTimeout
+	command.Opts.ConnectionIdleTimeout = 180
+	defer func() {
+		command.Opts.ConnectionIdleTimeout = idleTimeout
+	}()
+
+	client := &Client{lastQueryTime: time.Now().Add(time.Minute * -240)}
+	assert.True(t, client.IsIdle())
+
+	client.Touch()
+	assert.WithinDuration(t, time.Now(), client.LastQueryTime(), time.Second)
+	assert.False(t, client.IsIdle())
+}
+
+func TestIsIdleWhileBusy(t *testing.T) {
+	idleTimeout := command.Opts.ConnectionIdleTimeout
+	command.Opts.ConnectionIdleTimeout = 180
+	defer func() {
+		command.Opts.ConnectionIdleTimeout = idleTimeout
+	}()
+
+	client := &Client{}
+	done := client.BeginQuery()
+	client.lastQueryTime = time.Now().Add(time.Minute * -240)
+	assert.True(t, client.IsBusy())
+	assert.False(t, client.IsIdle())
+
+	// Finished queries mark the client as used
+	done()
+	assert.False(t, client.IsBusy())
+	assert.False(t, client.IsIdle())
+	assert.WithinDuration(t, time.Now(), client.LastQueryTime(), time.Second)
+}
+
+func TestRequireSSH(t *testing.T) {
+	command.Opts.RequireSSH = true
+	defer func() {
+		command.Opts.RequireSSH = false
+	}()
+
+	_, err := New()
+	assert.Equal(t, ErrSSHRequired, err)
+
+	_, err = NewFromUrl("postgres://user@localhost:5432/db?sslmode=disable", nil)
+	assert.Equal(t, ErrSSHRequired, err)
+
+	// SSH connections proceed to establishing the tunnel
+	sshInfo := &shared.SSHInfo{Host: "127.0.0.1", Port: "1", User: "user", Password: "pass"}
+	_, err = NewFromUrl("postgres://user@localhost:5432/db?sslmode=disable", sshInfo)
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrSSHRequired, err)
+}
+
+func TestGetSchemaAndTable(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	command.Opts = command.Options{}
+	schema, table := getSchemaAndTable("books")
+	assert.Equal(t, "public", schema)
+	assert.Equal(t, "books", table)
+
+	opts, err := command.ParseOptions([]string{"--default-schema=app"})
+	require.NoError(t, err)
+	command.Opts = opts
+
+	schema, table = getSchemaAndTable("books")
+	assert.Equal(t, "app", schema)
+	assert.Equal(t, "books", table)
+
+	schema, table = getSchemaAndTable("public.books")
+	assert.Equal(t, "public", schema)
+	assert.Equal(t, "books", table)
+}
+
+func TestInvalidateTableCache(t *testing.T) {
+	defer func() {
+		MetadataCache = nil
+	}()
+	MetadataCache = cache.NewWithoutCleanup(time.Minute)
+
+	cl := &Client{ConnectionString: "postgres://localhost/booktown"}
+	other := &Client{ConnectionString: "postgres://localhost/other"}
+
+	booksKey := cl.tableCachePrefix("public", "books") + "rows_count||"
+	MetadataCache.Set(booksKey, 15, 0)
+	MetadataCache.Set(cl.tableCachePrefix("public", "books_backup")+"rows_count||", 5, 0)
+	MetadataCache.Set(other.tableCachePrefix("public", "books")+"rows_count||", 10, 0)
+
+	cl.invalidateTableCache("SELECT * FROM books")
+	_, found := MetadataCache.Get(booksKey)
+	assert.True(t, found)
+
+	cl.invalidateTableCache("INSERT INTO books (id, title) VALUES (1, 'test')")
+	_, found = MetadataCache.Get(booksKey)
+	assert.False(t, found)
+
+	// Tables with the same name prefix and other connections are kept
+	_, found = MetadataCache.Get(cl.tableCachePrefix("public", "books_backup") + "rows_count||")
+	assert.True(t, found)
+	_, found = MetadataCache.Get(other.tableCachePrefix("public", "books") + "rows_count||")
+	assert.True(t, found)
+}
+
+func TestMetadataCacheBypass(t *testing.T) {
+	defer func() {
+		MetadataCache = nil
+	}()
+	MetadataCache = cache.NewWithoutCleanup(time.Minute)
+
+	cl := &Client{ConnectionString: "postgres://localhost/booktown"}
+	debug := &Client{ConnectionString: "postgres://localhost/booktown"}
+	debug.SetCacheBypass(true)
+
+	cached := &Result{Columns: []string{"index_name"}}
+	MetadataCache.Set(cl.generateMetadataCacheKey("table_indexes", "public", "books"), cached, 0)
+
+	res, err := cl.TableIndexes("books")
+	assert.NoError(t, err)
+	assert.Same(t, cached, res)
+
+	// Bypassing client goes to the (missing) connection
+	res, err = debug.TableIndexes("books")
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Nil(t, debug.metadataCache())
+
+	// Shared entries are not replaced by the bypassing client
+	res, err = cl.TableIndexes("books")
+	assert.NoError(t, err)
+	assert.Same(t, cached, res)
+}
+
+func TestTerminateValidation(t *testing.T) {
+	cl := &Client{serverType: postgresType, readonly: true}
+	_, err := cl.TerminateBackend(1)
+	assert.Equal(t, ErrReadOnly, err)
+	_, err = cl.TerminateIdleBackends(10)
+	assert.Equal(t, ErrReadOnly, err)
+
+	cl = &Client{serverType: cockroachType}
+	_, err = cl.TerminateBackend(1)
+	assert.Equal(t, ErrTerminateUnsupported, err)
+
+	cl = &Client{serverType: postgresType}
+	_, err = cl.TerminateBackend(0)
+	assert.Equal(t, ErrInvalidPid, err)
+	_, err = cl.TerminateIdleBackends(-5)
+	assert.Equal(t, ErrInvalidIdleMinutes, err)
+}
+
+func TestTablePartitionsUnsupported(t *testing.T) {
+	cl := &Client{serverType: cockroachType}
+
+	res, err := cl.TablePartitions("public.events")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"schema", "name", "parent", "level", "bound", "partitioned"}, res.Columns)
+	assert.Empty(t, res.Rows)
+}
+
 func TestAll(t *testing.T) {
 	if onWindows() {
 		t.Log("Unit testing on Windows platform is not supported.")
@@ -779,19 +2151,63 @@ func TestAll(t *testing.T) {
 	testInfo(t)
 	testActivity(t)
 	testDatabases(t)
+	testAllowedDatabases(t)
 	testSchemas(t)
 	testObjects(t)
+	testObjectsWithOptions(t)
+	testSchemaObjects(t)
+	testSchemaSummary(t)
 	testTable(t)
+	testView(t)
 	testTableRows(t)
+	testTableRowsSortColumns(t)
+	testGroupBy(t)
+	testCrosstab(t)
+	testMetadataContextCancel(t)
+	testTableStorageParams(t)
+	testColumnStatistics(t)
+	testUnindexedForeignKeys(t)
+	testSearchObjects(t)
+	testListen(t)
+	testTerminateBackends(t)
+	testQueryLogger(t)
+	testApplicationName(t)
+	testBatch(t)
+	testStream(t)
+	testQueryStream(t)
+	testQueryScript(t)
+	testMoneyAsNumeric(t)
+	testWrapSelectReadOnly(t)
+	testWorkMem(t)
+	testLargeObject(t)
+	testPgBouncer(t)
+	testPreparedStatements(t)
+	testQueryWithNamedParams(t)
+	testExplainQuery(t)
+	testExplainGenericPlan(t)
+	testCancelQuery(t)
+	testLintQuery(t)
+	testReadOnlySchemas(t)
+	testColumnHistogram(t)
 	testTableInfo(t)
 	testEstimatedTableRowsCount(t)
 	testTableRowsCount(t)
+	testTableRowsCountCache(t)
+	testEmptyResult(t)
+	testRequestIDTag(t)
 	testTableRowsCountWithLargeTable(t)
 	testTableIndexes(t)
 	testTableConstraints(t)
+	testTableTriggers(t)
+	testTablePolicies(t)
+	testSequences(t)
+	testTablePartitions(t)
+	testColumnStats(t)
+	testTablePrimaryKey(t)
 	testTableNameWithCamelCase(t)
 	testQuery(t)
 	testUpdateQuery(t)
+	testSavepoints(t)
 	testTableRowsOrderEscape(t)
 	testFunctions(t)
 	testResult(t)
@@ -799,6 +2215,8 @@ func TestAll(t *testing.T) {
 	testReadOnlyMode(t)
 	testDumpExport(t)
 	testTablesStats(t)
+	testTopStatements(t)
+	testResetStats(t)
 	testConnContext(t)
 	testServerSettings(t)
 
@@ -1,9 +1,13 @@
 package client
 
 import (
+	"encoding/ascii85"
+	"encoding/base32"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/mr-tron/base58"
 )
@@ -13,6 +17,8 @@ const (
 	CodecHex    = "hex"
 	CodecBase58 = "base58"
 	CodecBase64 = "base64"
+	CodecBase85 = "base85"
+	CodecBase32 = "base32"
 )
 
 var (
@@ -22,7 +28,7 @@ var (
 
 func SetBinaryCodec(codec string) error {
 	switch codec {
-	case CodecNone, CodecHex, CodecBase58, CodecBase64:
+	case CodecNone, CodecHex, CodecBase58, CodecBase64, CodecBase85, CodecBase32:
 		BinaryCodec = codec
 	default:
 		return fmt.Errorf("invalid binary codec: %v", codec)
@@ -39,7 +45,25 @@ func encodeBinaryData(data []byte, codec string) string {
 		return base58.Encode(data)
 	case CodecBase64:
 		return base64.StdEncoding.EncodeToString(data)
+	case CodecBase85:
+		buf := make([]byte, ascii85.MaxEncodedLen(len(data)))
+		return string(buf[:ascii85.Encode(buf, data)])
+	case CodecBase32:
+		return base32.StdEncoding.EncodeToString(data)
 	default:
 		return string(data)
 	}
 }
+
+// decodeTextData returns the column value as a string. Values that are not valid
+// UTF-8, ie. binary data stored in text columns, are serialized with the binary
+// codec, or have the invalid bytes replaced when the codec is disabled.
+func decodeTextData(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	if BinaryCodec == CodecNone {
+		return strings.ToValidUTF8(string(data), string(utf8.RuneError))
+	}
+	return encodeBinaryData(data, BinaryCodec)
+}
@@ -1,10 +1,18 @@
 package client
 
 import (
+	"encoding/ascii85"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/mr-tron/base58"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetBinaryCodec(t *testing.T) {
@@ -16,6 +24,8 @@ func TestSetBinaryCodec(t *testing.T) {
 		{input: CodecBase58, err: nil},
 		{input: CodecBase64, err: nil},
 		{input: CodecHex, err: nil},
+		{input: CodecBase85, err: nil},
+		{input: CodecBase32, err: nil},
 		{input: "foobar", err: errors.New("invalid binary codec: foobar")},
 	}
 
@@ -41,6 +51,8 @@ func Test_encodeBinaryData(t *testing.T) {
 		{input: "hello world", expected: "StV1DL6CwTryKyV", encoding: CodecBase58},
 		{input: "hello world", expected: "aGVsbG8gd29ybGQ=", encoding: CodecBase64},
 		{input: "hello world", expected: "68656c6c6f20776f726c64", encoding: CodecHex},
+		{input: "hello world", expected: "BOu!rD]j7BEbo7", encoding: CodecBase85},
+		{input: "hello world", expected: "NBSWY3DPEB3W64TMMQ======", encoding: CodecBase32},
 	}
 
 	for _, ex := range examples {
@@ -49,3 +61,64 @@ func Test_encodeBinaryData(t *testing.T) {
 		})
 	}
 }
+
+func Test_encodeBinaryDataRoundTrip(t *testing.T) {
+	data := []byte{0, 1, 2, 0xfe, 0xff, 0, 0, 0, 0, 'a'}
+
+	decoders := map[string]func(string) ([]byte, error){
+		CodecNone:   func(s string) ([]byte, error) { return []byte(s), nil },
+		CodecHex:    hex.DecodeString,
+		CodecBase58: base58.Decode,
+		CodecBase64: base64.StdEncoding.DecodeString,
+		CodecBase85: func(s string) ([]byte, error) {
+			buf := make([]byte, len(s))
+			n, _, err := ascii85.Decode(buf, []byte(s), true)
+			return buf[:n], err
+		},
+		CodecBase32: base32.StdEncoding.DecodeString,
+	}
+
+	for codec, decode := range decoders {
+		t.Run(codec, func(t *testing.T) {
+			decoded, err := decode(encodeBinaryData(data, codec))
+			require.NoError(t, err)
+			assert.Equal(t, data, decoded)
+		})
+	}
+}
+
+func Test_decodeTextData(t *testing.T) {
+	defer func(codec string) {
+		BinaryCodec = codec
+	}(BinaryCodec)
+
+	invalid := []byte("caf\xe9 au lait")
+
+	examples := []struct {
+		input    []byte
+		expected string
+		encoding string
+	}{
+		{input: []byte("café"), expected: "café", encoding: CodecBase64},
+		{input: invalid, expected: "Y2Fm6SBhdSBsYWl0", encoding: CodecBase64},
+		{input: invalid, expected: "636166e9206175206c616974", encoding: CodecHex},
+		{input: invalid, expected: "caf\uFFFD au lait", encoding: CodecNone},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.encoding, func(t *testing.T) {
+			BinaryCodec = ex.encoding
+			assert.Equal(t, ex.expected, decodeTextData(ex.input))
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		BinaryCodec = CodecNone
+
+		result := Result{Columns: []string{"name"}, Rows: []Row{{decodeTextData(invalid)}}}
+		data, err := json.Marshal(result)
+		assert.NoError(t, err)
+		assert.True(t, utf8.Valid(data))
+		assert.Contains(t, string(data), "\"rows\":[[\"caf\uFFFD au lait\"]]")
+	})
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var (
+	// copyCommand builds the psql command used for COPY exports
+	copyCommand = exec.CommandContext
+
+	// copyCancelDelay is how long psql has to cancel the COPY before it's killed
+	copyCancelDelay = 5 * time.Second
+
+	ErrExportNotResumable = errors.New("resumable export requires a table with a primary key")
+)
+
+// CopyExport represents a table export streamed with COPY TO STDOUT
+type CopyExport struct {
+	Table   string
+	Gzip    bool
+	OrderBy []string // Columns giving rows a stable order, required for ranged exports
+	Offset  int64    // Number of leading output bytes to skip
+	Limit   int64    // Maximum number of output bytes to write, 0 for no limit
+
+	// Server-side limit of the COPY statement, in case psql is gone before canceling it
+	StatementTimeout time.Duration
+}
+
+// trackingWriter records whether any output was produced
+type trackingWriter struct {
+	io.Writer
+	started bool
+}
+
+func (w *trackingWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.started = true
+	}
+	return w.Writer.Write(p)
+}
+
+// rangeWriter only passes through the output bytes within the export range
+type rangeWriter struct {
+	writer   io.Writer
+	skip     int64
+	limit    int64
+	written  int64
+	complete bool
+	done     func()
+}
+
+func (w *rangeWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if w.skip >= int64(n) {
+		w.skip -= int64(n)
+		return n, nil
+	}
+	p = p[w.skip:]
+	w.skip = 0
+
+	if w.limit > 0 && int64(len(p)) >= w.limit-w.written {
+		p = p[:w.limit-w.written]
+		if !w.complete {
+			// Stop the export once the whole range is written
+			w.complete = true
+			defer w.done()
+		}
+	}
+
+	written, err := w.writer.Write(p)
+	w.written += int64(written)
+	if err != nil {
+		return written, err
+	}
+	return n, nil
+}
+
+// Validate checks availability of psql CLI
+func (e *CopyExport) Validate() error {
+	if e.Table == "" {
+		return errors.New("table name is required")
+	}
+
+	if (e.Offset > 0 || e.Limit > 0) && len(e.OrderBy) == 0 {
+		return ErrExportNotResumable
+	}
+
+	out := bytes.NewBuffer(nil)
+
+	cmd := exec.Command("psql", "--version")
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("psql command failed: %s", out.Bytes())
+	}
+
+	return nil
+}
+
+// Statement returns the COPY statement for the exported table
+func (e *CopyExport) Statement() string {
+	schema, table := getSchemaAndTable(e.Table)
+	source := quoteIdentifier(schema) + "." + quoteIdentifier(table)
+
+	if len(e.OrderBy) > 0 {
+		columns := make([]string, len(e.OrderBy))
+		for i, col := range e.OrderBy {
+			columns[i] = quoteIdentifier(col)
+		}
+		source = fmt.Sprintf("(SELECT * FROM %s ORDER BY %s)", source, strings.Join(columns, ", "))
+	}
+
+	return fmt.Sprintf("COPY %s TO STDOUT WITH (FORMAT csv, HEADER)", source)
+}
+
+// Export streams the table data in CSV format to the specified writer,
+// optionally compressing it on the fly. When a byte range is set only that
+// part of the (compressed) output is written.
+func (e *CopyExport) Export(ctx context.Context, connstr string, writer io.Writer) error {
+	if str, err := removeUnsupportedOptions(connstr); err != nil {
+		return err
+	} else {
+		connstr = str
+	}
+
+	if e.StatementTimeout > 0 {
+		str, err := withStatementTimeout(connstr, e.StatementTimeout)
+		if err != nil {
+			return err
+		}
+		connstr = str
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var ranged *rangeWriter
+	if e.Offset > 0 || e.Limit > 0 {
+		ranged = &rangeWriter{writer: writer, skip: e.Offset, limit: e.Limit, done: cancel}
+		writer = ranged
+	}
+
+	var gzipWriter *gzip.Writer
+	if e.Gzip {
+		gzipWriter = gzip.NewWriter(writer)
+		writer = gzipWriter
+	}
+
+	errOutput := bytes.NewBuffer(nil)
+
+	cmd := copyCommand(ctx, "psql", "--no-psqlrc", "--quiet", "--command", e.Statement(), connstr)
+	output := &trackingWriter{Writer: writer}
+	cmd.Stdout = output
+	cmd.Stderr = errOutput
+
+	// On interrupt psql sends a cancel request, so the COPY stops on the server
+	// as soon as the context is done, ie. when the client disconnects
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = copyCancelDelay
+
+	if err := cmd.Run(); err != nil && (ranged == nil || !ranged.complete) {
+		if gzipWriter != nil && output.started {
+			// Terminate the compressed stream that was already started
+			gzipWriter.Close()
+		}
+		return fmt.Errorf("error: %s. output: %s", err.Error(), errOutput.Bytes())
+	}
+
+	if gzipWriter != nil {
+		return gzipWriter.Close()
+	}
+	return nil
+}
+
+// withStatementTimeout adds the statement_timeout setting to the connection options
+func withStatementTimeout(connstr string, timeout time.Duration) (string, error) {
+	uri, err := url.Parse(connstr)
+	if err != nil {
+		return "", err
+	}
+
+	q := uri.Query()
+	options := strings.TrimSpace(q.Get("options") + fmt.Sprintf(" -c statement_timeout=%d", timeout.Milliseconds()))
+	q.Set("options", options)
+	uri.RawQuery = q.Encode()
+
+	return uri.String(), nil
+}
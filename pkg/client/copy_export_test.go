@@ -0,0 +1,161 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyExport(t *testing.T) {
+	if onWindows() {
+		t.Skip("not supported on windows")
+	}
+
+	var capturedArgs []string
+
+	copyCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		capturedArgs = args
+		return exec.CommandContext(ctx, "printf", "id,title\n1,Foo\n2,Bar\n")
+	}
+	defer func() {
+		copyCommand = exec.CommandContext
+	}()
+
+	t.Run("statement", func(t *testing.T) {
+		export := CopyExport{Table: `public.my "table"`}
+		assert.Equal(t, `COPY "public"."my ""table""" TO STDOUT WITH (FORMAT csv, HEADER)`, export.Statement())
+
+		export = CopyExport{Table: "books", OrderBy: []string{"id", "isbn"}}
+		assert.Equal(t, `COPY (SELECT * FROM "public"."books" ORDER BY "id", "isbn") TO STDOUT WITH (FORMAT csv, HEADER)`, export.Statement())
+	})
+
+	t.Run("range", func(t *testing.T) {
+		examples := []struct {
+			offset   int64
+			limit    int64
+			expected string
+		}{
+			{0, 9, "id,title\n"},
+			{9, 0, "1,Foo\n2,Bar\n"},
+			{9, 6, "1,Foo\n"},
+			{12, 100, "oo\n2,Bar\n"},
+			{100, 0, ""},
+		}
+
+		for _, ex := range examples {
+			buf := bytes.NewBuffer(nil)
+			export := CopyExport{Table: "books", OrderBy: []string{"id"}, Offset: ex.offset, Limit: ex.limit}
+
+			require.NoError(t, export.Export(context.Background(), "postgres://localhost/booktown", buf))
+			assert.Equal(t, ex.expected, buf.String())
+		}
+	})
+
+	t.Run("range without order", func(t *testing.T) {
+		export := CopyExport{Table: "books", Offset: 10}
+		assert.Equal(t, ErrExportNotResumable, export.Validate())
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		export := CopyExport{Table: "books"}
+
+		require.NoError(t, export.Export(context.Background(), "postgres://localhost/booktown?search_path=foo", buf))
+		assert.Equal(t, "id,title\n1,Foo\n2,Bar\n", buf.String())
+		assert.Contains(t, capturedArgs, `COPY "public"."books" TO STDOUT WITH (FORMAT csv, HEADER)`)
+		assert.Contains(t, capturedArgs, "postgres://localhost/booktown")
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		export := CopyExport{Table: "books", Gzip: true}
+
+		require.NoError(t, export.Export(context.Background(), "postgres://localhost/booktown", buf))
+
+		reader, err := gzip.NewReader(buf)
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "id,title\n1,Foo\n2,Bar\n", string(data))
+	})
+
+	t.Run("statement timeout", func(t *testing.T) {
+		export := CopyExport{Table: "books", StatementTimeout: 90 * time.Second}
+		require.NoError(t, export.Export(context.Background(), "postgres://localhost/booktown?options=-c%20work_mem%3D64MB", io.Discard))
+		assert.Contains(t, capturedArgs, "postgres://localhost/booktown?options=-c+work_mem%3D64MB+-c+statement_timeout%3D90000")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		defer func(delay time.Duration) {
+			copyCancelDelay = delay
+		}(copyCancelDelay)
+		copyCancelDelay = 100 * time.Millisecond
+
+		examples := map[string]string{
+			"interrupted":        "printf 'id,title\n'; exec sleep 10",
+			"ignoring interrupt": "trap '' INT; printf 'id,title\n'; sleep 10",
+		}
+
+		for name, script := range examples {
+			t.Run(name, func(t *testing.T) {
+				copyCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+					return exec.CommandContext(ctx, "sh", "-c", script)
+				}
+
+				// Cancel the export once it started, like a client disconnect does
+				ctx, cancel := context.WithCancel(context.Background())
+				writer := &cancelingWriter{cancel: cancel}
+
+				start := time.Now()
+				err := (&CopyExport{Table: "books"}).Export(ctx, "postgres://localhost/booktown", writer)
+				assert.Error(t, err)
+				assert.Less(t, time.Since(start), 5*time.Second)
+				assert.Equal(t, "id,title\n", writer.String())
+			})
+		}
+	})
+
+	t.Run("gzip failure", func(t *testing.T) {
+		copyCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "sh", "-c", "printf 'id,title\n'; exit 1")
+		}
+
+		buf := bytes.NewBuffer(nil)
+		export := CopyExport{Table: "books", Gzip: true}
+		assert.Error(t, export.Export(context.Background(), "postgres://localhost/booktown", buf))
+
+		reader, err := gzip.NewReader(buf)
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "id,title\n", string(data))
+
+		copyCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "sh", "-c", "exit 1")
+		}
+
+		buf.Reset()
+		assert.Error(t, export.Export(context.Background(), "postgres://localhost/booktown", buf))
+		assert.Equal(t, 0, buf.Len())
+	})
+}
+
+// cancelingWriter cancels the context after the first write
+type cancelingWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelingWriter) Write(p []byte) (int, error) {
+	defer w.cancel()
+	return w.Buffer.Write(p)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// netDialer adapts net.Dialer to the pq dialer interfaces
+type netDialer struct {
+	dialer *net.Dialer
+}
+
+func (d netDialer) Dial(network, address string) (net.Conn, error) {
+	return d.dialer.Dial(network, address)
+}
+
+func (d netDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := *d.dialer
+	dialer.Timeout = timeout
+	return dialer.Dial(network, address)
+}
+
+func (d netDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.dialer.DialContext(ctx, network, address)
+}
+
+// dsnConnector opens postgres connections with a connection string that can be
+// replaced, ie. with refreshed credentials, while the connection pool is in use
+type dsnConnector struct {
+	mu     sync.RWMutex
+	dsn    string
+	dialer pq.Dialer // Connections are dialed by the driver when not set
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn := c.DSN()
+	if c.dialer != nil {
+		return pq.DialOpen(c.dialer, dsn)
+	}
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// DSN returns the connection string new connections are opened with
+func (c *dsnConnector) DSN() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.dsn
+}
+
+// SetDSN replaces the connection string new connections are opened with
+func (c *dsnConnector) SetDSN(dsn string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dsn = dsn
+}
+
+// openConnector returns a database handle establishing all connections with the connector
+func openConnector(connector *dsnConnector) (*sqlx.DB, error) {
+	// Fail early on malformed connection strings
+	if _, err := pq.NewConnector(connector.DSN()); err != nil {
+		return nil, err
+	}
+
+	return sqlx.NewDb(sql.OpenDB(connector), "postgres"), nil
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrExplainMultipleStatements = errors.New("only a single statement can be explained")
+	ErrExplainAnalyzeReadOnly    = errors.New("EXPLAIN ANALYZE is only allowed for SELECT queries in read-only mode")
+	ErrExplainNoPlan             = errors.New("query plan is missing from the EXPLAIN output")
+
+	reSelectStatement = regexp.MustCompile(`(?i)^\s*SELECT\b`)
+
+	// Name of the statement prepared to explain its generic plan
+	genericPlanStatement = "pgweb_generic_plan"
+
+	// Estimated number of rows above which a join without a condition is reported
+	explainCrossJoinRows = 10000.0
+)
+
+// ExplainPlan is the query plan returned by EXPLAIN (FORMAT JSON). Timings are
+// only reported by EXPLAIN ANALYZE.
+type ExplainPlan struct {
+	Plan          *ExplainNode  `json:"plan"`
+	PlanningTime  *float64      `json:"planning_time,omitempty"`
+	ExecutionTime *float64      `json:"execution_time,omitempty"`
+	Warnings      []LintWarning `json:"warnings,omitempty"`
+}
+
+// ExplainNode is a node of the query plan tree. Actual values are only
+// reported by EXPLAIN ANALYZE.
+type ExplainNode struct {
+	NodeType        string         `json:"node_type"`
+	RelationName    string         `json:"relation_name,omitempty"`
+	Alias           string         `json:"alias,omitempty"`
+	StartupCost     float64        `json:"startup_cost"`
+	TotalCost       float64        `json:"total_cost"`
+	PlanRows        float64        `json:"plan_rows"`
+	JoinFilter      string         `json:"join_filter,omitempty"`
+	HashCond        string         `json:"hash_cond,omitempty"`
+	MergeCond       string         `json:"merge_cond,omitempty"`
+	IndexCond       string         `json:"index_cond,omitempty"`
+	ActualTotalTime *float64       `json:"actual_total_time,omitempty"`
+	ActualRows      *float64       `json:"actual_rows,omitempty"`
+	Loops           *float64       `json:"loops,omitempty"`
+	Plans           []*ExplainNode `json:"plans,omitempty"`
+}
+
+// UnmarshalJSON reads the plan in the format produced by the server
+func (plan *ExplainPlan) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Plan          *ExplainNode `json:"Plan"`
+		PlanningTime  *float64     `json:"Planning Time"`
+		ExecutionTime *float64     `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*plan = ExplainPlan{Plan: raw.Plan, PlanningTime: raw.PlanningTime, ExecutionTime: raw.ExecutionTime}
+	return nil
+}
+
+// UnmarshalJSON reads the plan node in the format produced by the server
+func (node *ExplainNode) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		NodeType        string         `json:"Node Type"`
+		RelationName    string         `json:"Relation Name"`
+		Alias           string         `json:"Alias"`
+		StartupCost     float64        `json:"Startup Cost"`
+		TotalCost       float64        `json:"Total Cost"`
+		PlanRows        float64        `json:"Plan Rows"`
+		JoinFilter      string         `json:"Join Filter"`
+		HashCond        string         `json:"Hash Cond"`
+		MergeCond       string         `json:"Merge Cond"`
+		IndexCond       string         `json:"Index Cond"`
+		ActualTotalTime *float64       `json:"Actual Total Time"`
+		ActualRows      *float64       `json:"Actual Rows"`
+		Loops           *float64       `json:"Actual Loops"`
+		Plans           []*ExplainNode `json:"Plans"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*node = ExplainNode(raw)
+	return nil
+}
+
+// ExplainQuery returns the plan of the query. With analyze the query is executed,
+// which is only allowed for SELECT queries in read-only mode.
+func (client *Client) ExplainQuery(query string, analyze bool) (*ExplainPlan, error) {
+	return client.ExplainQueryContext(context.Background(), query, analyze)
+}
+
+// ExplainQueryContext returns the plan of the query like ExplainQuery, in read-only
+// mode if the context is
+func (client *Client) ExplainQueryContext(ctx context.Context, query string, analyze bool) (*ExplainPlan, error) {
+	statements := splitLintStatements(query)
+	if len(statements) != 1 {
+		return nil, ErrExplainMultipleStatements
+	}
+	if analyze && client.isReadOnlyContext(ctx) && !reSelectStatement.MatchString(statements[0]) {
+		return nil, ErrExplainAnalyzeReadOnly
+	}
+
+	if client.db == nil {
+		return nil, ErrNotConnected
+	}
+
+	res, err := client.queryContext(ctx, fmt.Sprintf("EXPLAIN (FORMAT JSON, ANALYZE %v) %s", analyze, query))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) == 0 || len(res.Rows[0]) == 0 {
+		return nil, ErrExplainNoPlan
+	}
+
+	return parseExplainPlan(res.Rows[0][0])
+}
+
+// ExplainGenericPlan returns the generic plan of the parameterized query, the plan
+// prepared statements use regardless of the parameter values. The query is
+// prepared with plan_cache_mode forced to the generic plan, it's never executed.
+func (client *Client) ExplainGenericPlan(query string) (*ExplainPlan, error) {
+	return client.ExplainGenericPlanContext(context.Background(), query)
+}
+
+// ExplainGenericPlanContext returns the generic plan of the query like
+// ExplainGenericPlan, in read-only mode if the context is
+func (client *Client) ExplainGenericPlanContext(parent context.Context, query string) (*ExplainPlan, error) {
+	if len(splitLintStatements(query)) != 1 {
+		return nil, ErrExplainMultipleStatements
+	}
+	if client.isReadOnlyContext(parent) {
+		if err := checkReadOnlyQuery(query); err != nil {
+			return nil, err
+		}
+	}
+	if client.db == nil {
+		return nil, ErrNotConnected
+	}
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	// Prepared statements outlive transactions, so the connection is pinned to
+	// deallocate the statement once done
+	conn, err := client.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Execute SET ROLE as a separate command if specified via X-Database-Role header
+	if client.defaultRole != "" {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`SET ROLE "%s"`, client.defaultRole)); err != nil {
+			return nil, fmt.Errorf("failed to set role %s: %w", client.defaultRole, err)
+		}
+	}
+
+	if err := client.checkReadOnlySchemasOn(ctx, conn, query); err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginTxx(ctx, &sql.TxOptions{ReadOnly: client.isReadOnlyContext(parent)})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tx.Rollback()                                             //nolint:errcheck
+		conn.ExecContext(ctx, "DEALLOCATE "+genericPlanStatement) //nolint:errcheck
+	}()
+
+	for _, stmt := range genericPlanStatements(query) {
+		// Statements are sent with the extended protocol, which rejects multiple
+		// commands, unlike the simple protocol used by Exec without arguments
+		prepared, err := tx.PreparexContext(ctx, stmt)
+		if err != nil {
+			return nil, err
+		}
+		_, err = prepared.ExecContext(ctx)
+		prepared.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var params int
+	err = tx.QueryRowxContext(ctx, "SELECT cardinality(parameter_types) FROM pg_prepared_statements WHERE name = $1", genericPlanStatement).Scan(&params)
+	if err != nil {
+		return nil, err
+	}
+
+	var output string
+	if err := tx.QueryRowxContext(ctx, genericPlanExplain(params)).Scan(&output); err != nil {
+		return nil, err
+	}
+
+	return parseExplainPlan(output)
+}
+
+// genericPlanStatements returns the statements preparing the query with the
+// generic plan forced for the rest of the transaction
+func genericPlanStatements(query string) []string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+
+	return []string{
+		"SET LOCAL plan_cache_mode = force_generic_plan",
+		fmt.Sprintf("PREPARE %s AS %s", genericPlanStatement, query),
+	}
+}
+
+// genericPlanExplain returns the EXPLAIN of the prepared statement. Parameter
+// values do not affect a generic plan, so they are all NULL.
+func genericPlanExplain(params int) string {
+	args := ""
+	if params > 0 {
+		args = "(" + strings.TrimSuffix(strings.Repeat("NULL, ", params), ", ") + ")"
+	}
+	return fmt.Sprintf("EXPLAIN (FORMAT JSON) EXECUTE %s%s", genericPlanStatement, args)
+}
+
+// parseExplainPlan parses the EXPLAIN (FORMAT JSON) output value
+func parseExplainPlan(value interface{}) (*ExplainPlan, error) {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return nil, ErrExplainNoPlan
+	}
+
+	plans := []ExplainPlan{}
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, ErrExplainNoPlan
+	}
+
+	plan := &plans[0]
+	plan.Warnings = crossJoinWarnings(plan.Plan)
+
+	return plan, nil
+}
+
+// crossJoinWarnings reports joins without any join condition, which produce a
+// cartesian product of the joined relations. Small products are not reported.
+func crossJoinWarnings(node *ExplainNode) []LintWarning {
+	warnings := []LintWarning{}
+
+	if isCrossJoin(node) && node.PlanRows >= explainCrossJoinRows {
+		warnings = append(warnings, LintWarning{
+			Severity:      LintSeverityWarning,
+			Message:       fmt.Sprintf("%s without a join condition produces a cartesian product of an estimated %.0f rows", node.NodeType, node.PlanRows),
+			Statement:     1,
+			EstimatedRows: int64(node.PlanRows),
+		})
+	}
+
+	for _, child := range node.Plans {
+		warnings = append(warnings, crossJoinWarnings(child)...)
+	}
+
+	return warnings
+}
+
+// isCrossJoin returns true if the node joins its inputs without any condition.
+// Nested loops with an index lookup on the inner side are parameterized by the
+// outer rows, so they are not cross joins.
+func isCrossJoin(node *ExplainNode) bool {
+	switch node.NodeType {
+	case "Nested Loop", "Hash Join", "Merge Join":
+	default:
+		return false
+	}
+
+	if node.JoinFilter != "" || node.HashCond != "" || node.MergeCond != "" {
+		return false
+	}
+	if len(node.Plans) == 2 && hasIndexCond(node.Plans[1]) {
+		return false
+	}
+
+	return true
+}
+
+func hasIndexCond(node *ExplainNode) bool {
+	if node.IndexCond != "" {
+		return true
+	}
+	for _, child := range node.Plans {
+		if hasIndexCond(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func TestParseExplainPlan(t *testing.T) {
+	output := `[
+  {
+    "Plan": {
+      "Node Type": "Hash Join",
+      "Startup Cost": 1.09,
+      "Total Cost": 2.31,
+      "Plan Rows": 15,
+      "Actual Total Time": 0.05,
+      "Actual Rows": 15,
+      "Actual Loops": 1,
+      "Plans": [
+        {
+          "Node Type": "Seq Scan",
+          "Relation Name": "books",
+          "Alias": "b",
+          "Startup Cost": 0.00,
+          "Total Cost": 1.15,
+          "Plan Rows": 15,
+          "Actual Total Time": 0.01,
+          "Actual Rows": 15,
+          "Actual Loops": 1
+        }
+      ]
+    },
+    "Planning Time": 0.2,
+    "Execution Time": 0.1
+  }
+]`
+
+	plan, err := parseExplainPlan(output)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hash Join", plan.Plan.NodeType)
+	assert.Equal(t, 2.31, plan.Plan.TotalCost)
+	assert.Equal(t, 15.0, *plan.Plan.ActualRows)
+	assert.Equal(t, 0.1, *plan.ExecutionTime)
+	require.Len(t, plan.Plan.Plans, 1)
+
+	scan := plan.Plan.Plans[0]
+	assert.Equal(t, "Seq Scan", scan.NodeType)
+	assert.Equal(t, "books", scan.RelationName)
+	assert.Equal(t, 1.0, *scan.Loops)
+	assert.Empty(t, scan.Plans)
+	assert.Empty(t, plan.Warnings)
+
+	_, err = parseExplainPlan(`[]`)
+	assert.Equal(t, ErrExplainNoPlan, err)
+
+	_, err = parseExplainPlan(nil)
+	assert.Equal(t, ErrExplainNoPlan, err)
+}
+
+func TestExplainQueryValidation(t *testing.T) {
+	client := &Client{readonly: true}
+
+	_, err := client.ExplainQuery("SELECT 1; SELECT 2", false)
+	assert.Equal(t, ErrExplainMultipleStatements, err)
+
+	_, err = client.ExplainQuery("DELETE FROM books", true)
+	assert.Equal(t, ErrExplainAnalyzeReadOnly, err)
+
+	_, err = client.ExplainQuery("/* delete */ SELECT 1;", true)
+	assert.Equal(t, ErrNotConnected, err)
+
+	_, err = client.ExplainQuery("DELETE FROM books", false)
+	assert.Equal(t, ErrNotConnected, err)
+}
+
+func TestExplainGenericPlanStatements(t *testing.T) {
+	assert.Equal(t, []string{
+		"SET LOCAL plan_cache_mode = force_generic_plan",
+		"PREPARE pgweb_generic_plan AS SELECT * FROM books WHERE id = $1",
+	}, genericPlanStatements(" SELECT * FROM books WHERE id = $1; "))
+
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) EXECUTE pgweb_generic_plan", genericPlanExplain(0))
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) EXECUTE pgweb_generic_plan(NULL, NULL)", genericPlanExplain(2))
+
+	client := &Client{}
+
+	_, err := client.ExplainGenericPlan("SELECT $1; SELECT $2")
+	assert.Equal(t, ErrExplainMultipleStatements, err)
+
+	_, err = client.ExplainGenericPlan(`SELECT E'\'' ; COMMIT; DROP TABLE books; SELECT E'\''`)
+	assert.Equal(t, ErrExplainMultipleStatements, err)
+
+	command.Opts.ReadOnly = true
+	_, err = client.ExplainGenericPlan("DELETE FROM books WHERE id = $1")
+	command.Opts.ReadOnly = false
+	assert.IsType(t, RestrictedKeywordError{}, err)
+
+	_, err = client.ExplainGenericPlan("SELECT * FROM books WHERE id = $1")
+	assert.Equal(t, ErrNotConnected, err)
+}
+
+func TestParseExplainPlanCrossJoin(t *testing.T) {
+	output := `[
+  {
+    "Plan": {
+      "Node Type": "Nested Loop",
+      "Startup Cost": 0.00,
+      "Total Cost": 150282.50,
+      "Plan Rows": 10000000,
+      "Plans": [
+        {
+          "Node Type": "Seq Scan",
+          "Relation Name": "books",
+          "Alias": "b",
+          "Startup Cost": 0.00,
+          "Total Cost": 155.00,
+          "Plan Rows": 10000
+        },
+        {
+          "Node Type": "Materialize",
+          "Startup Cost": 0.00,
+          "Total Cost": 20.00,
+          "Plan Rows": 1000,
+          "Plans": [
+            {
+              "Node Type": "Seq Scan",
+              "Relation Name": "authors",
+              "Alias": "a",
+              "Startup Cost": 0.00,
+              "Total Cost": 15.00,
+              "Plan Rows": 1000
+            }
+          ]
+        }
+      ]
+    }
+  }
+]`
+
+	plan, err := parseExplainPlan(output)
+	require.NoError(t, err)
+	require.Len(t, plan.Warnings, 1)
+
+	warning := plan.Warnings[0]
+	assert.Equal(t, LintSeverityWarning, warning.Severity)
+	assert.Equal(t, int64(10000000), warning.EstimatedRows)
+	assert.Contains(t, warning.Message, "Nested Loop without a join condition")
+}
+
+func Test_isCrossJoin(t *testing.T) {
+	scan := &ExplainNode{NodeType: "Seq Scan"}
+	lookup := &ExplainNode{NodeType: "Index Scan", IndexCond: "(id = b.author_id)"}
+
+	examples := []struct {
+		node     *ExplainNode
+		expected bool
+	}{
+		{&ExplainNode{NodeType: "Seq Scan"}, false},
+		{&ExplainNode{NodeType: "Nested Loop", Plans: []*ExplainNode{scan, scan}}, true},
+		{&ExplainNode{NodeType: "Nested Loop", JoinFilter: "(a.id = b.author_id)", Plans: []*ExplainNode{scan, scan}}, false},
+		{&ExplainNode{NodeType: "Nested Loop", Plans: []*ExplainNode{scan, lookup}}, false},
+		{&ExplainNode{NodeType: "Hash Join", HashCond: "(a.id = b.author_id)", Plans: []*ExplainNode{scan, scan}}, false},
+		{&ExplainNode{NodeType: "Merge Join", MergeCond: "(a.id = b.author_id)", Plans: []*ExplainNode{scan, scan}}, false},
+	}
+
+	for _, ex := range examples {
+		assert.Equal(t, ex.expected, isCrossJoin(ex.node))
+	}
+}
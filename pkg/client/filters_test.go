@@ -4,6 +4,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/connection"
+	"github.com/flowbi/pgweb/pkg/shared"
 )
 
 func TestCompileRegexPatterns(t *testing.T) {
@@ -120,3 +124,121 @@ func TestFilterObjectsResultNoPatterns(t *testing.T) {
 	filtered := filterObjectsResult(result, nil, nil)
 	assert.Equal(t, result, filtered) // Should be the same object
 }
+
+func TestCategorizeObjectsResult(t *testing.T) {
+	rules, err := command.CompileCategoryRules("_h$:Hub,_l$:Link,_s$:Satellite")
+	assert.NoError(t, err)
+
+	result := &Result{
+		Columns: []string{"oid", "schema", "name", "type", "owner", "comment"},
+		Rows: []Row{
+			{"1", "dv", "customer_h", "table", "postgres", nil},
+			{"2", "dv", "customer_order_l", "table", "postgres", nil},
+			{"3", "dv", "customer_s", "view", "postgres", nil},
+			{"4", "dv", "customer_history", "table", "postgres", nil},
+		},
+	}
+
+	categorized := categorizeObjectsResult(result, rules)
+	assert.Equal(t, "category", categorized.Columns[6])
+	assert.Equal(t, "Hub", categorized.Rows[0][6])
+	assert.Equal(t, "Link", categorized.Rows[1][6])
+	assert.Equal(t, "Satellite", categorized.Rows[2][6])
+	assert.Equal(t, "", categorized.Rows[3][6])
+
+	// Original result must not be modified
+	assert.Len(t, result.Columns, 6)
+	assert.Len(t, result.Rows[0], 6)
+
+	objects := ObjectsFromResult(categorized)
+	assert.Equal(t, "Hub", objects["dv"].Tables[0].Category)
+	assert.Equal(t, "Satellite", objects["dv"].Views[0].Category)
+
+	// No rules should return original result
+	assert.Equal(t, result, categorizeObjectsResult(result, nil))
+}
+
+func TestIsDatabaseAllowed(t *testing.T) {
+	defer func() {
+		command.Opts.AllowedDatabases = ""
+	}()
+
+	command.Opts.AllowedDatabases = ""
+	allowed, err := isDatabaseAllowed("anything")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	command.Opts.AllowedDatabases = "app, tenant_[0-9]+"
+	examples := map[string]bool{
+		"app":        true,
+		"myapp":      false,
+		"app_backup": false,
+		"tenant_1":   true,
+		"tenant_12":  true,
+		"tenant_a":   false,
+		"postgres":   false,
+		"":           false,
+	}
+	for name, expected := range examples {
+		allowed, err := isDatabaseAllowed(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, allowed, name)
+	}
+
+	command.Opts.AllowedDatabases = "[invalid"
+	_, err = isDatabaseAllowed("app")
+	assert.Error(t, err)
+}
+
+func TestNewFromUrlAllowedDatabases(t *testing.T) {
+	command.Opts.AllowedDatabases = "app"
+	defer func() {
+		command.Opts.AllowedDatabases = ""
+	}()
+
+	_, err := NewFromUrl("postgres://user@localhost:1/other?sslmode=disable", nil)
+	assert.Equal(t, ErrDatabaseNotAllowed, err)
+
+	// Database set in both the path and query is validated as the driver resolves it
+	assert.Equal(t, ErrDatabaseNotAllowed, checkConnectionTarget("postgres://user@localhost:1/other?dbname=app", nil))
+	assert.Equal(t, ErrDatabaseNotAllowed, checkConnectionTarget("postgres://user@localhost:1/app?dbname=other", nil))
+	assert.Equal(t, ErrDatabaseNotAllowed, checkConnectionTarget("host=localhost dbname=other", nil))
+}
+
+func TestCheckConnectionTargetAllowedHosts(t *testing.T) {
+	command.Opts.AllowedHosts = "10.0.0.0/8"
+	defer func() {
+		command.Opts.AllowedHosts = ""
+	}()
+
+	assert.NoError(t, checkConnectionTarget("postgres://user@10.1.2.3:5432/db", nil))
+	assert.Equal(t, connection.ErrHostNotAllowed, checkConnectionTarget("postgres://user@192.168.0.1:5432/db", nil))
+	assert.Equal(t, connection.ErrHostNotAllowed, checkConnectionTarget("postgres://user@10.1.2.3:5432/db?host=192.168.0.1", nil))
+	assert.Equal(t, connection.ErrHostNotAllowed, checkConnectionTarget("host=192.168.0.1 dbname=db", nil))
+
+	// Only the SSH host is reached directly when tunneling
+	sshInfo := &shared.SSHInfo{Host: "10.1.2.3"}
+	assert.NoError(t, checkConnectionTarget("postgres://user@192.168.0.1:5432/db", sshInfo))
+	sshInfo.Host = "169.254.169.254"
+	assert.Equal(t, connection.ErrHostNotAllowed, checkConnectionTarget("postgres://user@10.1.2.3:5432/db", sshInfo))
+
+	_, err := NewFromUrl("postgres://user@192.168.0.1:5432/db?sslmode=disable", nil)
+	assert.Equal(t, connection.ErrHostNotAllowed, err)
+}
+
+func TestNewFromUrlSessionsRestrictedHosts(t *testing.T) {
+	command.Opts.Sessions = true
+	defer func() {
+		command.Opts.Sessions = false
+		command.Opts.AllowLocalhost = false
+	}()
+
+	_, err := NewFromUrl("postgres://user@169.254.169.254:5432/db?sslmode=disable", nil)
+	assert.Equal(t, connection.ErrHostRestricted, err)
+
+	_, err = NewFromUrl("postgres://user@127.0.0.1:5432/db?sslmode=disable", nil)
+	assert.Equal(t, connection.ErrHostRestricted, err)
+
+	command.Opts.AllowLocalhost = true
+	assert.NoError(t, checkConnectionTarget("postgres://user@127.0.0.1:5432/db", nil))
+}
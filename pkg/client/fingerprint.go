@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Numeric constants that are not part of identifiers or positional parameters
+var reNumericLiteral = regexp.MustCompile(`(^|[^\w$.])(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+
+// QueryLogEntry describes an executed user query without its literals
+type QueryLogEntry struct {
+	Fingerprint string
+	Duration    time.Duration
+	RowsCount   int
+	Err         error
+}
+
+// QueryLogger receives an entry for each user query when set, ie. with the
+// --log-query-fingerprint option
+var QueryLogger func(ctx context.Context, entry QueryLogEntry)
+
+// Fingerprint returns the normalized query, with the string and numeric literals
+// replaced by ?, comments removed and whitespace collapsed. Queries only differing
+// by their values have the same fingerprint.
+func Fingerprint(query string) string {
+	var buf strings.Builder
+	last := 0
+	querySections(query, func(kind querySection, start, end int) {
+		buf.WriteString(replaceNumericLiterals(query[last:start]))
+		if kind == sectionComment {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte('?')
+		}
+		last = end
+	})
+	buf.WriteString(replaceNumericLiterals(query[last:]))
+
+	normalized := strings.Join(strings.Fields(buf.String()), " ")
+	return strings.TrimRight(normalized, "; ")
+}
+
+// replaceNumericLiterals replaces the numbers of the SQL code by ?, quoted identifiers are kept
+func replaceNumericLiterals(code string) string {
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(code, '"')
+		if start < 0 {
+			break
+		}
+		end := skipQuoted(code, start, '"')
+
+		buf.WriteString(reNumericLiteral.ReplaceAllString(code[:start], "$1?"))
+		buf.WriteString(code[start:end])
+		code = code[end:]
+	}
+	buf.WriteString(reNumericLiteral.ReplaceAllString(code, "$1?"))
+
+	return buf.String()
+}
+
+// logQuery sends the entry of the query started at start to the QueryLogger
+func logQuery(ctx context.Context, query string, start time.Time, rowsCount int, err error) {
+	if QueryLogger == nil {
+		return
+	}
+
+	QueryLogger(ctx, QueryLogEntry{
+		Fingerprint: Fingerprint(query),
+		Duration:    time.Since(start),
+		RowsCount:   rowsCount,
+		Err:         err,
+	})
+}
+
+// resultRowsCount returns the number of rows of the result, if any
+func resultRowsCount(res *Result) int {
+	if res == nil {
+		return 0
+	}
+	return len(res.Rows)
+}
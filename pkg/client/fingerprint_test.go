@@ -0,0 +1,27 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, Fingerprint("SELECT * FROM t WHERE id=5"), Fingerprint("SELECT * FROM t WHERE id=6"))
+
+	examples := map[string]string{
+		"SELECT * FROM t WHERE id=5":                                  "SELECT * FROM t WHERE id=?",
+		"SELECT *\n  FROM t\n WHERE id = 5;":                          "SELECT * FROM t WHERE id = ?",
+		"SELECT * FROM users WHERE email = 'a@b.c' AND age > 30":      "SELECT * FROM users WHERE email = ? AND age > ?",
+		"SELECT 1.5, .5, 1e10, -2.5E-3, 10::int":                      "SELECT ?, ?, ?, -?, ?::int",
+		"SELECT col1, t2.x FROM t2 WHERE a = $1 LIMIT 10":             "SELECT col1, t2.x FROM t2 WHERE a = $1 LIMIT ?",
+		"SELECT E'it\\'s', $$body$$, $tag$x$tag$ -- trailing comment": "SELECT ?, ?, ?",
+		`SELECT "Col 1" /* note 42 */ FROM "Table2" WHERE x IN (1,2)`: `SELECT "Col 1" FROM "Table2" WHERE x IN (?,?)`,
+		"INSERT INTO logs (msg, level) VALUES ('started', 3);":        "INSERT INTO logs (msg, level) VALUES (?, ?)",
+		"UPDATE accounts SET balance = balance - 100.00 WHERE id = 7": "UPDATE accounts SET balance = balance - ? WHERE id = ?",
+	}
+
+	for query, expected := range examples {
+		assert.Equal(t, expected, Fingerprint(query), query)
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/history"
+)
+
+// Maximum number of persisted records loaded into the client history
+const historyLoadLimit = 500
+
+// Session IDs used in history file names
+var reHistorySession = regexp.MustCompile(`^[\w-]+$`)
+
+// OpenHistory loads the persisted query history and recently opened objects of the
+// session into the client, new records are appended to it. The history of the whole
+// server is used when not running with sessions, the session ID is empty then.
+func (client *Client) OpenHistory(session string) error {
+	path := historyPath(session)
+	if path == "" {
+		return nil
+	}
+
+	store := history.NewStore(path)
+	records, err := store.Load(historyLoadLimit)
+	if err != nil {
+		store.Close()
+		return err
+	}
+	objects, err := store.LoadObjects(maxRecentObjects)
+	if err != nil {
+		store.Close()
+		return err
+	}
+
+	client.History = append(records, client.History...)
+
+	// Objects opened by the client are more recent than the persisted ones
+	client.recentObjectsMu.Lock()
+	recent := slices.Clone(client.recentObjects)
+	for _, object := range objects {
+		if len(recent) < maxRecentObjects && !slices.Contains(recent, object) {
+			recent = append(recent, object)
+		}
+	}
+	client.recentObjects = recent
+	client.recentObjectsMu.Unlock()
+
+	client.historyStore = store
+	return nil
+}
+
+// historyPath returns the file of the session history, or an empty string if
+// the history is not persisted
+func historyPath(session string) string {
+	if command.Opts.DisableHistory {
+		return ""
+	}
+	if command.Opts.HistoryFile != "" {
+		return command.Opts.HistoryFile
+	}
+	if command.Opts.HistoryDir == "" {
+		return ""
+	}
+
+	if session == "" {
+		session = "default"
+	}
+	if !reHistorySession.MatchString(session) {
+		return ""
+	}
+
+	return filepath.Join(command.Opts.HistoryDir, session+".jsonl")
+}
+
+// addHistoryRecord adds the query to the client history, unless it's already there
+func (client *Client) addHistoryRecord(query string) {
+	if client.hasHistoryRecord(query) {
+		return
+	}
+
+	record := history.NewRecord(query)
+	client.History = append(client.History, record)
+
+	if client.historyStore != nil {
+		client.historyStore.Append(record)
+	}
+}
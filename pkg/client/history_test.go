@@ -0,0 +1,65 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/history"
+)
+
+func TestHistoryPath(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	command.Opts = command.Options{HistoryDir: "/home/user/.pgweb/history"}
+	assert.Equal(t, "/home/user/.pgweb/history/default.jsonl", historyPath(""))
+	assert.Equal(t, "/home/user/.pgweb/history/3f2a-b1.jsonl", historyPath("3f2a-b1"))
+	assert.Equal(t, "", historyPath("../../etc/passwd"))
+
+	command.Opts.HistoryFile = "/tmp/history.jsonl"
+	assert.Equal(t, "/tmp/history.jsonl", historyPath("3f2a-b1"))
+
+	command.Opts.DisableHistory = true
+	assert.Equal(t, "", historyPath(""))
+}
+
+func TestOpenHistory(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts = command.Options{HistoryDir: t.TempDir()}
+
+	client := &Client{History: history.New()}
+	require.NoError(t, client.OpenHistory("session"))
+	assert.Empty(t, client.History)
+
+	client.addHistoryRecord("SELECT 1")
+	client.addHistoryRecord("SELECT 1")
+	client.addHistoryRecord("SELECT 2")
+	client.TrackRecentObject("books")
+	client.TrackRecentObject("authors")
+	require.NoError(t, client.Close())
+
+	assert.FileExists(t, filepath.Join(command.Opts.HistoryDir, "session.jsonl"))
+
+	// History of the session is loaded back by new clients
+	client = &Client{History: history.New()}
+	require.NoError(t, client.OpenHistory("session"))
+	defer client.Close()
+
+	require.Len(t, client.History, 2)
+	assert.Equal(t, "SELECT 1", client.History[0].Query)
+	assert.Equal(t, "SELECT 2", client.History[1].Query)
+	assert.Equal(t, []string{"public.authors", "public.books"}, client.GetRecentObjects())
+
+	other := &Client{History: history.New()}
+	require.NoError(t, other.OpenHistory("other"))
+	defer other.Close()
+	assert.Empty(t, other.History)
+	assert.Empty(t, other.GetRecentObjects())
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Interval is the structured value of an interval column
+type Interval struct {
+	Months       int64  `json:"months"`       // Number of months, years are 12 months
+	Days         int64  `json:"days"`         // Number of days, not converted to months
+	Microseconds int64  `json:"microseconds"` // Time part of the interval
+	ISO          string `json:"iso"`          // ISO 8601 duration, ie. P3M4DT5H6M7S
+	Text         string `json:"text"`         // Value as returned by the server
+}
+
+// String returns the interval as returned by the server, so exports keep the
+// human-readable form
+func (i Interval) String() string {
+	return i.Text
+}
+
+// parseInterval parses an interval in the default postgres output style,
+// ie. "1 year 2 mons -3 days +04:05:06.5". Other styles are not supported.
+func parseInterval(text string) (Interval, error) {
+	interval := Interval{Text: text}
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return interval, fmt.Errorf("invalid interval: %q", text)
+	}
+
+	for i := 0; i < len(fields); i++ {
+		if strings.Contains(fields[i], ":") {
+			usec, err := parseIntervalTime(fields[i])
+			if err != nil {
+				return interval, err
+			}
+			interval.Microseconds += usec
+			continue
+		}
+
+		if i+1 >= len(fields) {
+			return interval, fmt.Errorf("invalid interval: %q", text)
+		}
+
+		num, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return interval, fmt.Errorf("invalid interval: %q", text)
+		}
+
+		switch strings.TrimSuffix(fields[i+1], "s") {
+		case "year":
+			interval.Months += num * 12
+		case "mon":
+			interval.Months += num
+		case "day":
+			interval.Days += num
+		default:
+			return interval, fmt.Errorf("invalid interval: %q", text)
+		}
+		i++
+	}
+
+	interval.ISO = interval.isoDuration()
+	return interval, nil
+}
+
+// parseIntervalTime returns the number of microseconds of a [+-]HH:MM:SS[.ffffff]
+// interval time
+func parseIntervalTime(value string) (int64, error) {
+	sign := int64(1)
+	str := value
+
+	switch {
+	case strings.HasPrefix(str, "-"):
+		sign = -1
+		str = str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+
+	parts := strings.Split(str, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid interval time: %q", value)
+	}
+
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval time: %q", value)
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval time: %q", value)
+	}
+
+	secs, frac, _ := strings.Cut(parts[2], ".")
+	seconds, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval time: %q", value)
+	}
+
+	usec := int64(0)
+	if frac != "" {
+		if len(frac) > 6 {
+			return 0, fmt.Errorf("invalid interval time: %q", value)
+		}
+		if usec, err = strconv.ParseInt(frac+strings.Repeat("0", 6-len(frac)), 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid interval time: %q", value)
+		}
+	}
+
+	return sign * (((hours*60+minutes)*60+seconds)*1000000 + usec), nil
+}
+
+// isoDuration formats the interval as an ISO 8601 duration, with the same
+// component signs as the iso_8601 interval style of postgres
+func (i Interval) isoDuration() string {
+	var sb strings.Builder
+	sb.WriteString("P")
+
+	if years := i.Months / 12; years != 0 {
+		fmt.Fprintf(&sb, "%dY", years)
+	}
+	if months := i.Months % 12; months != 0 {
+		fmt.Fprintf(&sb, "%dM", months)
+	}
+	if i.Days != 0 {
+		fmt.Fprintf(&sb, "%dD", i.Days)
+	}
+
+	if i.Microseconds != 0 {
+		sb.WriteString("T")
+
+		usec := i.Microseconds
+		if hours := usec / 3600000000; hours != 0 {
+			fmt.Fprintf(&sb, "%dH", hours)
+		}
+		if minutes := usec / 60000000 % 60; minutes != 0 {
+			fmt.Fprintf(&sb, "%dM", minutes)
+		}
+		if seconds := usec % 60000000; seconds != 0 {
+			sb.WriteString(strconv.FormatFloat(float64(seconds)/1000000, 'f', -1, 64) + "S")
+		}
+	}
+
+	if sb.Len() == 1 {
+		return "PT0S"
+	}
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterval(t *testing.T) {
+	examples := []struct {
+		text     string
+		expected Interval
+	}{
+		{"3 mons 4 days 05:06:07", Interval{Months: 3, Days: 4, Microseconds: 18367000000, ISO: "P3M4DT5H6M7S"}},
+		{"1 year 2 mons", Interval{Months: 14, ISO: "P1Y2M"}},
+		{"-1 years -2 mons +3 days -04:05:06.5", Interval{Months: -14, Days: 3, Microseconds: -14706500000, ISO: "P-1Y-2M3DT-4H-5M-6.5S"}},
+		{"1 day", Interval{Days: 1, ISO: "P1D"}},
+		{"00:00:00.000001", Interval{Microseconds: 1, ISO: "PT0.000001S"}},
+		{"36:00:00", Interval{Microseconds: 129600000000, ISO: "PT36H"}},
+		{"00:00:00", Interval{ISO: "PT0S"}},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.text, func(t *testing.T) {
+			ex.expected.Text = ex.text
+
+			interval, err := parseInterval(ex.text)
+			assert.NoError(t, err)
+			assert.Equal(t, ex.expected, interval)
+			assert.Equal(t, ex.text, interval.String())
+		})
+	}
+
+	for _, text := range []string{"", "P1D", "@ 1 day", "1 week", "1", "1:2", "00:00:00.1234567"} {
+		t.Run(text, func(t *testing.T) {
+			_, err := parseInterval(text)
+			assert.Error(t, err)
+		})
+	}
+}
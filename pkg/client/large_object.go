@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// Number of bytes fetched from a large object at once
+	largeObjectChunkSize = 256 * 1024
+
+	ErrLargeObjectNotFound = errors.New("large object does not exist")
+)
+
+// largeObjectReader streams the large object content in chunks, all read
+// within a single snapshot so the content stays consistent.
+type largeObjectReader struct {
+	tx     *sqlx.Tx
+	cancel context.CancelFunc
+	oid    uint32
+	offset int64
+	buf    []byte
+	eof    bool
+}
+
+// LargeObject returns a reader streaming the content of the large object
+func (client *Client) LargeObject(oid uint32) (io.ReadCloser, error) {
+	if client.db == nil {
+		return nil, ErrNotConnected
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tx, err := client.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Large object privileges are checked against the role from X-Database-Role header
+	if client.defaultRole != "" {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`SET LOCAL ROLE "%s"`, client.defaultRole))
+	}
+
+	var exists bool
+	if err == nil {
+		err = tx.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM pg_largeobject_metadata WHERE oid = $1)", oid)
+	}
+	if err == nil && !exists {
+		err = ErrLargeObjectNotFound
+	}
+	if err != nil {
+		tx.Rollback() //nolint:errcheck
+		cancel()
+		return nil, err
+	}
+
+	return &largeObjectReader{tx: tx, cancel: cancel, oid: oid}, nil
+}
+
+func (r *largeObjectReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+		if len(r.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *largeObjectReader) fetch() error {
+	chunk := []byte{}
+	if err := r.tx.Get(&chunk, "SELECT lo_get($1, $2, $3)", r.oid, r.offset, largeObjectChunkSize); err != nil {
+		return err
+	}
+
+	r.buf = chunk
+	r.offset += int64(len(chunk))
+	r.eof = len(chunk) < largeObjectChunkSize
+	return nil
+}
+
+// Close ends the transaction the large object is read in
+func (r *largeObjectReader) Close() error {
+	defer r.cancel()
+	return r.tx.Rollback()
+}
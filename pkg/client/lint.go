@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+var (
+	// Number of table columns above which SELECT * is reported
+	lintWideTableColumns = 20
+
+	reLintUpdate     = regexp.MustCompile(`(?i)^\s*UPDATE\b`)
+	reLintDelete     = regexp.MustCompile(`(?i)^\s*DELETE\s+FROM\b`)
+	reLintWhere      = regexp.MustCompile(`(?i)\bWHERE\b`)
+	reLintSelectStar = regexp.MustCompile(`(?i)^\s*SELECT\s+(?:DISTINCT\s+)?\*\s+FROM\s+(?:ONLY\s+)?(` + reTarget + `)`)
+	reLintLimit      = regexp.MustCompile(`(?i)\bLIMIT\b`)
+	reLintOrderBy    = regexp.MustCompile(`(?i)\bORDER\s+BY\b`)
+)
+
+// LintWarning is an advisory note about a query statement
+type LintWarning struct {
+	Severity      string `json:"severity"`
+	Message       string `json:"message"`
+	Statement     int    `json:"statement"`                // 1-based position of the statement in the query
+	EstimatedRows int64  `json:"estimated_rows,omitempty"` // Planner estimate of the rows involved, if any
+}
+
+// LintQuery inspects the query statements for common mistakes. Warnings are
+// purely advisory, the query is never executed.
+func (client *Client) LintQuery(query string) []LintWarning {
+	warnings := lintQuery(query)
+
+	for i, stmt := range splitLintStatements(query) {
+		match := reLintSelectStar.FindStringSubmatch(stmt)
+		if match == nil {
+			continue
+		}
+
+		table := lintTableName(match[1])
+		res, err := client.Table(table)
+		if err != nil || res == nil {
+			// Table might be a CTE or a function, nothing to report
+			continue
+		}
+
+		if len(res.Rows) > lintWideTableColumns {
+			warnings = append(warnings, LintWarning{
+				Severity:  LintSeverityInfo,
+				Message:   fmt.Sprintf("SELECT * on a wide table %s with %d columns, consider listing the needed columns", table, len(res.Rows)),
+				Statement: i + 1,
+			})
+		}
+	}
+
+	return warnings
+}
+
+// lintQuery returns the warnings that do not need the database schema
+func lintQuery(query string) []LintWarning {
+	warnings := []LintWarning{}
+
+	for i, stmt := range splitLintStatements(query) {
+		add := func(severity, message string) {
+			warnings = append(warnings, LintWarning{Severity: severity, Message: message, Statement: i + 1})
+		}
+
+		hasWhere := reLintWhere.MatchString(stmt)
+
+		switch {
+		case reLintUpdate.MatchString(stmt) && !hasWhere:
+			add(LintSeverityWarning, "UPDATE without WHERE affects all rows")
+		case reLintDelete.MatchString(stmt) && !hasWhere:
+			add(LintSeverityWarning, "DELETE without WHERE deletes all rows")
+		}
+
+		if reLintLimit.MatchString(stmt) && !reLintOrderBy.MatchString(stmt) {
+			add(LintSeverityInfo, "LIMIT without ORDER BY returns an unpredictable subset of rows")
+		}
+	}
+
+	return warnings
+}
+
+// splitLintStatements returns the query statements with comments, string literals
+// and parenthesized expressions blanked out, so only the top-level clauses remain
+func splitLintStatements(query string) []string {
+	query = blankCommentsAndLiterals(query)
+
+	statements := []string{}
+	current := []byte{}
+	depth := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '(':
+			depth++
+			c = ' '
+		case c == ')' && depth > 0:
+			depth--
+			c = ' '
+		case depth > 0:
+			c = ' '
+		case c == ';':
+			statements = append(statements, string(current))
+			current = current[:0]
+			continue
+		}
+		current = append(current, c)
+	}
+	statements = append(statements, string(current))
+
+	result := []string{}
+	for _, stmt := range statements {
+		if strings.TrimSpace(stmt) != "" {
+			result = append(result, stmt)
+		}
+	}
+	return result
+}
+
+// lintTableName returns the table name in the schema.table format used by Table
+func lintTableName(target string) string {
+	parts := splitOutsideQuotes(target, '.')
+	for i, part := range parts {
+		parts[i] = unquoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintQuery(t *testing.T) {
+	examples := []struct {
+		query    string
+		warnings []LintWarning
+	}{
+		{
+			query: "UPDATE books SET title = 'a'",
+			warnings: []LintWarning{
+				{Severity: LintSeverityWarning, Message: "UPDATE without WHERE affects all rows", Statement: 1},
+			},
+		},
+		{
+			query: "delete from books",
+			warnings: []LintWarning{
+				{Severity: LintSeverityWarning, Message: "DELETE without WHERE deletes all rows", Statement: 1},
+			},
+		},
+		{
+			query:    "UPDATE books SET title = 'a' WHERE id = 1",
+			warnings: []LintWarning{},
+		},
+		{
+			query:    "DELETE FROM books WHERE id IN (SELECT id FROM old_books)",
+			warnings: []LintWarning{},
+		},
+		{
+			// WHERE in a subquery or a string does not scope the update
+			query: "UPDATE books SET title = (SELECT name FROM authors WHERE id = 1), note = 'where'",
+			warnings: []LintWarning{
+				{Severity: LintSeverityWarning, Message: "UPDATE without WHERE affects all rows", Statement: 1},
+			},
+		},
+		{
+			query: "SELECT 1; -- update books\nUPDATE books SET title = 'a;b'",
+			warnings: []LintWarning{
+				{Severity: LintSeverityWarning, Message: "UPDATE without WHERE affects all rows", Statement: 2},
+			},
+		},
+		{
+			query: "SELECT * FROM books LIMIT 10",
+			warnings: []LintWarning{
+				{Severity: LintSeverityInfo, Message: "LIMIT without ORDER BY returns an unpredictable subset of rows", Statement: 1},
+			},
+		},
+		{
+			query:    "SELECT * FROM books ORDER BY id LIMIT 10",
+			warnings: []LintWarning{},
+		},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.query, func(t *testing.T) {
+			assert.Equal(t, ex.warnings, lintQuery(ex.query))
+		})
+	}
+}
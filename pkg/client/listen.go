@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrChannelRequired = errors.New("notification channel is required")
+
+var (
+	// Delays between the attempts to reconnect a dropped listener connection
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+
+	// Interval of the checks that the listener connection is still alive
+	listenPingInterval = 30 * time.Second
+)
+
+// Listen forwards the payloads of the notifications sent to the channel with NOTIFY
+// until the client is closed. It's like ListenContext with a background context.
+func (client *Client) Listen(channel string, ch chan<- string) error {
+	return client.ListenContext(context.Background(), channel, ch)
+}
+
+// ListenContext listens to the channel on a dedicated connection and forwards the
+// notification payloads to ch, until the context is cancelled or the client is closed.
+// A dropped connection is reopened, notifications sent in the meantime are lost.
+func (client *Client) ListenContext(ctx context.Context, channel string, ch chan<- string) error {
+	if channel == "" {
+		return ErrChannelRequired
+	}
+	if client.connector == nil {
+		return ErrNotConnected
+	}
+
+	listener := client.newListener()
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(listenPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case notification := <-listener.Notify:
+			// Nil notifications are sent once the connection is reopened
+			if notification == nil {
+				continue
+			}
+
+			select {
+			case ch <- notification.Extra:
+			case <-ctx.Done():
+				return nil
+			case <-client.done:
+				return nil
+			}
+
+		case <-ticker.C:
+			// Failed pings make the listener reconnect
+			go listener.Ping() //nolint:errcheck
+
+		case <-ctx.Done():
+			return nil
+
+		case <-client.done:
+			return nil
+		}
+	}
+}
+
+// newListener returns a listener connecting like the client connections, ie. over the SSH tunnel
+func (client *Client) newListener() *pq.Listener {
+	dsn := client.connector.DSN()
+	if client.connector.dialer != nil {
+		return pq.NewDialListener(client.connector.dialer, dsn, listenMinReconnect, listenMaxReconnect, nil)
+	}
+	return pq.NewListener(dsn, listenMinReconnect, listenMaxReconnect, nil)
+}
@@ -0,0 +1,14 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenValidation(t *testing.T) {
+	client := &Client{}
+
+	assert.Equal(t, ErrChannelRequired, client.Listen("", make(chan string)))
+	assert.Equal(t, ErrNotConnected, client.Listen("progress", make(chan string)))
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseMoney converts a money value formatted with the lc_monetary locale of the
+// server, ie. "$1,234.56" or "-1.234,56 €", into a plain decimal string like "1234.56".
+// The last separator is taken as the decimal one, unless it's followed by exactly
+// three digits and no different separator comes before it.
+func parseMoney(text string) (string, error) {
+	negative := strings.Contains(text, "-") || strings.HasPrefix(strings.TrimSpace(text), "(")
+
+	digits := strings.Builder{}
+	separators := []byte{}
+	positions := []int{}
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c >= '0' && c <= '9':
+			digits.WriteByte(c)
+		case c == '.' || c == ',':
+			// Separators are only between digits, not part of the currency symbol
+			if digits.Len() > 0 && i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9' {
+				separators = append(separators, c)
+				positions = append(positions, digits.Len())
+			}
+		}
+	}
+
+	value := digits.String()
+	if value == "" {
+		return "", fmt.Errorf("invalid money value: %q", text)
+	}
+
+	if n := len(separators); n > 0 {
+		last, pos := separators[n-1], positions[n-1]
+		fraction := len(value) - pos
+
+		isDecimal := fraction != 3 || (n > 1 && separators[0] != last)
+		if n > 1 && separators[n-2] == last {
+			isDecimal = false
+		}
+		if isDecimal {
+			value = value[:pos] + "." + value[pos:]
+		}
+	}
+
+	value = strings.TrimLeft(value, "0")
+	if value == "" || value[0] == '.' {
+		value = "0" + value
+	}
+	if negative && strings.Trim(value, "0.") != "" {
+		value = "-" + value
+	}
+
+	return value, nil
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	examples := map[string]string{
+		"$1,234.56":      "1234.56",
+		"-$1,234.56":     "-1234.56",
+		"($5.00)":        "-5.00",
+		"$0.50":          "0.50",
+		"$0.00":          "0.00",
+		"$1,234,567.89":  "1234567.89",
+		"1.234,56 €":     "1234.56",
+		"-1.234.567,8 €": "-1234567.8",
+		"1 234,56 kr.":   "1234.56",
+		"¥1,234":         "1234",
+		"¥1,234,567":     "1234567",
+		"1,234.567 KD":   "1234.567",
+	}
+
+	for text, expected := range examples {
+		t.Run(text, func(t *testing.T) {
+			value, err := parseMoney(text)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, value)
+		})
+	}
+
+	_, err := parseMoney("$")
+	assert.EqualError(t, err, `invalid money value: "$"`)
+}
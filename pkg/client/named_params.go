@@ -0,0 +1,88 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QueryWithNamedParams runs the user query with the @name placeholders bound to
+// the params values, the same way QueryWithParams binds them.
+func (client *Client) QueryWithNamedParams(query string, params map[string]interface{}) (*Result, error) {
+	query, args, err := BindNamedParams(query, params)
+	if err != nil {
+		return nil, err
+	}
+	return client.QueryWithParams(query, args)
+}
+
+// BindNamedParams replaces @name placeholders with $N placeholders and returns
+// the values in the placeholder order, a name used more than once keeps its
+// number. Placeholders within string literals, quoted identifiers and comments
+// are kept, as well as operators like @> or <@.
+func BindNamedParams(query string, params map[string]interface{}) (string, []interface{}, error) {
+	args := []interface{}{}
+	numbers := map[string]int{}
+
+	var sb strings.Builder
+	for i := 0; i < len(query); {
+		end := i + 1
+		switch {
+		case query[i] == '\'' || query[i] == '"':
+			end = skipQuoted(query, i, query[i])
+		case query[i] == '$':
+			end = skipDollarQuoted(query, i)
+		case strings.HasPrefix(query[i:], "--"):
+			end = strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end = strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+		case query[i] == '@' && (i == 0 || !isOperatorChar(query[i-1])):
+			j := i + 1
+			for j < len(query) && isNameChar(query[j], j == i+1) {
+				j++
+			}
+			if j == i+1 {
+				break
+			}
+
+			name := query[i+1 : j]
+			num, ok := numbers[name]
+			if !ok {
+				value, found := params[name]
+				if !found {
+					return "", nil, fmt.Errorf("parameter %q is missing", name)
+				}
+				args = append(args, value)
+				num = len(args)
+				numbers[name] = num
+			}
+
+			sb.WriteString("$" + strconv.Itoa(num))
+			i = j
+			continue
+		}
+
+		sb.WriteString(query[i:end])
+		i = end
+	}
+
+	return sb.String(), args, nil
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindNamedParams(t *testing.T) {
+	params := map[string]interface{}{"id": 1, "title": "Dune", "tags": "{a}"}
+
+	examples := []struct {
+		query    string
+		expected string
+		args     []interface{}
+	}{
+		{"SELECT 1", "SELECT 1", []interface{}{}},
+		{"SELECT * FROM books WHERE id = @id", "SELECT * FROM books WHERE id = $1", []interface{}{1}},
+		{"SELECT @title, @id, @title", "SELECT $1, $2, $1", []interface{}{"Dune", 1}},
+		{"SELECT '@id', \"@id\", $$@id$$ -- @id\n, @id /* @id */", "SELECT '@id', \"@id\", $$@id$$ -- @id\n, $1 /* @id */", []interface{}{1}},
+		{"SELECT tags @> @tags, tags <@tags, @ -5", "SELECT tags @> $1, tags <@tags, @ -5", []interface{}{"{a}"}},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.query, func(t *testing.T) {
+			query, args, err := BindNamedParams(ex.query, params)
+			assert.NoError(t, err)
+			assert.Equal(t, ex.expected, query)
+			assert.Equal(t, ex.args, args)
+		})
+	}
+
+	_, _, err := BindNamedParams("SELECT @missing", params)
+	assert.EqualError(t, err, `parameter "missing" is missing`)
+}
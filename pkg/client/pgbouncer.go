@@ -0,0 +1,148 @@
+package client
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+// Error code returned when the statement prepared on one server connection is
+// executed on another one, ie. behind PgBouncer in transaction pooling mode.
+const errCodeInvalidStatementName = "26000"
+
+// useSimpleProtocol returns true when query arguments are formatted by pgweb
+// instead of being sent with a server-side prepared statement.
+func (client *Client) useSimpleProtocol() bool {
+	return command.Opts.PgBouncer || client.pgbouncer
+}
+
+// queryArgs returns the query and arguments to send to the server. With the
+// simple protocol the arguments are inlined into the query text.
+func (client *Client) queryArgs(query string, args []interface{}) (string, []interface{}, error) {
+	if len(args) == 0 || !client.useSimpleProtocol() {
+		return query, args, nil
+	}
+
+	query, err := interpolateArgs(query, args)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, nil, nil
+}
+
+// detectPgBouncer switches the client to the simple protocol when the error
+// shows that prepared statements do not survive between server connections.
+func (client *Client) detectPgBouncer(err error, args []interface{}) bool {
+	if len(args) == 0 || client.useSimpleProtocol() {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != errCodeInvalidStatementName {
+		return false
+	}
+
+	client.pgbouncer = true
+	return true
+}
+
+// interpolateArgs replaces $N placeholders with literals of the arguments.
+// Placeholders within string literals, quoted identifiers and comments are kept.
+func interpolateArgs(query string, args []interface{}) (string, error) {
+	literals := make([]string, len(args))
+	for i, arg := range args {
+		literal, err := formatLiteral(arg)
+		if err != nil {
+			return "", fmt.Errorf("argument $%d: %w", i+1, err)
+		}
+		literals[i] = literal
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(query); {
+		switch {
+		case query[i] == '\'' || query[i] == '"':
+			end := skipQuoted(query, i, query[i])
+			sb.WriteString(query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case query[i] == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				// Dollar-quoted string, ie. $$text$$ or $tag$text$tag$
+				end := skipDollarQuoted(query, i)
+				sb.WriteString(query[i:end])
+				i = end
+				continue
+			}
+			n, _ := strconv.Atoi(query[i+1 : j])
+			if n < 1 || n > len(literals) {
+				return "", fmt.Errorf("placeholder $%d has no matching argument", n)
+			}
+			sb.WriteString(literals[n-1])
+			i = j
+		default:
+			sb.WriteByte(query[i])
+			i++
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// formatLiteral formats the argument as an SQL literal
+func formatLiteral(arg interface{}) (string, error) {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		if v < 0 {
+			// Avoid forming a comment when following a minus sign
+			return "(" + strconv.FormatInt(v, 10) + ")", nil
+		}
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return pq.QuoteLiteral(strconv.FormatFloat(v, 'g', -1, 64)) + "::float8", nil
+	case []byte:
+		return pq.QuoteLiteral(`\x`+hex.EncodeToString(v)) + "::bytea", nil
+	case string:
+		return pq.QuoteLiteral(v), nil
+	case time.Time:
+		return pq.QuoteLiteral(v.Format(time.RFC3339Nano)) + "::timestamptz", nil
+	default:
+		return "", fmt.Errorf("unsupported argument type %T", value)
+	}
+}
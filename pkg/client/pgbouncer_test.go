@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+// recordingConn captures the statements sent to the server
+type recordingConn struct {
+	sqlx.ExtContext
+	query string
+	args  []interface{}
+	err   error
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c.query = query
+	c.args = args
+	if c.err != nil {
+		err := c.err
+		c.err = nil
+		return nil, err
+	}
+	return driverResult(1), nil
+}
+
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestInterpolateArgs(t *testing.T) {
+	examples := []struct {
+		name     string
+		query    string
+		args     []interface{}
+		expected string
+		err      string
+	}{
+		{
+			name:     "no placeholders",
+			query:    "SELECT 1",
+			expected: "SELECT 1",
+		},
+		{
+			name:     "basic types",
+			query:    "SELECT $1, $2, $3, $4, $5",
+			args:     []interface{}{"it's", 10, -5, true, nil},
+			expected: "SELECT 'it''s', 10, (-5), true, NULL",
+		},
+		{
+			name:     "reused placeholder",
+			query:    "SELECT $2 WHERE a = $1 OR b = $1",
+			args:     []interface{}{1, "x"},
+			expected: "SELECT 'x' WHERE a = 1 OR b = 1",
+		},
+		{
+			name:     "float and binary",
+			query:    "SELECT $1, $2",
+			args:     []interface{}{1.5, []byte{0xde, 0xad}},
+			expected: `SELECT '1.5'::float8,  E'\\xdead'::bytea`,
+		},
+		{
+			name:     "time",
+			query:    "SELECT $1",
+			args:     []interface{}{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			expected: "SELECT '2024-01-02T03:04:05Z'::timestamptz",
+		},
+		{
+			name:     "quoted sections",
+			query:    `SELECT '$1', "$1", $$ $1 $$, $tag$ $1 $tag$, $1 -- $1` + "\n" + `/* $1 */ FROM t`,
+			args:     []interface{}{"a"},
+			expected: `SELECT '$1', "$1", $$ $1 $$, $tag$ $1 $tag$, 'a' -- $1` + "\n" + `/* $1 */ FROM t`,
+		},
+		{
+			name:  "missing argument",
+			query: "SELECT $1, $2",
+			args:  []interface{}{1},
+			err:   "placeholder $2 has no matching argument",
+		},
+		{
+			name:  "unsupported argument",
+			query: "SELECT $1",
+			args:  []interface{}{struct{}{}},
+			err:   "argument $1: unsupported type struct {}, a struct",
+		},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			query, err := interpolateArgs(ex.query, ex.args)
+			if ex.err != "" {
+				assert.EqualError(t, err, ex.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, ex.expected, query)
+		})
+	}
+}
+
+func TestPgBouncerSimpleProtocol(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	t.Run("prepared statements by default", func(t *testing.T) {
+		command.Opts = command.Options{}
+		conn := &recordingConn{}
+
+		_, err := (&Client{}).runExecOn(context.Background(), conn, "DELETE FROM books WHERE id = $1", 5)
+		require.NoError(t, err)
+		assert.Equal(t, "DELETE FROM books WHERE id = $1", conn.query)
+		assert.Equal(t, []interface{}{5}, conn.args)
+	})
+
+	t.Run("simple protocol with flag", func(t *testing.T) {
+		command.Opts = command.Options{PgBouncer: true}
+		conn := &recordingConn{}
+
+		_, err := (&Client{}).runExecOn(context.Background(), conn, "DELETE FROM books WHERE id = $1", 5)
+		require.NoError(t, err)
+		assert.Equal(t, "DELETE FROM books WHERE id = 5", conn.query)
+		assert.Empty(t, conn.args)
+	})
+
+	t.Run("detected from error", func(t *testing.T) {
+		command.Opts = command.Options{}
+		conn := &recordingConn{err: &pq.Error{Code: errCodeInvalidStatementName}}
+		cl := &Client{}
+
+		_, err := cl.runExecOn(context.Background(), conn, "DELETE FROM books WHERE id = $1", 5)
+		require.NoError(t, err)
+		assert.True(t, cl.pgbouncer)
+		assert.Equal(t, "DELETE FROM books WHERE id = 5", conn.query)
+		assert.Empty(t, conn.args)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		command.Opts = command.Options{}
+		conn := &recordingConn{err: errors.New("boom")}
+		cl := &Client{}
+
+		_, err := cl.runExecOn(context.Background(), conn, "DELETE FROM books WHERE id = $1", 5)
+		assert.EqualError(t, err, "boom")
+		assert.False(t, cl.pgbouncer)
+	})
+}
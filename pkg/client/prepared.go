@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+// Maximum number of prepared statements kept per client, the least recently
+// used statement is closed when the limit is reached
+const maxPreparedStatements = 100
+
+// preparedConn runs parameterized queries with the client prepared statements,
+// queries without arguments are passed through to the connection.
+type preparedConn struct {
+	queryConn
+	client *Client
+}
+
+func (c preparedConn) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	if len(args) == 0 {
+		return c.queryConn.QueryxContext(ctx, query, args...)
+	}
+
+	stmt, err := c.client.preparedStatement(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryxContext(ctx, args...)
+}
+
+func (c preparedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if len(args) == 0 {
+		return c.queryConn.ExecContext(ctx, query, args...)
+	}
+
+	stmt, err := c.client.preparedStatement(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// QueryWithParams runs the user query with the $N placeholders bound to params.
+// With --prepared-statements the query is prepared on its first run and the
+// statement is reused by subsequent runs of the same query.
+func (client *Client) QueryWithParams(query string, params []interface{}) (*Result, error) {
+	return client.QueryWithParamsContext(context.Background(), query, params)
+}
+
+// QueryWithParamsContext runs the query like QueryWithParams, with the values of the context
+func (client *Client) QueryWithParamsContext(ctx context.Context, query string, params []interface{}) (*Result, error) {
+	if len(params) == 0 {
+		return client.QueryContext(ctx, query)
+	}
+	if client.db == nil {
+		return nil, ErrNotConnected
+	}
+
+	start := time.Now()
+	res, err := client.withReconnect(ctx, func() (*Result, error) {
+		if !client.usePreparedStatements(ctx) {
+			return client.runQuery(ctx, query, params...)
+		}
+		return client.runQueryOn(ctx, preparedConn{queryConn: client.db, client: client}, query, params...)
+	})
+	logQuery(ctx, query, start, resultRowsCount(res), err)
+
+	if err == nil {
+		client.addHistoryRecord(query)
+	}
+
+	return res, err
+}
+
+// usePreparedStatements returns true if parameterized queries run with the
+// client prepared statements. Transactions and work_mem settings need a pinned
+// connection, and read-only requests a transaction, so their queries are not prepared.
+func (client *Client) usePreparedStatements(ctx context.Context) bool {
+	return command.Opts.PreparedStatements && !client.useSimpleProtocol() && client.tx == nil &&
+		workMemFrom(ctx) == "" && !ReadOnlyFromContext(ctx)
+}
+
+// preparedStatement returns the statement prepared for the query, preparing it
+// on first use
+func (client *Client) preparedStatement(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	key := normalizeStatement(query)
+
+	client.statementsMu.Lock()
+	defer client.statementsMu.Unlock()
+
+	if stmt, ok := client.statements[key]; ok {
+		client.touchPreparedStatement(key)
+		return stmt, nil
+	}
+
+	stmt, err := client.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.statements == nil {
+		client.statements = map[string]*sqlx.Stmt{}
+	}
+	client.statements[key] = stmt
+	client.touchPreparedStatement(key)
+
+	for len(client.statementKeys) > maxPreparedStatements {
+		last := len(client.statementKeys) - 1
+		evicted := client.statementKeys[last]
+		client.statementKeys = client.statementKeys[:last]
+
+		client.statements[evicted].Close() //nolint:errcheck
+		delete(client.statements, evicted)
+	}
+
+	return stmt, nil
+}
+
+// touchPreparedStatement moves the statement key to the front of the
+// statement keys, which are ordered from the most recently used
+func (client *Client) touchPreparedStatement(key string) {
+	keys := make([]string, 0, len(client.statementKeys)+1)
+	keys = append(keys, key)
+
+	for _, item := range client.statementKeys {
+		if item != key {
+			keys = append(keys, item)
+		}
+	}
+
+	client.statementKeys = keys
+}
+
+// closePreparedStatements deallocates all the client prepared statements
+func (client *Client) closePreparedStatements() {
+	client.statementsMu.Lock()
+	defer client.statementsMu.Unlock()
+
+	for _, stmt := range client.statements {
+		stmt.Close() //nolint:errcheck
+	}
+	client.statements = nil
+	client.statementKeys = nil
+}
+
+// normalizeStatement returns the query with whitespace collapsed and the trailing
+// semicolon removed, so formatting differences do not prepare the query again.
+// String literals, quoted identifiers and comments are kept as is.
+func normalizeStatement(query string) string {
+	var sb strings.Builder
+	space := false
+
+	for i := 0; i < len(query); {
+		end := i + 1
+		switch {
+		case query[i] == ' ' || query[i] == '\t' || query[i] == '\n' || query[i] == '\r':
+			space = true
+			i++
+			continue
+		case query[i] == '\'' || query[i] == '"':
+			end = skipQuoted(query, i, query[i])
+		case query[i] == '$':
+			end = skipDollarQuoted(query, i)
+		case strings.HasPrefix(query[i:], "--"):
+			end = strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				// Line break ends the comment, so it's kept
+				end += i + 1
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end = strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+		}
+
+		if space && sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteString(query[i:end])
+		i = end
+	}
+
+	return strings.TrimSpace(strings.TrimSuffix(sb.String(), ";"))
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeStatement(t *testing.T) {
+	examples := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"  SELECT *\n  FROM books\n\tWHERE id = $1;  ", "SELECT * FROM books WHERE id = $1"},
+		{"SELECT 'a  b', \"x  y\" FROM t", "SELECT 'a  b', \"x  y\" FROM t"},
+		{"SELECT $$a  b$$", "SELECT $$a  b$$"},
+		{"SELECT /* a  b */ 1", "SELECT /* a  b */ 1"},
+		{"-- comment\n   SELECT 1", "-- comment\nSELECT 1"},
+		{"-- comment SELECT 1", "-- comment SELECT 1"},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.query, func(t *testing.T) {
+			assert.Equal(t, ex.expected, normalizeStatement(ex.query))
+		})
+	}
+}
+
+func TestTouchPreparedStatement(t *testing.T) {
+	client := &Client{}
+
+	client.touchPreparedStatement("a")
+	client.touchPreparedStatement("b")
+	client.touchPreparedStatement("c")
+	assert.Equal(t, []string{"c", "b", "a"}, client.statementKeys)
+
+	client.touchPreparedStatement("a")
+	assert.Equal(t, []string{"a", "c", "b"}, client.statementKeys)
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+var ErrQueryTimeoutNotAllowed = errors.New("per-request query timeout is not allowed")
+
+// queryTimeoutKey is the context key of the query timeout requested for the queries
+type queryTimeoutKey struct{}
+
+// ParseQueryTimeout parses the query timeout requested for the queries, in seconds,
+// within the --max-query-timeout limit
+func ParseQueryTimeout(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || seconds == 0 {
+		return 0, fmt.Errorf("invalid query timeout: %q", value)
+	}
+	if command.Opts.MaxQueryTimeout == 0 {
+		return 0, ErrQueryTimeoutNotAllowed
+	}
+	if seconds > uint64(command.Opts.MaxQueryTimeout) {
+		return 0, fmt.Errorf("query timeout must not exceed %d seconds", command.Opts.MaxQueryTimeout)
+	}
+
+	return time.Second * time.Duration(seconds), nil
+}
+
+// WithQueryTimeout returns a copy of the context carrying the timeout overriding the
+// global one for the queries run with it
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// QueryTimeout returns the timeout applied to the queries run with the context, 0 if none
+func (client *Client) QueryTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return client.queryTimeout
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	command.Opts = command.Options{}
+	client := &Client{queryTimeout: 300 * time.Second}
+
+	assert.Equal(t, 300*time.Second, client.QueryTimeout(context.Background()))
+	_, err := ParseQueryTimeout("5")
+	assert.Equal(t, ErrQueryTimeoutNotAllowed, err)
+
+	command.Opts.MaxQueryTimeout = 3600
+	timeout, err := ParseQueryTimeout("5")
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, timeout)
+
+	parent := WithQueryTimeout(context.Background(), timeout)
+	assert.Equal(t, 5*time.Second, client.QueryTimeout(parent))
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(5*time.Second), deadline, time.Second)
+
+	timeout, err = ParseQueryTimeout("3600")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, timeout)
+
+	for value, message := range map[string]string{
+		"3601": "query timeout must not exceed 3600 seconds",
+		"0":    `invalid query timeout: "0"`,
+		"-1":   `invalid query timeout: "-1"`,
+		"5s":   `invalid query timeout: "5s"`,
+	} {
+		_, err := ParseQueryTimeout(value)
+		assert.EqualError(t, err, message, value)
+	}
+
+	// The override only applies to the queries run with the context
+	assert.Equal(t, 300*time.Second, client.QueryTimeout(context.Background()))
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+)
+
+// readOnlyKey is the context key marking the queries of read-only requests
+type readOnlyKey struct{}
+
+// WithReadOnly returns a copy of the context whose queries run in read-only mode,
+// whatever the mode of the client they run on
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// ReadOnlyFromContext returns true if the queries run with the context are read-only
+func ReadOnlyFromContext(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+// isReadOnlyContext returns true if the queries run with the context are read-only,
+// either for all the client queries or only for the request ones
+func (client *Client) isReadOnlyContext(ctx context.Context) bool {
+	return client.isReadOnly() || ReadOnlyFromContext(ctx)
+}
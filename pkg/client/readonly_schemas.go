@@ -0,0 +1,266 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+const (
+	// Plain or double-quoted identifier, optionally schema-qualified
+	reIdentifier = `(?:"(?:[^"]|"")+"|[\w$]+)`
+	reTarget     = reIdentifier + `(?:\s*\.\s*` + reIdentifier + `)*`
+)
+
+var (
+	ErrReadOnlySchema = errors.New("writes are not allowed in read-only schema")
+
+	// Statements writing into the target objects
+	reWriteTargets = regexp.MustCompile(`(?i)\b(?:INSERT\s+INTO|UPDATE|DELETE\s+FROM|MERGE\s+INTO|TRUNCATE(?:\s+TABLE)?|` +
+		`(?:CREATE|ALTER|DROP)(?:\s+OR\s+REPLACE)?(?:\s+(?:TEMP|TEMPORARY|UNLOGGED|MATERIALIZED|FOREIGN))*\s+` +
+		`(?:TABLE|VIEW|SEQUENCE|FUNCTION|PROCEDURE|TYPE)|(?:ALTER|DROP)\s+INDEX(?:\s+CONCURRENTLY)?|` +
+		`REFRESH\s+MATERIALIZED\s+VIEW(?:\s+CONCURRENTLY)?|` +
+		`COMMENT\s+ON\s+(?:(?:MATERIALIZED|FOREIGN)\s+)?(?:TABLE|VIEW|SEQUENCE|INDEX|FUNCTION|PROCEDURE|TYPE))` +
+		`(?:\s+IF\s+(?:NOT\s+)?EXISTS)?\s+(?:ONLY\s+)?(` + reTarget + `(?:\s*,\s*` + reTarget + `)*)`)
+
+	// Statements creating indexes, loading data into a table or creating a table from a query
+	reIndexTarget      = regexp.MustCompile(`(?i)\bCREATE\s+(?:UNIQUE\s+)?INDEX\b[^;]*?\bON\s+(?:ONLY\s+)?(` + reTarget + `)`)
+	reCopyTarget       = regexp.MustCompile(`(?i)\bCOPY\s+(` + reTarget + `)\s*(?:\([^)]*\))?\s*FROM\b`)
+	reSelectIntoTarget = regexp.MustCompile(`(?i)\bSELECT\b[^;]*?\bINTO\s+(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?(?:TABLE\s+)?(` + reTarget + `)`)
+
+	// Comments on columns, the target name ends with the column name
+	reCommentColumnTarget = regexp.MustCompile(`(?i)\bCOMMENT\s+ON\s+COLUMN\s+(` + reTarget + `)`)
+
+	// Statements on a schema itself, or moving objects into a schema
+	reSchemaTargets = regexp.MustCompile(`(?i)\b(?:(?:CREATE|ALTER|DROP)\s+SCHEMA(?:\s+IF\s+(?:NOT\s+)?EXISTS)?|COMMENT\s+ON\s+SCHEMA|SET\s+SCHEMA)` +
+		`\s+(` + reIdentifier + `(?:\s*,\s*` + reIdentifier + `)*)`)
+
+	// Search path changes, unqualified names after them may resolve to any of the schemas
+	reSetSearchPath = regexp.MustCompile(`(?i)\bSET\s+(?:(?:SESSION|LOCAL)\s+)?search_path\s*(?:TO|=)([^;]*)`)
+
+	// UPDATE keywords that are not UPDATE statements, ie. row locks, upserts, triggers and grants
+	reNonWriteUpdate = regexp.MustCompile(`(?i)\b(?:(?:FOR(?:\s+NO\s+KEY)?|DO|ON)\s+UPDATE|UPDATE\s+(?:ON|OF|OR))\b`)
+
+	// String literals, looked up for dynamic statements in function bodies
+	reStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+)
+
+// checkReadOnlySchemas returns an error if the query writes into a schema
+// matching the --readonly-schemas patterns. Unqualified names are checked against
+// every schema of the search path returned by searchPath, or the default schema
+// when it's nil, and every schema the query sets the search path to.
+//
+// The check is best-effort: statements in function bodies and string literals
+// executed by them are checked, but dynamic SQL built at runtime, ie. with
+// format() or concatenation, and functions called by the query are not.
+func checkReadOnlySchemas(query string, searchPath func() ([]string, error)) error {
+	patterns := command.Opts.ReadOnlySchemaPatterns
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	defaultSchemas := []string{defaultSchema()}
+	if searchPath != nil && hasUnqualifiedWriteTargets(query) {
+		var err error
+		if defaultSchemas, err = searchPath(); err != nil {
+			return err
+		}
+	}
+
+	for _, schema := range writeTargetSchemas(query, defaultSchemas) {
+		for _, pattern := range patterns {
+			if pattern.MatchString(schema) {
+				return fmt.Errorf("%w: %s", ErrReadOnlySchema, schema)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkReadOnlySchemasOn checks the query against the read-only schemas,
+// resolving unqualified names with the search path of the connection
+func (client *Client) checkReadOnlySchemasOn(ctx context.Context, conn queryConn, query string) error {
+	return checkReadOnlySchemas(query, func() ([]string, error) {
+		schemas := []string{}
+		err := sqlx.GetContext(ctx, conn, pq.Array(&schemas), "SELECT current_schemas(false)")
+		return schemas, err
+	})
+}
+
+// writeTargetSchemas returns the schemas of objects the query writes into.
+// Unqualified objects belong to any of the default schemas.
+func writeTargetSchemas(query string, defaultSchemas []string) []string {
+	schemas := []string{}
+	seen := map[string]bool{}
+	add := func(schema string) {
+		if !seen[schema] {
+			seen[schema] = true
+			schemas = append(schemas, schema)
+		}
+	}
+
+	for _, statement := range append([]string{query}, embeddedStatements(query)...) {
+		blanked := blankNonWriteParts(statement)
+
+		unqualified := append([]string{}, defaultSchemas...)
+		for _, match := range reSetSearchPath.FindAllStringSubmatchIndex(blanked, -1) {
+			// Schemas of the search path may be string literals, blanked in the query
+			for _, schema := range splitOutsideQuotes(statement[match[2]:match[3]], ',') {
+				if schema = strings.Trim(schema, "'"); schema != "" {
+					unqualified = append(unqualified, unquoteIdentifier(schema))
+				}
+			}
+		}
+
+		for _, target := range writeTargetNames(blanked) {
+			if target[0] != "" {
+				add(target[0])
+				continue
+			}
+			for _, schema := range unqualified {
+				add(schema)
+			}
+		}
+
+		for _, match := range reSchemaTargets.FindAllStringSubmatch(blanked, -1) {
+			for _, schema := range splitOutsideQuotes(match[1], ',') {
+				add(unquoteIdentifier(schema))
+			}
+		}
+	}
+
+	return schemas
+}
+
+// hasUnqualifiedWriteTargets returns true if the query writes into objects
+// without a schema name
+func hasUnqualifiedWriteTargets(query string) bool {
+	for _, statement := range append([]string{query}, embeddedStatements(query)...) {
+		for _, target := range writeTargetNames(blankNonWriteParts(statement)) {
+			if target[0] == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// embeddedStatements returns the bodies of dollar-quoted strings in the query,
+// ie. DO blocks and function bodies, and string literals within them, which
+// may be executed as dynamic statements
+func embeddedStatements(query string) []string {
+	statements := []string{}
+
+	querySections(query, func(kind querySection, start, end int) {
+		if kind != sectionDollarQuoted {
+			return
+		}
+		tag := strings.IndexByte(query[start+1:], '$') + 2
+		if end-start < 2*tag {
+			return
+		}
+
+		body := query[start+tag : end-tag]
+		statements = append(statements, body)
+		querySections(body, func(kind querySection, start, end int) {
+			if kind == sectionLiteral && body[end-1] == '\'' {
+				literal := body[strings.IndexByte(body[start:], '\'')+start+1 : end-1]
+				statements = append(statements, strings.ReplaceAll(literal, "''", "'"))
+			}
+		})
+		statements = append(statements, embeddedStatements(body)...)
+	})
+
+	return statements
+}
+
+// writeTargetTables returns the schema and name of objects the query writes into
+func writeTargetTables(query string) [][2]string {
+	return writeTargets(blankNonWriteParts(query))
+}
+
+// blankNonWriteParts blanks out comments, string literals and UPDATE keywords
+// that do not start statements, so they are not mistaken for write targets
+func blankNonWriteParts(query string) string {
+	blank := func(s string) string { return strings.Repeat(" ", len(s)) }
+	return reNonWriteUpdate.ReplaceAllStringFunc(blankCommentsAndLiterals(query), blank)
+}
+
+// writeTargets returns the schema and name of the objects the blanked query
+// writes into. Unqualified objects belong to the default schema.
+func writeTargets(query string) [][2]string {
+	targets := writeTargetNames(query)
+	for i := range targets {
+		if targets[i][0] == "" {
+			targets[i][0] = defaultSchema()
+		}
+	}
+	return targets
+}
+
+// writeTargetNames returns the schema and name of the objects the blanked query
+// writes into. The schema is empty for unqualified objects.
+func writeTargetNames(query string) [][2]string {
+	targets := [][2]string{}
+
+	add := func(target string, columns int) {
+		parts := splitOutsideQuotes(target, '.')
+		parts = parts[:max(len(parts)-columns, 1)]
+
+		schema := ""
+		if len(parts) > 1 {
+			schema = unquoteIdentifier(parts[len(parts)-2])
+		}
+		targets = append(targets, [2]string{schema, unquoteIdentifier(parts[len(parts)-1])})
+	}
+
+	for _, re := range []*regexp.Regexp{reWriteTargets, reIndexTarget, reCopyTarget, reSelectIntoTarget} {
+		for _, match := range re.FindAllStringSubmatch(query, -1) {
+			for _, target := range splitOutsideQuotes(match[1], ',') {
+				add(target, 0)
+			}
+		}
+	}
+
+	for _, match := range reCommentColumnTarget.FindAllStringSubmatch(query, -1) {
+		add(match[1], 1)
+	}
+
+	return targets
+}
+
+// splitOutsideQuotes splits the string on the separator outside of double quotes
+func splitOutsideQuotes(str string, sep byte) []string {
+	result := []string{}
+	quoted := false
+	start := 0
+
+	for i := 0; i < len(str); i++ {
+		switch {
+		case str[i] == '"':
+			quoted = !quoted
+		case str[i] == sep && !quoted:
+			result = append(result, strings.TrimSpace(str[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(result, strings.TrimSpace(str[start:]))
+}
+
+// unquoteIdentifier returns the identifier name, case-folded the way PostgreSQL
+// does unless it's double-quoted
+func unquoteIdentifier(str string) string {
+	if len(str) > 1 && strings.HasPrefix(str, `"`) && strings.HasSuffix(str, `"`) {
+		return strings.ReplaceAll(str[1:len(str)-1], `""`, `"`)
+	}
+	return strings.ToLower(str)
+}
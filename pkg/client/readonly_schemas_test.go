@@ -0,0 +1,99 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func TestWriteTargetSchemas(t *testing.T) {
+	examples := []struct {
+		query   string
+		schemas []string
+	}{
+		{"SELECT * FROM reporting.sales", []string{}},
+		{"SELECT * FROM books FOR UPDATE", []string{}},
+		{"SELECT 'DELETE FROM reporting.sales'", []string{}},
+		{"-- UPDATE reporting.sales\nSELECT 1", []string{}},
+		{"COPY reporting.sales TO STDOUT", []string{}},
+		{"INSERT INTO reporting.sales VALUES (1)", []string{"reporting"}},
+		{"insert into books values (1) on conflict (id) do update set title = 'a'", []string{"public"}},
+		{"UPDATE Reporting.sales SET total = 0", []string{"reporting"}},
+		{`DELETE FROM "Reporting"."sales"`, []string{"Reporting"}},
+		{`DELETE FROM "a.b".sales`, []string{"a.b"}},
+		{"TRUNCATE books, reporting.sales", []string{"public", "reporting"}},
+		{"WITH x AS (DELETE FROM reporting.sales RETURNING *) SELECT * FROM x", []string{"reporting"}},
+		{"CREATE TABLE IF NOT EXISTS reporting.totals (id int)", []string{"reporting"}},
+		{"DROP MATERIALIZED VIEW reporting.totals", []string{"reporting"}},
+		{"CREATE UNIQUE INDEX sales_idx ON reporting.sales (id)", []string{"reporting"}},
+		{"CREATE INDEX ON ONLY reporting.sales (id)", []string{"reporting"}},
+		{"DROP INDEX CONCURRENTLY IF EXISTS reporting.sales_idx", []string{"reporting"}},
+		{"COPY reporting.sales (id, total) FROM STDIN", []string{"reporting"}},
+		{"DROP SCHEMA reporting CASCADE", []string{"reporting"}},
+		{"UPDATE books SET title = 'a'; DELETE FROM reporting.sales", []string{"public", "reporting"}},
+		{"SET search_path = reporting; INSERT INTO sales VALUES (1)", []string{"public", "reporting"}},
+		{"SET LOCAL search_path TO 'reporting', public; DELETE FROM sales", []string{"public", "reporting"}},
+		{"SELECT 1 INTO reporting.totals", []string{"reporting"}},
+		{"SELECT * INTO TEMP TABLE totals FROM reporting.sales", []string{"public"}},
+		{"INSERT INTO/**/reporting.sales VALUES (1)", []string{"reporting"}},
+		{"DELETE FROM--\nreporting.sales", []string{"reporting"}},
+		{"REFRESH MATERIALIZED VIEW CONCURRENTLY reporting.totals", []string{"reporting"}},
+		{"ALTER TABLE public.sales SET SCHEMA reporting", []string{"public", "reporting"}},
+		{"COMMENT ON TABLE reporting.sales IS 'sales'", []string{"reporting"}},
+		{"COMMENT ON COLUMN reporting.sales.total IS 'total'", []string{"reporting"}},
+		{"COMMENT ON SCHEMA reporting IS 'reports'", []string{"reporting"}},
+		{"DO $$ BEGIN EXECUTE 'INSERT INTO reporting.sales VALUES (1)'; END $$", []string{"reporting"}},
+		{"CREATE FUNCTION f() RETURNS void AS $fn$ DELETE FROM reporting.sales $fn$ LANGUAGE sql", []string{"public", "reporting"}},
+		{"SELECT $$DELETE FROM reporting.sales$$ LIKE 'x'", []string{"reporting"}},
+		{"SELECT * FROM books WHERE id = $1", []string{}},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.query, func(t *testing.T) {
+			assert.Equal(t, ex.schemas, writeTargetSchemas(ex.query, []string{"public"}))
+		})
+	}
+}
+
+func TestWriteTargetTables(t *testing.T) {
+	assert.Equal(t, [][2]string{}, writeTargetTables("SELECT * FROM books"))
+	assert.Equal(t, [][2]string{{"public", "books"}}, writeTargetTables("INSERT INTO books VALUES (1)"))
+	assert.Equal(t, [][2]string{{"Reporting", "Sales"}}, writeTargetTables(`UPDATE "Reporting"."Sales" SET total = 0`))
+	assert.Equal(t, [][2]string{{"public", "books"}, {"reporting", "sales"}}, writeTargetTables("DELETE FROM books; TRUNCATE reporting.sales"))
+}
+
+func TestCheckReadOnlySchemas(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+
+	command.Opts = command.Options{}
+	assert.NoError(t, checkReadOnlySchemas("DELETE FROM reporting.sales", nil))
+
+	command.Opts.ReadOnlySchemaPatterns, _ = command.CompileAllowPatterns("reporting,audit_.*")
+
+	err := checkReadOnlySchemas("DELETE FROM reporting.sales", nil)
+	assert.True(t, errors.Is(err, ErrReadOnlySchema))
+	assert.EqualError(t, err, "writes are not allowed in read-only schema: reporting")
+
+	assert.Error(t, checkReadOnlySchemas("INSERT INTO audit_2024.events VALUES (1)", nil))
+	assert.NoError(t, checkReadOnlySchemas("DELETE FROM public.books", nil))
+	assert.NoError(t, checkReadOnlySchemas("DELETE FROM reporting_old.sales", nil))
+	assert.NoError(t, checkReadOnlySchemas("SELECT * FROM reporting.sales", nil))
+
+	// Unqualified names are resolved against the search path
+	searchPath := func() ([]string, error) {
+		return []string{"reporting", "public"}, nil
+	}
+	assert.Error(t, checkReadOnlySchemas("UPDATE sales SET total = 0", searchPath))
+	assert.NoError(t, checkReadOnlySchemas("UPDATE public.sales SET total = 0", func() ([]string, error) {
+		return nil, errors.New("search path is not needed")
+	}))
+
+	// Or the default schema when the search path is not known
+	command.Opts.DefaultSchema = "reporting"
+	assert.Error(t, checkReadOnlySchemas("UPDATE sales SET total = 0", nil))
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyContext(t *testing.T) {
+	client := &Client{}
+	ctx := WithReadOnly(context.Background())
+
+	assert.False(t, ReadOnlyFromContext(context.Background()))
+	assert.True(t, ReadOnlyFromContext(ctx))
+	assert.False(t, client.isReadOnlyContext(context.Background()))
+	assert.True(t, client.isReadOnlyContext(ctx))
+
+	run := func(conn queryConn) (*Result, error) {
+		return &Result{}, nil
+	}
+
+	// Statements that can't run in a read-only transaction are rejected
+	_, err := client.withWorkMem(ctx, nil, "VACUUM", run)
+	assert.Equal(t, ErrReadOnly, err)
+
+	// An open read-write transaction can't be used by read-only requests
+	client.tx = &sqlx.Tx{}
+	_, err = client.withWorkMem(ctx, nil, "SELECT 1", run)
+	assert.Equal(t, ErrReadOnly, err)
+
+	client.txReadOnly = true
+	res, err := client.withWorkMem(ctx, nil, "SELECT 1", run)
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/shared"
+)
+
+const (
+	// Maximum number of reconnects attempted for a single failed query
+	maxReconnectAttempts = 2
+
+	// Maximum number of idle pool connections, the database/sql default
+	poolMaxIdleConns = 2
+)
+
+// CredentialRefresher returns a fresh connection string for the client
+type CredentialRefresher func(ctx context.Context) (string, error)
+
+// SetCredentialRefresher configures the client to reconnect with refreshed
+// credentials whenever a query fails with an authentication error.
+func (client *Client) SetCredentialRefresher(fn CredentialRefresher) {
+	client.refresher = fn
+}
+
+// withReconnect runs the query function, refreshing the credentials and
+// reconnecting on authentication errors up to maxReconnectAttempts times.
+func (client *Client) withReconnect(ctx context.Context, fn func() (*Result, error)) (*Result, error) {
+	res, err := fn()
+
+	for attempt := 0; attempt < maxReconnectAttempts && client.canReconnect(err); attempt++ {
+		if command.Opts.Debug {
+			log.Println("Authentication failed, refreshing connection credentials:", err)
+		}
+
+		if rerr := client.reconnect(ctx); rerr != nil {
+			if command.Opts.Debug {
+				log.Println("Credentials refresh failed:", rerr)
+			}
+			return nil, err
+		}
+
+		res, err = fn()
+	}
+
+	return res, err
+}
+
+// canReconnect reports whether the error may be resolved by reconnecting.
+// Open transactions are never reconnected since their state would be lost.
+func (client *Client) canReconnect(err error) bool {
+	return client.refresher != nil && client.tx == nil && isAuthError(err)
+}
+
+// reconnect opens new connections with refreshed credentials. The connection
+// pool is kept, so queries running on other connections are not interrupted.
+func (client *Client) reconnect(ctx context.Context) error {
+	client.reconnectMu.Lock()
+	defer client.reconnectMu.Unlock()
+
+	url, err := client.refresher(ctx)
+	if err != nil {
+		return err
+	}
+
+	var sshInfo *shared.SSHInfo
+	if client.tunnel != nil {
+		sshInfo = client.tunnel.SSHInfo
+	}
+	if err := checkConnectionTarget(url, sshInfo); err != nil {
+		return err
+	}
+	if client.tunnel != nil {
+		if url, err = tunnelURL(url, client.tunnel); err != nil {
+			return err
+		}
+	}
+
+	if client.connector == nil {
+		// Client was never connected
+		connector := newConnector(url, client.tunnel)
+		db, err := openConnector(connector)
+		if err != nil {
+			return err
+		}
+		client.db, client.connector = db, connector
+	} else {
+		// Fail early on malformed connection strings
+		if _, err := pq.NewConnector(url); err != nil {
+			return err
+		}
+		client.connector.SetDSN(url)
+
+		// Close idle connections opened with the previous credentials
+		client.db.SetMaxIdleConns(0)
+		client.db.SetMaxIdleConns(poolMaxIdleConns)
+	}
+
+	client.ConnectionString = url
+	return nil
+}
+
+// isAuthError reports whether the error is a postgres authorization failure
+func isAuthError(err error) bool {
+	pqErr := &pq.Error{}
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	// Class 28 — Invalid Authorization Specification
+	return pqErr.Code.Class() == "28"
+}
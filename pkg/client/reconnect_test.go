@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+func TestIsAuthError(t *testing.T) {
+	assert.True(t, isAuthError(&pq.Error{Code: "28P01"}))
+	assert.True(t, isAuthError(&pq.Error{Code: "28000"}))
+	assert.False(t, isAuthError(&pq.Error{Code: "42P01"}))
+	assert.False(t, isAuthError(errors.New("password authentication failed")))
+	assert.False(t, isAuthError(nil))
+}
+
+func TestWithReconnect(t *testing.T) {
+	authErr := &pq.Error{Code: "28P01", Message: "password authentication failed"}
+
+	t.Run("reconnects on auth error", func(t *testing.T) {
+		fetches := 0
+		client := &Client{ConnectionString: "postgres://old@localhost/db"}
+		client.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+			fetches++
+			return "postgres://new@localhost/db", nil
+		})
+		defer client.Close()
+
+		calls := 0
+		res, err := client.withReconnect(context.Background(), func() (*Result, error) {
+			calls++
+			if calls == 1 {
+				return nil, authErr
+			}
+			return &Result{}, nil
+		})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, res)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, 1, fetches)
+		assert.NotNil(t, client.db)
+		assert.Equal(t, "postgres://new@localhost/db", client.ConnectionString)
+	})
+
+	t.Run("bounded retries", func(t *testing.T) {
+		fetches := 0
+		client := &Client{}
+		client.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+			fetches++
+			return "postgres://new@localhost/db", nil
+		})
+		defer client.Close()
+
+		_, err := client.withReconnect(context.Background(), func() (*Result, error) {
+			return nil, authErr
+		})
+
+		assert.Equal(t, authErr, err)
+		assert.Equal(t, maxReconnectAttempts, fetches)
+	})
+
+	t.Run("refresh failure", func(t *testing.T) {
+		client := &Client{}
+		client.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+			return "", errors.New("backend unavailable")
+		})
+
+		_, err := client.withReconnect(context.Background(), func() (*Result, error) {
+			return nil, authErr
+		})
+		assert.Equal(t, authErr, err)
+	})
+
+	t.Run("non-auth error", func(t *testing.T) {
+		fetches := 0
+		client := &Client{}
+		client.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+			fetches++
+			return "postgres://new@localhost/db", nil
+		})
+
+		_, err := client.withReconnect(context.Background(), func() (*Result, error) {
+			return nil, errors.New("syntax error")
+		})
+		assert.EqualError(t, err, "syntax error")
+		assert.Equal(t, 0, fetches)
+	})
+
+	t.Run("keeps the connection pool", func(t *testing.T) {
+		client, err := NewFromUrl("postgres://old@localhost:1/db?sslmode=disable", nil)
+		assert.NoError(t, err)
+		defer client.Close()
+
+		db := client.db
+		client.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+			return "postgres://new@localhost:1/db?sslmode=disable", nil
+		})
+
+		assert.NoError(t, client.reconnect(context.Background()))
+		assert.Same(t, db, client.db)
+		assert.Equal(t, "postgres://new@localhost:1/db?sslmode=disable", client.connector.DSN())
+	})
+
+	t.Run("validates the connection target", func(t *testing.T) {
+		client := &Client{}
+		client.SetCredentialRefresher(func(ctx context.Context) (string, error) {
+			return "postgres://new@localhost/other", nil
+		})
+
+		command.Opts.AllowedDatabases = "db"
+		defer func() {
+			command.Opts.AllowedDatabases = ""
+		}()
+
+		assert.Equal(t, ErrDatabaseNotAllowed, client.reconnect(context.Background()))
+		assert.Nil(t, client.db)
+	})
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"regexp"
+)
+
+// Request IDs are embedded into query comments, so only a safe subset of characters is allowed
+var reRequestID = regexp.MustCompile(`^[\w.:;=-]{1,128}$`)
+
+// ValidRequestID returns true if the request ID can be embedded into queries
+func ValidRequestID(id string) bool {
+	return reRequestID.MatchString(id)
+}
+
+// requestIDKey is the context key of the HTTP request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the ID of the HTTP request
+// queries are run for. Queries run with the context are tagged with a comment
+// carrying the ID, so they can be traced back to the request in the server logs.
+// Invalid IDs are ignored.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if !ValidRequestID(id) {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID queries run with the context are tagged with
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// tagQuery returns the query prefixed with the request ID comment. Prepared
+// statements are shared by all requests, so queries run with them are not tagged.
+func (client *Client) tagQuery(ctx context.Context, conn queryConn, query string, args []interface{}) string {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return query
+	}
+	if _, ok := conn.(preparedConn); ok && len(args) > 0 {
+		return query
+	}
+	return "/* request_id=" + id + " */ " + query
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "Root=1-5759e988;req.42")
+	assert.Equal(t, "Root=1-5759e988;req.42", RequestIDFromContext(ctx))
+
+	ctx = WithRequestID(context.Background(), "abc */ DROP TABLE books; /*")
+	assert.Equal(t, "", RequestIDFromContext(ctx))
+
+	assert.Equal(t, "", RequestIDFromContext(context.Background()))
+}
+
+func Test_tagQuery(t *testing.T) {
+	client := &Client{}
+	assert.Equal(t, "SELECT 1", client.tagQuery(context.Background(), nil, "SELECT 1", nil))
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	assert.Equal(t, "/* request_id=req-123 */ SELECT 1", client.tagQuery(ctx, nil, "SELECT 1", nil))
+
+	// Prepared statements are shared by requests
+	conn := preparedConn{client: client}
+	assert.Equal(t, "SELECT $1", client.tagQuery(ctx, conn, "SELECT $1", []interface{}{1}))
+	assert.Equal(t, "/* request_id=req-123 */ SELECT 1", client.tagQuery(ctx, conn, "SELECT 1", nil))
+}
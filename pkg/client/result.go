@@ -5,9 +5,11 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/flowbi/pgweb/pkg/command"
@@ -22,17 +24,51 @@ const (
 	ObjTypeForeignTable     = "foreign_table"
 )
 
+const (
+	ResultTypeRows    = "rows"    // Query returned a result set, possibly without rows
+	ResultTypeCommand = "command" // Statement was executed without returning a result set
+)
+
 type (
 	// Row represents a single row of data
 	Row []interface{}
 
 	// RowsOptions contains a list of parameters for table browsing requests
 	RowsOptions struct {
-		Where      string // Custom filter
-		Offset     int    // Number of rows to skip
-		Limit      int    // Number of rows to fetch
-		SortColumn string // Column to sort by
-		SortOrder  string // Sort direction (ASC, DESC)
+		Where       string     // Custom filter
+		Offset      int        // Number of rows to skip
+		Limit       int        // Number of rows to fetch, 0 for the default limit and RowsUnlimited for all rows
+		SortColumn  string     // Column to sort by
+		SortOrder   string     // Sort direction (ASC, DESC)
+		SortColumns []SortSpec // Columns to sort by, applied after SortColumn
+	}
+
+	// SortSpec describes a column the rows are sorted by
+	SortSpec struct {
+		Column string // Column name
+		Order  string // Sort direction (ASC, DESC), defaults to ASC
+	}
+
+	// ObjectsOptions contains a list of parameters for objects list requests
+	ObjectsOptions struct {
+		Schema string // Schema to list the objects of
+		Name   string // Case-insensitive object name substring
+		Offset int    // Number of objects to skip
+		Limit  int    // Number of objects to fetch, 0 = unlimited
+	}
+
+	// CrosstabOptions contains a list of parameters for pivot requests
+	CrosstabOptions struct {
+		Query    string      // Source query
+		RowKey   string      // Source column used for result rows
+		Category string      // Source column used for result columns
+		Value    Aggregation // Aggregate used for result values
+	}
+
+	// Aggregation describes an aggregate function applied to a table column
+	Aggregation struct {
+		Function string `json:"function"` // Aggregate function (count, sum, avg, min, max)
+		Column   string `json:"column"`   // Column to aggregate, "*" is only allowed for count
 	}
 
 	Pagination struct {
@@ -43,10 +79,14 @@ type (
 	}
 
 	Result struct {
-		Pagination *Pagination  `json:"pagination,omitempty"`
-		Columns    []string     `json:"columns"`
-		Rows       []Row        `json:"rows"`
-		Stats      *ResultStats `json:"stats,omitempty"`
+		Type             string       `json:"type"`
+		Pagination       *Pagination  `json:"pagination,omitempty"`
+		Columns          []string     `json:"columns"`
+		ColumnTypes      []string     `json:"-" xml:"-"` // Database type names of the columns, ie. INT8 or TIMESTAMPTZ
+		ColumnsTruncated bool         `json:"columns_truncated,omitempty"`
+		ColumnsTotal     int          `json:"columns_total,omitempty"` // Number of columns before truncation
+		Rows             []Row        `json:"rows"`
+		Stats            *ResultStats `json:"stats,omitempty"`
 	}
 
 	ResultStats struct {
@@ -59,8 +99,9 @@ type (
 	}
 
 	Object struct {
-		OID  string `json:"oid"`
-		Name string `json:"name"`
+		OID      string `json:"oid"`
+		Name     string `json:"name"`
+		Category string `json:"category,omitempty"`
 	}
 
 	Objects struct {
@@ -74,8 +115,20 @@ type (
 )
 
 // Due to big int number limitations in javascript, numbers should be encoded
-// as strings so they could be properly loaded on the frontend.
+// as strings so they could be properly loaded on the frontend. With the
+// --numbers-as-strings option all numbers are encoded as strings. Intervals
+// are converted to structured values based on the column types, money values
+// to plain decimal strings with the --money-as-numeric option, and json values
+// are indented unless --no-pretty-json is set.
 func (res *Result) PostProcess() {
+	numbersAsStrings := command.Opts.NumbersAsStrings
+	moneyAsNumeric := command.Opts.MoneyAsNumeric
+	prettyJSON := !command.Opts.DisablePrettyJSON
+
+	if len(command.Opts.ColumnPrefixes) > 0 {
+		res.Columns = stripColumnPrefixes(res.Columns, command.Opts.ColumnPrefixes)
+	}
+
 	for i, row := range res.Rows {
 		for j, col := range row {
 			if col == nil {
@@ -84,7 +137,7 @@ func (res *Result) PostProcess() {
 
 			switch val := col.(type) {
 			case int64:
-				if val < -9007199254740991 || val > 9007199254740991 {
+				if numbersAsStrings || val < -9007199254740991 || val > 9007199254740991 {
 					res.Rows[i][j] = strconv.FormatInt(col.(int64), 10)
 				}
 			case float64:
@@ -95,10 +148,38 @@ func (res *Result) PostProcess() {
 					break
 				}
 
+				if numbersAsStrings {
+					// Shortest representation that parses back to the same value
+					res.Rows[i][j] = strconv.FormatFloat(val, 'f', -1, 64)
+					break
+				}
+
 				if val < -999999999999999 || val > 999999999999999 {
 					res.Rows[i][j] = strconv.FormatFloat(val, 'e', -1, 64)
 				}
 			case string:
+				if j < len(res.ColumnTypes) && res.ColumnTypes[j] == "INTERVAL" {
+					if interval, err := parseInterval(val); err == nil {
+						res.Rows[i][j] = interval
+					}
+					break
+				}
+
+				if moneyAsNumeric && j < len(res.ColumnTypes) && res.ColumnTypes[j] == "MONEY" {
+					if value, err := parseMoney(val); err == nil {
+						res.Rows[i][j] = value
+					}
+					break
+				}
+
+				// Json values are text, never encoded as binary data
+				if j < len(res.ColumnTypes) && (res.ColumnTypes[j] == "JSON" || res.ColumnTypes[j] == "JSONB") {
+					if prettyJSON {
+						res.Rows[i][j] = indentJSON(val)
+					}
+					break
+				}
+
 				if hasBinary(val, 8) && BinaryCodec != CodecNone {
 					res.Rows[i][j] = encodeBinaryData([]byte(val), BinaryCodec)
 				}
@@ -115,6 +196,106 @@ func (res *Result) PostProcess() {
 	}
 }
 
+// stripColumnPrefixes returns the column names without the first matching prefix.
+// Names are kept qualified when stripping would make them ambiguous.
+func stripColumnPrefixes(columns []string, prefixes []string) []string {
+	stripped := make([]string, len(columns))
+	counts := map[string]int{}
+
+	for i, name := range columns {
+		stripped[i] = name
+		for _, prefix := range prefixes {
+			if len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+				stripped[i] = name[len(prefix):]
+				break
+			}
+		}
+		counts[stripped[i]]++
+	}
+
+	for i, name := range stripped {
+		if counts[name] > 1 {
+			stripped[i] = columns[i]
+		}
+	}
+
+	return stripped
+}
+
+// indentJSON returns the indented json value, invalid values are returned as is
+func indentJSON(val string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(val), "", " "); err != nil {
+		return val
+	}
+	return buf.String()
+}
+
+// Int64Value returns the integer value of a numeric result cell. Numbers may
+// be encoded as strings by PostProcess, ie. with --numbers-as-strings.
+func Int64Value(value interface{}) (int64, error) {
+	switch val := value.(type) {
+	case int64:
+		return val, nil
+	case float64:
+		return int64(val), nil
+	case string:
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid numeric value: %q", val)
+		}
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("invalid numeric value: %v", value)
+}
+
+// TruncateColumns returns the result limited to the first max columns, flagged
+// as truncated. The result itself is not modified, so it's safe for cached ones.
+func (res *Result) TruncateColumns(max int) *Result {
+	if max <= 0 || len(res.Columns) <= max {
+		return res
+	}
+
+	truncated := *res
+	truncated.Columns = res.Columns[:max:max]
+	if len(res.ColumnTypes) > max {
+		truncated.ColumnTypes = res.ColumnTypes[:max:max]
+	}
+	truncated.ColumnsTruncated = true
+	truncated.ColumnsTotal = len(res.Columns)
+
+	truncated.Rows = make([]Row, len(res.Rows))
+	for i, row := range res.Rows {
+		if len(row) > max {
+			row = row[:max:max]
+		}
+		truncated.Rows[i] = row
+	}
+
+	return &truncated
+}
+
+// MarshalJSON makes sure columns and rows are never serialized as null, so an
+// empty result can't be mistaken for a missing one
+func (res Result) MarshalJSON() ([]byte, error) {
+	type result Result
+
+	if res.Type == "" {
+		res.Type = ResultTypeRows
+	}
+	if res.Columns == nil {
+		res.Columns = []string{}
+	}
+	if res.Rows == nil {
+		res.Rows = []Row{}
+	}
+
+	return json.Marshal(result(res))
+}
+
 func (res *Result) Format() []map[string]interface{} {
 	items := make([]map[string]interface{}, len(res.Rows))
 
@@ -131,13 +312,39 @@ func (res *Result) Format() []map[string]interface{} {
 }
 
 func (res *Result) CSV() []byte {
+	return res.delimited(',')
+}
+
+// TSV returns tab-separated values, quoting cells with tabs or line breaks the
+// way spreadsheets expect them when pasted
+func (res *Result) TSV() []byte {
+	return res.delimited('\t')
+}
+
+// WriteCSV writes the header and rows as comma-separated values into w,
+// without buffering the whole output in memory
+func (res *Result) WriteCSV(w io.Writer) error {
+	return res.writeDelimited(w, ',')
+}
+
+func (res *Result) delimited(comma rune) []byte {
 	buff := &bytes.Buffer{}
-	writer := csv.NewWriter(buff)
 
-	if err := writer.Write(res.Columns); err != nil {
+	if err := res.writeDelimited(buff, comma); err != nil {
 		log.Printf("result csv write error: %v\n", err)
 	}
 
+	return buff.Bytes()
+}
+
+func (res *Result) writeDelimited(w io.Writer, comma rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	if err := writer.Write(res.Columns); err != nil {
+		return err
+	}
+
 	for _, row := range res.Rows {
 		record := make([]string, len(res.Columns))
 
@@ -145,6 +352,8 @@ func (res *Result) CSV() []byte {
 			switch v := item.(type) {
 			case time.Time:
 				record[i] = v.Format("2006-01-02 15:04:05")
+			case []byte:
+				record[i] = string(v)
 			case nil:
 				record[i] = ""
 			default:
@@ -152,15 +361,13 @@ func (res *Result) CSV() []byte {
 			}
 		}
 
-		err := writer.Write(record)
-		if err != nil {
-			fmt.Println(err)
-			break
+		if err := writer.Write(record); err != nil {
+			return err
 		}
 	}
 
 	writer.Flush()
-	return buff.Bytes()
+	return writer.Error()
 }
 
 func (res *Result) JSON() []byte {
@@ -196,6 +403,9 @@ func ObjectsFromResult(res *Result) map[string]*Objects {
 		}
 
 		obj := Object{OID: oid, Name: name}
+		if len(row) > 6 {
+			obj.Category, _ = row[6].(string)
+		}
 
 		switch objectType {
 		case ObjTypeTable:
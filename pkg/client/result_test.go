@@ -32,6 +32,43 @@ func TestPostProcess(t *testing.T) {
 		assert.Equal(t, "9.999999999999999e+14", result.Rows[4][0])
 	})
 
+	t.Run("numbers as strings", func(t *testing.T) {
+		defer func(opts command.Options) {
+			command.Opts = opts
+		}(command.Opts)
+		command.Opts.NumbersAsStrings = true
+
+		result := Result{
+			Columns: []string{"value"},
+			Rows: []Row{
+				{int64(42)},
+				{int64(9007199254740993)},
+				{float64(0.1)},
+				{float64(999999999999999.9)},
+				{"12345678901234567890.123456789"},
+			},
+		}
+
+		result.PostProcess()
+
+		data, err := json.Marshal(result.Rows)
+		assert.NoError(t, err)
+		assert.Equal(t, `[["42"],["9007199254740993"],["0.1"],["999999999999999.9"],["12345678901234567890.123456789"]]`, string(data))
+	})
+
+	t.Run("bigint beyond float precision", func(t *testing.T) {
+		result := Result{
+			Columns: []string{"value"},
+			Rows:    []Row{{int64(9007199254740993)}, {int64(42)}},
+		}
+
+		result.PostProcess()
+
+		data, err := json.Marshal(result.Rows)
+		assert.NoError(t, err)
+		assert.Equal(t, `[["9007199254740993"],[42]]`, string(data))
+	})
+
 	t.Run("binary encoding", func(t *testing.T) {
 		result := Result{
 			Columns: []string{"data"},
@@ -48,6 +85,144 @@ func TestPostProcess(t *testing.T) {
 		assert.Equal(t, "text with symbols !@#$%", result.Rows[1][0])
 		assert.Equal(t, "CgsMDQ==", result.Rows[2][0])
 	})
+
+	t.Run("intervals", func(t *testing.T) {
+		result := Result{
+			Columns:     []string{"duration", "label"},
+			ColumnTypes: []string{"INTERVAL", "TEXT"},
+			Rows: []Row{
+				{"3 mons 4 days 05:06:07", "3 mons"},
+				{nil, "none"},
+			},
+		}
+
+		result.PostProcess()
+
+		data, err := json.Marshal(result.Rows)
+		assert.NoError(t, err)
+		assert.Equal(t, `[[{"months":3,"days":4,"microseconds":18367000000,"iso":"P3M4DT5H6M7S","text":"3 mons 4 days 05:06:07"},"3 mons"],[null,"none"]]`, string(data))
+		assert.Equal(t, "duration,label\n3 mons 4 days 05:06:07,3 mons\n,none\n", string(result.CSV()))
+	})
+
+	t.Run("money as numeric", func(t *testing.T) {
+		defer func(opts command.Options) {
+			command.Opts = opts
+		}(command.Opts)
+
+		newResult := func() Result {
+			return Result{
+				Columns:     []string{"price", "label"},
+				ColumnTypes: []string{"MONEY", "TEXT"},
+				Rows:        []Row{{"$1,234.56", "$1,234.56"}},
+			}
+		}
+
+		result := newResult()
+		result.PostProcess()
+		assert.Equal(t, Row{"$1,234.56", "$1,234.56"}, result.Rows[0])
+
+		command.Opts.MoneyAsNumeric = true
+		result = newResult()
+		result.PostProcess()
+		assert.Equal(t, Row{"1234.56", "$1,234.56"}, result.Rows[0])
+	})
+
+	t.Run("pretty json", func(t *testing.T) {
+		defer func(opts command.Options) {
+			command.Opts = opts
+		}(command.Opts)
+
+		newResult := func() Result {
+			return Result{
+				Columns:     []string{"doc", "config", "invalid", "label"},
+				ColumnTypes: []string{"JSONB", "JSON", "JSON", "TEXT"},
+				Rows:        []Row{{`{"a": {"b": [1, 2]}}`, `{"enabled":true}`, `{"a":`, `{"a":1}`}},
+			}
+		}
+
+		result := newResult()
+		result.PostProcess()
+		assert.Equal(t, Row{"{\n \"a\": {\n  \"b\": [\n   1,\n   2\n  ]\n }\n}", "{\n \"enabled\": true\n}", `{"a":`, `{"a":1}`}, result.Rows[0])
+
+		command.Opts.DisablePrettyJSON = true
+		result = newResult()
+		result.PostProcess()
+		assert.Equal(t, newResult().Rows[0], result.Rows[0])
+	})
+
+	t.Run("column prefixes", func(t *testing.T) {
+		defer func(opts command.Options) {
+			command.Opts = opts
+		}(command.Opts)
+
+		command.Opts.ColumnPrefixes = []string{"public.", "reporting."}
+
+		result := Result{
+			Columns: []string{"public.id", "reporting.total", "public.name", "reporting.name", "id2", "audit.id", "public."},
+			Rows:    []Row{{int64(1), int64(2), "a", "b", int64(3), int64(4), "c"}},
+		}
+		result.PostProcess()
+
+		assert.Equal(t, []string{"id", "total", "public.name", "reporting.name", "id2", "audit.id", "public."}, result.Columns)
+		assert.Equal(t, Row{int64(1), int64(2), "a", "b", int64(3), int64(4), "c"}, result.Rows[0])
+
+		// Stripped names must not collide with the unqualified ones either
+		result = Result{Columns: []string{"id", "public.id", "public.total"}}
+		result.PostProcess()
+		assert.Equal(t, []string{"id", "public.id", "total"}, result.Columns)
+	})
+
+	t.Run("json binary encoding", func(t *testing.T) {
+		result := Result{
+			Columns:     []string{"data", "doc"},
+			ColumnTypes: []string{"BYTEA", "JSON"},
+			Rows:        []Row{{string([]byte{10, 11, 12, 13}), string([]byte{10, 11, 12, 13})}},
+		}
+		result.PostProcess()
+
+		// Invalid json values are passed through, binary columns are still encoded
+		assert.Equal(t, Row{"CgsMDQ==", string([]byte{10, 11, 12, 13})}, result.Rows[0])
+	})
+}
+
+func TestInt64Value(t *testing.T) {
+	examples := map[interface{}]int64{
+		int64(42):             42,
+		float64(1234):         1234,
+		"9223372036854775807": 9223372036854775807,
+		"100000":              100000,
+		"1.5e+06":             1500000,
+		"999999999999999.9":   999999999999999,
+	}
+
+	for value, expected := range examples {
+		n, err := Int64Value(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, n, value)
+	}
+
+	_, err := Int64Value("abc")
+	assert.EqualError(t, err, `invalid numeric value: "abc"`)
+
+	_, err = Int64Value(nil)
+	assert.Error(t, err)
+
+	// Numbers encoded as strings by PostProcess are still readable
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.NumbersAsStrings = true
+
+	result := Result{Columns: []string{"count"}, Rows: []Row{{int64(123)}, {float64(45)}}}
+	result.PostProcess()
+
+	n, err := Int64Value(result.Rows[0][0])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), n)
+
+	n, err = Int64Value(result.Rows[1][0])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(45), n)
 }
 
 func TestCSV(t *testing.T) {
@@ -68,6 +243,49 @@ func TestCSV(t *testing.T) {
 	assert.Equal(t, expected, string(result.CSV()))
 }
 
+func TestWriteCSV(t *testing.T) {
+	result := Result{
+		Columns: []string{"id", "name", "notes"},
+		Rows: []Row{
+			{1, "Doe, John", []byte("raw")},
+			{2, "Alice \"Al\"", "multi\nline"},
+			{3, nil, time.Date(2022, 1, 1, 10, 30, 0, 0, time.UTC)},
+		},
+	}
+
+	expected := strings.Join([]string{
+		"id,name,notes",
+		"1,\"Doe, John\",raw",
+		"2,\"Alice \"\"Al\"\"\",\"multi\nline\"",
+		"3,,2022-01-01 10:30:00",
+	}, "\n") + "\n"
+
+	buff := &strings.Builder{}
+	assert.NoError(t, result.WriteCSV(buff))
+	assert.Equal(t, expected, buff.String())
+	assert.Equal(t, expected, string(result.CSV()))
+}
+
+func TestTSV(t *testing.T) {
+	result := Result{
+		Columns: []string{"id", "name", "notes"},
+		Rows: []Row{
+			{1, "John", "tab\there"},
+			{2, "Bob", "multi\nline"},
+			{3, "Alice \"Al\"", nil},
+		},
+	}
+
+	expected := strings.Join([]string{
+		"id\tname\tnotes",
+		"1\tJohn\t\"tab\there\"",
+		"2\tBob\t\"multi\nline\"",
+		"3\t\"Alice \"\"Al\"\"\"\t",
+	}, "\n") + "\n"
+
+	assert.Equal(t, expected, string(result.TSV()))
+}
+
 func TestJSON(t *testing.T) {
 	result := Result{
 		Columns: []string{"id", "name", "email"},
@@ -137,3 +355,50 @@ func TestResultFormat(t *testing.T) {
 
 	assert.Equal(t, expected, result.Format())
 }
+
+func TestResultMarshalJSON(t *testing.T) {
+	t.Run("empty result", func(t *testing.T) {
+		data, err := json.Marshal(&Result{})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type":"rows","columns":[],"rows":[]}`, string(data))
+	})
+
+	t.Run("zero rows", func(t *testing.T) {
+		data, err := json.Marshal(Result{Type: ResultTypeRows, Columns: []string{"id"}})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type":"rows","columns":["id"],"rows":[]}`, string(data))
+	})
+
+	t.Run("command", func(t *testing.T) {
+		data, err := json.Marshal(Result{Type: ResultTypeCommand})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type":"command","columns":[],"rows":[]}`, string(data))
+	})
+}
+
+func TestResultTruncateColumns(t *testing.T) {
+	result := &Result{
+		Columns:     []string{"a", "b", "c"},
+		ColumnTypes: []string{"INT4", "TEXT", "BOOL"},
+		Rows:        []Row{{1, "x", true}, {2, "y", false}},
+	}
+
+	assert.Same(t, result, result.TruncateColumns(0))
+	assert.Same(t, result, result.TruncateColumns(3))
+
+	truncated := result.TruncateColumns(2)
+	assert.Equal(t, []string{"a", "b"}, truncated.Columns)
+	assert.Equal(t, []string{"INT4", "TEXT"}, truncated.ColumnTypes)
+	assert.Equal(t, []Row{{1, "x"}, {2, "y"}}, truncated.Rows)
+	assert.True(t, truncated.ColumnsTruncated)
+	assert.Equal(t, 3, truncated.ColumnsTotal)
+
+	data, err := json.Marshal(truncated)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"rows","columns":["a","b"],"columns_truncated":true,"columns_total":3,"rows":[[1,"x"],[2,"y"]]}`, string(data))
+
+	// Original result is left intact
+	assert.Equal(t, []string{"a", "b", "c"}, result.Columns)
+	assert.Equal(t, []Row{{1, "x", true}, {2, "y", false}}, result.Rows)
+	assert.False(t, result.ColumnsTruncated)
+}
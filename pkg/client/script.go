@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrScriptEmpty = errors.New("script must contain at least one statement")
+
+// ScriptResult holds the outcome of a single statement of a script
+type ScriptResult struct {
+	Query  string  `json:"query"`
+	Result *Result `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// QueryScript runs the statements of the query one after another on the same
+// connection, so session state like settings and temporary tables carries over
+// between them. The script stops at the first failing statement, the statements
+// after it have no result.
+func (client *Client) QueryScript(parent context.Context, query string) ([]ScriptResult, error) {
+	stmts := splitStatements(query)
+	if len(stmts) == 0 {
+		return nil, ErrScriptEmpty
+	}
+	if client.db == nil {
+		return nil, ErrNotConnected
+	}
+
+	var db txConn = client.db
+	var conn queryConn = client.tx
+
+	if client.tx == nil {
+		pinned, err := client.db.Connx(parent)
+		if err != nil {
+			return nil, err
+		}
+		defer pinned.Close()
+
+		// Session state set by the script must not leak into other queries, so the
+		// connection is closed instead of being returned to the pool
+		defer pinned.Raw(func(interface{}) error { //nolint:errcheck
+			return driver.ErrBadConn
+		})
+
+		db, conn = pinned, pinned
+
+		// The name is not restored, the connection is closed anyway
+		parent, _, err = client.pinApplicationName(parent, pinned)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	// Read-only mode is set by runQueryOn on another connection from the pool,
+	// so it's set on the pinned connection too
+	if client.tx == nil && client.isReadOnlyContext(parent) {
+		if _, err := conn.ExecContext(ctx, "SET default_transaction_read_only=on;"); err != nil {
+			return nil, err
+		}
+	}
+
+	var pid int
+	if err := sqlx.GetContext(ctx, conn, &pid, "SELECT pg_backend_pid()"); err != nil {
+		return nil, err
+	}
+
+	client.setBackendPID(pid)
+	defer client.setBackendPID(0)
+
+	results := make([]ScriptResult, len(stmts))
+	for i, stmt := range stmts {
+		results[i].Query = stmt
+
+		start := time.Now()
+		res, err := client.withSelectReadOnly(parent, db, stmt, func(conn queryConn) (*Result, error) {
+			return client.runQueryOn(parent, conn, stmt)
+		})
+		logQuery(parent, stmt, start, resultRowsCount(res), err)
+		if err != nil {
+			results[i].Error = err.Error()
+			return results[:i+1], nil
+		}
+		results[i].Result = res
+	}
+
+	client.addHistoryRecord(query)
+
+	return results, nil
+}
+
+// splitStatements returns the statements of the query separated by semicolons,
+// ignoring the ones within comments and literals. Statements with only comments are skipped.
+func splitStatements(query string) []string {
+	blank := blankCommentsAndLiterals(query)
+	stmts := []string{}
+
+	start := 0
+	for i := 0; i <= len(blank); i++ {
+		if i < len(blank) && blank[i] != ';' {
+			continue
+		}
+
+		if strings.TrimSpace(blank[start:i]) != "" {
+			stmts = append(stmts, strings.TrimSpace(query[start:i]))
+		}
+		start = i + 1
+	}
+
+	return stmts
+}
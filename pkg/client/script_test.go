@@ -0,0 +1,28 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	examples := []struct {
+		query    string
+		expected []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;\n SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"SELECT ';'; SELECT 2", []string{"SELECT ';'", "SELECT 2"}},
+		{"SELECT 1; -- a;b\nSELECT 2", []string{"SELECT 1", "-- a;b\nSELECT 2"}},
+		{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT f()", []string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", "SELECT f()"}},
+		{"SELECT 1; /* done */ ;", []string{"SELECT 1"}},
+		{";;", []string{}},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.query, func(t *testing.T) {
+			assert.Equal(t, ex.expected, splitStatements(ex.query))
+		})
+	}
+}
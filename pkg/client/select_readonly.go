@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/flowbi/pgweb/pkg/command"
+)
+
+// Statements returning rows, writes can still hide in CTEs and functions they call
+var reSelectLikeStatement = regexp.MustCompile(`(?is)^[\s(]*(?:SELECT|WITH|VALUES|TABLE)\b`)
+
+// isSelectQuery returns true if all statements of the query are SELECT-like
+func isSelectQuery(query string) bool {
+	stmts := splitStatements(query)
+	if len(stmts) == 0 {
+		return false
+	}
+
+	for _, stmt := range stmts {
+		if !reSelectLikeStatement.MatchString(blankCommentsAndLiterals(stmt)) {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapsSelectReadOnly returns true if the query is run in a read-only transaction
+// with the --wrap-select-readonly option. Queries of an open transaction run in it.
+func (client *Client) wrapsSelectReadOnly(query string) bool {
+	return command.Opts.WrapSelectReadOnly && client.tx == nil && isSelectQuery(query)
+}
+
+// withSelectReadOnly runs fn like withWorkMem, except SELECT queries are run in a
+// read-only transaction with the --wrap-select-readonly option, so a write hidden
+// in them fails instead of being committed.
+func (client *Client) withSelectReadOnly(parent context.Context, db txConn, query string, fn func(conn queryConn) (*Result, error)) (*Result, error) {
+	if !client.wrapsSelectReadOnly(query) {
+		return client.withWorkMem(parent, db, query, fn)
+	}
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if workMem := workMemFrom(parent); workMem != "" {
+		if _, err := tx.ExecContext(ctx, workMemStatement(workMem)); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := fn(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, tx.Commit()
+}
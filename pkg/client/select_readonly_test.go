@@ -0,0 +1,30 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSelectQuery(t *testing.T) {
+	examples := map[string]bool{
+		"SELECT 1":                      true,
+		"  (select 1) UNION (select 2)": true,
+		"WITH moved AS (DELETE FROM books RETURNING *) SELECT * FROM moved": true,
+		"VALUES (1), (2)":                      true,
+		"TABLE books":                          true,
+		"/* report */ SELECT 1; SELECT 2;":     true,
+		"SELECT 1; DELETE FROM books":          false,
+		"DELETE FROM books":                    false,
+		"-- SELECT 1\nUPDATE books SET id = 1": false,
+		"EXPLAIN SELECT 1":                     false,
+		"SELECTED":                             false,
+		"":                                     false,
+	}
+
+	for query, expected := range examples {
+		t.Run(query, func(t *testing.T) {
+			assert.Equal(t, expected, isSelectQuery(query))
+		})
+	}
+}
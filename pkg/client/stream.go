@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Number of rows delivered in a single streamed batch unless configured otherwise
+const defaultStreamBatchSize = 100
+
+type (
+	// Notice is a message raised by the server while running a query
+	Notice struct {
+		Severity string `json:"severity"`
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		Detail   string `json:"detail,omitempty"`
+		Hint     string `json:"hint,omitempty"`
+	}
+
+	// QueryStream receives the output of a streamed query as it is produced
+	QueryStream struct {
+		BatchSize int                          // Number of rows per batch
+		OnColumns func(columns []string) error // Called once the result columns are known
+		OnRows    func(rows []Row) error       // Called for every batch of rows
+		OnNotice  func(notice Notice)          // Called for every server notice, optional
+	}
+
+	// PartialStreamError is returned when a streamed query fails after some of
+	// its rows were already delivered
+	PartialStreamError struct {
+		RowsCount int   // Number of rows delivered before the error
+		Err       error // Error the query failed with
+	}
+)
+
+func (e *PartialStreamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialStreamError) Unwrap() error {
+	return e.Err
+}
+
+// Stream runs the query and delivers its rows in batches instead of buffering the
+// whole result. The query is aborted once the context is cancelled or a callback
+// returns an error. Notices are only captured outside of transactions.
+func (client *Client) Stream(parent context.Context, query string, stream QueryStream) (stats *ResultStats, err error) {
+	if client.db == nil {
+		return nil, ErrNotConnected
+	}
+
+	start := time.Now()
+	defer func() {
+		rowsCount := 0
+		var partial *PartialStreamError
+		if stats != nil {
+			rowsCount = stats.RowsCount
+		} else if errors.As(err, &partial) {
+			rowsCount = partial.RowsCount
+		}
+		logQuery(parent, query, start, rowsCount, err)
+	}()
+
+	if stream.BatchSize <= 0 {
+		stream.BatchSize = defaultStreamBatchSize
+	}
+
+	defer client.BeginQuery()()
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	readOnly := ReadOnlyFromContext(parent)
+	if client.tx != nil && readOnly && !client.txReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	var conn queryConn = client.tx
+	var selectTx *sqlx.Tx // Read-only transaction of the --wrap-select-readonly option or read-only requests
+	if client.tx == nil {
+		// Pin a connection so that session settings and the notice handler apply to the query
+		dbConn, err := client.db.Connx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer dbConn.Close()
+
+		if stream.OnNotice != nil {
+			setNoticeHandler := func(handler func(*pq.Error)) {
+				dbConn.Raw(func(driverConn interface{}) error { //nolint:errcheck
+					pq.SetNoticeHandler(driverConn.(driver.Conn), handler)
+					return nil
+				})
+			}
+			setNoticeHandler(func(err *pq.Error) {
+				stream.OnNotice(Notice{
+					Severity: err.Severity,
+					Code:     string(err.Code),
+					Message:  err.Message,
+					Detail:   err.Detail,
+					Hint:     err.Hint,
+				})
+			})
+			defer setNoticeHandler(nil)
+		}
+
+		var restore func()
+		ctx, restore, err = client.pinApplicationName(ctx, dbConn)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+
+		conn = dbConn
+
+		if client.wrapsSelectReadOnly(query) || readOnly {
+			tx, err := dbConn.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+			if err != nil {
+				return nil, err
+			}
+			defer tx.Rollback() //nolint:errcheck
+
+			conn, selectTx = tx, tx
+		}
+	}
+
+	// Execute SET ROLE as a separate command if specified via X-Database-Role header
+	if client.defaultRole != "" {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`SET ROLE "%s"`, client.defaultRole)); err != nil {
+			return nil, fmt.Errorf("failed to set role %s: %w", client.defaultRole, err)
+		}
+	}
+
+	if err := client.setApplicationName(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	if err := client.checkReadOnlySchemasOn(ctx, conn, query); err != nil {
+		return nil, err
+	}
+
+	if client.isReadOnlyContext(parent) {
+		if err := checkReadOnlyQuery(query); err != nil {
+			return nil, err
+		}
+	}
+	if client.isReadOnly() {
+		if _, err := conn.ExecContext(ctx, "SET default_transaction_read_only=on;"); err != nil {
+			return nil, err
+		}
+	}
+
+	stats, err = client.streamRows(ctx, conn, query, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if selectTx != nil {
+		if err := selectTx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	client.invalidateTableCache(query)
+
+	client.addHistoryRecord(query)
+
+	return stats, nil
+}
+
+// QueryStream runs the query and writes its result to w as newline-delimited JSON:
+// the column names on the first line, followed by one array of values per row.
+// Rows are written in batches, flushing w after each one if supported. When the
+// query fails after some rows were written, a *PartialStreamError is returned.
+func (client *Client) QueryStream(query string, w io.Writer) error {
+	return client.QueryStreamContext(context.Background(), query, w)
+}
+
+// QueryStreamContext is like QueryStream but the query is cancelled along with the context
+func (client *Client) QueryStreamContext(ctx context.Context, query string, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(interface{ Flush() })
+
+	_, err := client.Stream(ctx, query, QueryStream{
+		OnColumns: func(columns []string) error {
+			return encoder.Encode(columns)
+		},
+		OnRows: func(rows []Row) error {
+			for _, row := range rows {
+				if err := encoder.Encode(row); err != nil {
+					return err
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		},
+	})
+
+	return err
+}
+
+func (client *Client) streamRows(ctx context.Context, conn queryConn, query string, stream QueryStream) (*ResultStats, error) {
+	action := strings.ToLower(strings.Split(query, " ")[0])
+	hasReturnValues := hasReturning(query)
+
+	queryStart := time.Now()
+
+	if (action == "update" || action == "delete") && !hasReturnValues {
+		res, err := conn.ExecContext(ctx, client.tagQuery(ctx, conn, query, nil))
+		if err != nil {
+			return nil, err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		queryFinish := time.Now()
+
+		if err := stream.OnColumns([]string{"Rows Affected"}); err != nil {
+			return nil, err
+		}
+		if err := stream.OnRows([]Row{{affected}}); err != nil {
+			return nil, err
+		}
+
+		return &ResultStats{
+			ColumnsCount:    1,
+			RowsCount:       1,
+			RowsAffected:    affected,
+			QueryStartTime:  queryStart.UTC(),
+			QueryFinishTime: queryFinish.UTC(),
+			QueryDuration:   queryFinish.Sub(queryStart).Milliseconds(),
+		}, nil
+	}
+
+	rows, err := conn.QueryxContext(ctx, client.tagQuery(ctx, conn, query, nil))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure to never return null columns
+	if cols == nil {
+		cols = []string{}
+	}
+
+	if err := stream.OnColumns(cols); err != nil {
+		return nil, err
+	}
+
+	count := 0
+	batch := &Result{Columns: cols, ColumnTypes: columnTypeNames(rows), Rows: []Row{}}
+
+	flush := func() error {
+		if len(batch.Rows) == 0 {
+			return nil
+		}
+
+		batch.PostProcess()
+		if err := stream.OnRows(batch.Rows); err != nil {
+			return err
+		}
+
+		count += len(batch.Rows)
+		batch.Rows = []Row{}
+		return nil
+	}
+
+	// Rows delivered before an error are valid, so the error tells how many there were
+	partial := func(err error) error {
+		if count > 0 {
+			return &PartialStreamError{RowsCount: count, Err: err}
+		}
+		return err
+	}
+
+	for rows.Next() {
+		obj, err := rows.SliceScan()
+		if err != nil {
+			return nil, partial(err)
+		}
+
+		for i, item := range obj {
+			if item != nil && reflect.TypeOf(item).Kind() == reflect.Slice {
+				obj[i] = decodeTextData(item.([]byte))
+			}
+		}
+
+		batch.Rows = append(batch.Rows, obj)
+		if len(batch.Rows) >= stream.BatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if flushErr := flush(); flushErr != nil {
+			return nil, partial(flushErr)
+		}
+		return nil, partial(err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	queryFinish := time.Now()
+
+	return &ResultStats{
+		ColumnsCount:    len(cols),
+		RowsCount:       count,
+		QueryStartTime:  queryStart.UTC(),
+		QueryFinishTime: queryFinish.UTC(),
+		QueryDuration:   queryFinish.Sub(queryStart).Milliseconds(),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/statements"
+)
+
+const (
+	// Allowed range of per-column statistics targets
+	minStatisticsTarget = -1
+	maxStatisticsTarget = 10000
+)
+
+var (
+	// Storage parameters that could be changed with ALTER TABLE ... SET
+	storageParams = map[string]bool{
+		"fillfactor":                            true,
+		"parallel_workers":                      true,
+		"toast_tuple_target":                    true,
+		"vacuum_index_cleanup":                  true,
+		"vacuum_truncate":                       true,
+		"log_autovacuum_min_duration":           true,
+		"autovacuum_enabled":                    true,
+		"autovacuum_vacuum_threshold":           true,
+		"autovacuum_vacuum_scale_factor":        true,
+		"autovacuum_vacuum_insert_threshold":    true,
+		"autovacuum_vacuum_insert_scale_factor": true,
+		"autovacuum_analyze_threshold":          true,
+		"autovacuum_analyze_scale_factor":       true,
+		"autovacuum_vacuum_cost_delay":          true,
+		"autovacuum_vacuum_cost_limit":          true,
+		"autovacuum_freeze_min_age":             true,
+		"autovacuum_freeze_max_age":             true,
+		"autovacuum_freeze_table_age":           true,
+		"toast.autovacuum_enabled":              true,
+		"toast.autovacuum_vacuum_threshold":     true,
+		"toast.autovacuum_vacuum_scale_factor":  true,
+	}
+
+	// Storage parameter values are numbers, booleans or enum keywords
+	reStorageParamValue = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)
+)
+
+// TableStorageParams returns the storage parameters set on the table
+func (client *Client) TableStorageParams(table string) (*Result, error) {
+	schema, tableName := getSchemaAndTable(table)
+	return client.query(statements.TableStorageParams, schema, tableName)
+}
+
+// SetStorageParam changes a storage parameter of the table
+func (client *Client) SetStorageParam(table, key, value string) (*Result, error) {
+	if client.isReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	if err := validateStorageParam(key, value); err != nil {
+		return nil, err
+	}
+
+	schema, tableName := getSchemaAndTable(table)
+	sql := fmt.Sprintf("ALTER TABLE %s.%s SET (%s = %s)", quoteIdentifier(schema), quoteIdentifier(tableName), key, value)
+
+	return client.exec(sql)
+}
+
+// SetColumnStatistics changes the statistics target of the table column.
+// Target of -1 reverts the column to the system default statistics target.
+func (client *Client) SetColumnStatistics(table, column string, target int) (*Result, error) {
+	if client.isReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	if target < minStatisticsTarget || target > maxStatisticsTarget {
+		return nil, fmt.Errorf("statistics target must be between %d and %d", minStatisticsTarget, maxStatisticsTarget)
+	}
+
+	tableSchema, err := client.Table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, row := range tableSchema.Rows {
+		if row[0] == column {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("column %q does not exist", column)
+	}
+
+	schema, tableName := getSchemaAndTable(table)
+	sql := fmt.Sprintf(
+		"ALTER TABLE %s.%s ALTER COLUMN %s SET STATISTICS %d",
+		quoteIdentifier(schema), quoteIdentifier(tableName), quoteIdentifier(column), target,
+	)
+
+	// Column statistics are part of the table schema, drop its cached copy
+	if MetadataCache != nil {
+		MetadataCache.Delete(client.generateMetadataCacheKey("table", schema, tableName))
+	}
+
+	return client.exec(sql)
+}
+
+func (client *Client) isReadOnly() bool {
+	return command.Opts.ReadOnly || client.readonly
+}
+
+func validateStorageParam(key, value string) error {
+	if !storageParams[key] {
+		return fmt.Errorf("unsupported storage parameter: %s", key)
+	}
+	if !reStorageParamValue.MatchString(value) {
+		return fmt.Errorf("invalid value for storage parameter %s: %q", key, value)
+	}
+	return nil
+}
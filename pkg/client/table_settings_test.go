@@ -0,0 +1,44 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStorageParam(t *testing.T) {
+	assert.NoError(t, validateStorageParam("fillfactor", "70"))
+	assert.NoError(t, validateStorageParam("autovacuum_enabled", "false"))
+	assert.NoError(t, validateStorageParam("autovacuum_vacuum_scale_factor", "0.05"))
+	assert.NoError(t, validateStorageParam("vacuum_index_cleanup", "auto"))
+
+	assert.EqualError(t, validateStorageParam("oids", "true"), "unsupported storage parameter: oids")
+	assert.EqualError(t, validateStorageParam("fillfactor", "70); DROP TABLE books; --"), `invalid value for storage parameter fillfactor: "70); DROP TABLE books; --"`)
+	assert.Error(t, validateStorageParam("fillfactor", ""))
+}
+
+func TestSetStorageParamReadOnly(t *testing.T) {
+	client := &Client{readonly: true}
+
+	res, err := client.SetStorageParam("books", "fillfactor", "70")
+	assert.Equal(t, ErrReadOnly, err)
+	assert.Nil(t, res)
+}
+
+func TestSetColumnStatisticsReadOnly(t *testing.T) {
+	client := &Client{readonly: true}
+
+	res, err := client.SetColumnStatistics("books", "title", 500)
+	assert.Equal(t, ErrReadOnly, err)
+	assert.Nil(t, res)
+}
+
+func TestSetColumnStatisticsRange(t *testing.T) {
+	client := &Client{}
+
+	_, err := client.SetColumnStatistics("books", "title", 10001)
+	assert.EqualError(t, err, "statistics target must be between -1 and 10000")
+
+	_, err = client.SetColumnStatistics("books", "title", -2)
+	assert.EqualError(t, err, "statistics target must be between -1 and 10000")
+}
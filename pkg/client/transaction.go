@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNoTransaction       = errors.New("no active transaction")
+	ErrTransactionActive   = errors.New("transaction is already active")
+	ErrInvalidSavepoint    = errors.New("invalid savepoint name")
+	ErrSavepointNotFound   = errors.New("savepoint does not exist")
+	ErrSavepointNameExists = errors.New("savepoint already exists")
+
+	// Savepoint names are interpolated into SQL, so only plain identifiers are allowed
+	reSavepointName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// queryConn runs queries on the connection pool, a pinned connection or a transaction
+type queryConn interface {
+	sqlx.QueryerContext
+	sqlx.ExecerContext
+}
+
+// txConn is a queryConn able to start transactions, ie. the pool or a pinned connection
+type txConn interface {
+	queryConn
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// conn returns the handle queries should run on: the open transaction if there
+// is one, otherwise the connection pool.
+func (client *Client) conn() sqlx.ExtContext {
+	if client.tx != nil {
+		return client.tx
+	}
+	return client.db
+}
+
+// InTransaction returns true if the client has an open transaction
+func (client *Client) InTransaction() bool {
+	return client.tx != nil
+}
+
+// Savepoints returns the current savepoint stack, oldest first
+func (client *Client) Savepoints() []string {
+	result := make([]string, len(client.savepoints))
+	copy(result, client.savepoints)
+	return result
+}
+
+// BeginTransaction starts a transaction that all subsequent queries will run in
+func (client *Client) BeginTransaction() error {
+	return client.BeginTransactionContext(context.Background())
+}
+
+// BeginTransactionContext starts a transaction like BeginTransaction, read-only if
+// the context is. The transaction outlives the context.
+func (client *Client) BeginTransactionContext(ctx context.Context) error {
+	if client.tx != nil {
+		return ErrTransactionActive
+	}
+
+	opts := &sql.TxOptions{
+		ReadOnly: client.isReadOnlyContext(ctx),
+	}
+
+	tx, err := client.db.BeginTxx(context.WithoutCancel(ctx), opts)
+	if err != nil {
+		return err
+	}
+
+	client.tx = tx
+	client.txReadOnly = opts.ReadOnly
+	client.savepoints = nil
+	return nil
+}
+
+// CommitTransaction commits the open transaction
+func (client *Client) CommitTransaction() error {
+	if client.tx == nil {
+		return ErrNoTransaction
+	}
+	defer client.resetTransaction()
+
+	return client.tx.Commit()
+}
+
+// RollbackTransaction aborts the open transaction
+func (client *Client) RollbackTransaction() error {
+	if client.tx == nil {
+		return ErrNoTransaction
+	}
+	defer client.resetTransaction()
+
+	return client.tx.Rollback()
+}
+
+// Savepoint creates a new named savepoint within the open transaction
+func (client *Client) Savepoint(name string) error {
+	if err := client.checkSavepointName(name); err != nil {
+		return err
+	}
+	if client.savepointIndex(name) >= 0 {
+		return ErrSavepointNameExists
+	}
+
+	if err := client.execSavepoint("SAVEPOINT %s", name); err != nil {
+		return err
+	}
+
+	client.savepoints = append(client.savepoints, name)
+	return nil
+}
+
+// ReleaseSavepoint destroys the savepoint and all savepoints created after it,
+// keeping the changes made since
+func (client *Client) ReleaseSavepoint(name string) error {
+	idx, err := client.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+
+	if err := client.execSavepoint("RELEASE SAVEPOINT %s", name); err != nil {
+		return err
+	}
+
+	client.savepoints = client.savepoints[:idx]
+	return nil
+}
+
+// RollbackToSavepoint discards all changes made after the savepoint was created.
+// The savepoint itself remains active, later savepoints are destroyed.
+func (client *Client) RollbackToSavepoint(name string) error {
+	idx, err := client.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+
+	if err := client.execSavepoint("ROLLBACK TO SAVEPOINT %s", name); err != nil {
+		return err
+	}
+
+	// Settings made since the savepoint are rolled back too
+	client.txApplicationName = ""
+	client.savepoints = client.savepoints[:idx+1]
+	return nil
+}
+
+func (client *Client) findSavepoint(name string) (int, error) {
+	if err := client.checkSavepointName(name); err != nil {
+		return -1, err
+	}
+
+	idx := client.savepointIndex(name)
+	if idx < 0 {
+		return -1, ErrSavepointNotFound
+	}
+
+	return idx, nil
+}
+
+func (client *Client) checkSavepointName(name string) error {
+	if client.tx == nil {
+		return ErrNoTransaction
+	}
+	if !isValidSavepointName(name) {
+		return ErrInvalidSavepoint
+	}
+	return nil
+}
+
+func (client *Client) savepointIndex(name string) int {
+	for i, sp := range client.savepoints {
+		if sp == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (client *Client) execSavepoint(format string, name string) error {
+	ctx, cancel := client.context()
+	defer cancel()
+
+	_, err := client.tx.ExecContext(ctx, fmt.Sprintf(format, name))
+	return err
+}
+
+func (client *Client) resetTransaction() {
+	client.tx = nil
+	client.txReadOnly = false
+	client.txApplicationName = ""
+	client.savepoints = nil
+}
+
+// isValidSavepointName validates that the savepoint name is safe to use in SQL
+func isValidSavepointName(name string) bool {
+	return reSavepointName.MatchString(name) && len(name) <= 63
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidSavepointName(t *testing.T) {
+	examples := map[string]bool{
+		"":                      false,
+		"sp1":                   true,
+		"_before_update":        true,
+		"Before_Update_2":       true,
+		"1sp":                   false,
+		"sp 1":                  false,
+		"sp1; DROP TABLE":       false,
+		`"quoted"`:              false,
+		"sp-1":                  false,
+		strings.Repeat("a", 63): true,
+		strings.Repeat("a", 64): false,
+	}
+
+	for name, expected := range examples {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, isValidSavepointName(name))
+		})
+	}
+}
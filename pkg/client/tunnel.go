@@ -15,7 +15,10 @@ import (
 
 	"github.com/ScaleFT/sshkeys"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
 
+	"github.com/flowbi/pgweb/pkg/command"
 	"github.com/flowbi/pgweb/pkg/connection"
 	"github.com/flowbi/pgweb/pkg/shared"
 )
@@ -40,6 +43,10 @@ func defaultKeyPath() string {
 	return filepath.Join(os.Getenv("HOME"), ".ssh/id_rsa")
 }
 
+func defaultKnownHostsPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".ssh/known_hosts")
+}
+
 func expandKeyPath(path string) string {
 	home := os.Getenv("HOME")
 	if home == "" {
@@ -70,8 +77,26 @@ func parsePrivateKey(keyPath string, keyPass string) (ssh.Signer, error) {
 	return signer, err
 }
 
-func makeConfig(info *shared.SSHInfo) (*ssh.ClientConfig, error) {
+// dialAgent connects to the ssh-agent of the SSH_AUTH_SOCK socket, if any
+func dialAgent() net.Conn {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		log.Println("Can't connect to ssh-agent:", err)
+		return nil
+	}
+	return conn
+}
+
+// makeConfig returns the tunnel client config. The keys of the ssh-agent on agentConn
+// are offered after the key file, the default key is optional when other methods are available.
+func makeConfig(info *shared.SSHInfo, agentConn net.Conn) (*ssh.ClientConfig, error) {
 	methods := []ssh.AuthMethod{}
+	signers := []ssh.Signer{}
 
 	// Try to use user-provided key, fallback to system default key
 	keyPath := info.Key
@@ -80,35 +105,99 @@ func makeConfig(info *shared.SSHInfo) (*ssh.ClientConfig, error) {
 	} else {
 		keyPath = expandKeyPath(keyPath)
 	}
+	keyOptional := info.Key == "" && (agentConn != nil || info.Password != "")
 
-	if !fileExists(keyPath) {
+	if fileExists(keyPath) {
+		key, err := parsePrivateKey(keyPath, info.KeyPassword)
+		if err != nil && !keyOptional {
+			return nil, err
+		}
+		if err == nil {
+			signers = append(signers, key)
+		}
+	} else if !keyOptional {
 		return nil, fmt.Errorf("ssh public key not found at path %q", keyPath)
 	}
 
-	// Append public key authentication method
-	key, err := parsePrivateKey(keyPath, info.KeyPassword)
-	if err != nil {
-		return nil, err
+	// Append public key authentication method, a single one since the methods
+	// of the same type are only tried once
+	var agentClient agent.ExtendedAgent
+	if agentConn != nil {
+		agentClient = agent.NewClient(agentConn)
 	}
-	methods = append(methods, ssh.PublicKeys(key))
+	methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		if agentClient == nil {
+			return signers, nil
+		}
+
+		agentSigners, err := agentClient.Signers()
+		if err != nil {
+			log.Println("Can't list ssh-agent keys:", err)
+			return signers, nil
+		}
+		return append(signers, agentSigners...), nil
+	}))
 
 	// Append password authentication method
 	if info.Password != "" {
 		methods = append(methods, ssh.Password(info.Password))
 	}
 
+	hostKeyCallback, err := makeHostKeyCallback(info)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &ssh.ClientConfig{
-		User:    info.User,
-		Auth:    methods,
-		Timeout: time.Second * 10,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
+		User:            info.User,
+		Auth:            methods,
+		Timeout:         time.Second * 10,
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	return cfg, nil
 }
 
+// makeHostKeyCallback returns the check of the server host key against the known_hosts
+// file, unless it's explicitly disabled with --ssh-insecure-ignore-host-key
+func makeHostKeyCallback(info *shared.SSHInfo) (ssh.HostKeyCallback, error) {
+	if command.Opts.SSHInsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := info.KnownHosts
+	if path == "" {
+		path = command.Opts.SSHKnownHosts
+	}
+	if path == "" {
+		path = defaultKnownHostsPath()
+	} else {
+		path = expandKeyPath(path)
+	}
+
+	if !fileExists(path) {
+		return nil, fmt.Errorf("ssh known_hosts file not found at path %q", path)
+	}
+
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return fmt.Errorf("ssh host key of %s is unknown, add it to %q", hostname, path)
+			}
+			return fmt.Errorf("ssh host key of %s does not match the one in %q", hostname, path)
+		}
+		return err
+	}, nil
+}
+
 func (tunnel *Tunnel) sshEndpoint() string {
 	return fmt.Sprintf("%s:%v", tunnel.SSHInfo.Host, tunnel.SSHInfo.Port)
 }
@@ -153,7 +242,13 @@ func (tunnel *Tunnel) Close() {
 
 // Configure establishes the tunnel between localhost and remote machine
 func (tunnel *Tunnel) Configure() error {
-	config, err := makeConfig(tunnel.SSHInfo)
+	// The agent is only needed during the authentication
+	agentConn := dialAgent()
+	if agentConn != nil {
+		defer agentConn.Close()
+	}
+
+	config, err := makeConfig(tunnel.SSHInfo, agentConn)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/shared"
+)
+
+func generateSSHKey(t *testing.T) (ed25519.PrivateKey, ssh.Signer) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	return key, signer
+}
+
+// startSSHServer starts a server accepting the user key and password, and returns its address and host key
+func startSSHServer(t *testing.T, userKey ssh.PublicKey, password string) (string, ssh.PublicKey) {
+	_, hostKey := generateSSHKey(t)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if userKey != nil && bytes.Equal(key.Marshal(), userKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if password != "" && string(pass) == password {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				go func() {
+					for ch := range chans {
+						ch.Reject(ssh.Prohibited, "no channels") //nolint:errcheck
+					}
+				}()
+				serverConn.Wait() //nolint:errcheck
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), hostKey.PublicKey()
+}
+
+func writeKnownHosts(t *testing.T, addr string, key ssh.PublicKey) string {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(path, []byte(knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)+"\n"), 0600))
+	return path
+}
+
+func writePrivateKey(t *testing.T, key ed25519.PrivateKey, passphrase string) string {
+	var block *pem.Block
+	var err error
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	}
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+	return path
+}
+
+func startAgent(t *testing.T, key ed25519.PrivateKey) string {
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+
+	dir, err := os.MkdirTemp("", "agent")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	sock := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				agent.ServeAgent(keyring, conn) //nolint:errcheck
+			}()
+		}
+	}()
+
+	return sock
+}
+
+func dialSSH(addr string, info *shared.SSHInfo, agentConn net.Conn) error {
+	config, err := makeConfig(info, agentConn)
+	if err != nil {
+		return err
+	}
+
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+func TestTunnelAuth(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts = command.Options{}
+
+	// No default key in the home directory
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	userKey, userSigner := generateSSHKey(t)
+	addr, hostKey := startSSHServer(t, userSigner.PublicKey(), "ssh-password")
+	knownHosts := writeKnownHosts(t, addr, hostKey)
+
+	t.Run("key", func(t *testing.T) {
+		info := &shared.SSHInfo{User: "pgweb", Key: writePrivateKey(t, userKey, ""), KnownHosts: knownHosts}
+		assert.NoError(t, dialSSH(addr, info, nil))
+	})
+
+	t.Run("key with passphrase", func(t *testing.T) {
+		info := &shared.SSHInfo{User: "pgweb", Key: writePrivateKey(t, userKey, "secret"), KnownHosts: knownHosts}
+		assert.EqualError(t, dialSSH(addr, info, nil), "ssh key password is not provided")
+
+		info.KeyPassword = "secret"
+		assert.NoError(t, dialSSH(addr, info, nil))
+	})
+
+	t.Run("password", func(t *testing.T) {
+		info := &shared.SSHInfo{User: "pgweb", Password: "ssh-password", KnownHosts: knownHosts}
+		assert.NoError(t, dialSSH(addr, info, nil))
+	})
+
+	t.Run("agent", func(t *testing.T) {
+		t.Setenv("SSH_AUTH_SOCK", startAgent(t, userKey))
+
+		agentConn := dialAgent()
+		require.NotNil(t, agentConn)
+		defer agentConn.Close()
+
+		info := &shared.SSHInfo{User: "pgweb", KnownHosts: knownHosts}
+		assert.NoError(t, dialSSH(addr, info, agentConn))
+	})
+
+	t.Run("no auth methods", func(t *testing.T) {
+		info := &shared.SSHInfo{User: "pgweb", KnownHosts: knownHosts}
+		err := dialSSH(addr, info, nil)
+		assert.EqualError(t, err, "ssh public key not found at path \""+defaultKeyPath()+"\"")
+	})
+}
+
+func TestTunnelHostKey(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts = command.Options{}
+
+	t.Setenv("HOME", t.TempDir())
+
+	addr, hostKey := startSSHServer(t, nil, "ssh-password")
+	_, otherKey := generateSSHKey(t)
+
+	info := func(knownHosts string) *shared.SSHInfo {
+		return &shared.SSHInfo{User: "pgweb", Password: "ssh-password", KnownHosts: knownHosts}
+	}
+
+	t.Run("known", func(t *testing.T) {
+		assert.NoError(t, dialSSH(addr, info(writeKnownHosts(t, addr, hostKey)), nil))
+	})
+
+	t.Run("global known hosts", func(t *testing.T) {
+		command.Opts.SSHKnownHosts = writeKnownHosts(t, addr, hostKey)
+		defer func() { command.Opts.SSHKnownHosts = "" }()
+
+		assert.NoError(t, dialSSH(addr, info(""), nil))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		path := writeKnownHosts(t, "example.com:22", hostKey)
+		err := dialSSH(addr, info(path), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ssh host key of "+addr+" is unknown")
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		path := writeKnownHosts(t, addr, otherKey.PublicKey())
+		err := dialSSH(addr, info(path), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ssh host key of "+addr+" does not match")
+	})
+
+	t.Run("missing known hosts", func(t *testing.T) {
+		err := dialSSH(addr, info(""), nil)
+		assert.EqualError(t, err, "ssh known_hosts file not found at path \""+defaultKnownHostsPath()+"\"")
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		command.Opts.SSHInsecureIgnoreHostKey = true
+		defer func() { command.Opts.SSHInsecureIgnoreHostKey = false }()
+
+		assert.NoError(t, dialSSH(addr, info(""), nil))
+	})
+}
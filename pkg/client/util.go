@@ -1,19 +1,68 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/statements"
+)
+
+const (
+	// Maximum number of pivoted columns returned by crosstab
+	maxCrosstabCategories = 100
+
+	// Maximum number of buckets in a column histogram
+	maxHistogramBuckets = 1000
 )
 
 var (
+	// Aggregate functions allowed in group by summaries
+	allowedAggregations = map[string]bool{
+		"count": true,
+		"sum":   true,
+		"avg":   true,
+		"min":   true,
+		"max":   true,
+	}
+
+	// Column types supported by histograms, mapped to the expression converting
+	// a bucket bound back from the float8 value the buckets are computed on
+	histogramTypes = map[string]string{
+		"smallint":                    "%s",
+		"integer":                     "%s",
+		"bigint":                      "%s",
+		"numeric":                     "%s",
+		"real":                        "%s",
+		"double precision":            "%s",
+		"date":                        "to_timestamp(%s) AT TIME ZONE 'UTC'",
+		"timestamp without time zone": "to_timestamp(%s) AT TIME ZONE 'UTC'",
+		"timestamp with time zone":    "to_timestamp(%s)",
+	}
+
 	// List of keywords that are not allowed in read-only mode
 	reRestrictedKeywords = regexp.MustCompile(`(?mi)\s?(CREATE|INSERT|UPDATE|DROP|DELETE|TRUNCATE|GRANT|OPEN|IMPORT|COPY)\s`)
 
+	// Single call of a function or procedure, matched against the query with blanked
+	// literals so the arguments can only be constants
+	reFunctionCall = regexp.MustCompile(`(?is)^\s*(?:SELECT\s+(?:\*\s+FROM\s+)?|CALL\s+)((?:[a-z_][\w$]*\.)?[a-z_][\w$]*)\s*\(([\s\w.,+\-:$]*)\)\s*;?\s*$`)
+	reArgumentWord = regexp.MustCompile(`(?i)[a-z_][\w$]*`)
+
+	// RETURNING clause of a data modifying statement
+	reReturning = regexp.MustCompile(`(?i)\bRETURNING\b`)
+
 	// Comment regular expressions
 	reSlashComment = regexp.MustCompile(`(?m)/\*.+\*/`)
 	reDashComment  = regexp.MustCompile(`(?m)--.+`)
 
+	// Characters escaped in LIKE patterns
+	reLikeSpecialChars = regexp.MustCompile(`[\\%_]`)
+
 	// Postgres version signature
 	postgresSignature     = regexp.MustCompile(`(?i)postgresql ([\d\.]+)\s?`)
 	postgresDumpSignature = regexp.MustCompile(`\s([\d\.]+)\s?`)
@@ -22,6 +71,10 @@ var (
 	// Cockroach version signature
 	cockroachSignature = regexp.MustCompile(`(?i)cockroachdb ccl v([\d\.]+)\s?`)
 	cockroachType      = "CockroachDB"
+
+	// Redshift version signature, it also reports a PostgreSQL 8.0 version
+	redshiftSignature = regexp.MustCompile(`(?i)redshift ([\d\.]+)\s?`)
+	redshiftType      = "Redshift"
 )
 
 // Get major and minor version components
@@ -45,8 +98,14 @@ func getMajorMinorVersionString(str string) string {
 func detectServerTypeAndVersion(version string) (bool, string, string) {
 	version = strings.TrimSpace(version)
 
+	// Detect redshift before postgresql
+	matches := redshiftSignature.FindAllStringSubmatch(version, 1)
+	if len(matches) > 0 {
+		return true, redshiftType, matches[0][1]
+	}
+
 	// Detect postgresql
-	matches := postgresSignature.FindAllStringSubmatch(version, 1)
+	matches = postgresSignature.FindAllStringSubmatch(version, 1)
 	if len(matches) > 0 {
 		return true, postgresType, matches[0][1]
 	}
@@ -80,12 +139,500 @@ func checkVersionRequirement(client, server string) bool {
 	return clientMajor >= serverMajor
 }
 
-// containsRestrictedKeywords returns true if given keyword is not allowed in read-only mode
-func containsRestrictedKeywords(str string) bool {
-	str = reSlashComment.ReplaceAllString(str, "")
-	str = reDashComment.ReplaceAllString(str, "")
+// RestrictedKeywordError is returned when a query is rejected in read-only mode
+type RestrictedKeywordError struct {
+	Keyword  string // Offending keyword, upper-cased
+	Position int    // 1-based character position of the keyword in the query
+}
+
+func (e RestrictedKeywordError) Error() string {
+	return fmt.Sprintf("query contains keywords not allowed in read-only mode: %s at position %d", e.Keyword, e.Position)
+}
+
+// findRestrictedKeyword returns the first keyword not allowed in read-only mode
+// along with its 1-based position in the original query.
+func findRestrictedKeyword(query string) (string, int, bool) {
+	// Blank out comments byte for byte instead of removing them, so that the
+	// match offsets are also offsets into the original query.
+	blank := func(s string) string { return strings.Repeat(" ", len(s)) }
+	str := reSlashComment.ReplaceAllStringFunc(query, blank)
+	str = reDashComment.ReplaceAllStringFunc(str, blank)
+
+	loc := reRestrictedKeywords.FindStringSubmatchIndex(str)
+	if loc == nil {
+		return "", 0, false
+	}
+
+	return strings.ToUpper(str[loc[2]:loc[3]]), utf8.RuneCountInString(query[:loc[2]]) + 1, true
+}
+
+// checkRestrictedKeywords returns an error describing the first keyword that is
+// not allowed in read-only mode, if any.
+func checkRestrictedKeywords(str string) error {
+	keyword, pos, found := findRestrictedKeyword(str)
+	if !found {
+		return nil
+	}
+	return RestrictedKeywordError{Keyword: keyword, Position: pos}
+}
+
+// checkReadOnlyQuery returns an error if the query is not allowed in read-only mode.
+// Calls of the functions listed with --readonly-allow-functions are always allowed.
+func checkReadOnlyQuery(query string) error {
+	if isAllowedFunctionCall(query, command.Opts.ReadOnlyFunctionNames) {
+		return nil
+	}
+	return checkRestrictedKeywords(query)
+}
+
+// isAllowedFunctionCall returns true if the query is a single SELECT or CALL of one
+// of the allowed functions with only constants as arguments. Unqualified names only
+// match unqualified calls, so a function of another schema can't pass for an allowed one.
+func isAllowedFunctionCall(query string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return false
+	}
+
+	blank := blankCommentsAndLiterals(query)
+	loc := reFunctionCall.FindStringSubmatchIndex(blank)
+	if loc == nil {
+		return false
+	}
+
+	name := strings.ToLower(blank[loc[2]:loc[3]])
+	if !slices.Contains(allowed, name) {
+		return false
+	}
+
+	args := blank[loc[4]:loc[5]]
+	for _, word := range reArgumentWord.FindAllStringIndex(args, -1) {
+		start, end := loc[4]+word[0], loc[4]+word[1]
+
+		switch {
+		case strings.HasSuffix(strings.TrimRight(blank[:start], " \t\n"), "::"):
+			// Type of a cast
+		case end < len(query) && query[end] == '\'':
+			// Prefix of a literal, ie. E'\n'
+		case slices.Contains([]string{"true", "false", "null"}, strings.ToLower(blank[start:end])):
+		case unicode.IsDigit(rune(blank[start-1])):
+			// Exponent of a number, ie. 1e5
+		default:
+			return false
+		}
+
+		if _, _, found := findRestrictedKeyword(" " + blank[start:end] + " "); found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// querySection is a kind of query part that is not SQL code
+type querySection int
+
+const (
+	sectionComment querySection = iota
+	sectionLiteral
+	sectionDollarQuoted
+)
+
+// querySections calls fn with the bounds of every comment, string literal and
+// dollar-quoted string of the query, in order. Quoted identifiers are skipped.
+func querySections(query string, fn func(kind querySection, start, end int)) {
+	for i := 0; i < len(query); {
+		end, kind := i+1, sectionLiteral
+		switch c := query[i]; {
+		case c == '"':
+			i = skipQuoted(query, i, c)
+			continue
+		case c == '\'':
+			end = skipQuoted(query, i, c)
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'' && (i == 0 || !isIdentifierChar(query[i-1])):
+			end = skipEscapeQuoted(query, i+1)
+		case c == '$' && (i == 0 || !isIdentifierChar(query[i-1])):
+			// Positional parameters, ie. $1, are not dollar-quoted strings
+			if i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9' {
+				i++
+				continue
+			}
+			end, kind = skipDollarQuoted(query, i), sectionDollarQuoted
+		case strings.HasPrefix(query[i:], "--"):
+			end, kind = strings.IndexByte(query[i:], '\n'), sectionComment
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end, kind = skipBlockComment(query, i), sectionComment
+		case isIdentifierChar(c):
+			// Skip the whole word so that E'' prefixes are only matched at its start
+			for end < len(query) && isIdentifierChar(query[end]) {
+				end++
+			}
+			i = end
+			continue
+		default:
+			i++
+			continue
+		}
+		if end > i+1 {
+			fn(kind, i, end)
+		}
+		i = end
+	}
+}
+
+// quotedEnd returns the position of the closing quote, taking doubled quotes into account
+func quotedEnd(str string, start int, quote byte) (int, bool) {
+	for i := start + 1; i < len(str); i++ {
+		if str[i] != quote {
+			continue
+		}
+		if i+1 < len(str) && str[i+1] == quote {
+			i++
+			continue
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+// escapeQuotedEnd returns the position of the closing quote of an E'...' string,
+// where quotes can also be escaped with a backslash
+func escapeQuotedEnd(str string, start int) (int, bool) {
+	for i := start + 1; i < len(str); i++ {
+		switch {
+		case str[i] == '\\':
+			i++
+		case str[i] != '\'':
+			continue
+		case i+1 < len(str) && str[i+1] == '\'':
+			i++
+		default:
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// skipQuoted returns the position after the quoted section starting at pos, or the
+// end of the query if the section is not closed
+func skipQuoted(query string, pos int, quote byte) int {
+	if end, ok := quotedEnd(query, pos, quote); ok {
+		return end + 1
+	}
+	return len(query)
+}
+
+// skipEscapeQuoted returns the position after the E'...' string starting at pos, or
+// the end of the query if the string is not closed
+func skipEscapeQuoted(query string, pos int) int {
+	if end, ok := escapeQuotedEnd(query, pos); ok {
+		return end + 1
+	}
+	return len(query)
+}
+
+// skipDollarQuoted returns the position after the dollar-quoted string starting at pos
+func skipDollarQuoted(query string, pos int) int {
+	end := strings.IndexByte(query[pos+1:], '$')
+	if end < 0 {
+		return pos + 1
+	}
+
+	tag := query[pos : pos+end+2]
+	for _, r := range tag[1 : len(tag)-1] {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return pos + 1
+		}
+	}
+
+	closing := strings.Index(query[pos+len(tag):], tag)
+	if closing < 0 {
+		return len(query)
+	}
+	return pos + len(tag) + closing + len(tag)
+}
+
+// blankCommentsAndLiterals returns the query with comments, string literals and
+// dollar-quoted strings replaced by spaces. The query length is kept unchanged, so
+// byte offsets still point into the original query. Quoted identifiers are kept.
+func blankCommentsAndLiterals(query string) string {
+	out := []byte(query)
+	querySections(query, func(_ querySection, start, end int) {
+		for i := start; i < end; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	})
+	return string(out)
+}
+
+// skipBlockComment returns the position after the block comment starting at pos.
+// Block comments can be nested.
+func skipBlockComment(query string, pos int) int {
+	depth := 0
+	for i := pos; i < len(query)-1; i++ {
+		switch {
+		case query[i] == '/' && query[i+1] == '*':
+			depth++
+			i++
+		case query[i] == '*' && query[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// isIdentifierChar returns true if the byte can be part of an unquoted identifier
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// statStatementsOrderColumn returns the top statements sort column for the given order
+func statStatementsOrderColumn(orderBy string) (string, error) {
+	switch orderBy {
+	case "", "total_time":
+		return "total_time", nil
+	case "mean_time", "calls":
+		return orderBy, nil
+	default:
+		return "", ErrInvalidStatementsOrder
+	}
+}
+
+// statsResetQuery returns the statement resetting statistics of the given kind
+func statsResetQuery(kind string) (string, error) {
+	switch kind {
+	case "statements":
+		return "SELECT pg_stat_statements_reset()", nil
+	case "database":
+		return "SELECT pg_stat_reset()", nil
+	default:
+		return "", ErrInvalidStatsReset
+	}
+}
+
+// addReturning appends RETURNING * to a single UPDATE or DELETE statement.
+// Returns false when the statement can't be safely changed.
+func addReturning(query string) (string, bool) {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	if query == "" || strings.Contains(blankCommentsAndLiterals(query), ";") {
+		return "", false
+	}
+	if hasReturning(query) {
+		return query, true
+	}
+
+	// Statement on a new line, so it's not swallowed by a trailing comment
+	return query + "\nRETURNING *", true
+}
+
+// hasReturning returns true if the statement already has a RETURNING clause
+func hasReturning(query string) bool {
+	return reReturning.MatchString(blankCommentsAndLiterals(query))
+}
+
+// quoteIdentifier returns a double-quoted SQL identifier
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// buildGroupBySQL returns a group by summary query for the table. Column names
+// must be validated against the table schema before calling this function.
+func buildGroupBySQL(schema, table string, groupCols []string, aggs []Aggregation) (string, error) {
+	if len(groupCols) == 0 && len(aggs) == 0 {
+		return "", errors.New("at least one group column or aggregation is required")
+	}
+
+	groups := make([]string, len(groupCols))
+	for i, col := range groupCols {
+		groups[i] = quoteIdentifier(col)
+	}
+
+	selects := append([]string{}, groups...)
+	for _, agg := range aggs {
+		fn := strings.ToLower(agg.Function)
+		if !allowedAggregations[fn] {
+			return "", fmt.Errorf("aggregate function %q is not allowed", agg.Function)
+		}
+
+		if agg.Column == "*" {
+			if fn != "count" {
+				return "", fmt.Errorf("aggregate function %q requires a column", fn)
+			}
+			selects = append(selects, `count(*) AS "count"`)
+			continue
+		}
+
+		selects = append(selects, fmt.Sprintf("%s(%s) AS %s", fn, quoteIdentifier(agg.Column), quoteIdentifier(fn+"_"+agg.Column)))
+	}
+
+	sql := fmt.Sprintf("SELECT %s FROM %s.%s", strings.Join(selects, ", "), quoteIdentifier(schema), quoteIdentifier(table))
+	if len(groups) > 0 {
+		sql += fmt.Sprintf(" GROUP BY %s ORDER BY %s", strings.Join(groups, ", "), strings.Join(groups, ", "))
+	}
+
+	return sql, nil
+}
+
+// buildOrderBy returns the ORDER BY clause for the sort columns. Columns must
+// exist in the columns set and sort directions are limited to ASC and DESC.
+func buildOrderBy(sorts []SortSpec, columns map[string]bool) (string, error) {
+	terms := make([]string, len(sorts))
+
+	for i, sort := range sorts {
+		if !columns[sort.Column] {
+			return "", fmt.Errorf("column %q does not exist", sort.Column)
+		}
+
+		order := strings.ToUpper(strings.TrimSpace(sort.Order))
+		switch order {
+		case "":
+			order = "ASC"
+		case "ASC", "DESC":
+		default:
+			return "", fmt.Errorf("invalid sort order %q for column %q", sort.Order, sort.Column)
+		}
+
+		terms[i] = quoteIdentifier(sort.Column) + " " + order
+	}
+
+	return "ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// buildObjectsSQL returns the objects list query with the schema and name filters
+// and the pagination applied
+func buildObjectsSQL(opts ObjectsOptions) (string, []interface{}) {
+	if opts == (ObjectsOptions{}) {
+		return statements.Objects, nil
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	if opts.Schema != "" {
+		args = append(args, opts.Schema)
+		conditions = append(conditions, fmt.Sprintf("schema = $%d", len(args)))
+	}
+	if opts.Name != "" {
+		args = append(args, "%"+reLikeSpecialChars.ReplaceAllString(opts.Name, `\$0`)+"%")
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+
+	sql := "SELECT * FROM (\n" + strings.TrimSpace(statements.Objects) + "\n) objects"
+	if len(conditions) > 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sql += " ORDER BY 2, 3"
+
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		sql += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		sql += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return sql, args
+}
+
+// buildHistogramSQL returns a query counting the column values in buckets of equal
+// width between the column min and max values. Column must be validated against
+// the table schema before calling this function.
+func buildHistogramSQL(schema, table, column, dataType string, buckets int) (string, error) {
+	bound, ok := histogramTypes[dataType]
+	if !ok {
+		return "", fmt.Errorf("histogram is not supported for %s columns", dataType)
+	}
+	if buckets < 1 || buckets > maxHistogramBuckets {
+		return "", fmt.Errorf("buckets must be between 1 and %d", maxHistogramBuckets)
+	}
+
+	value := quoteIdentifier(column) + "::float8"
+	if bound != "%s" {
+		value = fmt.Sprintf("extract(epoch FROM %s)::float8", quoteIdentifier(column))
+	}
+
+	// All values fall into a single bucket when the column has just one distinct value
+	count := fmt.Sprintf("CASE WHEN b.hi = b.lo THEN 1 ELSE %d END", buckets)
+
+	sql := fmt.Sprintf(`WITH src AS (
+  SELECT %s AS v FROM %s.%s WHERE %s IS NOT NULL
+), bounds AS (
+  SELECT min(v) AS lo, max(v) AS hi FROM src
+), counts AS (
+  SELECT least(width_bucket(v, b.lo, b.hi + (b.hi = b.lo)::int, %d), %d) AS bucket, count(*) AS count
+  FROM src, bounds b
+  GROUP BY 1
+)
+SELECT
+  g.bucket,
+  %s AS lower_bound,
+  %s AS upper_bound,
+  coalesce(c.count, 0) AS count
+FROM bounds b
+CROSS JOIN generate_series(1, %s) AS g(bucket)
+LEFT JOIN counts c ON c.bucket = g.bucket
+WHERE b.lo IS NOT NULL
+ORDER BY g.bucket`,
+		value, quoteIdentifier(schema), quoteIdentifier(table), quoteIdentifier(column),
+		buckets, buckets,
+		fmt.Sprintf(bound, fmt.Sprintf("b.lo + (b.hi - b.lo) * (g.bucket - 1) / (%s)", count)),
+		fmt.Sprintf(bound, fmt.Sprintf("b.lo + (b.hi - b.lo) * g.bucket / (%s)", count)),
+		count,
+	)
+
+	return sql, nil
+}
+
+// buildCrosstabSourceSQL returns the source and category queries for crosstab
+func buildCrosstabSourceSQL(opts CrosstabOptions) (string, string, error) {
+	fn := strings.ToLower(opts.Value.Function)
+	if !allowedAggregations[fn] {
+		return "", "", fmt.Errorf("aggregate function %q is not allowed", opts.Value.Function)
+	}
+
+	value := "*"
+	if opts.Value.Column != "*" {
+		value = quoteIdentifier(opts.Value.Column)
+	} else if fn != "count" {
+		return "", "", fmt.Errorf("aggregate function %q requires a column", fn)
+	}
+
+	query := strings.TrimRight(strings.TrimSpace(opts.Query), ";")
+	rowKey := quoteIdentifier(opts.RowKey)
+	category := quoteIdentifier(opts.Category)
+
+	source := fmt.Sprintf(
+		"SELECT %s::text, %s::text, %s(%s)::text FROM (%s) src GROUP BY 1, 2 ORDER BY 1, 2",
+		rowKey, category, fn, value, query,
+	)
+	categories := fmt.Sprintf(
+		"SELECT DISTINCT %s::text FROM (%s) src WHERE %s IS NOT NULL ORDER BY 1",
+		category, query, category,
+	)
+
+	return source, categories, nil
+}
+
+// buildCrosstabSQL returns the crosstab query with a text column for each category.
+// Source and category queries are passed in as $1 and $2 parameters.
+func buildCrosstabSQL(rowKey string, categories []string) string {
+	columns := []string{quoteIdentifier(rowKey) + " text"}
+	for _, category := range categories {
+		columns = append(columns, quoteIdentifier(category)+" text")
+	}
 
-	return reRestrictedKeywords.MatchString(str)
+	return fmt.Sprintf("SELECT * FROM crosstab($1, $2) AS ct(%s)", strings.Join(columns, ", "))
 }
 
 func hasBinary(data string, checkLen int) bool {
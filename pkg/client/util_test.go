@@ -1,9 +1,13 @@
 package client
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/flowbi/pgweb/pkg/command"
+	"github.com/flowbi/pgweb/pkg/statements"
 )
 
 func TestDetectServerType(t *testing.T) {
@@ -36,6 +40,12 @@ func TestDetectServerType(t *testing.T) {
 			serverType: postgresType,
 			version:    "11.16",
 		},
+		{
+			input:      "PostgreSQL 8.0.2 on i686-pc-linux-gnu, compiled by GCC gcc (GCC) 3.4.2 20041017 (Red Hat 3.4.2-6.fc3), Redshift 1.0.12103",
+			match:      true,
+			serverType: redshiftType,
+			version:    "1.0.12103",
+		},
 	}
 
 	for _, ex := range examples {
@@ -114,3 +124,308 @@ func TestCheckVersionRequirement(t *testing.T) {
 		assert.Equal(t, ex.result, checkVersionRequirement(ex.client, ex.server))
 	}
 }
+
+func TestCheckRestrictedKeywords(t *testing.T) {
+	examples := []struct {
+		input    string
+		keyword  string
+		position int
+	}{
+		{"SELECT 1", "", 0},
+		{"DELETE FROM books", "DELETE", 1},
+		{"select 1; delete from books", "DELETE", 11},
+		{"-- DROP TABLE books\nSELECT 1", "", 0},
+		{"/* drop */ insert into books values(1)", "INSERT", 12},
+		{"/* déjà vu */ drop table books", "DROP", 15},
+		{"SELECT 'é'; DROP TABLE books", "DROP", 13},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.input, func(t *testing.T) {
+			err := checkRestrictedKeywords(ex.input)
+			if ex.keyword == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			kwErr, ok := err.(RestrictedKeywordError)
+			assert.True(t, ok)
+			assert.Equal(t, ex.keyword, kwErr.Keyword)
+			assert.Equal(t, ex.position, kwErr.Position)
+			assert.Contains(t, err.Error(), "not allowed in read-only mode")
+		})
+	}
+}
+
+func TestIsAllowedFunctionCall(t *testing.T) {
+	allowed := []string{"refresh_summary", "reporting.collect"}
+
+	examples := map[string]bool{
+		"SELECT refresh_summary()":                                 true,
+		"select Refresh_Summary(1, 2.5e3, -4);":                    true,
+		"SELECT * FROM refresh_summary('DROP TABLE books')":        true,
+		"CALL reporting.collect(E'\\n', '2024-01-01'::date, NULL)": true,
+		"/* nightly */ CALL reporting.collect($1, true)":           true,
+		"SELECT 1":                                   false,
+		"SELECT other_function()":                    false,
+		"SELECT public.refresh_summary()":            false,
+		"CALL collect()":                             false,
+		`SELECT "refresh_summary"()`:                 false,
+		"SELECT refresh_summary(); DROP TABLE books": false,
+		"SELECT refresh_summary(drop)":               false,
+		"SELECT refresh_summary(1::drop)":            false,
+		"SELECT refresh_summary((SELECT 1))":         false,
+		"SELECT refresh_summary(id) FROM books":      false,
+		"SELECT refresh_summary(x => 1)":             false,
+	}
+
+	for query, expected := range examples {
+		t.Run(query, func(t *testing.T) {
+			assert.Equal(t, expected, isAllowedFunctionCall(query, allowed))
+		})
+	}
+
+	assert.False(t, isAllowedFunctionCall("SELECT refresh_summary()", nil))
+}
+
+func TestCheckReadOnlyQuery(t *testing.T) {
+	defer func() { command.Opts.ReadOnlyFunctionNames = nil }()
+
+	query := "SELECT refresh_summary('CREATE INDEX')"
+	assert.Error(t, checkReadOnlyQuery(query))
+
+	command.Opts.ReadOnlyFunctionNames = []string{"refresh_summary"}
+	assert.NoError(t, checkReadOnlyQuery(query))
+	assert.Error(t, checkReadOnlyQuery("SELECT refresh_summary(); DROP TABLE books"))
+}
+
+func TestBlankCommentsAndLiterals(t *testing.T) {
+	examples := map[string]string{
+		"SELECT 1":                   "SELECT 1",
+		`SELECT 'a;b', "c;d" FROM t`: `SELECT , "c;d" FROM t`,
+		`SELECT E'\'' ; DROP TABLE t; SELECT E'\''`: "SELECT ; DROP TABLE t; SELECT",
+		"SELECT $$;$$, $tag$'$tag$ -- x":            "SELECT ,",
+		"SELECT /* a /* b */ ; */ 1":                "SELECT 1",
+		"SELECT 1 -- ;\n;":                          "SELECT 1 ;",
+		"SELECT $1, some'x'":                        "SELECT $1, some",
+	}
+
+	for input, expected := range examples {
+		t.Run(input, func(t *testing.T) {
+			blanked := blankCommentsAndLiterals(input)
+			assert.Len(t, blanked, len(input))
+			assert.Equal(t, expected, strings.Join(strings.Fields(blanked), " "))
+		})
+	}
+}
+
+func TestStatStatementsOrderColumn(t *testing.T) {
+	examples := map[string]string{
+		"":           "total_time",
+		"total_time": "total_time",
+		"mean_time":  "mean_time",
+		"calls":      "calls",
+	}
+
+	for input, expected := range examples {
+		column, err := statStatementsOrderColumn(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, column)
+	}
+
+	_, err := statStatementsOrderColumn("calls; DROP TABLE books")
+	assert.Equal(t, ErrInvalidStatementsOrder, err)
+}
+
+func TestStatsResetQuery(t *testing.T) {
+	query, err := statsResetQuery("statements")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT pg_stat_statements_reset()", query)
+
+	query, err = statsResetQuery("database")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT pg_stat_reset()", query)
+
+	_, err = statsResetQuery("everything")
+	assert.Equal(t, ErrInvalidStatsReset, err)
+}
+
+func TestResetStatsReadOnly(t *testing.T) {
+	client := &Client{readonly: true}
+	assert.Equal(t, ErrReadOnly, client.ResetStats("statements"))
+
+	command.Opts.ReadOnly = true
+	defer func() {
+		command.Opts.ReadOnly = false
+	}()
+
+	client = &Client{}
+	assert.Equal(t, ErrReadOnly, client.ResetStats("database"))
+}
+
+func TestAddReturning(t *testing.T) {
+	examples := []struct {
+		query    string
+		expected string
+		ok       bool
+	}{
+		{"DELETE FROM books", "DELETE FROM books\nRETURNING *", true},
+		{"UPDATE books SET title = 'a' WHERE id = 1;\n", "UPDATE books SET title = 'a' WHERE id = 1\nRETURNING *", true},
+		{"DELETE FROM books -- all of them", "DELETE FROM books -- all of them\nRETURNING *", true},
+		{"DELETE FROM books; DELETE FROM authors", "", false},
+		{";", "", false},
+		{"UPDATE books SET title = ';' WHERE id = 1", "UPDATE books SET title = ';' WHERE id = 1\nRETURNING *", true},
+		{"DELETE FROM books\nRETURNING id", "DELETE FROM books\nRETURNING id", true},
+		{"DELETE FROM books WHERE title = ' returning ' -- returning", "DELETE FROM books WHERE title = ' returning ' -- returning\nRETURNING *", true},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.query, func(t *testing.T) {
+			query, ok := addReturning(ex.query)
+			assert.Equal(t, ex.ok, ok)
+			assert.Equal(t, ex.expected, query)
+		})
+	}
+}
+
+func TestHasReturning(t *testing.T) {
+	assert.True(t, hasReturning("DELETE FROM books RETURNING id"))
+	assert.True(t, hasReturning("UPDATE books SET title = 'a'\nreturning *"))
+	assert.True(t, hasReturning("DELETE FROM books\tRETURNING\tid"))
+	assert.False(t, hasReturning("DELETE FROM books"))
+	assert.False(t, hasReturning("DELETE FROM books WHERE title = 'returning'"))
+	assert.False(t, hasReturning("DELETE FROM books -- returning"))
+	assert.False(t, hasReturning("DELETE FROM returnings"))
+}
+
+func TestBuildObjectsSQL(t *testing.T) {
+	t.Run("no options", func(t *testing.T) {
+		sql, args := buildObjectsSQL(ObjectsOptions{})
+		assert.Equal(t, statements.Objects, sql)
+		assert.Empty(t, args)
+	})
+
+	t.Run("filters and pagination", func(t *testing.T) {
+		sql, args := buildObjectsSQL(ObjectsOptions{Schema: "public", Name: "100%_books", Limit: 50, Offset: 100})
+		assert.True(t, strings.HasPrefix(sql, "SELECT * FROM (\nWITH all_objects AS ("))
+		assert.True(t, strings.HasSuffix(sql, ") objects WHERE schema = $1 AND name ILIKE $2 ORDER BY 2, 3 LIMIT $3 OFFSET $4"))
+		assert.Equal(t, []interface{}{"public", `%100\%\_books%`, 50, 100}, args)
+	})
+
+	t.Run("limit only", func(t *testing.T) {
+		sql, args := buildObjectsSQL(ObjectsOptions{Limit: 10})
+		assert.True(t, strings.HasSuffix(sql, ") objects ORDER BY 2, 3 LIMIT $1"))
+		assert.Equal(t, []interface{}{10}, args)
+	})
+}
+
+func TestBuildHistogramSQL(t *testing.T) {
+	t.Run("numeric column", func(t *testing.T) {
+		sql, err := buildHistogramSQL("public", "stock", "stock", "integer", 5)
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `SELECT "stock"::float8 AS v FROM "public"."stock" WHERE "stock" IS NOT NULL`)
+		assert.Contains(t, sql, "least(width_bucket(v, b.lo, b.hi + (b.hi = b.lo)::int, 5), 5)")
+		assert.Contains(t, sql, "b.lo + (b.hi - b.lo) * (g.bucket - 1) / (CASE WHEN b.hi = b.lo THEN 1 ELSE 5 END) AS lower_bound")
+	})
+
+	t.Run("date column", func(t *testing.T) {
+		sql, err := buildHistogramSQL("public", "shipments", "ship_date", "timestamp with time zone", 5)
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `SELECT extract(epoch FROM "ship_date")::float8 AS v`)
+		assert.Contains(t, sql, "to_timestamp(b.lo + (b.hi - b.lo) * g.bucket / (CASE WHEN b.hi = b.lo THEN 1 ELSE 5 END)) AS upper_bound")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := buildHistogramSQL("public", "books", "title", "text", 5)
+		assert.EqualError(t, err, "histogram is not supported for text columns")
+
+		_, err = buildHistogramSQL("public", "stock", "stock", "integer", 0)
+		assert.EqualError(t, err, "buckets must be between 1 and 1000")
+	})
+}
+
+func TestBuildOrderBy(t *testing.T) {
+	columns := map[string]bool{"id": true, "created_at": true, `odd"name`: true}
+
+	sql, err := buildOrderBy([]SortSpec{{Column: "created_at", Order: "desc"}, {Column: "id"}}, columns)
+	assert.NoError(t, err)
+	assert.Equal(t, `ORDER BY "created_at" DESC, "id" ASC`, sql)
+
+	sql, err = buildOrderBy([]SortSpec{{Column: `odd"name`, Order: "ASC"}}, columns)
+	assert.NoError(t, err)
+	assert.Equal(t, `ORDER BY "odd""name" ASC`, sql)
+
+	_, err = buildOrderBy([]SortSpec{{Column: `id"; DROP TABLE books; --`}}, columns)
+	assert.EqualError(t, err, `column "id\"; DROP TABLE books; --" does not exist`)
+
+	_, err = buildOrderBy([]SortSpec{{Column: "id", Order: "DESC; DROP TABLE books"}}, columns)
+	assert.EqualError(t, err, `invalid sort order "DESC; DROP TABLE books" for column "id"`)
+}
+
+func TestBuildGroupBySQL(t *testing.T) {
+	t.Run("count by column", func(t *testing.T) {
+		sql, err := buildGroupBySQL("public", "books", []string{"author_id"}, []Aggregation{{Function: "count", Column: "*"}})
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT "author_id", count(*) AS "count" FROM "public"."books" GROUP BY "author_id" ORDER BY "author_id"`, sql)
+	})
+
+	t.Run("sum with group", func(t *testing.T) {
+		sql, err := buildGroupBySQL("public", "stock", []string{"cost"}, []Aggregation{{Function: "SUM", Column: "stock"}})
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT "cost", sum("stock") AS "sum_stock" FROM "public"."stock" GROUP BY "cost" ORDER BY "cost"`, sql)
+	})
+
+	t.Run("aggregate only", func(t *testing.T) {
+		sql, err := buildGroupBySQL("public", "stock", nil, []Aggregation{{Function: "max", Column: "retail"}})
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT max("retail") AS "max_retail" FROM "public"."stock"`, sql)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := buildGroupBySQL("public", "books", nil, nil)
+		assert.EqualError(t, err, "at least one group column or aggregation is required")
+
+		_, err = buildGroupBySQL("public", "books", nil, []Aggregation{{Function: "pg_sleep", Column: "id"}})
+		assert.EqualError(t, err, `aggregate function "pg_sleep" is not allowed`)
+
+		_, err = buildGroupBySQL("public", "books", nil, []Aggregation{{Function: "sum", Column: "*"}})
+		assert.EqualError(t, err, `aggregate function "sum" requires a column`)
+	})
+}
+
+func TestBuildCrosstabSQL(t *testing.T) {
+	opts := CrosstabOptions{
+		Query:    "SELECT * FROM books;",
+		RowKey:   "author_id",
+		Category: "subject_id",
+		Value:    Aggregation{Function: "count", Column: "*"},
+	}
+
+	source, categories, err := buildCrosstabSourceSQL(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT "author_id"::text, "subject_id"::text, count(*)::text FROM (SELECT * FROM books) src GROUP BY 1, 2 ORDER BY 1, 2`, source)
+	assert.Equal(t, `SELECT DISTINCT "subject_id"::text FROM (SELECT * FROM books) src WHERE "subject_id" IS NOT NULL ORDER BY 1`, categories)
+
+	opts.Value = Aggregation{Function: "avg", Column: "*"}
+	_, _, err = buildCrosstabSourceSQL(opts)
+	assert.EqualError(t, err, `aggregate function "avg" requires a column`)
+
+	assert.Equal(t,
+		`SELECT * FROM crosstab($1, $2) AS ct("author_id" text, "0" text, "4" text)`,
+		buildCrosstabSQL("author_id", []string{"0", "4"}),
+	)
+}
+
+func TestRowsLimit(t *testing.T) {
+	defer func(opts command.Options) {
+		command.Opts = opts
+	}(command.Opts)
+	command.Opts.DefaultRowsLimit = 1000
+
+	assert.Equal(t, 1000, RowsLimit(0))
+	assert.Equal(t, 50, RowsLimit(50))
+	assert.Equal(t, 0, RowsLimit(RowsUnlimited))
+
+	command.Opts.DefaultRowsLimit = 0
+	assert.Equal(t, 0, RowsLimit(0))
+}
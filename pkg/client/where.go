@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Clauses that can't appear at the top level of a single boolean predicate
+	reFilterClauses = regexp.MustCompile(`(?i)\b(UNION|INTERSECT|EXCEPT|ORDER\s+BY|GROUP\s+BY|HAVING|LIMIT|OFFSET|FETCH|WINDOW|FOR\s+(?:UPDATE|SHARE|NO\s+KEY|KEY)|INTO|RETURNING)\b`)
+
+	// Dollar-quoted string tag, e.g. $$ or $tag$
+	reDollarTag = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z0-9_]*)?\$`)
+)
+
+// WhereValidationError is returned for filter expressions that are not a single predicate
+type WhereValidationError struct {
+	Reason string
+}
+
+func (e WhereValidationError) Error() string {
+	return "invalid filter: " + e.Reason
+}
+
+// validateWhere checks that the filter is a single boolean predicate without statement
+// terminators, comments or clauses that would change the shape of the enclosing query.
+func validateWhere(where string) error {
+	topLevel := strings.Builder{}
+	depth := 0
+
+	for i := 0; i < len(where); i++ {
+		ch := where[i]
+
+		switch {
+		case (ch == 'E' || ch == 'e') && i+1 < len(where) && where[i+1] == '\'' && (i == 0 || !isIdentifierChar(where[i-1])):
+			end, ok := escapeQuotedEnd(where, i+1)
+			if !ok {
+				return WhereValidationError{Reason: "unterminated quoted string"}
+			}
+			i = end
+			topLevel.WriteByte(' ')
+			continue
+		case ch == '\'' || ch == '"':
+			end, ok := quotedEnd(where, i, ch)
+			if !ok {
+				return WhereValidationError{Reason: "unterminated quoted string"}
+			}
+			i = end
+			topLevel.WriteByte(' ')
+			continue
+		case ch == '$':
+			if tag := reDollarTag.FindString(where[i:]); tag != "" {
+				end := strings.Index(where[i+len(tag):], tag)
+				if end < 0 {
+					return WhereValidationError{Reason: "unterminated quoted string"}
+				}
+				i += len(tag) + end + len(tag) - 1
+				topLevel.WriteByte(' ')
+				continue
+			}
+		case ch == ';':
+			return WhereValidationError{Reason: "statement terminators are not allowed"}
+		case ch == '-' && i+1 < len(where) && where[i+1] == '-',
+			ch == '/' && i+1 < len(where) && where[i+1] == '*':
+			return WhereValidationError{Reason: "comments are not allowed"}
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth < 0 {
+				return WhereValidationError{Reason: "unbalanced parentheses"}
+			}
+		}
+
+		if depth == 0 {
+			topLevel.WriteByte(ch)
+		} else {
+			topLevel.WriteByte(' ')
+		}
+	}
+
+	if depth != 0 {
+		return WhereValidationError{Reason: "unbalanced parentheses"}
+	}
+
+	if match := reFilterClauses.FindString(topLevel.String()); match != "" {
+		return WhereValidationError{Reason: fmt.Sprintf("%s is not allowed", strings.ToUpper(match))}
+	}
+
+	return nil
+}
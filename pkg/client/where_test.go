@@ -0,0 +1,54 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWhere(t *testing.T) {
+	valid := []string{
+		"id = 1",
+		"title ILIKE '%shining%' AND (author_id = 1 OR author_id = 2)",
+		"title = 'semi;colon -- not a comment /* nor this */'",
+		"title = 'it''s'",
+		`"order" > 10`,
+		"tags @> $$x;y$$",
+		"id IN (SELECT book_id FROM editions ORDER BY book_id LIMIT 10)",
+		"price - 1 > 0",
+		`title = E'it\'s; -- fine'`,
+		`title = e'back\\slash'`,
+		"type = 'E'",
+	}
+	for _, where := range valid {
+		assert.NoError(t, validateWhere(where), where)
+	}
+
+	invalid := map[string]string{
+		"1=1; DROP TABLE x":             "invalid filter: statement terminators are not allowed",
+		"1=1 -- AND id = 2":             "invalid filter: comments are not allowed",
+		"1=1 /* comment */":             "invalid filter: comments are not allowed",
+		"title = 'unterminated":         "invalid filter: unterminated quoted string",
+		"tags @> $x$foo":                "invalid filter: unterminated quoted string",
+		"(id = 1":                       "invalid filter: unbalanced parentheses",
+		"id = 1) OR (1=1":               "invalid filter: unbalanced parentheses",
+		"1=1 UNION SELECT * FROM users": "invalid filter: UNION is not allowed",
+		"1=1 ORDER BY id":               "invalid filter: ORDER BY is not allowed",
+		"1=1 LIMIT 1":                   "invalid filter: LIMIT is not allowed",
+		`x = E'\'' ; DROP TABLE t; --'`: "invalid filter: statement terminators are not allowed",
+		`x = E'unterminated\'`:          "invalid filter: unterminated quoted string",
+	}
+	for where, message := range invalid {
+		assert.EqualError(t, validateWhere(where), message, where)
+	}
+}
+
+func TestTableRowsInvalidWhere(t *testing.T) {
+	client := &Client{}
+
+	_, err := client.TableRows("books", RowsOptions{Where: "1=1; DROP TABLE books"})
+	assert.Equal(t, WhereValidationError{Reason: "statement terminators are not allowed"}, err)
+
+	_, err = client.TableRows("books", RowsOptions{Where: "id = 1"})
+	assert.NoError(t, err)
+}
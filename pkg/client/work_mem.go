@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// DefaultWorkMem is applied to queries that do not request a work_mem, empty to keep the server setting
+	DefaultWorkMem string
+
+	// MaxWorkMem is the largest work_mem a request may ask for, empty to disallow requests
+	MaxWorkMem string
+
+	ErrWorkMemNotAllowed = errors.New("per-request work_mem is not allowed")
+
+	// Statements that can't run in a transaction block, or control transactions
+	reNonTransactional = regexp.MustCompile(`(?is)^\s*(?:VACUUM|CLUSTER|(?:CREATE|DROP)\s+(?:DATABASE|TABLESPACE|SUBSCRIPTION)|ALTER\s+SYSTEM|` +
+		`ALTER\s+DATABASE\b.*\bSET\s+TABLESPACE|REINDEX\s+(?:\(.*\)\s*)?(?:SYSTEM|DATABASE)|` +
+		`(?:CREATE(?:\s+UNIQUE)?\s+INDEX|DROP\s+INDEX|REINDEX\b.*)\s+CONCURRENTLY|` +
+		`BEGIN|START\s+TRANSACTION|COMMIT|END|ROLLBACK|ABORT|SAVEPOINT|RELEASE|PREPARE\s+TRANSACTION)\b`)
+
+	// Memory sizes in the PostgreSQL format, defaulting to kilobytes
+	reMemorySize = regexp.MustCompile(`^([0-9]+)(kB|MB|GB|TB)?$`)
+
+	memoryUnits = map[string]int64{
+		"":   1,
+		"kB": 1,
+		"MB": 1024,
+		"GB": 1024 * 1024,
+		"TB": 1024 * 1024 * 1024,
+	}
+)
+
+// SetWorkMemLimits validates and configures the default and maximum work_mem
+func SetWorkMemLimits(defaultValue, maxValue string) error {
+	for _, value := range []string{defaultValue, maxValue} {
+		if value == "" {
+			continue
+		}
+		if _, err := parseMemorySize(value); err != nil {
+			return err
+		}
+	}
+
+	if defaultValue != "" && maxValue != "" && !memoryWithin(defaultValue, maxValue) {
+		return fmt.Errorf("default work_mem %s exceeds the maximum of %s", defaultValue, maxValue)
+	}
+
+	DefaultWorkMem = defaultValue
+	MaxWorkMem = maxValue
+	return nil
+}
+
+// workMemKey is the context key of the work_mem requested for the queries
+type workMemKey struct{}
+
+// ValidateWorkMem checks the work_mem requested for the queries is within the
+// configured maximum
+func ValidateWorkMem(value string) error {
+	if _, err := parseMemorySize(value); err != nil {
+		return err
+	}
+	if MaxWorkMem == "" {
+		return ErrWorkMemNotAllowed
+	}
+	if !memoryWithin(value, MaxWorkMem) {
+		return fmt.Errorf("work_mem must not exceed %s", MaxWorkMem)
+	}
+	return nil
+}
+
+// WithWorkMem returns a copy of the context carrying the work_mem used by the
+// queries run with it. The value must be validated with ValidateWorkMem.
+func WithWorkMem(ctx context.Context, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, workMemKey{}, value)
+}
+
+// workMemFrom returns the work_mem queries run with the context use, or the default one
+func workMemFrom(ctx context.Context) string {
+	if value, ok := ctx.Value(workMemKey{}).(string); ok {
+		return value
+	}
+	return DefaultWorkMem
+}
+
+// parseMemorySize returns the size in kilobytes
+func parseMemorySize(value string) (int64, error) {
+	match := reMemorySize.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("invalid memory size: %q", value)
+	}
+
+	size, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size: %q", value)
+	}
+
+	return size * memoryUnits[match[2]], nil
+}
+
+func memoryWithin(value, limit string) bool {
+	size, _ := parseMemorySize(value)
+	max, _ := parseMemorySize(limit)
+	return size <= max
+}
+
+// canRunInTransaction returns false if any statement of the query can't run in a
+// transaction block, or controls transactions itself
+func canRunInTransaction(query string) bool {
+	for _, statement := range splitLintStatements(query) {
+		if reNonTransactional.MatchString(statement) {
+			return false
+		}
+	}
+	return true
+}
+
+func workMemStatement(value string) string {
+	return fmt.Sprintf("SET LOCAL work_mem = '%s'", value)
+}
+
+// withWorkMem runs fn with work_mem set for the query. SET LOCAL only lasts until
+// the end of a transaction, so outside of an open one the query gets its own on db.
+// Queries that can't run in a transaction block are run with the server work_mem.
+// The queries of read-only requests always get a read-only transaction, and can't
+// run in an open read-write one.
+func (client *Client) withWorkMem(parent context.Context, db txConn, query string, fn func(conn queryConn) (*Result, error)) (*Result, error) {
+	workMem := workMemFrom(parent)
+	readOnly := ReadOnlyFromContext(parent)
+
+	if client.tx != nil && readOnly && !client.txReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	if !canRunInTransaction(query) {
+		if readOnly {
+			return nil, ErrReadOnly
+		}
+		workMem = ""
+	}
+
+	if client.tx == nil && workMem == "" && !readOnly {
+		return fn(db)
+	}
+
+	ctx, cancel := client.contextFrom(parent)
+	defer cancel()
+
+	if client.tx != nil {
+		if workMem != "" {
+			if _, err := client.tx.ExecContext(ctx, workMemStatement(workMem)); err != nil {
+				return nil, err
+			}
+		}
+		return fn(client.tx)
+	}
+
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: client.isReadOnlyContext(parent)})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if workMem != "" {
+		if _, err := tx.ExecContext(ctx, workMemStatement(workMem)); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := fn(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, tx.Commit()
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkMem(t *testing.T) {
+	defer func() {
+		DefaultWorkMem = ""
+		MaxWorkMem = ""
+	}()
+
+	t.Run("parse", func(t *testing.T) {
+		examples := map[string]int64{
+			"4096":  4096,
+			"64kB":  64,
+			"64MB":  64 * 1024,
+			"2GB":   2 * 1024 * 1024,
+			"1TB":   1024 * 1024 * 1024,
+			"0":     0,
+			"64mb":  -1,
+			"64 MB": -1,
+			"'1GB'": -1,
+			"":      -1,
+		}
+
+		for input, expected := range examples {
+			size, err := parseMemorySize(input)
+			if expected < 0 {
+				assert.Error(t, err, input)
+				continue
+			}
+			assert.NoError(t, err, input)
+			assert.Equal(t, expected, size, input)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		assert.EqualError(t, SetWorkMemLimits("foo", ""), `invalid memory size: "foo"`)
+		assert.EqualError(t, SetWorkMemLimits("1GB", "256MB"), "default work_mem 1GB exceeds the maximum of 256MB")
+		assert.NoError(t, SetWorkMemLimits("64MB", "256MB"))
+	})
+
+	t.Run("request value", func(t *testing.T) {
+		assert.NoError(t, SetWorkMemLimits("64MB", ""))
+		assert.Equal(t, "64MB", workMemFrom(context.Background()))
+		assert.Equal(t, ErrWorkMemNotAllowed, ValidateWorkMem("128MB"))
+
+		assert.NoError(t, SetWorkMemLimits("64MB", "256MB"))
+		assert.NoError(t, ValidateWorkMem("128MB"))
+		assert.EqualError(t, ValidateWorkMem("1GB"), "work_mem must not exceed 256MB")
+		assert.EqualError(t, ValidateWorkMem("1GB; DROP TABLE books"), `invalid memory size: "1GB; DROP TABLE books"`)
+
+		ctx := WithWorkMem(context.Background(), "128MB")
+		assert.Equal(t, "128MB", workMemFrom(ctx))
+		assert.Equal(t, "64MB", workMemFrom(WithWorkMem(context.Background(), "")))
+	})
+
+	t.Run("statement", func(t *testing.T) {
+		assert.Equal(t, "SET LOCAL work_mem = '64MB'", workMemStatement("64MB"))
+	})
+
+	t.Run("non-transactional statements", func(t *testing.T) {
+		examples := map[string]bool{
+			"SELECT * FROM books":                                true,
+			"UPDATE books SET title = 'VACUUM'":                  true,
+			"CREATE INDEX books_title_idx ON books (title)":      true,
+			"-- VACUUM\nSELECT 1":                                true,
+			"VACUUM ANALYZE books":                               false,
+			"vacuum":                                             false,
+			"CREATE DATABASE test":                               false,
+			"DROP DATABASE IF EXISTS test":                       false,
+			"CREATE UNIQUE INDEX CONCURRENTLY idx ON books (id)": false,
+			"DROP INDEX CONCURRENTLY idx":                        false,
+			"REINDEX (VERBOSE) TABLE CONCURRENTLY books":         false,
+			"ALTER SYSTEM SET work_mem = '64MB'":                 false,
+			"SELECT 1; COMMIT":                                   false,
+			"BEGIN; DELETE FROM books; COMMIT":                   false,
+		}
+
+		for query, expected := range examples {
+			assert.Equal(t, expected, canRunInTransaction(query), query)
+		}
+
+		// Run with the server work_mem, outside of a transaction
+		client := &Client{}
+		res, err := client.withWorkMem(WithWorkMem(context.Background(), "64MB"), nil, "VACUUM", func(conn queryConn) (*Result, error) {
+			assert.Nil(t, conn)
+			return &Result{}, nil
+		})
+		assert.NoError(t, err)
+		assert.NotNil(t, res)
+	})
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// Worksheet size limits of the XLSX format, the header takes up one row
+	xlsxMaxColumns = 16384
+	xlsxMaxRows    = 1048576
+
+	// Cell styles defined in xlsxStyles
+	xlsxStyleHeader   = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleDate     = 3
+)
+
+var (
+	ErrXLSXTooManyColumns = fmt.Errorf("result has too many columns for XLSX export, at most %d are allowed", xlsxMaxColumns)
+	ErrXLSXTooManyRows    = fmt.Errorf("result has too many rows for XLSX export, at most %d are allowed", xlsxMaxRows-1)
+
+	// Column types written as numeric cells
+	xlsxNumericTypes = map[string]bool{
+		"INT2": true, "INT4": true, "INT8": true, "OID": true,
+		"FLOAT4": true, "FLOAT8": true, "NUMERIC": true,
+	}
+
+	// Excel stores dates as days since 1899-12-30
+	xlsxEpoch   = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	xlsxMinDate = time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Result" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`
+
+// WriteXLSX writes the result as an Excel workbook into w, with the column names
+// in the frozen first row. Numbers and timestamps are written as typed cells.
+func (res *Result) WriteXLSX(w io.Writer) error {
+	if len(res.Columns) > xlsxMaxColumns {
+		return ErrXLSXTooManyColumns
+	}
+	if len(res.Rows) > xlsxMaxRows-1 {
+		return ErrXLSXTooManyRows
+	}
+
+	archive := zip.NewWriter(w)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if err := res.writeXLSXSheet(f); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (res *Result) writeXLSXSheet(w io.Writer) error {
+	buff := bufio.NewWriter(w)
+
+	buff.WriteString(xml.Header)
+	buff.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	buff.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	buff.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	buff.WriteString(`</sheetView></sheetViews><sheetData>`)
+
+	buff.WriteString(`<row r="1">`)
+	for i, col := range res.Columns {
+		writeXLSXString(buff, xlsxCellRef(i, 1), col, xlsxStyleHeader)
+	}
+	buff.WriteString(`</row>`)
+
+	for rowIdx, row := range res.Rows {
+		num := rowIdx + 2
+		fmt.Fprintf(buff, `<row r="%d">`, num)
+
+		for i, item := range row {
+			if i >= len(res.Columns) {
+				break
+			}
+
+			colType := ""
+			if i < len(res.ColumnTypes) {
+				colType = res.ColumnTypes[i]
+			}
+			writeXLSXCell(buff, xlsxCellRef(i, num), item, colType)
+		}
+
+		buff.WriteString(`</row>`)
+	}
+
+	buff.WriteString(`</sheetData></worksheet>`)
+	return buff.Flush()
+}
+
+func writeXLSXCell(w *bufio.Writer, ref string, item interface{}, colType string) {
+	switch v := item.(type) {
+	case nil:
+		return
+	case bool:
+		val := 0
+		if v {
+			val = 1
+		}
+		fmt.Fprintf(w, `<c r="%s" t="b"><v>%d</v></c>`, ref, val)
+	case int:
+		fmt.Fprintf(w, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case int64:
+		fmt.Fprintf(w, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case float64:
+		writeXLSXNumber(w, ref, v, strconv.FormatFloat(v, 'g', -1, 64))
+	case time.Time:
+		serial, ok := xlsxDateSerial(v)
+		if !ok {
+			writeXLSXString(w, ref, v.Format("2006-01-02 15:04:05"), 0)
+			return
+		}
+
+		style := xlsxStyleDateTime
+		if colType == "DATE" {
+			style = xlsxStyleDate
+		}
+		fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+	case string:
+		// Numeric and big integer values are serialized as strings
+		if xlsxNumericTypes[colType] {
+			if num, err := strconv.ParseFloat(v, 64); err == nil {
+				writeXLSXNumber(w, ref, num, v)
+				return
+			}
+		}
+		writeXLSXString(w, ref, v, 0)
+	default:
+		writeXLSXString(w, ref, fmt.Sprintf("%v", v), 0)
+	}
+}
+
+// writeXLSXNumber writes a numeric cell, or the text when the value is not a
+// finite number, ie. NaN or Infinity
+func writeXLSXNumber(w *bufio.Writer, ref string, num float64, text string) {
+	if math.IsNaN(num) || math.IsInf(num, 0) {
+		writeXLSXString(w, ref, text, 0)
+		return
+	}
+	fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(num, 'g', -1, 64))
+}
+
+func writeXLSXString(w *bufio.Writer, ref string, val string, style int) {
+	w.WriteString(`<c r="` + ref + `" t="inlineStr"`)
+	if style > 0 {
+		w.WriteString(` s="` + strconv.Itoa(style) + `"`)
+	}
+	w.WriteString(`><is><t xml:space="preserve">`)
+
+	// Characters not allowed in XML are replaced with U+FFFD
+	xml.EscapeText(w, []byte(val)) //nolint:errcheck
+	w.WriteString(`</t></is></c>`)
+}
+
+// xlsxCellRef returns the cell reference of the 0-based column and 1-based row, ie. AB12
+func xlsxCellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
+
+// xlsxDateSerial returns the wall clock time as days since the Excel epoch.
+// Dates before March 1900 are not supported due to the Excel 1900 leap year bug.
+func xlsxDateSerial(t time.Time) (float64, bool) {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if wall.Before(xlsxMinDate) || wall.Year() > 9999 {
+		return 0, false
+	}
+
+	days := float64(wall.Unix()-xlsxEpoch.Unix()) / 86400
+	return days + float64(wall.Nanosecond())/float64(24*time.Hour), true
+}
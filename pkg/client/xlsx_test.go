@@ -0,0 +1,78 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readXLSXPart(t *testing.T, data []byte, name string) string {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	f, err := archive.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestWriteXLSX(t *testing.T) {
+	result := Result{
+		Columns:     []string{"id", "price", "name", "created_at", "day", "active"},
+		ColumnTypes: []string{"INT4", "NUMERIC", "TEXT", "TIMESTAMP", "DATE", "BOOL"},
+		Rows: []Row{
+			{int64(1), "10.50", "Tom & <Jerry>", time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), true},
+			{int64(2), "NaN", nil, time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC), nil, false},
+		},
+	}
+
+	buff := &bytes.Buffer{}
+	require.NoError(t, result.WriteXLSX(buff))
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.NotEmpty(t, readXLSXPart(t, buff.Bytes(), name))
+	}
+
+	sheet := readXLSXPart(t, buff.Bytes(), "xl/worksheets/sheet1.xml")
+	assert.Contains(t, sheet, `<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	assert.Contains(t, sheet, `<c r="A1" t="inlineStr" s="1"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>10.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2" t="inlineStr"><is><t xml:space="preserve">Tom &amp; &lt;Jerry&gt;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="D2" s="2"><v>44562.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="E2" s="3"><v>44563</v></c>`)
+	assert.Contains(t, sheet, `<c r="F2" t="b"><v>1</v></c>`)
+	assert.Contains(t, sheet, `<c r="B3" t="inlineStr"><is><t xml:space="preserve">NaN</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="D3" t="inlineStr"><is><t xml:space="preserve">1800-01-01 00:00:00</t></is></c>`)
+	assert.NotContains(t, sheet, `r="C3"`)
+}
+
+func TestWriteXLSXLimits(t *testing.T) {
+	result := Result{Columns: make([]string, xlsxMaxColumns+1)}
+
+	buff := &bytes.Buffer{}
+	assert.Equal(t, ErrXLSXTooManyColumns, result.WriteXLSX(buff))
+	assert.Equal(t, 0, buff.Len())
+}
+
+func Test_xlsxCellRef(t *testing.T) {
+	examples := map[string][2]int{
+		"A1":     {0, 1},
+		"Z2":     {25, 2},
+		"AA3":    {26, 3},
+		"AZ10":   {51, 10},
+		"XFD100": {16383, 100},
+	}
+
+	for expected, ex := range examples {
+		assert.Equal(t, expected, xlsxCellRef(ex[0], ex[1]))
+	}
+}
@@ -3,9 +3,12 @@ package command
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -20,55 +23,99 @@ const (
 	envVarPrefix = "PGWEB_"
 )
 
+// Styles supported by the --banner-level option
+var bannerLevels = []string{"info", "warning", "danger"}
+
+// Codecs supported by the --binary-codec option
+var binaryCodecs = []string{"none", "hex", "base58", "base64", "base85", "base32"}
+
 type Options struct {
 	Version                      bool   `short:"v" long:"version" description:"Print version"`
 	Debug                        bool   `short:"d" long:"debug" description:"Enable debugging mode"`
+	Check                        bool   `long:"check" description:"Validate options and database connectivity, then exit"`
 	LogLevel                     string `long:"log-level" description:"Logging level" default:"info"`
 	LogFormat                    string `long:"log-format" description:"Logging output format" default:"text"`
 	LogForwardedUser             bool   `long:"log-forwarded-user" description:"Log user information available in X-Forwarded-User/Email headers"`
+	LogQueryFingerprint          bool   `long:"log-query-fingerprint" description:"Log each query with its literals replaced by ?, along with its duration and rows count"`
 	URL                          string `long:"url" description:"Database connection string"`
 	Host                         string `long:"host" description:"Server hostname or IP" default:"localhost"`
 	Port                         int    `long:"port" description:"Server port" default:"5432"`
+	Socket                       string `long:"socket" description:"Directory of the server Unix domain socket, used instead of the host (e.g., /var/run/postgresql)"`
 	User                         string `long:"user" description:"Database user"`
-	Pass                         string `long:"pass" description:"Password for user"`
+	Pass                         string `long:"pass" description:"Password for user" secret:"true"`
 	Passfile                     string `long:"passfile" description:"Local passwords file location"`
 	DbName                       string `long:"db" description:"Database name"`
+	DefaultSchema                string `long:"default-schema" description:"Schema used for unqualified object names" default:"public"`
 	SSLMode                      string `long:"ssl" description:"SSL mode"`
 	SSLRootCert                  string `long:"ssl-rootcert" description:"SSL certificate authority file"`
 	SSLCert                      string `long:"ssl-cert" description:"SSL client certificate file"`
 	SSLKey                       string `long:"ssl-key" description:"SSL client certificate key file"`
+	PgBouncer                    bool   `long:"pgbouncer" description:"Use the simple query protocol for PgBouncer transaction pooling compatibility"`
+	PreparedStatements           bool   `long:"prepared-statements" description:"Prepare parameterized queries once per session and reuse them on repeated runs"`
 	OpenTimeout                  int    `long:"open-timeout" description:"Maximum wait time for connection, in seconds" default:"30"`
 	RetryDelay                   uint   `long:"open-retry-delay" description:"Number of seconds to wait before retrying the connection" default:"3"`
 	RetryCount                   uint   `long:"open-retry" description:"Number of times to retry establishing connection" default:"0"`
-	HTTPHost                     string `long:"bind" description:"HTTP server host" default:"localhost"`
+	HTTPHost                     string `long:"bind" description:"HTTP server host, or comma-separated list of host[:port] addresses" default:"localhost"`
 	HTTPPort                     uint   `long:"listen" description:"HTTP server listen port" default:"8081"`
 	AuthUser                     string `long:"auth-user" description:"HTTP basic auth user"`
-	AuthPass                     string `long:"auth-pass" description:"HTTP basic auth password"`
+	AuthPass                     string `long:"auth-pass" description:"HTTP basic auth password" secret:"true"`
+	AuthUsers                    string `long:"auth-users" description:"File of HTTP basic auth users, one user:bcrypt-hash:mode line per user where mode is rw or ro (read-only)"`
+	AdminToken                   string `long:"admin-token" description:"Token required in X-Admin-Token header to access admin endpoints" secret:"true"`
 	SkipOpen                     bool   `short:"s" long:"skip-open" description:"Skip browser open on start"`
 	Sessions                     bool   `long:"sessions" description:"Enable multiple database sessions"`
 	Prefix                       string `long:"prefix" description:"Add a url prefix"`
+	StripPrefix                  bool   `long:"strip-prefix" description:"Accept requests with the url prefix already stripped by a reverse proxy"`
 	ReadOnly                     bool   `long:"readonly" description:"Run database connection in readonly mode"`
+	ReadOnlySchemas              string `long:"readonly-schemas" description:"Comma-separated list of schema names or regex patterns that can't be written to, checked against the query text (e.g., 'reporting,audit_.*')"`
+	WrapSelectReadOnly           bool   `long:"wrap-select-readonly" description:"Run SELECT queries in a read-only transaction, so writes hidden in them fail"`
+	ReadOnlyAllowFunctions       string `long:"readonly-allow-functions" description:"Comma-separated list of functions and procedures that can be called in read-only mode, schema-qualified names are recommended (e.g., 'reporting.refresh_summary')"`
+	AutoReturning                bool   `long:"auto-returning" description:"Append RETURNING * to UPDATE and DELETE statements to show the affected rows"`
+	ApplicationName              string `long:"application-name" description:"Template of the application_name set for the queries of each request, with {user}, {session}, {database} and {request_id} variables (e.g., 'pgweb:{user}:{request_id}')"`
 	LockSession                  bool   `long:"lock-session" description:"Lock session to a single database connection"`
 	Bookmark                     string `short:"b" long:"bookmark" description:"Bookmark to use for connection. Bookmark files are stored under $HOME/.pgweb/bookmarks/*.toml" default:""`
 	BookmarksDir                 string `long:"bookmarks-dir" description:"Overrides default directory for bookmark files to search" default:""`
 	BookmarksOnly                bool   `long:"bookmarks-only" description:"Allow only connections from bookmarks"`
 	QueriesDir                   string `long:"queries-dir" description:"Overrides default directory for local queries"`
-	DisablePrettyJSON            bool   `long:"no-pretty-json" description:"Disable JSON formatting feature for result export"`
+	SQLOverrideDir               string `long:"sql-override-dir" description:"Directory of SQL files overriding the built-in metadata queries, ie. objects.sql or table_info.sql"`
+	HistoryFile                  string `long:"history-file" description:"Overrides default file for the query history. History files are stored under $HOME/.pgweb/history/<session>.jsonl"`
+	DisableHistory               bool   `long:"no-history" description:"Disable saving the query history to disk"`
+	DisablePrettyJSON            bool   `long:"no-pretty-json" description:"Disable JSON formatting of result exports and json column values"`
 	DisableSSH                   bool   `long:"no-ssh" description:"Disable database connections via SSH"`
+	RequireSSH                   bool   `long:"require-ssh" description:"Require all database connections to use SSH"`
+	SSHKnownHosts                string `long:"ssh-known-hosts" description:"known_hosts file used to verify the SSH server host keys (default: ~/.ssh/known_hosts)"`
+	SSHInsecureIgnoreHostKey     bool   `long:"ssh-insecure-ignore-host-key" description:"Trust any SSH server host key, connections are open to man-in-the-middle attacks"`
 	ConnectBackend               string `long:"connect-backend" description:"Enable database authentication through a third party backend"`
-	ConnectToken                 string `long:"connect-token" description:"Authentication token for the third-party connect backend"`
+	ConnectToken                 string `long:"connect-token" description:"Authentication token for the third-party connect backend" secret:"true"`
 	ConnectHeaders               string `long:"connect-headers" description:"List of headers to pass to the connect backend"`
 	DisableConnectionIdleTimeout bool   `long:"no-idle-timeout" description:"Disable connection idle timeout"`
 	ConnectionIdleTimeout        int    `long:"idle-timeout" description:"Set connection idle timeout in minutes" default:"180"`
+	MaxSessions                  int    `long:"max-sessions" description:"Maximum number of concurrent database sessions (0 for unlimited)"`
+	MaxColumns                   int    `long:"max-columns" description:"Maximum number of columns returned to the UI, extra columns are truncated (0 for unlimited)"`
+	DefaultRowsLimit             int    `long:"default-rows-limit" description:"Number of rows returned when browsing a table without a limit (0 for unlimited)" default:"1000"`
+	EvictIdleSessions            bool   `long:"evict-idle-sessions" description:"Close the least recently used idle session when the sessions limit is reached"`
 	QueryTimeout                 uint   `long:"query-timeout" description:"Set global query execution timeout in seconds" default:"300"`
+	MaxQueryTimeout              uint   `long:"max-query-timeout" description:"Maximum query timeout in seconds a request may set with the X-Query-Timeout header or timeout parameter"`
+	MetadataTimeout              uint   `long:"metadata-timeout" description:"Set execution timeout in seconds for metadata queries like the objects list"`
+	WorkMem                      string `long:"work-mem" description:"Default work_mem applied to each query (e.g., '64MB')"`
+	MaxWorkMem                   string `long:"max-work-mem" description:"Maximum work_mem a request may set with the X-Work-Mem header"`
 	Cors                         bool   `long:"cors" description:"Enable Cross-Origin Resource Sharing (CORS)"`
 	CorsOrigin                   string `long:"cors-origin" description:"Allowed CORS origins" default:"*"`
-	BinaryCodec                  string `long:"binary-codec" description:"Codec for binary data serialization, one of 'none', 'hex', 'base58', 'base64'" default:"none"`
+	BinaryCodec                  string `long:"binary-codec" description:"Codec for binary data serialization, one of 'none', 'hex', 'base58', 'base64', 'base85', 'base32'" default:"none"`
+	NumbersAsStrings             bool   `long:"numbers-as-strings" description:"Serialize all integer and floating point values as JSON strings"`
+	NotifyChannel                string `long:"notify-channel" description:"Channel listened to for notifications forwarded to the browser, ie. sent with NOTIFY pgweb_progress, '42%'" default:"pgweb_progress"`
+	StripColumnPrefixes          string `long:"strip-column-prefixes" description:"Comma-separated list of schema names stripped from qualified result column names, unless it makes them ambiguous (e.g., 'public,reporting')"`
+	MoneyAsNumeric               bool   `long:"money-as-numeric" description:"Serialize money values as plain decimal strings instead of the locale-formatted ones"`
+	BannerText                   string `long:"banner-text" description:"Message displayed in a banner to all users, ie. 'You are connected to PRODUCTION'"`
+	BannerLevel                  string `long:"banner-level" description:"Style of the banner, one of 'info', 'warning', 'danger'" default:"info"`
 	MetricsEnabled               bool   `long:"metrics" description:"Enable Prometheus metrics endpoint"`
 	MetricsPath                  string `long:"metrics-path" description:"Path prefix for Prometheus metrics endpoint" default:"/metrics"`
 	MetricsAddr                  string `long:"metrics-addr" description:"Listen host and port for Prometheus metrics server"`
 	HideSchemas                  string `long:"hide-schemas" description:"Comma-separated list of regex patterns to hide schemas (e.g., 'public,meta')"`
+	AllowedDatabases             string `long:"allowed-databases" description:"Comma-separated list of database names or regex patterns allowed for connection (e.g., 'app,tenant_.*')"`
+	AllowLocalhost               bool   `long:"allow-localhost" description:"Allow sessions mode connections to loopback addresses"`
+	AllowedHosts                 string `long:"allowed-hosts" description:"Comma-separated list of CIDR ranges or host name regex patterns allowed for connection (e.g., '10.0.0.0/8,db[0-9]+')"`
 	HideObjects                  string `long:"hide-objects" description:"Comma-separated list of regex patterns to hide objects/tables (e.g., '^temp_,_backup$')"`
+	ObjectCategories             string `long:"object-categories" description:"Comma-separated list of regex:label rules to categorize objects (e.g., '_h$:Hub,_l$:Link')"`
 	FontFamily                   string `long:"font-family" description:"CSS font family to use (e.g., 'Inter', 'Roboto', 'Space Grotesk')"`
 	FontSize                     string `long:"font-size" description:"CSS font size to use (e.g., '14px', '16px')" default:"14px"`
 	GoogleFonts                  string `long:"google-fonts" description:"Comma-separated list of Google Fonts to preload (e.g., 'Inter:300,400,500,700')"`
@@ -76,6 +123,16 @@ type Options struct {
 	DisableMetadataCache         bool   `long:"no-metadata-cache" description:"Disable metadata caching"`
 	QueryCacheTTL                uint   `long:"query-cache-ttl" description:"Query cache TTL in seconds" default:"300"`
 	MetadataCacheTTL             uint   `long:"metadata-cache-ttl" description:"Metadata cache TTL in seconds" default:"600"`
+	MetadataCacheRefresh         bool   `long:"metadata-cache-refresh" description:"Refresh frequently read metadata in the background before it expires"`
+
+	// Patterns compiled from the options above when they are parsed
+	CategoryRules          []CategoryRule   `no-flag:"true"`
+	ReadOnlySchemaPatterns []*regexp.Regexp `no-flag:"true"`
+	ReadOnlyFunctionNames  []string         `no-flag:"true"`
+	ColumnPrefixes         []string         `no-flag:"true"`
+
+	// Directory of the query history files of the sessions
+	HistoryDir string `no-flag:"true"`
 }
 
 var Opts Options
@@ -102,6 +159,26 @@ func ParseOptions(args []string) (Options, error) {
 		opts.Prefix = getPrefixedEnvVar("URL_PREFIX")
 	}
 
+	if opts.HistoryFile == "" {
+		opts.HistoryFile = getPrefixedEnvVar("HISTORY_FILE")
+	}
+
+	if getPrefixedEnvVar("NO_HISTORY") != "" {
+		opts.DisableHistory = true
+	}
+
+	if opts.Socket == "" {
+		opts.Socket = getPrefixedEnvVar("SOCKET")
+	}
+
+	// Socket directory takes the place of the host, connection strings tell them apart by the leading slash
+	if opts.Socket != "" {
+		if !filepath.IsAbs(opts.Socket) {
+			return opts, errors.New("--socket flag must be an absolute directory path")
+		}
+		opts.Host = opts.Socket
+	}
+
 	if opts.Passfile == "" {
 		passfile := os.Getenv("PGPASSFILE")
 		if passfile == "" {
@@ -133,10 +210,28 @@ func ParseOptions(args []string) (Options, error) {
 		opts.BookmarksOnly = true
 	}
 
+	if getPrefixedEnvVar("STRIP_PREFIX") != "" {
+		opts.StripPrefix = true
+	}
+
 	if getPrefixedEnvVar("SESSIONS") != "" {
 		opts.Sessions = true
 	}
 
+	if getPrefixedEnvVar("ALLOW_LOCALHOST") != "" {
+		opts.AllowLocalhost = true
+	}
+
+	if opts.MaxSessions == 0 {
+		if max, err := strconv.Atoi(getPrefixedEnvVar("MAX_SESSIONS")); err == nil {
+			opts.MaxSessions = max
+		}
+	}
+
+	if getPrefixedEnvVar("EVICT_IDLE_SESSIONS") != "" {
+		opts.EvictIdleSessions = true
+	}
+
 	if getPrefixedEnvVar("LOCK_SESSION") != "" {
 		opts.LockSession = true
 		opts.Sessions = false
@@ -164,14 +259,66 @@ func ParseOptions(args []string) (Options, error) {
 		opts.AuthPass = getPrefixedEnvVar("AUTH_PASS")
 	}
 
+	if opts.SSHKnownHosts == "" {
+		opts.SSHKnownHosts = getPrefixedEnvVar("SSH_KNOWN_HOSTS")
+	}
+
+	if opts.AuthUsers == "" {
+		opts.AuthUsers = getPrefixedEnvVar("AUTH_USERS")
+	}
+
+	if opts.AdminToken == "" {
+		opts.AdminToken = getPrefixedEnvVar("ADMIN_TOKEN")
+	}
+
 	if opts.HideSchemas == "" {
 		opts.HideSchemas = getPrefixedEnvVar("HIDE_SCHEMAS")
 	}
 
+	if opts.AllowedDatabases == "" {
+		opts.AllowedDatabases = getPrefixedEnvVar("ALLOWED_DATABASES")
+	}
+
+	if opts.ReadOnlySchemas == "" {
+		opts.ReadOnlySchemas = getPrefixedEnvVar("READONLY_SCHEMAS")
+	}
+
+	if opts.ReadOnlyAllowFunctions == "" {
+		opts.ReadOnlyAllowFunctions = getPrefixedEnvVar("READONLY_ALLOW_FUNCTIONS")
+	}
+
+	if opts.StripColumnPrefixes == "" {
+		opts.StripColumnPrefixes = getPrefixedEnvVar("STRIP_COLUMN_PREFIXES")
+	}
+
+	if opts.SQLOverrideDir == "" {
+		opts.SQLOverrideDir = getPrefixedEnvVar("SQL_OVERRIDE_DIR")
+	}
+
+	if opts.ApplicationName == "" {
+		opts.ApplicationName = getPrefixedEnvVar("APPLICATION_NAME")
+	}
+
+	if opts.AllowedHosts == "" {
+		opts.AllowedHosts = getPrefixedEnvVar("ALLOWED_HOSTS")
+	}
+
 	if opts.HideObjects == "" {
 		opts.HideObjects = getPrefixedEnvVar("HIDE_OBJECTS")
 	}
 
+	if opts.ObjectCategories == "" {
+		opts.ObjectCategories = getPrefixedEnvVar("OBJECT_CATEGORIES")
+	}
+
+	if opts.BannerText == "" {
+		opts.BannerText = getPrefixedEnvVar("BANNER_TEXT")
+	}
+
+	if envBannerLevel := getPrefixedEnvVar("BANNER_LEVEL"); envBannerLevel != "" && opts.BannerLevel == "info" {
+		opts.BannerLevel = envBannerLevel
+	}
+
 	if opts.FontFamily == "" {
 		opts.FontFamily = getPrefixedEnvVar("FONT_FAMILY")
 	}
@@ -224,6 +371,56 @@ func ParseOptions(args []string) (Options, error) {
 		}
 	}
 
+	if len(BindAddresses(opts)) == 0 {
+		return opts, errors.New("--bind flag must contain at least one address")
+	}
+
+	if opts.AuthUsers != "" && (opts.AuthUser != "" || opts.AuthPass != "") {
+		return opts, errors.New("--auth-users and --auth-user flags can't be used together")
+	}
+
+	if opts.StripPrefix && opts.Prefix == "" {
+		return opts, errors.New("--prefix flag must be set")
+	}
+
+	if opts.RequireSSH && opts.DisableSSH {
+		return opts, errors.New("--require-ssh and --no-ssh flags can't be used together")
+	}
+
+	if opts.SSHInsecureIgnoreHostKey && opts.SSHKnownHosts != "" {
+		return opts, errors.New("--ssh-known-hosts and --ssh-insecure-ignore-host-key flags can't be used together")
+	}
+
+	if opts.DefaultRowsLimit < 0 {
+		return opts, errors.New("--default-rows-limit flag must not be negative")
+	}
+
+	if opts.PgBouncer && opts.PreparedStatements {
+		return opts, errors.New("--pgbouncer and --prepared-statements flags can't be used together")
+	}
+
+	if !slices.Contains(bannerLevels, opts.BannerLevel) {
+		return opts, fmt.Errorf("--banner-level flag must be one of: %s", strings.Join(bannerLevels, ", "))
+	}
+
+	if opts.BinaryCodec != "" && !slices.Contains(binaryCodecs, opts.BinaryCodec) {
+		return opts, fmt.Errorf("--binary-codec flag must be one of: %s", strings.Join(binaryCodecs, ", "))
+	}
+
+	if opts.CategoryRules, err = CompileCategoryRules(opts.ObjectCategories); err != nil {
+		return opts, fmt.Errorf("--object-categories flag is invalid: %v", err)
+	}
+
+	if opts.ReadOnlySchemaPatterns, err = CompileAllowPatterns(opts.ReadOnlySchemas); err != nil {
+		return opts, fmt.Errorf("--readonly-schemas flag is invalid: %v", err)
+	}
+
+	if opts.ReadOnlyFunctionNames, err = ParseFunctionNames(opts.ReadOnlyAllowFunctions); err != nil {
+		return opts, fmt.Errorf("--readonly-allow-functions flag is invalid: %v", err)
+	}
+
+	opts.ColumnPrefixes = ParseColumnPrefixes(opts.StripColumnPrefixes)
+
 	if opts.BookmarksOnly {
 		if opts.URL != "" {
 			return opts, errors.New("--url not supported in bookmarks-only mode")
@@ -254,11 +451,34 @@ func ParseOptions(args []string) (Options, error) {
 		if opts.QueriesDir == "" {
 			opts.QueriesDir = filepath.Join(homePath, ".pgweb/queries")
 		}
+
+		opts.HistoryDir = filepath.Join(homePath, ".pgweb/history")
 	}
 
 	return opts, nil
 }
 
+// BindAddresses returns the HTTP server listen addresses. Entries without a port
+// use the --listen port.
+func BindAddresses(opts Options) []string {
+	addrs := []string{}
+
+	for _, host := range strings.Split(opts.HTTPHost, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			addrs = append(addrs, host)
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(int(opts.HTTPPort))))
+	}
+
+	return addrs
+}
+
 // SetDefaultOptions parses and assigns the options
 func SetDefaultOptions() error {
 	opts, err := ParseOptions([]string{})
@@ -298,13 +518,19 @@ func AvailableEnvVars() string {
 	return strings.Join([]string{
 		"  " + envVarPrefix + "DATABASE_URL  Database connection string",
 		"  " + envVarPrefix + "URL_PREFIX    HTTP server path prefix",
+		"  " + envVarPrefix + "STRIP_PREFIX  Accept requests with the path prefix stripped by a proxy",
 		"  " + envVarPrefix + "SESSIONS      Enable multiple database sessions",
 		"  " + envVarPrefix + "LOCK_SESSION  Lock session to a single database connection",
 		"  " + envVarPrefix + "AUTH_USER     HTTP basic auth username",
 		"  " + envVarPrefix + "AUTH_PASS     HTTP basic auth password",
+		"  " + envVarPrefix + "ADMIN_TOKEN   Token required to access admin endpoints",
 		"  " + envVarPrefix + "BOOKMARKS_DIR Overrides default directory for bookmark files",
 		"  " + envVarPrefix + "HIDE_SCHEMAS  Comma-separated regex patterns to hide schemas",
 		"  " + envVarPrefix + "HIDE_OBJECTS  Comma-separated regex patterns to hide objects/tables",
+		"  " + envVarPrefix + "OBJECT_CATEGORIES Comma-separated regex:label rules to categorize objects",
+		"  " + envVarPrefix + "ALLOWED_DATABASES Comma-separated database names or regex patterns allowed for connection",
+		"  " + envVarPrefix + "ALLOW_LOCALHOST Allow sessions mode connections to loopback addresses",
+		"  " + envVarPrefix + "ALLOWED_HOSTS Comma-separated CIDR ranges or host name patterns allowed for connection",
 		"  " + envVarPrefix + "FONT_FAMILY   CSS font family to use",
 		"  " + envVarPrefix + "FONT_SIZE     CSS font size to use (default: 14px)",
 		"  " + envVarPrefix + "GOOGLE_FONTS  Comma-separated list of Google Fonts to preload",
@@ -20,6 +20,7 @@ func TestParseOptions(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, false, opts.Sessions)
 		assert.Equal(t, "", opts.Prefix)
+		assert.Equal(t, "public", opts.DefaultSchema)
 		assert.Equal(t, "", opts.ConnectToken)
 		assert.Equal(t, "", opts.ConnectHeaders)
 		assert.Equal(t, false, opts.DisableSSH)
@@ -30,6 +31,16 @@ func TestParseOptions(t *testing.T) {
 		assert.Equal(t, "*", opts.CorsOrigin)
 		assert.Equal(t, "", opts.Passfile)
 		assert.Equal(t, filepath.Join(hdir, ".pgweb/bookmarks"), opts.BookmarksDir)
+		assert.Equal(t, 1000, opts.DefaultRowsLimit)
+	})
+
+	t.Run("default rows limit", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--default-rows-limit", "0"})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, opts.DefaultRowsLimit)
+
+		_, err = ParseOptions([]string{"--default-rows-limit", "-1"})
+		assert.EqualError(t, err, "--default-rows-limit flag must not be negative")
 	})
 
 	t.Run("sessions", func(t *testing.T) {
@@ -48,6 +59,29 @@ func TestParseOptions(t *testing.T) {
 		assert.Equal(t, "pgweb/", opts.Prefix)
 	})
 
+	t.Run("bind addresses", func(t *testing.T) {
+		opts, err := ParseOptions([]string{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"localhost:8081"}, BindAddresses(opts))
+
+		opts, err = ParseOptions([]string{"--bind", "10.0.0.1, ::1,[fd00::1]:9000,0.0.0.0:9001,[::]", "--listen", "8000"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1:8000", "[::1]:8000", "[fd00::1]:9000", "0.0.0.0:9001", "[::]:8000"}, BindAddresses(opts))
+
+		_, err = ParseOptions([]string{"--bind", " , "})
+		assert.EqualError(t, err, "--bind flag must contain at least one address")
+	})
+
+	t.Run("strip prefix", func(t *testing.T) {
+		_, err := ParseOptions([]string{"--strip-prefix"})
+		assert.EqualError(t, err, "--prefix flag must be set")
+
+		opts, err := ParseOptions([]string{"--strip-prefix", "--prefix", "pgweb"})
+		assert.NoError(t, err)
+		assert.Equal(t, true, opts.StripPrefix)
+		assert.Equal(t, "pgweb/", opts.Prefix)
+	})
+
 	t.Run("connect backend", func(t *testing.T) {
 		_, err := ParseOptions([]string{"--connect-backend", "test"})
 		assert.EqualError(t, err, "--sessions flag must be set")
@@ -59,6 +93,24 @@ func TestParseOptions(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("require ssh", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--require-ssh"})
+		assert.NoError(t, err)
+		assert.Equal(t, true, opts.RequireSSH)
+
+		_, err = ParseOptions([]string{"--require-ssh", "--no-ssh"})
+		assert.EqualError(t, err, "--require-ssh and --no-ssh flags can't be used together")
+	})
+
+	t.Run("prepared statements", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--prepared-statements"})
+		assert.NoError(t, err)
+		assert.Equal(t, true, opts.PreparedStatements)
+
+		_, err = ParseOptions([]string{"--pgbouncer", "--prepared-statements"})
+		assert.EqualError(t, err, "--pgbouncer and --prepared-statements flags can't be used together")
+	})
+
 	t.Run("passfile", func(t *testing.T) {
 		defer os.Unsetenv("PGPASSFILE")
 
@@ -101,6 +153,22 @@ func TestParseOptions(t *testing.T) {
 		assert.Equal(t, flagDir, opts.BookmarksDir)
 	})
 
+	t.Run("max sessions from env vars", func(t *testing.T) {
+		os.Setenv("PGWEB_MAX_SESSIONS", "10")
+		os.Setenv("PGWEB_EVICT_IDLE_SESSIONS", "1")
+		defer os.Unsetenv("PGWEB_MAX_SESSIONS")
+		defer os.Unsetenv("PGWEB_EVICT_IDLE_SESSIONS")
+
+		opts, err := ParseOptions([]string{})
+		assert.NoError(t, err)
+		assert.Equal(t, 10, opts.MaxSessions)
+		assert.True(t, opts.EvictIdleSessions)
+
+		opts, err = ParseOptions([]string{"--max-sessions", "5"})
+		assert.NoError(t, err)
+		assert.Equal(t, 5, opts.MaxSessions)
+	})
+
 	t.Run("bookmarks only mode", func(t *testing.T) {
 		_, err := ParseOptions([]string{"--bookmarks-only"})
 		assert.NoError(t, err)
@@ -130,4 +198,100 @@ func TestParseOptions(t *testing.T) {
 		assert.Equal(t, "public", opts.HideSchemas)
 		assert.Equal(t, "temp", opts.HideObjects)
 	})
+
+	t.Run("compiled patterns", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--object-categories", "_h$:Hub", "--readonly-schemas", "reporting"})
+		assert.NoError(t, err)
+		assert.Len(t, opts.CategoryRules, 1)
+		assert.Equal(t, "Hub", opts.CategoryRules[0].Label)
+		assert.Len(t, opts.ReadOnlySchemaPatterns, 1)
+		assert.True(t, opts.ReadOnlySchemaPatterns[0].MatchString("reporting"))
+
+		_, err = ParseOptions([]string{"--object-categories", "_h$"})
+		assert.EqualError(t, err, "--object-categories flag is invalid: invalid category rule '_h$': expected regex:label")
+
+		_, err = ParseOptions([]string{"--readonly-schemas", "[invalid"})
+		assert.ErrorContains(t, err, "--readonly-schemas flag is invalid: invalid regex pattern '[invalid'")
+	})
+
+	t.Run("history", func(t *testing.T) {
+		opts, err := ParseOptions([]string{})
+		assert.NoError(t, err)
+		assert.False(t, opts.DisableHistory)
+		assert.Equal(t, "", opts.HistoryFile)
+
+		opts, err = ParseOptions([]string{"--history-file", "/tmp/history.jsonl", "--no-history"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/tmp/history.jsonl", opts.HistoryFile)
+		assert.True(t, opts.DisableHistory)
+	})
+
+	t.Run("socket", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--socket", "/var/run/postgresql", "--user", "postgres"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/var/run/postgresql", opts.Host)
+
+		_, err = ParseOptions([]string{"--socket", "var/run/postgresql"})
+		assert.EqualError(t, err, "--socket flag must be an absolute directory path")
+	})
+
+	t.Run("ssh host keys", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--ssh-known-hosts", "/etc/ssh/ssh_known_hosts"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/etc/ssh/ssh_known_hosts", opts.SSHKnownHosts)
+		assert.False(t, opts.SSHInsecureIgnoreHostKey)
+
+		_, err = ParseOptions([]string{"--ssh-known-hosts", "/etc/ssh/ssh_known_hosts", "--ssh-insecure-ignore-host-key"})
+		assert.EqualError(t, err, "--ssh-known-hosts and --ssh-insecure-ignore-host-key flags can't be used together")
+	})
+
+	t.Run("auth users", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--auth-users", "/etc/pgweb/users"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/etc/pgweb/users", opts.AuthUsers)
+
+		_, err = ParseOptions([]string{"--auth-users", "/etc/pgweb/users", "--auth-user", "admin"})
+		assert.EqualError(t, err, "--auth-users and --auth-user flags can't be used together")
+	})
+
+	t.Run("banner", func(t *testing.T) {
+		opts, err := ParseOptions([]string{})
+		assert.NoError(t, err)
+		assert.Equal(t, "", opts.BannerText)
+		assert.Equal(t, "info", opts.BannerLevel)
+
+		opts, err = ParseOptions([]string{"--banner-text", "You are connected to PRODUCTION", "--banner-level", "danger"})
+		assert.NoError(t, err)
+		assert.Equal(t, "You are connected to PRODUCTION", opts.BannerText)
+		assert.Equal(t, "danger", opts.BannerLevel)
+
+		_, err = ParseOptions([]string{"--banner-level", "critical"})
+		assert.EqualError(t, err, "--banner-level flag must be one of: info, warning, danger")
+	})
+
+	t.Run("binary codec", func(t *testing.T) {
+		for _, codec := range []string{"none", "hex", "base58", "base64", "base85", "base32"} {
+			opts, err := ParseOptions([]string{"--binary-codec", codec})
+			assert.NoError(t, err)
+			assert.Equal(t, codec, opts.BinaryCodec)
+		}
+
+		_, err := ParseOptions([]string{"--binary-codec", "base16"})
+		assert.EqualError(t, err, "--binary-codec flag must be one of: none, hex, base58, base64, base85, base32")
+	})
+
+	t.Run("strip column prefixes", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--strip-column-prefixes", "public, reporting"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"public.", "reporting."}, opts.ColumnPrefixes)
+	})
+
+	t.Run("readonly allowed functions", func(t *testing.T) {
+		opts, err := ParseOptions([]string{"--readonly-allow-functions", "reporting.Refresh_Summary"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"reporting.refresh_summary"}, opts.ReadOnlyFunctionNames)
+
+		_, err = ParseOptions([]string{"--readonly-allow-functions", "refresh; drop"})
+		assert.EqualError(t, err, "--readonly-allow-functions flag is invalid: invalid function name 'refresh; drop'")
+	})
 }
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CategoryRule assigns a category label to objects with names matching the pattern
+type CategoryRule struct {
+	Pattern *regexp.Regexp
+	Label   string
+}
+
+// CompileAllowPatterns compiles comma-separated regex patterns anchored to match the whole item
+func CompileAllowPatterns(patterns string) ([]*regexp.Regexp, error) {
+	regexes := []*regexp.Regexp{}
+
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		regex, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern '%s': %v", pattern, err)
+		}
+		regexes = append(regexes, regex)
+	}
+
+	if len(regexes) == 0 {
+		return nil, nil
+	}
+	return regexes, nil
+}
+
+// CompileCategoryRules compiles comma-separated "regex:label" rules
+func CompileCategoryRules(rules string) ([]CategoryRule, error) {
+	if rules == "" {
+		return nil, nil
+	}
+
+	result := []CategoryRule{}
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		// Label is separated by the last colon so patterns may contain colons
+		idx := strings.LastIndex(rule, ":")
+		if idx <= 0 || idx == len(rule)-1 {
+			return nil, fmt.Errorf("invalid category rule '%s': expected regex:label", rule)
+		}
+
+		pattern := strings.TrimSpace(rule[:idx])
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern '%s': %v", pattern, err)
+		}
+
+		result = append(result, CategoryRule{Pattern: regex, Label: strings.TrimSpace(rule[idx+1:])})
+	}
+
+	return result, nil
+}
+
+var reFunctionName = regexp.MustCompile(`^(?:[a-z_][a-z0-9_$]*\.)?[a-z_][a-z0-9_$]*$`)
+
+// ParseFunctionNames parses comma-separated function names, optionally schema-qualified.
+// Names are lower-cased the same way postgres folds unquoted identifiers.
+func ParseFunctionNames(names string) ([]string, error) {
+	result := []string{}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !reFunctionName.MatchString(name) {
+			return nil, fmt.Errorf("invalid function name '%s'", name)
+		}
+		result = append(result, name)
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// ParseColumnPrefixes parses comma-separated schema names into the prefixes stripped
+// from result column names, ie. "public" matches the "public.id" column.
+func ParseColumnPrefixes(schemas string) []string {
+	result := []string{}
+
+	for _, schema := range strings.Split(schemas, ",") {
+		schema = strings.TrimSuffix(strings.TrimSpace(schema), ".")
+		if schema == "" {
+			continue
+		}
+		result = append(result, schema+".")
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
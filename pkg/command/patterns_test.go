@@ -0,0 +1,62 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileAllowPatterns(t *testing.T) {
+	patterns, err := CompileAllowPatterns("")
+	assert.NoError(t, err)
+	assert.Nil(t, patterns)
+
+	patterns, err = CompileAllowPatterns("reporting, audit_.*,")
+	assert.NoError(t, err)
+	assert.Len(t, patterns, 2)
+	assert.True(t, patterns[1].MatchString("audit_2024"))
+	assert.False(t, patterns[0].MatchString("reporting_old"))
+	assert.False(t, patterns[1].MatchString("old_audit_2024"))
+
+	_, err = CompileAllowPatterns("reporting,[invalid")
+	assert.EqualError(t, err, "invalid regex pattern '[invalid': error parsing regexp: missing closing ]: `[invalid)$`")
+}
+
+func TestCompileCategoryRules(t *testing.T) {
+	rules, err := CompileCategoryRules("")
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+
+	rules, err = CompileCategoryRules("_h$:Hub, _l$:Link,^ns:[a-z]+$:Namespaced")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+	assert.Equal(t, "Hub", rules[0].Label)
+	assert.Equal(t, "^ns:[a-z]+$", rules[2].Pattern.String())
+
+	_, err = CompileCategoryRules("_h$")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "expected regex:label")
+
+	_, err = CompileCategoryRules("[invalid:Label")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid regex pattern")
+}
+
+func TestParseFunctionNames(t *testing.T) {
+	names, err := ParseFunctionNames("")
+	assert.NoError(t, err)
+	assert.Nil(t, names)
+
+	names, err = ParseFunctionNames("Refresh_Report, stats.collect,")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refresh_report", "stats.collect"}, names)
+
+	_, err = ParseFunctionNames("refresh(), stats")
+	assert.EqualError(t, err, "invalid function name 'refresh()'")
+}
+
+func TestParseColumnPrefixes(t *testing.T) {
+	assert.Nil(t, ParseColumnPrefixes(""))
+	assert.Nil(t, ParseColumnPrefixes(" , "))
+	assert.Equal(t, []string{"public.", "Reporting."}, ParseColumnPrefixes("public, Reporting.,"))
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"net/url"
+	"reflect"
+)
+
+// Value shown instead of the secret options, like in url.URL.Redacted
+const redactedValue = "xxxxx"
+
+// RedactedOptions returns the options keyed by their flag name, with the values of
+// the options tagged as secret masked, ie. to check the effective configuration.
+// The password of the connection URL is masked too.
+func RedactedOptions(opts Options) map[string]any {
+	result := map[string]any{}
+
+	val := reflect.ValueOf(opts)
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		// Skip the values compiled from the other options
+		name := field.Tag.Get("long")
+		if name == "" {
+			continue
+		}
+
+		value := val.Field(i).Interface()
+		if field.Tag.Get("secret") == "true" && !val.Field(i).IsZero() {
+			value = redactedValue
+		}
+		result[name] = value
+	}
+
+	if opts.URL != "" {
+		result["url"] = redactURL(opts.URL)
+	}
+
+	return result
+}
+
+// redactURL masks the password of the connection URL, the URLs that can't be
+// parsed are masked entirely
+func redactURL(str string) string {
+	uri, err := url.Parse(str)
+	if err != nil || uri.Scheme == "" {
+		return redactedValue
+	}
+
+	query := uri.Query()
+	if query.Has("password") {
+		query.Set("password", redactedValue)
+		uri.RawQuery = query.Encode()
+	}
+
+	return uri.Redacted()
+}
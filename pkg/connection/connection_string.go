@@ -8,8 +8,10 @@ import (
 	"os/user"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/jackc/pgpassfile"
+	"github.com/lib/pq"
 
 	"github.com/flowbi/pgweb/pkg/command"
 )
@@ -40,6 +42,18 @@ func hasValidPrefix(str string) bool {
 	return strings.HasPrefix(str, "postgres://") || strings.HasPrefix(str, "postgresql://")
 }
 
+// IsSocketHost returns true if the host is the directory of a Unix domain socket
+// rather than a network host, the same way lib/pq tells them apart
+func IsSocketHost(host string) bool {
+	return strings.HasPrefix(host, "/")
+}
+
+// IsSocketURL returns true if the connection string connects over a Unix domain socket
+func IsSocketURL(str string) bool {
+	params, err := ParseParams(str)
+	return err == nil && IsSocketHost(params["host"])
+}
+
 // Extract all query vals and return as a map
 func valsFromQuery(vals neturl.Values) map[string]string {
 	result := map[string]string{}
@@ -49,6 +63,70 @@ func valsFromQuery(vals neturl.Values) map[string]string {
 	return result
 }
 
+// ParseParams returns the connection parameters of the URL or key/value connection
+// string as the driver parses them. Parameters set multiple times, ie. both in the
+// URL and its query, resolve to the value the driver uses.
+func ParseParams(str string) (map[string]string, error) {
+	if hasValidPrefix(str) {
+		dsn, err := pq.ParseURL(str)
+		if err != nil {
+			return nil, err
+		}
+		str = dsn
+	}
+
+	params := map[string]string{}
+	runes := []rune(str)
+	i := 0
+	skipSpaces := func() {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+	}
+
+	for skipSpaces(); i < len(runes); skipSpaces() {
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '=' {
+			i++
+		}
+		key := string(runes[start:i])
+
+		skipSpaces()
+		if i >= len(runes) || runes[i] != '=' {
+			return nil, fmt.Errorf("missing \"=\" after %q in connection string", key)
+		}
+		i++
+		skipSpaces()
+
+		value := []rune{}
+		quoted := i < len(runes) && runes[i] == '\''
+		if quoted {
+			i++
+		}
+		for ; i < len(runes); i++ {
+			r := runes[i]
+			if quoted && r == '\'' || !quoted && unicode.IsSpace(r) {
+				break
+			}
+			if r == '\\' && i+1 < len(runes) {
+				i++
+				r = runes[i]
+			}
+			value = append(value, r)
+		}
+		if quoted {
+			if i >= len(runes) {
+				return nil, errors.New("unterminated quoted string in connection string")
+			}
+			i++
+		}
+
+		params[key] = string(value)
+	}
+
+	return params, nil
+}
+
 // FormatURL reformats the existing connection string
 func FormatURL(opts command.Options) (string, error) {
 	url := opts.URL
@@ -71,7 +149,7 @@ func FormatURL(opts command.Options) (string, error) {
 	if params["sslmode"] == "" {
 		if opts.SSLMode == "" {
 			// Only modify sslmode for local connections
-			if strings.Contains(uri.Host, "localhost") || strings.Contains(uri.Host, "127.0.0.1") {
+			if strings.Contains(uri.Host, "localhost") || strings.Contains(uri.Host, "127.0.0.1") || IsSocketHost(params["host"]) {
 				params["sslmode"] = "disable"
 			}
 		} else {
@@ -90,8 +168,8 @@ func FormatURL(opts command.Options) (string, error) {
 		}
 	}
 
-	// Configure default connect timeout
-	if opts.OpenTimeout > 0 {
+	// Configure default connect timeout, unless already set in the connection string
+	if opts.OpenTimeout > 0 && params["connect_timeout"] == "" {
 		params["connect_timeout"] = strconv.Itoa(opts.OpenTimeout)
 	}
 
@@ -130,7 +208,7 @@ func BuildStringFromOptions(opts command.Options) (string, error) {
 	if opts.SSLMode != "" {
 		query.Add("sslmode", opts.SSLMode)
 	} else {
-		if opts.Host == "localhost" || opts.Host == "127.0.0.1" {
+		if opts.Host == "localhost" || opts.Host == "127.0.0.1" || IsSocketHost(opts.Host) {
 			query.Add("sslmode", "disable")
 		}
 	}
@@ -154,9 +232,18 @@ func BuildStringFromOptions(opts command.Options) (string, error) {
 		query.Add("connect_timeout", strconv.Itoa(opts.OpenTimeout))
 	}
 
+	// Socket directory can't be part of the URL authority, it's passed as a parameter
+	// with the port naming the socket file in it
+	host := fmt.Sprintf("%v:%v", opts.Host, opts.Port)
+	if IsSocketHost(opts.Host) {
+		host = ""
+		query.Add("host", opts.Host)
+		query.Add("port", strconv.Itoa(opts.Port))
+	}
+
 	url := neturl.URL{
 		Scheme:   "postgres",
-		Host:     fmt.Sprintf("%v:%v", opts.Host, opts.Port),
+		Host:     host,
 		User:     neturl.UserPassword(opts.User, opts.Pass),
 		Path:     fmt.Sprintf("/%s", opts.DbName),
 		RawQuery: query.Encode(),
@@ -188,8 +275,14 @@ func lookupPassword(opts command.Options, url *neturl.URL) string {
 		)
 	}
 
+	// Socket connections match the localhost entries, as with libpq
+	host := opts.Host
+	if IsSocketHost(host) {
+		host = "localhost"
+	}
+
 	return passfile.FindPassword(
-		opts.Host,
+		host,
 		fmt.Sprintf("%d", opts.Port),
 		opts.DbName,
 		opts.User,
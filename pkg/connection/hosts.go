@@ -0,0 +1,222 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+var (
+	ErrHostNotAllowed = errors.New("connection to this host is not allowed")
+	ErrHostRestricted = errors.New("connection to local, link-local or metadata addresses is not allowed")
+
+	// Networks never reachable from user-supplied connections
+	restrictedNetworks = mustParseCIDRs(
+		"0.0.0.0/8",         // "This" network, routed to the local host
+		"169.254.0.0/16",    // Link-local, includes cloud metadata endpoints
+		"fe80::/10",         // IPv6 link-local
+		"fd00:ec2::254/128", // AWS IPv6 metadata endpoint
+		"::/128",            // IPv6 unspecified address
+	)
+
+	// Loopback networks, reachable only when explicitly allowed
+	loopbackNetworks = mustParseCIDRs(
+		"127.0.0.0/8",
+		"::1/128",
+	)
+
+	// lookupIP resolves host names, replaced in tests
+	lookupIP = net.LookupIP
+)
+
+// HostAllowlist contains CIDR ranges and host name patterns allowed for connection
+type HostAllowlist struct {
+	networks []*net.IPNet
+	patterns []*regexp.Regexp
+}
+
+// ParseHostAllowlist parses a comma-separated list of CIDR ranges, IP addresses
+// and regex patterns. Patterns must match the whole host name.
+func ParseHostAllowlist(list string) (*HostAllowlist, error) {
+	allowlist := &HostAllowlist{}
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(item); err == nil {
+			allowlist.networks = append(allowlist.networks, network)
+			continue
+		}
+
+		if ip := net.ParseIP(item); ip != nil {
+			allowlist.networks = append(allowlist.networks, singleIPNet(ip))
+			continue
+		}
+
+		pattern, err := regexp.Compile("^(?:" + item + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid host pattern '%s': %v", item, err)
+		}
+		allowlist.patterns = append(allowlist.patterns, pattern)
+	}
+
+	return allowlist, nil
+}
+
+// Check returns an error unless the host matches a pattern, or all of its
+// addresses belong to the allowed networks.
+func (list *HostAllowlist) Check(host string) error {
+	if host == "" {
+		host = "localhost"
+	}
+
+	for _, pattern := range list.patterns {
+		if pattern.MatchString(host) {
+			return nil
+		}
+	}
+
+	if len(list.networks) == 0 {
+		return ErrHostNotAllowed
+	}
+
+	ips, err := resolveHost(host)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range ips {
+		if !list.containsIP(ip) {
+			return ErrHostNotAllowed
+		}
+	}
+
+	return nil
+}
+
+func (list *HostAllowlist) containsIP(ip net.IP) bool {
+	for _, network := range list.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveHost returns the host IP addresses
+func resolveHost(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	ips, err := lookupIP(host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve host %s: %v", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("unable to resolve host %s", host)
+	}
+
+	return ips, nil
+}
+
+func singleIPNet(ip net.IP) *net.IPNet {
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// CheckHostRestricted resolves the host and returns an error if any of its
+// addresses belongs to a restricted network.
+func CheckHostRestricted(host string, allowLoopback bool) error {
+	if host == "" {
+		host = "localhost"
+	}
+
+	// Unix sockets always point to the local host
+	if IsSocketHost(host) {
+		if allowLoopback {
+			return nil
+		}
+		return ErrHostRestricted
+	}
+
+	ips, err := resolveHost(host)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range ips {
+		if err := checkIPRestricted(ip, allowLoopback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestrictedDialer returns a dialer refusing connections to restricted networks.
+// The check is done on the address actually being dialed, so host names resolving
+// to a different address after validation (DNS rebinding) are still rejected.
+func RestrictedDialer(allowLoopback bool) *net.Dialer {
+	return &net.Dialer{
+		Control: func(network, address string, _ syscall.RawConn) error {
+			// Unix sockets always point to the local host
+			if strings.HasPrefix(network, "unix") {
+				if allowLoopback {
+					return nil
+				}
+				return ErrHostRestricted
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return ErrHostRestricted
+			}
+
+			return checkIPRestricted(ip, allowLoopback)
+		},
+	}
+}
+
+func checkIPRestricted(ip net.IP, allowLoopback bool) error {
+	for _, network := range restrictedNetworks {
+		if network.Contains(ip) {
+			return ErrHostRestricted
+		}
+	}
+
+	if !allowLoopback {
+		for _, network := range loopbackNetworks {
+			if network.Contains(ip) {
+				return ErrHostRestricted
+			}
+		}
+	}
+
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
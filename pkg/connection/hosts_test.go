@@ -0,0 +1,121 @@
+package connection
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockLookupIP(t *testing.T, hosts map[string][]string) {
+	orig := lookupIP
+	lookupIP = func(host string) ([]net.IP, error) {
+		addrs, ok := hosts[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		ips := []net.IP{}
+		for _, addr := range addrs {
+			ips = append(ips, net.ParseIP(addr))
+		}
+		return ips, nil
+	}
+	t.Cleanup(func() { lookupIP = orig })
+}
+
+func TestParseHostAllowlist(t *testing.T) {
+	list, err := ParseHostAllowlist("10.0.0.0/8, 192.168.1.5, db[0-9]+\\.example\\.com")
+	require.NoError(t, err)
+	assert.Len(t, list.networks, 2)
+	assert.Len(t, list.patterns, 1)
+
+	_, err = ParseHostAllowlist("[invalid")
+	assert.EqualError(t, err, "invalid host pattern '[invalid': error parsing regexp: missing closing ]: `[invalid)$`")
+}
+
+func TestHostAllowlistCheck(t *testing.T) {
+	mockLookupIP(t, map[string][]string{
+		"db.internal": {"10.1.2.3"},
+		"mixed.host":  {"10.1.2.3", "172.16.0.1"},
+		"localhost":   {"127.0.0.1"},
+	})
+
+	list, err := ParseHostAllowlist("10.0.0.0/8,192.168.1.5,db[0-9]+\\.example\\.com")
+	require.NoError(t, err)
+
+	examples := []struct {
+		host string
+		err  error
+	}{
+		{host: "10.20.30.40"},
+		{host: "192.168.1.5"},
+		{host: "db.internal"},
+		{host: "db1.example.com"},
+		{host: "192.168.1.6", err: ErrHostNotAllowed},
+		{host: "169.254.169.254", err: ErrHostNotAllowed},
+		{host: "mixed.host", err: ErrHostNotAllowed},
+		{host: "", err: ErrHostNotAllowed},
+		{host: "db1.example.com.evil.org", err: errors.New("unable to resolve host db1.example.com.evil.org: no such host")},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.host, func(t *testing.T) {
+			assert.Equal(t, ex.err, list.Check(ex.host))
+		})
+	}
+}
+
+func TestCheckHostRestricted(t *testing.T) {
+	mockLookupIP(t, map[string][]string{
+		"db.example.com": {"203.0.113.10"},
+		"localhost":      {"127.0.0.1", "::1"},
+		"rebind.example": {"203.0.113.10", "169.254.169.254"},
+	})
+
+	examples := []struct {
+		host          string
+		allowLoopback bool
+		err           error
+	}{
+		{host: "db.example.com"},
+		{host: "10.0.0.5"},
+		{host: "169.254.169.254", err: ErrHostRestricted},
+		{host: "169.254.169.254", allowLoopback: true, err: ErrHostRestricted},
+		{host: "fd00:ec2::254", err: ErrHostRestricted},
+		{host: "fe80::1", err: ErrHostRestricted},
+		{host: "0.0.0.0", err: ErrHostRestricted},
+		{host: "rebind.example", err: ErrHostRestricted},
+		{host: "127.0.0.1", err: ErrHostRestricted},
+		{host: "localhost", err: ErrHostRestricted},
+		{host: "", err: ErrHostRestricted},
+		{host: "localhost", allowLoopback: true},
+		{host: "::ffff:127.0.0.1", allowLoopback: true},
+		{host: "/var/run/postgresql", err: ErrHostRestricted},
+		{host: "/var/run/postgresql", allowLoopback: true},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.host, func(t *testing.T) {
+			assert.Equal(t, ex.err, CheckHostRestricted(ex.host, ex.allowLoopback))
+		})
+	}
+}
+
+func TestRestrictedDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Dialed addresses are verified even if the host passed an earlier check
+	_, err = RestrictedDialer(false).Dial("tcp", listener.Addr().String())
+	assert.ErrorIs(t, err, ErrHostRestricted)
+
+	conn, err := RestrictedDialer(true).Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+
+	_, err = RestrictedDialer(true).Dial("tcp", "169.254.169.254:5432")
+	assert.ErrorIs(t, err, ErrHostRestricted)
+}
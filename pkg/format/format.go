@@ -0,0 +1,364 @@
+// Package format pretty-prints SQL queries
+package format
+
+import (
+	"errors"
+	"strings"
+)
+
+const indentation = "  "
+
+var (
+	ErrUnterminatedString  = errors.New("unterminated string literal")
+	ErrUnterminatedComment = errors.New("unterminated comment")
+	ErrUnbalancedParens    = errors.New("unbalanced parentheses")
+
+	// Keywords written in upper case
+	keywords = wordSet(`
+		ALL ALTER ANALYZE AND ANY AS ASC BEGIN BETWEEN BY CASCADE CASE CHECK COLLATE COMMIT
+		CONFLICT CONSTRAINT CREATE CROSS DEFAULT DELETE DESC DISTINCT DO DROP ELSE END ESCAPE
+		EXCEPT EXISTS EXPLAIN FALSE FETCH FILTER FIRST FOR FOREIGN FROM FULL GROUP HAVING IF
+		ILIKE IN INDEX INNER INSERT INTERSECT INTO IS JOIN KEY LAST LATERAL LEFT LIKE LIMIT
+		NATURAL NOT NOTHING NULL NULLS OFFSET ON ONLY OR ORDER OUTER OVER PARTITION PRIMARY
+		RECURSIVE REFERENCES RETURNING RIGHT ROLLBACK SELECT SET SIMILAR SOME TABLE THEN TRUE
+		TRUNCATE UNION UNIQUE UPDATE USING VALUES VIEW WHEN WHERE WINDOW WITH WITHIN`)
+
+	// Clauses starting on a new line, with their content indented on the next lines
+	blockClauses = wordSet(`SELECT FROM WHERE GROUP ORDER HAVING SET VALUES RETURNING WINDOW`)
+
+	// Clauses starting on a new line, with their content on the same line
+	lineClauses = wordSet(`INSERT UPDATE DELETE WITH UNION INTERSECT EXCEPT LIMIT OFFSET FETCH`)
+
+	// Join keywords starting a new line within the FROM clause
+	joinKeywords = wordSet(`JOIN LEFT RIGHT FULL INNER CROSS NATURAL`)
+
+	// Words kept on the clause keyword line, ie. GROUP BY or SELECT DISTINCT
+	clauseCompanions = map[string]string{
+		"GROUP":     "BY",
+		"ORDER":     "BY",
+		"SELECT":    "DISTINCT",
+		"UNION":     "ALL",
+		"INTERSECT": "ALL",
+		"EXCEPT":    "ALL",
+	}
+)
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// Format returns the query with upper-cased keywords, each clause on its own
+// line and the clause content indented. String literals, quoted identifiers
+// and comments are kept as is.
+func Format(sql string) (string, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+
+	f := &formatter{
+		frames:  []frame{{block: true}},
+		pending: -1,
+	}
+	for i, tok := range tokens {
+		if err := f.write(tokens, i, tok); err != nil {
+			return "", err
+		}
+	}
+	if len(f.frames) > 1 {
+		return "", ErrUnbalancedParens
+	}
+
+	return strings.TrimSpace(string(f.out)), nil
+}
+
+// frame is the formatting state within a pair of parentheses
+type frame struct {
+	block   bool // Parentheses enclose a subquery formatted as a block
+	base    int  // Indentation level of the clauses
+	line    int  // Indentation level of the line with the opening parenthesis
+	between bool // BETWEEN is waiting for its AND
+	cases   int  // Number of open CASE expressions
+}
+
+type formatter struct {
+	out       []byte
+	frames    []frame
+	level     int    // Indentation level of the current line
+	pending   int    // Indentation level of a pending line break, -1 if none
+	companion string // Word kept on the current clause line
+	prev      *token
+	keyword   string // Last keyword or opening parenthesis
+	unary     bool   // Previous token is a unary sign
+	endOfStmt bool   // Previous token ended a statement
+}
+
+func (f *formatter) top() *frame {
+	return &f.frames[len(f.frames)-1]
+}
+
+func (f *formatter) atLineStart() bool {
+	return len(f.out) == 0 || f.out[len(f.out)-1] == '\n'
+}
+
+func (f *formatter) writeString(str string) {
+	f.out = append(f.out, str...)
+}
+
+func (f *formatter) newline(level int) {
+	if !f.atLineStart() {
+		f.writeString("\n")
+	}
+	f.writeString(strings.Repeat(indentation, level))
+	f.level = level
+}
+
+func (f *formatter) startStatement() {
+	f.writeString("\n\n")
+	f.frames = []frame{{block: true}}
+	f.level = 0
+	f.pending = -1
+	f.endOfStmt = false
+}
+
+func (f *formatter) write(tokens []token, i int, tok token) error {
+	defer func() {
+		if tok.kind != tokenComment {
+			prev := tok
+			f.prev = &prev
+			if tok.text == "(" || tok.kind == tokenWord && keywords[tok.text] {
+				f.keyword = tok.text
+			}
+		}
+	}()
+
+	if tok.kind == tokenComment {
+		f.writeComment(tok)
+		return nil
+	}
+
+	if f.endOfStmt {
+		f.startStatement()
+	}
+
+	upper := strings.ToUpper(tok.text)
+	if tok.kind == tokenWord && keywords[upper] {
+		tok.text = upper
+	}
+
+	fr := f.top()
+
+	switch {
+	case tok.text == ";":
+		f.writeString(";")
+		f.endOfStmt = true
+		return nil
+
+	case tok.text == "(":
+		block := i+1 < len(tokens) && tokens[i+1].kind == tokenWord &&
+			(strings.EqualFold(tokens[i+1].text, "SELECT") || strings.EqualFold(tokens[i+1].text, "WITH"))
+		f.writeToken(tok)
+		f.frames = append(f.frames, frame{block: block, base: f.level + 1, line: f.level})
+		if block {
+			f.pending = f.level + 1
+		}
+		return nil
+
+	case tok.text == ")":
+		if len(f.frames) == 1 {
+			return ErrUnbalancedParens
+		}
+		if fr.block {
+			f.pending = fr.line
+		}
+		f.frames = f.frames[:len(f.frames)-1]
+		f.writeToken(tok)
+		return nil
+	}
+
+	switch tok.text {
+	case "CASE":
+		fr.cases++
+	case "END":
+		if fr.cases > 0 {
+			fr.cases--
+		}
+	}
+
+	if !fr.block || fr.cases > 0 || tok.kind != tokenWord && tok.text != "," {
+		f.writeToken(tok)
+		return nil
+	}
+
+	switch {
+	case tok.text == ",":
+		f.writeToken(tok)
+		f.pending = fr.base + 1
+		return nil
+
+	case tok.text == f.companion && f.pending >= 0:
+		f.writeInline(tok)
+		return nil
+
+	case f.isClause(tokens, i, tok):
+		f.pending = fr.base
+		f.writeToken(tok)
+		f.companion = clauseCompanions[tok.text]
+		if blockClauses[tok.text] {
+			f.pending = fr.base + 1
+		}
+		return nil
+
+	case tok.text == "ON" && i+1 < len(tokens) && strings.EqualFold(tokens[i+1].text, "CONFLICT"):
+		f.pending = fr.base
+		f.writeToken(tok)
+		return nil
+
+	case joinKeywords[tok.text] && f.prev != nil && !joinKeywords[f.prev.text] && f.prev.text != "OUTER" && f.isJoin(tokens, i):
+		f.pending = fr.base + 1
+		f.writeToken(tok)
+		return nil
+
+	case tok.text == "BETWEEN":
+		fr.between = true
+
+	case tok.text == "AND" && fr.between:
+		fr.between = false
+
+	case tok.text == "AND" || tok.text == "OR":
+		f.pending = fr.base + 1
+	}
+
+	f.writeToken(tok)
+	return nil
+}
+
+// isClause returns true if the keyword starts a new clause
+func (f *formatter) isClause(tokens []token, i int, tok token) bool {
+	if !blockClauses[tok.text] && !lineClauses[tok.text] {
+		return false
+	}
+
+	prev := ""
+	if f.prev != nil {
+		prev = f.prev.text
+	}
+
+	switch tok.text {
+	case "UPDATE", "DELETE", "INSERT":
+		// Statements, not FOR UPDATE, ON DELETE and similar
+		return prev == "" || prev == "(" || prev == ")" || prev == ";" || prev == "EXPLAIN" || prev == "ANALYZE"
+	case "SET":
+		// Not ON DELETE SET NULL or ALTER ... SET
+		return prev != "DELETE" && prev != "UPDATE" && prev != "ALTER" && prev != "COLUMN"
+	case "FROM":
+		// Not DELETE FROM or IS DISTINCT FROM
+		return prev != "DELETE" && prev != "DISTINCT"
+	case "GROUP", "ORDER":
+		// Not WITHIN GROUP
+		return prev != "WITHIN" && i+1 < len(tokens) && strings.EqualFold(tokens[i+1].text, "BY")
+	}
+
+	return true
+}
+
+// isJoin returns true if the keyword starts a join, ie. not the LEFT() function
+func (f *formatter) isJoin(tokens []token, i int) bool {
+	for ; i < len(tokens); i++ {
+		if tokens[i].kind != tokenWord {
+			return false
+		}
+		word := strings.ToUpper(tokens[i].text)
+		if word == "JOIN" {
+			return true
+		}
+		if !joinKeywords[word] && word != "OUTER" {
+			return false
+		}
+	}
+	return false
+}
+
+func (f *formatter) writeComment(tok token) {
+	switch {
+	case tok.newline && f.endOfStmt:
+		// Comment belongs to the next statement
+		f.startStatement()
+		f.pending = 0
+	case tok.newline:
+		level := f.pending
+		if level < 0 {
+			level = f.level
+		}
+		f.newline(level)
+	case !f.atLineStart():
+		f.writeString(" ")
+	}
+
+	f.writeString(tok.text)
+
+	// Comment is followed by a line break, unless written within a line
+	if strings.HasPrefix(tok.text, "--") || tok.newline {
+		if f.pending < 0 {
+			f.pending = f.level
+		}
+	}
+}
+
+func (f *formatter) writeInline(tok token) {
+	f.writeString(" " + tok.text)
+	f.prev = &tok
+}
+
+func (f *formatter) writeToken(tok token) {
+	if f.pending >= 0 {
+		f.newline(f.pending)
+		f.pending = -1
+		f.companion = ""
+	} else if !f.atLineStart() && f.needsSpace(tok) {
+		f.writeString(" ")
+	}
+
+	f.unary = (tok.text == "-" || tok.text == "+") && f.isOperandExpected()
+	f.writeString(tok.text)
+}
+
+// isOperandExpected returns true if the previous token can't end an expression
+func (f *formatter) isOperandExpected() bool {
+	if f.prev == nil {
+		return true
+	}
+	switch f.prev.kind {
+	case tokenOperator:
+		return true
+	case tokenWord:
+		return keywords[f.prev.text] && f.prev.text != "NULL" && f.prev.text != "TRUE" && f.prev.text != "FALSE" && f.prev.text != "END"
+	}
+	return f.prev.text == "(" || f.prev.text == "," || f.prev.text == "["
+}
+
+func (f *formatter) needsSpace(tok token) bool {
+	if f.prev == nil || f.unary {
+		return false
+	}
+
+	switch tok.text {
+	case ",", ";", ")", "]", ".", "::", "[", ":":
+		return false
+	}
+	switch f.prev.text {
+	case "(", "[", ".", "::", ":":
+		return false
+	}
+
+	// Function calls, but not column lists following the table name
+	if tok.text == "(" && (f.prev.kind == tokenIdentifier || f.prev.kind == tokenWord && !keywords[f.prev.text]) {
+		return f.keyword == "INTO" || f.keyword == "TABLE"
+	}
+
+	return true
+}
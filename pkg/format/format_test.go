@@ -0,0 +1,127 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	examples := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:  "minified query",
+			input: "select id, title from books b left join authors a on a.id = b.author_id where b.id > 1 and title like 'A%' order by id desc limit 10",
+			expected: []string{
+				"SELECT",
+				"  id,",
+				"  title",
+				"FROM",
+				"  books b",
+				"  LEFT JOIN authors a ON a.id = b.author_id",
+				"WHERE",
+				"  b.id > 1",
+				"  AND title LIKE 'A%'",
+				"ORDER BY",
+				"  id DESC",
+				"LIMIT 10",
+			},
+		},
+		{
+			name:  "subquery",
+			input: "select * from books where author_id in (select id from authors) and id between 1 and 5",
+			expected: []string{
+				"SELECT",
+				"  *",
+				"FROM",
+				"  books",
+				"WHERE",
+				"  author_id IN (",
+				"    SELECT",
+				"      id",
+				"    FROM",
+				"      authors",
+				"  )",
+				"  AND id BETWEEN 1 AND 5",
+			},
+		},
+		{
+			name:  "string literals and comments",
+			input: "select 'select  from;' as s, \"Mixed Case\", $$ where $$ -- where\nfrom t /* select */",
+			expected: []string{
+				"SELECT",
+				"  'select  from;' AS s,",
+				`  "Mixed Case",`,
+				"  $$ where $$ -- where",
+				"FROM",
+				"  t /* select */",
+			},
+		},
+		{
+			name:  "multiple statements",
+			input: "insert into books (id, title) values (1, 'a'), (2, 'b');\n-- cleanup\ndelete from books where id = -1",
+			expected: []string{
+				"INSERT INTO books (id, title)",
+				"VALUES",
+				"  (1, 'a'),",
+				"  (2, 'b');",
+				"",
+				"-- cleanup",
+				"DELETE FROM books",
+				"WHERE",
+				"  id = -1",
+			},
+		},
+		{
+			name:  "expressions",
+			input: "select count(*) filter (where x::int > 0), case when a and b then 1 end, arr[1] from t group by 1",
+			expected: []string{
+				"SELECT",
+				"  count(*) FILTER (WHERE x::int > 0),",
+				"  CASE WHEN a AND b THEN 1 END,",
+				"  arr[1]",
+				"FROM",
+				"  t",
+				"GROUP BY",
+				"  1",
+			},
+		},
+	}
+
+	for _, ex := range examples {
+		t.Run(ex.name, func(t *testing.T) {
+			result, err := Format(ex.input)
+			require.NoError(t, err)
+			assert.Equal(t, strings.Join(ex.expected, "\n"), result)
+
+			// Formatting is idempotent
+			again, err := Format(result)
+			require.NoError(t, err)
+			assert.Equal(t, result, again)
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	examples := map[string]error{
+		"select 'abc":   ErrUnterminatedString,
+		`select "abc`:   ErrUnterminatedString,
+		"select $$abc":  ErrUnterminatedString,
+		"select /* abc": ErrUnterminatedComment,
+		"select (1":     ErrUnbalancedParens,
+		"select 1)":     ErrUnbalancedParens,
+		"select 1; ) (": ErrUnbalancedParens,
+	}
+
+	for input, expected := range examples {
+		t.Run(input, func(t *testing.T) {
+			_, err := Format(input)
+			assert.Equal(t, expected, err)
+		})
+	}
+}
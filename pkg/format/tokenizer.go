@@ -0,0 +1,176 @@
+package format
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord       tokenKind = iota // Keywords, names, numbers and parameters
+	tokenIdentifier                  // Double-quoted identifiers
+	tokenString                      // String literals
+	tokenComment                     // Line and block comments
+	tokenOperator                    // Operators, ie. = or ||
+	tokenPunct                       // Parentheses, commas and other punctuation
+)
+
+const operatorChars = "+-*/<>=~!@#%^&|`?"
+
+type token struct {
+	kind    tokenKind
+	text    string
+	newline bool // Token is preceded by a line break
+}
+
+// tokenize splits the query into tokens, dropping the whitespace
+func tokenize(sql string) ([]token, error) {
+	tokens := []token{}
+	newline := false
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start := i
+
+		switch {
+		case c == '\n':
+			newline = true
+			i++
+			continue
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+			continue
+
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end
+			tokens = append(tokens, token{kind: tokenComment, text: strings.TrimRight(sql[start:i], " \t\r"), newline: newline})
+
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, ErrUnterminatedComment
+			}
+			i += end + 4
+			tokens = append(tokens, token{kind: tokenComment, text: sql[start:i], newline: newline})
+
+		case c == '\'':
+			end, err := skipQuoted(sql, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: sql[start:i], newline: newline})
+
+		case c == '"':
+			end, err := skipQuoted(sql, i, '"')
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenIdentifier, text: sql[start:i], newline: newline})
+
+		case c == '$' && i+1 < len(sql) && !isDigit(sql[i+1]):
+			end, ok, err := skipDollarQuoted(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				i++
+				tokens = append(tokens, token{kind: tokenOperator, text: "$", newline: newline})
+				break
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: sql[start:i], newline: newline})
+
+		case isWordChar(c):
+			for i < len(sql) && (isWordChar(sql[i]) || sql[i] == '.' && isDigit(sql[start]) && !strings.HasPrefix(sql[i:], "..")) {
+				i++
+			}
+			// Prefixed string literals, ie. E'\n' or B'101'
+			if i-start == 1 && i < len(sql) && sql[i] == '\'' && strings.ContainsRune("eEbBxXnN", rune(c)) {
+				end, err := skipQuoted(sql, i, '\'')
+				if err != nil {
+					return nil, err
+				}
+				i = end
+				tokens = append(tokens, token{kind: tokenString, text: sql[start:i], newline: newline})
+				break
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: sql[start:i], newline: newline})
+
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			i += 2
+			tokens = append(tokens, token{kind: tokenPunct, text: "::", newline: newline})
+
+		case strings.IndexByte(operatorChars, c) >= 0:
+			for i < len(sql) && strings.IndexByte(operatorChars, sql[i]) >= 0 &&
+				!strings.HasPrefix(sql[i:], "--") && !strings.HasPrefix(sql[i:], "/*") {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: sql[start:i], newline: newline})
+
+		default:
+			i++
+			tokens = append(tokens, token{kind: tokenPunct, text: sql[start:i], newline: newline})
+		}
+
+		newline = false
+	}
+
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c >= 0x80
+}
+
+// skipQuoted returns the position after the quoted section starting at pos
+func skipQuoted(sql string, pos int, quote byte) (int, error) {
+	escapes := quote == '\'' && pos > 0 && (sql[pos-1] == 'e' || sql[pos-1] == 'E')
+
+	for i := pos + 1; i < len(sql); i++ {
+		switch {
+		case escapes && sql[i] == '\\':
+			i++
+		case sql[i] != quote:
+		case i+1 < len(sql) && sql[i+1] == quote:
+			// Doubled quote is an escaped one
+			i++
+		default:
+			return i + 1, nil
+		}
+	}
+
+	return 0, ErrUnterminatedString
+}
+
+// skipDollarQuoted returns the position after the dollar-quoted string starting at
+// pos. Returns false if there's no dollar quote at the position.
+func skipDollarQuoted(sql string, pos int) (int, bool, error) {
+	end := strings.IndexByte(sql[pos+1:], '$')
+	if end < 0 {
+		return 0, false, nil
+	}
+
+	tag := sql[pos : pos+end+2]
+	for i := 1; i < len(tag)-1; i++ {
+		if !isWordChar(tag[i]) || tag[i] == '$' {
+			return 0, false, nil
+		}
+	}
+
+	closing := strings.Index(sql[pos+len(tag):], tag)
+	if closing < 0 {
+		return 0, false, ErrUnterminatedString
+	}
+	return pos + len(tag) + closing + len(tag), true, nil
+}
@@ -7,6 +7,7 @@ import (
 type Record struct {
 	Query     string `json:"query"`
 	Timestamp string `json:"timestamp"`
+	Object    string `json:"object,omitempty"` // Opened object, the query is empty then
 }
 
 func New() []Record {
@@ -19,3 +20,11 @@ func NewRecord(query string) Record {
 		Timestamp: time.Now().String(),
 	}
 }
+
+// NewObjectRecord returns the record of the object opened by the user
+func NewObjectRecord(object string) Record {
+	return Record{
+		Timestamp: time.Now().String(),
+		Object:    object,
+	}
+}
@@ -0,0 +1,165 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Number of records waiting to be written before new ones are dropped
+const storeBufferSize = 100
+
+// Store persists history records as JSON lines appended to a file. Records are
+// written in the background so appending never waits on the disk.
+type Store struct {
+	path    string
+	records chan Record
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+}
+
+func NewStore(path string) *Store {
+	store := &Store{
+		path:    path,
+		records: make(chan Record, storeBufferSize),
+		done:    make(chan struct{}),
+	}
+	go store.run()
+	return store
+}
+
+// Append queues the record to be written. The record is dropped if the writes
+// can't keep up, the in-memory history still has it.
+func (s *Store) Append(record Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.records <- record:
+	default:
+		fmt.Fprintf(os.Stderr, "[WARN] history file %q is busy, record dropped\n", s.path)
+	}
+}
+
+// Load returns up to limit most recent records of the file, oldest first.
+// A missing file has no records.
+func (s *Store) Load(limit int) ([]Record, error) {
+	records := New()
+
+	err := s.scan(func(record Record) {
+		if record.Query == "" {
+			return
+		}
+
+		records = append(records, record)
+		if len(records) > limit {
+			records = records[1:]
+		}
+	})
+
+	return records, err
+}
+
+// LoadObjects returns up to limit most recently opened objects of the file, most
+// recent first. Objects opened multiple times are listed once.
+func (s *Store) LoadObjects(limit int) ([]string, error) {
+	objects := []string{}
+
+	err := s.scan(func(record Record) {
+		if record.Object == "" {
+			return
+		}
+
+		objects = slices.DeleteFunc(objects, func(object string) bool {
+			return object == record.Object
+		})
+		objects = slices.Insert(objects, 0, record.Object)
+		if len(objects) > limit {
+			objects = objects[:limit]
+		}
+	})
+
+	return objects, err
+}
+
+// scan calls fn for each valid record of the file, oldest first
+func (s *Store) scan(fn func(Record)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		fn(record)
+	}
+
+	return scanner.Err()
+}
+
+// Close writes the queued records and stops the store
+func (s *Store) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+
+	var file *os.File
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	for record := range s.records {
+		if file == nil {
+			if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] can't create history directory: %v\n", err)
+				continue
+			}
+
+			f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] can't open history file: %v\n", err)
+				continue
+			}
+			file = f
+		}
+
+		line, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] can't write history file: %v\n", err)
+		}
+	}
+}
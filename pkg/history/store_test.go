@@ -0,0 +1,72 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "default.jsonl")
+
+	store := NewStore(path)
+	records, err := store.Load(10)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	for _, query := range []string{"SELECT 1", "SELECT 2", "SELECT 3"} {
+		store.Append(Record{Query: query, Timestamp: "now"})
+	}
+	store.Close()
+	store.Append(Record{Query: "SELECT 4"})
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Records of previous runs are appended to
+	store = NewStore(path)
+	store.Append(Record{Query: "SELECT 5", Timestamp: "later"})
+	store.Close()
+
+	records, err = NewStore(path).Load(2)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Query: "SELECT 3", Timestamp: "now"}, {Query: "SELECT 5", Timestamp: "later"}}, records)
+}
+
+func TestStoreLoadInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"query\":\"SELECT 1\"}\nnot json\n{\"query\":\"\"}\n{\"query\":\"SELECT 2\"}"), 0600))
+
+	records, err := NewStore(path).Load(10)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{Query: "SELECT 1"}, {Query: "SELECT 2"}}, records)
+}
+
+func TestStoreLoadObjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.jsonl")
+
+	store := NewStore(path)
+	store.Append(NewObjectRecord("public.books"))
+	store.Append(NewRecord("SELECT 1"))
+	store.Append(NewObjectRecord("public.authors"))
+	store.Append(NewObjectRecord("public.books"))
+	store.Append(NewObjectRecord("public.subjects"))
+	store.Close()
+
+	store = NewStore(path)
+	defer store.Close()
+
+	objects, err := store.LoadObjects(2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"public.subjects", "public.books"}, objects)
+
+	// Object records are not part of the query history
+	records, err := store.Load(10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "SELECT 1", records[0].Query)
+}
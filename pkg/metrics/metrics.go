@@ -1,8 +1,12 @@
 package metrics
 
 import (
+	"errors"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -13,11 +17,21 @@ var (
 		Help: "Total number of database sessions",
 	})
 
+	activeSessionsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pgweb_active_sessions",
+		Help: "Number of active database sessions per database",
+	}, []string{"database"})
+
 	queriesCounter = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "pgweb_queries_count",
 		Help: "Total number of custom queries executed",
 	})
 
+	queryErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgweb_query_errors_total",
+		Help: "Total number of failed custom queries by SQLSTATE class",
+	}, []string{"class"})
+
 	healthyGauge = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "pgweb_healthy",
 		Help: "Server health status",
@@ -32,8 +46,25 @@ var (
 		Name: "pgweb_uptime",
 		Help: "Server application uptime in seconds",
 	})
+
+	cacheHitsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pgweb_cache_hits_total",
+		Help: "Number of cache lookups returning a cached value",
+	}, []string{"cache"})
+
+	cacheMissesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pgweb_cache_misses_total",
+		Help: "Number of cache lookups finding no cached value",
+	}, []string{"cache"})
+
+	// Cache counters reported on every scrape, by cache name
+	cacheCounters   = map[string]func() (uint64, uint64){}
+	cacheCountersMu sync.Mutex
 )
 
+// Maximum number of distinct database labels, remaining databases are reported as "other"
+const maxDatabaseLabels = 50
+
 func init() {
 	startTimeGauge.Set(float64(time.Now().Unix()))
 }
@@ -42,10 +73,48 @@ func IncrementQueriesCount() {
 	queriesCounter.Inc()
 }
 
+// IncrementQueryErrors counts the failed query by the two-character SQLSTATE
+// class of the error. Errors not returned by the server are counted as "other".
+func IncrementQueryErrors(err error) {
+	queryErrorsCounter.WithLabelValues(sqlStateClass(err)).Inc()
+}
+
+func sqlStateClass(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && len(pqErr.Code) == 5 {
+		return string(pqErr.Code.Class())
+	}
+	return "other"
+}
+
 func SetSessionsCount(val int) {
 	sessionsGauge.Set(float64(val))
 }
 
+// SetActiveSessions reports the number of sessions for each database
+func SetActiveSessions(databases map[string]int) {
+	names := make([]string, 0, len(databases))
+	for name := range databases {
+		names = append(names, name)
+	}
+
+	// Keep labels for the busiest databases when over the limit
+	sort.Slice(names, func(i, j int) bool {
+		if databases[names[i]] != databases[names[j]] {
+			return databases[names[i]] > databases[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	activeSessionsGauge.Reset()
+	for i, name := range names {
+		if i >= maxDatabaseLabels {
+			name = "other"
+		}
+		activeSessionsGauge.WithLabelValues(name).Add(float64(databases[names[i]]))
+	}
+}
+
 func SetHealthy(val bool) {
 	healthy := 0.0
 	if val {
@@ -53,3 +122,23 @@ func SetHealthy(val bool) {
 	}
 	healthyGauge.Set(float64(healthy))
 }
+
+// RegisterCache reports the hits and misses returned by the counters function
+// under the cache name label
+func RegisterCache(name string, counters func() (hits uint64, misses uint64)) {
+	cacheCountersMu.Lock()
+	defer cacheCountersMu.Unlock()
+
+	cacheCounters[name] = counters
+}
+
+func updateCacheGauges() {
+	cacheCountersMu.Lock()
+	defer cacheCountersMu.Unlock()
+
+	for name, counters := range cacheCounters {
+		hits, misses := counters()
+		cacheHitsGauge.WithLabelValues(name).Set(float64(hits))
+		cacheMissesGauge.WithLabelValues(name).Set(float64(misses))
+	}
+}
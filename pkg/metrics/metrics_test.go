@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetActiveSessions(t *testing.T) {
+	SetActiveSessions(map[string]int{"foo": 2, "bar": 1})
+	assert.Equal(t, 2.0, testutil.ToFloat64(activeSessionsGauge.WithLabelValues("foo")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(activeSessionsGauge.WithLabelValues("bar")))
+
+	SetActiveSessions(map[string]int{"foo": 1})
+	assert.Equal(t, 1.0, testutil.ToFloat64(activeSessionsGauge.WithLabelValues("foo")))
+	assert.Equal(t, 1, testutil.CollectAndCount(activeSessionsGauge))
+
+	t.Run("labels cap", func(t *testing.T) {
+		databases := map[string]int{}
+		for i := 0; i < maxDatabaseLabels+10; i++ {
+			databases[fmt.Sprintf("db_%03d", i)] = 1
+		}
+		databases["busy"] = 5
+
+		SetActiveSessions(databases)
+		assert.Equal(t, maxDatabaseLabels+1, testutil.CollectAndCount(activeSessionsGauge))
+		assert.Equal(t, 5.0, testutil.ToFloat64(activeSessionsGauge.WithLabelValues("busy")))
+		assert.Equal(t, 11.0, testutil.ToFloat64(activeSessionsGauge.WithLabelValues("other")))
+	})
+}
+
+func TestRegisterCache(t *testing.T) {
+	hits, misses := uint64(3), uint64(1)
+	RegisterCache("test", func() (uint64, uint64) { return hits, misses })
+
+	updateCacheGauges()
+	assert.Equal(t, 3.0, testutil.ToFloat64(cacheHitsGauge.WithLabelValues("test")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(cacheMissesGauge.WithLabelValues("test")))
+
+	hits = 5
+	updateCacheGauges()
+	assert.Equal(t, 5.0, testutil.ToFloat64(cacheHitsGauge.WithLabelValues("test")))
+}
+
+func TestIncrementQueryErrors(t *testing.T) {
+	before := testutil.ToFloat64(queryErrorsCounter.WithLabelValues("23"))
+
+	// unique_violation
+	IncrementQueryErrors(fmt.Errorf("insert failed: %w", &pq.Error{Code: "23505"}))
+	assert.Equal(t, before+1, testutil.ToFloat64(queryErrorsCounter.WithLabelValues("23")))
+
+	other := testutil.ToFloat64(queryErrorsCounter.WithLabelValues("other"))
+	IncrementQueryErrors(errors.New("connection refused"))
+	assert.Equal(t, other+1, testutil.ToFloat64(queryErrorsCounter.WithLabelValues("other")))
+}
@@ -12,6 +12,7 @@ type SSHInfo struct {
 	Password    string `json:"password,omitempty"`
 	Key         string `json:"key,omitempty"`
 	KeyPassword string `json:"keypassword,omitempty"`
+	KnownHosts  string `json:"knownhosts,omitempty"`
 }
 
 func (info SSHInfo) String() string {
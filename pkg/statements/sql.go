@@ -1,7 +1,8 @@
 package statements
 
 import (
-	_ "embed"
+	"embed"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,52 +12,40 @@ import (
 )
 
 var (
-	//go:embed sql/databases.sql
-	Databases string
-
-	//go:embed sql/schemas.sql
-	Schemas string
-
-	//go:embed sql/info.sql
-	Info string
-
-	//go:embed sql/info_simple.sql
-	InfoSimple string
-
-	//go:embed sql/estimated_row_count.sql
-	EstimatedTableRowCount string
-
-	//go:embed sql/table_indexes.sql
-	TableIndexes string
-
-	//go:embed sql/table_constraints.sql
-	tableConstraintsEmbedded string
-
-	TableConstraints string
-
-	//go:embed sql/table_info.sql
-	TableInfo string
-
-	//go:embed sql/table_info_cockroach.sql
-	TableInfoCockroach string
-
-	//go:embed sql/table_schema.sql
-	TableSchema string
-
-	//go:embed sql/materialized_view.sql
-	MaterializedView string
-
-	//go:embed sql/objects.sql
-	Objects string
-
-	//go:embed sql/tables_stats.sql
-	TablesStats string
-
-	//go:embed sql/function.sql
-	Function string
-
-	//go:embed sql/settings.sql
-	Settings string
+	// Statements of the sql/*.sql files, see statementFiles
+	Databases                      string
+	Schemas                        string
+	Info                           string
+	InfoSimple                     string
+	EstimatedTableRowCount         string
+	EstimatedTableRowCountRedshift string
+	TableIndexes                   string
+	TableConstraints               string
+	TableTriggers                  string
+	TablePolicies                  string
+	TablePartitions                string
+	TablePrimaryKey                string
+	TableColumns                   string
+	TableInfo                      string
+	TableInfoCockroach             string
+	TableInfoRedshift              string
+	TableSchema                    string
+	TableStorageParams             string
+	MaterializedView               string
+	View                           string
+	Objects                        string
+	SearchObjects                  string
+	TablesStats                    string
+	ColumnStats                    string
+	UnindexedForeignKeys           string
+	TerminateIdleBackends          string
+	Function                       string
+	Sequences                      string
+	Settings                       string
+	SchemaSummary                  string
+	ExtensionInstalled             string
+	TopStatements                  string
+	ActivityRedshift               string
 
 	// Activity queries for specific PG versions
 	Activity = map[string]string{
@@ -69,48 +58,110 @@ var (
 		"9.6":     "SELECT datname, query, state, wait_event, wait_event_type, query_start, state_change, pid, datid, application_name, client_addr FROM pg_stat_activity WHERE datname = current_database() and usename = current_user",
 	}
 
+	//go:embed sql/*.sql
+	embeddedSQL embed.FS
+
+	// Statements loaded from the sql/<name>.sql files
+	statementFiles = map[string]*string{
+		"databases":                    &Databases,
+		"schemas":                      &Schemas,
+		"info":                         &Info,
+		"info_simple":                  &InfoSimple,
+		"estimated_row_count":          &EstimatedTableRowCount,
+		"estimated_row_count_redshift": &EstimatedTableRowCountRedshift,
+		"table_indexes":                &TableIndexes,
+		"table_constraints":            &TableConstraints,
+		"table_triggers":               &TableTriggers,
+		"table_policies":               &TablePolicies,
+		"table_partitions":             &TablePartitions,
+		"table_primary_key":            &TablePrimaryKey,
+		"table_columns":                &TableColumns,
+		"table_info":                   &TableInfo,
+		"table_info_cockroach":         &TableInfoCockroach,
+		"table_info_redshift":          &TableInfoRedshift,
+		"table_schema":                 &TableSchema,
+		"table_storage_params":         &TableStorageParams,
+		"materialized_view":            &MaterializedView,
+		"view":                         &View,
+		"objects":                      &Objects,
+		"search_objects":               &SearchObjects,
+		"tables_stats":                 &TablesStats,
+		"column_stats":                 &ColumnStats,
+		"unindexed_foreign_keys":       &UnindexedForeignKeys,
+		"terminate_idle_backends":      &TerminateIdleBackends,
+		"function":                     &Function,
+		"sequences":                    &Sequences,
+		"settings":                     &Settings,
+		"schema_summary":               &SchemaSummary,
+		"extension_installed":          &ExtensionInstalled,
+		"top_statements":               &TopStatements,
+		"activity_redshift":            &ActivityRedshift,
+	}
+
+	// Directory of the SQL files overriding the embedded ones, empty to use the embedded ones only
+	overrideDir string
+
 	// Cache for external SQL files
 	sqlFileCache *cache.Cache
 )
 
 func init() {
 	sqlFileCache = cache.New(30 * time.Minute)
-	TableConstraints = loadTableConstraintsSQL()
+	loadStatements()
 }
 
-func loadTableConstraintsSQL() string {
-	externalPath := filepath.Join("/tmp/queries", "table_constraints.sql")
-
-	// Check cache first
-	cacheKey := cache.GenerateKey("sql_file", externalPath)
-	if cached, found := sqlFileCache.Get(cacheKey); found {
-		return cached.(string)
+// SetOverrideDir configures the directory of the SQL files overriding the embedded
+// statements, ie. objects.sql, and reloads the statements
+func SetOverrideDir(dir string) error {
+	if dir != "" {
+		stat, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !stat.IsDir() {
+			return fmt.Errorf("sql override path %q is not a directory", dir)
+		}
 	}
 
-	// Check if external file exists and get its mod time
-	if stat, err := os.Stat(externalPath); err == nil {
-		// Check if we have cached this file with its mod time
-		modTimeCacheKey := cache.GenerateKey("sql_file_modtime", externalPath, stat.ModTime().String())
-		if cached, found := sqlFileCache.Get(modTimeCacheKey); found {
-			// Cache the content with the general key as well
-			content := cached.(string)
-			sqlFileCache.Set(cacheKey, content, 30*time.Minute)
-			return content
-		}
+	overrideDir = dir
+	loadStatements()
+	return nil
+}
 
-		// Read and cache the file
-		if data, err := os.ReadFile(externalPath); err == nil {
-			content := string(data)
-			log.Printf("Using external table_constraints.sql from: %s", externalPath)
+func loadStatements() {
+	for name, statement := range statementFiles {
+		*statement = loadSQL(name)
+	}
+}
 
-			// Cache with both keys
-			sqlFileCache.Set(cacheKey, content, 30*time.Minute)
-			sqlFileCache.Set(modTimeCacheKey, content, 30*time.Minute)
-			return content
+// loadSQL returns the <name>.sql statement from the override directory if the file
+// exists there, or the embedded one otherwise
+func loadSQL(name string) string {
+	if overrideDir != "" {
+		externalPath := filepath.Join(overrideDir, name+".sql")
+
+		// Check if external file exists and get its mod time
+		if stat, err := os.Stat(externalPath); err == nil {
+			// Check if we have cached this file with its mod time
+			cacheKey := cache.GenerateKey("sql_file_modtime", externalPath, stat.ModTime().String())
+			if cached, found := sqlFileCache.Get(cacheKey); found {
+				return cached.(string)
+			}
+
+			// Read and cache the file
+			if data, err := os.ReadFile(externalPath); err == nil {
+				content := string(data)
+				log.Printf("Using external %s.sql from: %s", name, externalPath)
+
+				sqlFileCache.Set(cacheKey, content, 30*time.Minute)
+				return content
+			}
 		}
 	}
 
-	// Cache the embedded fallback
-	sqlFileCache.Set(cacheKey, tableConstraintsEmbedded, 30*time.Minute)
-	return tableConstraintsEmbedded
+	data, err := embeddedSQL.ReadFile("sql/" + name + ".sql")
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
 }
@@ -0,0 +1,13 @@
+SELECT
+  pid,
+  user_name,
+  db_name,
+  query,
+  status,
+  starttime,
+  duration
+FROM
+  stv_recents
+WHERE
+  status = 'Running'
+  AND db_name = current_database()
@@ -0,0 +1,22 @@
+SELECT
+  s.attname AS column_name,
+  s.inherited,
+  s.null_frac,
+  s.avg_width,
+  s.n_distinct,
+  s.most_common_vals::text AS most_common_vals,
+  s.most_common_freqs::text AS most_common_freqs,
+  s.correlation
+FROM
+  pg_catalog.pg_stats s
+JOIN
+  pg_catalog.pg_namespace n ON n.nspname = s.schemaname
+JOIN
+  pg_catalog.pg_class c ON c.relnamespace = n.oid AND c.relname = s.tablename
+JOIN
+  pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attname = s.attname
+WHERE
+  s.schemaname = $1
+  AND s.tablename = $2
+ORDER BY
+  a.attnum, s.inherited
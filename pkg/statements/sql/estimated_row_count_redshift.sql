@@ -0,0 +1,5 @@
+SELECT
+  COALESCE(
+    (SELECT tbl_rows FROM svv_table_info WHERE schema = $1 AND "table" = $2),
+    0
+  )::float8 AS reltuples
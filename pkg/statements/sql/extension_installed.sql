@@ -0,0 +1,6 @@
+SELECT
+  extversion
+FROM
+  pg_catalog.pg_extension
+WHERE
+  extname = $1
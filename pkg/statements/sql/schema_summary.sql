@@ -0,0 +1,21 @@
+SELECT
+  n.nspname AS schema,
+  COUNT(c.oid) FILTER (WHERE c.relkind IN ('r', 'p')) AS tables_count,
+  COUNT(c.oid) FILTER (WHERE c.relkind = 'v') AS views_count,
+  COUNT(c.oid) FILTER (WHERE c.relkind = 'm') AS materialized_views_count,
+  COUNT(c.oid) FILTER (WHERE c.relkind = 'S') AS sequences_count,
+  (SELECT COUNT(1) FROM pg_catalog.pg_proc p WHERE p.pronamespace = n.oid) AS functions_count,
+  COALESCE(SUM(pg_catalog.pg_total_relation_size(c.oid)) FILTER (WHERE c.relkind IN ('r', 'm')), 0)::bigint AS total_size_bytes,
+  pg_catalog.pg_size_pretty(COALESCE(SUM(pg_catalog.pg_total_relation_size(c.oid)) FILTER (WHERE c.relkind IN ('r', 'm')), 0)::bigint) AS total_size
+FROM
+  pg_catalog.pg_namespace n
+LEFT JOIN
+  pg_catalog.pg_class c ON c.relnamespace = n.oid
+WHERE
+  n.nspname !~ '^pg_(toast|temp)'
+  AND n.nspname NOT IN ('information_schema', 'pg_catalog')
+  AND has_schema_privilege(n.nspname, 'USAGE')
+GROUP BY
+  n.oid, n.nspname
+ORDER BY
+  n.nspname
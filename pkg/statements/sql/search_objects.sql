@@ -0,0 +1,28 @@
+SELECT
+  c.oid,
+  n.nspname AS schema,
+  c.relname AS name,
+  CASE c.relkind
+    WHEN 'r' THEN 'table'
+    WHEN 'v' THEN 'view'
+    WHEN 'm' THEN 'materialized_view'
+    WHEN 'S' THEN 'sequence'
+    WHEN 's' THEN 'special'
+    WHEN 'f' THEN 'foreign_table'
+  END AS type,
+  CASE WHEN c.relname ILIKE $1 THEN 'name' ELSE 'comment' END AS matched_on,
+  d.description AS comment
+FROM
+  pg_catalog.pg_class c
+JOIN
+  pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN
+  pg_catalog.pg_description d ON d.objoid = c.oid AND d.classoid = 'pg_catalog.pg_class'::regclass AND d.objsubid = 0
+WHERE
+  c.relkind IN ('r','v','m','S','s','f')
+  AND n.nspname !~ '^pg_(toast|temp)'
+  AND n.nspname NOT IN ('information_schema', 'pg_catalog')
+  AND has_schema_privilege(n.nspname, 'USAGE')
+  AND (c.relname ILIKE $1 OR d.description ILIKE $1)
+ORDER BY
+  c.relname ILIKE $1 DESC, n.nspname, c.relname
@@ -0,0 +1,15 @@
+SELECT
+  a.attname AS column_name
+FROM
+  pg_attribute a
+JOIN
+  pg_class cl ON cl.oid = a.attrelid
+JOIN
+  pg_namespace n ON n.oid = cl.relnamespace
+WHERE
+  n.nspname = $1
+  AND cl.relname = $2
+  AND a.attnum > 0
+  AND NOT a.attisdropped
+ORDER BY
+  a.attnum
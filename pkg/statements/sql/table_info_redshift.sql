@@ -0,0 +1,10 @@
+SELECT
+  size || ' MB' AS data_size,
+  'n/a' AS index_size,
+  size || ' MB' AS total_size,
+  tbl_rows AS rows_count
+FROM
+  svv_table_info
+WHERE
+  schema = $1
+  AND "table" = $2
@@ -0,0 +1,43 @@
+WITH RECURSIVE partitions AS (
+  SELECT
+    i.inhrelid AS oid,
+    i.inhparent AS parent,
+    1 AS level
+  FROM
+    pg_catalog.pg_inherits i
+  JOIN
+    pg_catalog.pg_class p ON p.oid = i.inhparent
+  JOIN
+    pg_catalog.pg_namespace n ON n.oid = p.relnamespace
+  WHERE
+    n.nspname = $1
+    AND p.relname = $2
+
+  UNION ALL
+
+  SELECT
+    i.inhrelid,
+    i.inhparent,
+    partitions.level + 1
+  FROM
+    pg_catalog.pg_inherits i
+  JOIN
+    partitions ON partitions.oid = i.inhparent
+)
+SELECT
+  cn.nspname AS schema,
+  c.relname AS name,
+  pc.relname AS parent,
+  partitions.level,
+  pg_catalog.pg_get_expr(c.relpartbound, c.oid) AS bound,
+  c.relkind = 'p' AS partitioned
+FROM
+  partitions
+JOIN
+  pg_catalog.pg_class c ON c.oid = partitions.oid
+JOIN
+  pg_catalog.pg_namespace cn ON cn.oid = c.relnamespace
+JOIN
+  pg_catalog.pg_class pc ON pc.oid = partitions.parent
+ORDER BY
+  partitions.level, c.relname
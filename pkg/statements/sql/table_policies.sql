@@ -0,0 +1,20 @@
+SELECT
+  p.policyname AS name,
+  p.cmd AS command,
+  p.permissive,
+  array_to_string(p.roles, ', ') AS roles,
+  p.qual AS "using",
+  p.with_check,
+  c.relrowsecurity AS rls_enabled,
+  c.relforcerowsecurity AS rls_forced
+FROM
+  pg_class c
+JOIN
+  pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN
+  pg_policies p ON p.schemaname = n.nspname AND p.tablename = c.relname
+WHERE
+  n.nspname = $1
+  AND c.relname = $2
+ORDER BY
+  p.policyname
@@ -0,0 +1,16 @@
+SELECT
+  a.attname AS column_name
+FROM
+  pg_index i
+JOIN
+  pg_class cl ON cl.oid = i.indrelid
+JOIN
+  pg_namespace n ON n.oid = cl.relnamespace
+JOIN
+  pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+WHERE
+  n.nspname = $1
+  AND cl.relname = $2
+  AND i.indisprimary
+ORDER BY
+  array_position(i.indkey::int2[], a.attnum)
@@ -5,7 +5,12 @@ SELECT
   character_maximum_length,
   character_set_catalog,
   column_default,
-  pg_catalog.col_description(('"' || $1::text || '"."' || $2::text || '"')::regclass::oid, ordinal_position) as comment
+  pg_catalog.col_description(('"' || $1::text || '"."' || $2::text || '"')::regclass::oid, ordinal_position) as comment,
+  (
+    SELECT a.attstattarget
+    FROM pg_catalog.pg_attribute a
+    WHERE a.attrelid = ('"' || $1::text || '"."' || $2::text || '"')::regclass AND a.attname = column_name
+  ) AS statistics_target
 FROM
   information_schema.columns
 WHERE
@@ -0,0 +1,14 @@
+SELECT
+  opts.option_name,
+  opts.option_value
+FROM
+  pg_catalog.pg_class c
+JOIN
+  pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+CROSS JOIN
+  pg_catalog.pg_options_to_table(c.reloptions) opts
+WHERE
+  n.nspname = $1
+  AND c.relname = $2
+ORDER BY
+  opts.option_name
@@ -0,0 +1,33 @@
+SELECT
+  t.tgname AS name,
+  CASE
+    WHEN t.tgtype & 2 = 2 THEN 'BEFORE'
+    WHEN t.tgtype & 64 = 64 THEN 'INSTEAD OF'
+    ELSE 'AFTER'
+  END AS timing,
+  array_to_string(ARRAY_REMOVE(ARRAY[
+    CASE WHEN t.tgtype & 4 = 4 THEN 'INSERT' END,
+    CASE WHEN t.tgtype & 16 = 16 THEN 'UPDATE' END,
+    CASE WHEN t.tgtype & 8 = 8 THEN 'DELETE' END,
+    CASE WHEN t.tgtype & 32 = 32 THEN 'TRUNCATE' END
+  ], NULL), ' OR ') AS events,
+  CASE WHEN t.tgtype & 1 = 1 THEN 'ROW' ELSE 'STATEMENT' END AS level,
+  pn.nspname || '.' || p.proname AS function,
+  t.tgenabled != 'D' AS enabled,
+  pg_get_triggerdef(t.oid, true) AS definition
+FROM
+  pg_trigger t
+JOIN
+  pg_class cl ON cl.oid = t.tgrelid
+JOIN
+  pg_namespace n ON n.oid = cl.relnamespace
+JOIN
+  pg_proc p ON p.oid = t.tgfoid
+JOIN
+  pg_namespace pn ON pn.oid = p.pronamespace
+WHERE
+  n.nspname = $1
+  AND cl.relname = $2
+  AND NOT t.tgisinternal
+ORDER BY
+  t.tgname
@@ -0,0 +1,18 @@
+SELECT
+  pid,
+  usename,
+  datname,
+  application_name,
+  client_addr,
+  state_change,
+  pg_terminate_backend(pid) AS terminated
+FROM
+  pg_stat_activity
+WHERE
+  datname = current_database()
+  AND usename = current_user
+  AND state = 'idle'
+  AND state_change < NOW() - $1 * INTERVAL '1 minute'
+  AND pid <> pg_backend_pid()
+ORDER BY
+  state_change
@@ -0,0 +1,14 @@
+SELECT
+  queryid,
+  query,
+  calls,
+  total_%[1]s AS total_time,
+  mean_%[1]s AS mean_time,
+  rows
+FROM
+  pg_stat_statements
+WHERE
+  dbid = (SELECT oid FROM pg_catalog.pg_database WHERE datname = current_database())
+ORDER BY
+  %[2]s DESC
+LIMIT $1
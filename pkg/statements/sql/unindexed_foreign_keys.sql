@@ -0,0 +1,31 @@
+SELECT
+  t.relname AS table_name,
+  c.conname AS constraint_name,
+  (
+    SELECT string_agg(a.attname, ', ' ORDER BY k.ord)
+    FROM unnest(c.conkey) WITH ORDINALITY k(attnum, ord)
+    JOIN pg_catalog.pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+  ) AS columns,
+  c.confrelid::regclass::text AS referenced_table,
+  pg_catalog.pg_size_pretty(pg_catalog.pg_relation_size(c.conrelid)) AS table_size
+FROM
+  pg_catalog.pg_constraint c
+JOIN
+  pg_catalog.pg_class t ON t.oid = c.conrelid
+JOIN
+  pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+WHERE
+  c.contype = 'f'
+  AND n.nspname = $1
+  -- Index is covering when its leading columns are the constraint columns in any order
+  AND NOT EXISTS (
+    SELECT 1
+    FROM pg_catalog.pg_index i
+    WHERE
+      i.indrelid = c.conrelid
+      AND i.indisvalid
+      AND i.indpred IS NULL
+      AND (i.indkey::int2[])[0:array_length(c.conkey, 1) - 1] @> c.conkey
+  )
+ORDER BY
+  t.relname, c.conname
@@ -0,0 +1,18 @@
+SELECT
+  a.attname AS column_name,
+  a.atttypid::regtype AS data_type,
+  (CASE WHEN a.attnotnull IS TRUE THEN 'NO' ELSE 'YES' END) AS is_nullable,
+  NULL AS character_maximum_length,
+  NULL AS character_set_catalog,
+  NULL AS column_default,
+  pg_get_viewdef(c.oid, true) AS definition
+FROM
+  pg_class c
+  JOIN pg_attribute a ON a.attrelid = c.oid
+WHERE
+  c.oid = $1::regclass
+  AND c.relkind = 'v'
+  AND a.attnum > 0
+  AND NOT a.attisdropped
+ORDER BY
+  a.attnum
@@ -0,0 +1,42 @@
+package statements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadStatements(t *testing.T) {
+	defer func() {
+		require.NoError(t, SetOverrideDir(""))
+	}()
+
+	for name, statement := range statementFiles {
+		assert.NotEmpty(t, *statement, name)
+	}
+	embeddedObjects := Objects
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "objects.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT 1"), 0600))
+
+	require.NoError(t, SetOverrideDir(dir))
+	assert.Equal(t, "SELECT 1", Objects)
+	assert.NotEqual(t, "SELECT 1", TableInfo)
+
+	// Changed files are read again
+	require.NoError(t, os.WriteFile(path, []byte("SELECT 2"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	require.NoError(t, SetOverrideDir(dir))
+	assert.Equal(t, "SELECT 2", Objects)
+
+	require.NoError(t, SetOverrideDir(""))
+	assert.Equal(t, embeddedObjects, Objects)
+
+	assert.Error(t, SetOverrideDir(filepath.Join(dir, "missing")))
+	assert.EqualError(t, SetOverrideDir(path), `sql override path "`+path+`" is not a directory`)
+}
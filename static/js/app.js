@@ -10,6 +10,9 @@ var inputResizing       = false;
 var inputResizeOffset   = null;
 var globalSqlParams     = {};
 var parameterPatterns   = []; // Will be loaded dynamically from server config
+var sessionPingInterval = 60 * 1000; // Keep active sessions alive while the page is visible
+var notificationsSocket = null;
+var notificationsReconnectDelay = 5 * 1000;
 
 var filterOptions = {
   "equal":      "= 'DATA'",
@@ -48,6 +51,7 @@ function hideNavigationTabs(tabsToHide) {
     'structure': 'table_structure', 
     'indexes': 'table_indexes',
     'constraints': 'table_constraints',
+    'policies': 'table_policies',
     'query': 'table_query',
     'history': 'table_history',
     'activity': 'table_activity',
@@ -297,14 +301,17 @@ function getTableRows(table, opts, cb)      { apiCall("get", "/tables/" + table
 function getTableStructure(table, opts, cb) { apiCall("get", "/tables/" + table, opts, cb); }
 function getTableIndexes(table, cb)         { apiCall("get", "/tables/" + table + "/indexes", {}, cb); }
 function getTableConstraints(table, cb)     { apiCall("get", "/tables/" + table + "/constraints", {}, cb); }
+function getTablePolicies(table, cb)        { apiCall("get", "/tables/" + table + "/policies", {}, cb); }
 function getTablesStats(cb)                 { apiCall("get", "/tables_stats", {}, cb); }
 function getFunction(id, cb)                { apiCall("get", "/functions/" + id, {}, cb); }
+function getSequence(name, cb)              { apiCall("get", "/sequences/" + name, {}, cb); }
 function getHistory(cb)                     { apiCall("get", "/history", {}, cb); }
 function getBookmarks(cb)                   { apiCall("get", "/bookmarks", {}, cb); }
 function executeQuery(query, cb)            { apiCall("post", "/query", { query: substituteQueryParameters(query) }, cb); }
-function explainQuery(query, cb)            { apiCall("post", "/explain", { query: query }, cb); }
+function explainQuery(query, cb)            { apiCall("post", "/query", { query: "EXPLAIN " + query }, cb); }
 function analyzeQuery(query, cb)            { apiCall("post", "/analyze", { query: query }, cb); }
 function disconnect(cb)                     { apiCall("post", "/disconnect", {}, cb); }
+function pingSession(cb)                    { apiCall("post", "/session/ping", {}, cb); }
 
 function encodeQuery(query) {
   return Base64.encode(query).replace(/\+/g, "-").replace(/\//g, "_").replace(/=/g, ".");
@@ -483,6 +490,7 @@ function loadLocalQueries() {
 
 function loadSchemas() {
   $("#objects").html("");
+  listenNotifications();
 
   var emptyObjectList = function() {
     return {
@@ -562,6 +570,11 @@ function unescapeHtml(str){
 }
 
 function renderCellContent(content) {
+  // Structured values, ie. intervals, are displayed with their text form
+  if (content && typeof content === 'object' && content.text !== undefined) {
+    return escapeHtml(content.text);
+  }
+
   if (!content || typeof content !== 'string') {
     return escapeHtml(content);
   }
@@ -663,21 +676,21 @@ function performViewAction(view, action, el) {
       copyToClipboard(view.split('.')[1]);
       break;
     case "copy_def":
-      executeQuery("SELECT pg_get_viewdef('" + view + "', true);", function(data) {
+      getTableStructure(view, { type: "view" }, function(data) {
         if (data.error) {
           alert(data.error);
           return;
         }
-        copyToClipboard(data.rows[0]);
+        copyToClipboard(data.rows[0][data.columns.indexOf("definition")]);
       });
       break;
     case "view_def":
-      executeQuery("SELECT pg_get_viewdef('" + view + "', true);", function(data) {
+      getTableStructure(view, { type: "view" }, function(data) {
         if (data.error) {
           alert(data.error);
           return;
         }
-        showViewDefinition(view, data.rows[0]);
+        showViewDefinition(view, data.rows[0][data.columns.indexOf("definition")]);
       });
       break;
   }
@@ -772,6 +785,10 @@ function buildTable(results, sortColumn, sortOrder, options) {
   } else {
     $("#result-rows-count").html(results.rows.length + " rows");
   }
+
+  if (results.columns_truncated) {
+    $("#result-rows-count").append(", showing " + results.columns.length + " of " + results.columns_total + " columns");
+  }
 }
 
 function setCurrentTab(id) {
@@ -850,6 +867,37 @@ function showTableConstraints() {
   });
 }
 
+function showTablePolicies() {
+  var name = getCurrentObject().name;
+
+  if (name.length == 0) {
+    alert("Please select a table!");
+    return;
+  }
+
+  getTablePolicies(name, function(data) {
+    setCurrentTab("table_policies");
+    buildTable(data);
+
+    $("#input").hide();
+    $("#body").prop("class", "full");
+    $("#results").addClass("no-crop");
+  });
+}
+
+function showSequence() {
+  var name = getCurrentObject().name;
+
+  getSequence(name, function(data) {
+    setCurrentTab("table_content");
+    buildTable(data);
+
+    $("#input").hide();
+    $("#body").prop("class", "full");
+    $("#results").addClass("no-crop");
+  });
+}
+
 function showTableInfo() {
   var name = getCurrentObject().name;
 
@@ -1103,14 +1151,47 @@ function showActivityPanel() {
 }
 
 function showQueryProgressMessage() {
-  $("#run, #explain-dropdown-toggle, #csv, #json, #xml, #load-local-query").prop("disabled", true);
+  $("#run, #explain-dropdown-toggle, #csv, #json, #xml, #xlsx, #load-local-query").prop("disabled", true);
   $("#explain-dropdown").removeClass("open");
   $("#query_progress").show();
 }
 
 function hideQueryProgressMessage() {
-  $("#run, #explain-dropdown-toggle, #csv, #json, #xml, #load-local-query").prop("disabled", false);
-  $("#query_progress").hide();
+  $("#run, #explain-dropdown-toggle, #csv, #json, #xml, #xlsx, #load-local-query").prop("disabled", false);
+  $("#query_progress").hide().text("Please wait, query is executing...");
+}
+
+// Display the message configured with --banner-text, ie. the environment warning
+function showBanner(banner) {
+  if (!banner) return;
+
+  $("#banner").
+    text(banner.text).
+    attr("title", banner.text).
+    addClass("label-" + banner.level).
+    show();
+}
+
+// Show the progress reported with NOTIFY by the running queries
+function listenNotifications() {
+  if (notificationsSocket || !window.WebSocket) return;
+
+  var url = new URL(generateURL("api/ws/notifications", {}));
+  url.protocol = url.protocol == "https:" ? "wss:" : "ws:";
+
+  notificationsSocket = new WebSocket(url.toString());
+
+  notificationsSocket.onmessage = function(event) {
+    var frame = JSON.parse(event.data);
+    if (frame.type != "notification") return;
+
+    $("#query_progress").text("Please wait, query is executing... " + frame.payload);
+  };
+
+  notificationsSocket.onclose = function() {
+    notificationsSocket = null;
+    if (connected) setTimeout(listenNotifications, notificationsReconnectDelay);
+  };
 }
 
 function getEditorSelection() {
@@ -1957,6 +2038,13 @@ $(document).ready(function() {
   bindInputResizeEvents();
   bindContentModalEvents();
 
+  // Only ping while the page is visible so abandoned sessions still expire
+  setInterval(function() {
+    if (connected && document.visibilityState === "visible") {
+      pingSession(function() {});
+    }
+  }, sessionPingInterval);
+
   // Bind event handlers only for visible tabs
   if (!isTabHidden("table_content")) {
     $("#table_content").on("click", function() { showTableContent(); });
@@ -1970,6 +2058,9 @@ $(document).ready(function() {
   if (!isTabHidden("table_constraints")) {
     $("#table_constraints").on("click", function() { showTableConstraints(); });
   }
+  if (!isTabHidden("table_policies")) {
+    $("#table_policies").on("click", function() { showTablePolicies(); });
+  }
   if (!isTabHidden("table_history")) {
     $("#table_history").on("click", function() { showQueryHistory(); });
   }
@@ -2007,6 +2098,10 @@ $(document).ready(function() {
     exportTo("xml");
   });
 
+  $("#xlsx").on("click", function() {
+    exportTo("xlsx");
+  });
+
   $("#results_view").on("click", ".copy", function() {
     copyToClipboard($(this).parent().text());
   });
@@ -2035,6 +2130,12 @@ $(document).ready(function() {
     $(".current-page").data("page", 1);
     $(".filters select, .filters input").val("");
 
+    // Sequences have no rows or structure, only their current state
+    if (currentObject.type == "sequence") {
+      showSequence();
+      return;
+    }
+
     if (currentObject.type == "function") {
       sessionStorage.setItem("tab", "table_structure");
     } else if (currentObject.type == "foreign_table") {
@@ -2054,6 +2155,9 @@ $(document).ready(function() {
       case "table_constraints":
         showTableConstraints();
         break;
+      case "table_policies":
+        showTablePolicies();
+        break;
       case "table_indexes":
         showTableIndexes();
         break;
@@ -2369,6 +2473,7 @@ $(document).ready(function() {
 
     appInfo = resp.app;
     appFeatures = resp.features;
+    showBanner(resp.banner);
 
     getConnection(function(resp) {
       if (resp.error) {